@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pablojnd/rotacion/models"
+	"github.com/pablojnd/rotacion/services"
+)
+
+// tamanoMaximoArchivo limita el tamaño de los archivos subidos (10 MB)
+const tamanoMaximoArchivo = 10 << 20
+
+// AliasHandlers contiene handlers para administrar los alias de códigos inventario-ventas
+type AliasHandlers struct {
+	aliasService *services.AliasService
+}
+
+// AliasRequest representa una solicitud de creación o actualización de alias
+type AliasRequest struct {
+	CodigoInventario string `json:"codigoInventario"`
+	CodigoVentas     string `json:"codigoVentas"`
+	Notas            string `json:"notas"`
+	Usuario          string `json:"usuario"`
+}
+
+// NewAliasHandlers crea una nueva instancia de AliasHandlers
+func NewAliasHandlers(aliasService *services.AliasService) *AliasHandlers {
+	return &AliasHandlers{aliasService: aliasService}
+}
+
+// ListarAlias devuelve todos los alias registrados
+func (h *AliasHandlers) ListarAlias(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.aliasService.Listar())
+}
+
+// ObtenerAlias devuelve el alias de un código de inventario
+func (h *AliasHandlers) ObtenerAlias(w http.ResponseWriter, r *http.Request) {
+	alias, err := h.aliasService.Obtener(mux.Vars(r)["codigo"])
+	if err != nil {
+		responderErrorAlias(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alias)
+}
+
+// GuardarAlias crea o actualiza un alias. En PUT /api/alias/{codigo} el código
+// de inventario se toma de la ruta.
+func (h *AliasHandlers) GuardarAlias(w http.ResponseWriter, r *http.Request) {
+	var req AliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if codigo := mux.Vars(r)["codigo"]; codigo != "" {
+		req.CodigoInventario = codigo
+	}
+
+	alias, creado, err := h.aliasService.Guardar(models.Alias{
+		CodigoInventario: req.CodigoInventario,
+		CodigoVentas:     req.CodigoVentas,
+		Notas:            req.Notas,
+	}, usuarioSolicitud(r, req.Usuario))
+	if err != nil {
+		responderErrorAlias(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if creado {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(alias)
+}
+
+// EliminarAlias borra el alias de un código de inventario
+func (h *AliasHandlers) EliminarAlias(w http.ResponseWriter, r *http.Request) {
+	if err := h.aliasService.Eliminar(mux.Vars(r)["codigo"]); err != nil {
+		responderErrorAlias(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportarAlias carga alias de forma masiva desde un archivo XLSX o CSV
+// enviado como multipart/form-data en el campo "archivo"
+func (h *AliasHandlers) ImportarAlias(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(tamanoMaximoArchivo); err != nil {
+		http.Error(w, "Error al leer el formulario: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	archivo, header, err := r.FormFile("archivo")
+	if err != nil {
+		http.Error(w, "Debe enviar el archivo en el campo 'archivo'", http.StatusBadRequest)
+		return
+	}
+	defer archivo.Close()
+
+	resultado, err := h.aliasService.Importar(archivo, header.Filename, usuarioSolicitud(r, r.FormValue("usuario")))
+	if err != nil {
+		log.Printf("Error al importar alias: %v", err)
+		responderErrorAlias(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resultado)
+}
+
+// usuarioSolicitud obtiene quién realiza el cambio: el valor indicado o el encabezado X-Usuario
+func usuarioSolicitud(r *http.Request, usuario string) string {
+	if usuario = strings.TrimSpace(usuario); usuario != "" {
+		return usuario
+	}
+	return strings.TrimSpace(r.Header.Get("X-Usuario"))
+}
+
+// responderErrorAlias traduce los errores del servicio de alias a códigos HTTP
+func responderErrorAlias(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrAliasNoEncontrado):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrAliasInvalido):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
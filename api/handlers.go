@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pablojnd/rotacion/db"
@@ -20,6 +22,7 @@ type Handlers struct {
 	mysql             *db.MySQLDB
 	ventasService     *services.VentasService
 	inventarioService *services.InventarioService
+	sucursalService   *services.SucursalService
 }
 
 // QueryRequest representa una solicitud de consulta
@@ -28,14 +31,22 @@ type QueryRequest struct {
 	Args  []interface{} `json:"args,omitempty"`
 }
 
-// NewHandlers crea una nueva instancia de Handlers
-func NewHandlers(sqlServer *db.SQLServerDB, mysql *db.MySQLDB) *Handlers {
-	excelService := services.NewExcelService()
+// NewHandlers crea una nueva instancia de Handlers.
+// sqlServer y mysql pueden ser nil cuando la API se ejecuta sobre fixtures;
+// en ese caso las consultas SQL directas responden 503.
+func NewHandlers(
+	sqlServer *db.SQLServerDB,
+	mysql *db.MySQLDB,
+	ventasService *services.VentasService,
+	inventarioService *services.InventarioService,
+	sucursalService *services.SucursalService,
+) *Handlers {
 	return &Handlers{
 		sqlServer:         sqlServer,
 		mysql:             mysql,
-		ventasService:     services.NewVentasService(sqlServer, excelService),
-		inventarioService: services.NewInventarioService(mysql, excelService),
+		ventasService:     ventasService,
+		inventarioService: inventarioService,
+		sucursalService:   sucursalService,
 	}
 }
 
@@ -47,6 +58,11 @@ func (h *Handlers) SQLServerQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.sqlServer == nil {
+		http.Error(w, "SQL Server no disponible en modo fixtures", http.StatusServiceUnavailable)
+		return
+	}
+
 	rows, err := h.sqlServer.ExecuteQuery(req.Query, req.Args...)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -72,6 +88,11 @@ func (h *Handlers) MySQLQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.mysql == nil {
+		http.Error(w, "MySQL no disponible en modo fixtures", http.StatusServiceUnavailable)
+		return
+	}
+
 	rows, err := h.mysql.ExecuteQuery(req.Query, req.Args...)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -89,24 +110,36 @@ func (h *Handlers) MySQLQuery(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
-// GetVentas obtiene las ventas según los filtros proporcionados
+// GetVentas obtiene las ventas según los filtros proporcionados. Por POST se limita a la lista
+// de códigos exactos del cuerpo (JSON o archivo XLSX/CSV).
 func (h *Handlers) GetVentas(w http.ResponseWriter, r *http.Request) {
 	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
 	filtro := models.VentasFiltro{
 		FechaInicio:    r.URL.Query().Get("fechaInicio"),
 		FechaFin:       r.URL.Query().Get("fechaFin"),
-		Sucursal:       parseIntParam(r.URL.Query().Get("sucursal"), 211),
+		Sucursales:     sucursales,
 		CodigoProducto: r.URL.Query().Get("codigo"),
+		ExcluirNulas:   r.URL.Query().Get("excluirNulas") == "true",
 	}
-
-	// Determinar el tipo de consulta
-	tipo := models.ConsultaVentasDetallada
-	if r.URL.Query().Get("tipo") == "agrupada" {
-		tipo = models.ConsultaVentasAgrupada
+	if r.Method == http.MethodPost {
+		if filtro.Codigos, err = utils.LeerCodigosSolicitud(w, r, tamanoMaximoArchivo); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
-	// Usar el servicio para obtener los datos
-	result, err := h.ventasService.GetVentas(filtro, tipo)
+	// Usar el servicio para obtener los datos según el tipo de consulta
+	var result interface{}
+	if r.URL.Query().Get("tipo") == string(models.ConsultaVentasAgrupada) {
+		result, err = h.ventasService.GetVentasAgrupadas(filtro)
+	} else {
+		result, err = h.ventasService.GetVentasDetalladas(filtro)
+	}
 	if err != nil {
 		log.Printf("Error al consultar ventas: %v", err)
 		http.Error(w, fmt.Sprintf("Error al ejecutar consulta: %v", err), http.StatusInternalServerError)
@@ -118,14 +151,27 @@ func (h *Handlers) GetVentas(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
-// GetVentasAgrupadas obtiene las ventas agrupadas según los filtros proporcionados
+// GetVentasAgrupadas obtiene las ventas agrupadas según los filtros proporcionados. Por POST se
+// limita a la lista de códigos exactos del cuerpo (JSON o archivo XLSX/CSV).
 func (h *Handlers) GetVentasAgrupadas(w http.ResponseWriter, r *http.Request) {
 	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
 	filtro := models.VentasFiltro{
 		FechaInicio:    r.URL.Query().Get("fechaInicio"),
 		FechaFin:       r.URL.Query().Get("fechaFin"),
-		Sucursal:       parseIntParam(r.URL.Query().Get("sucursal"), 211),
+		Sucursales:     sucursales,
 		CodigoProducto: r.URL.Query().Get("codigo"),
+		ExcluirNulas:   r.URL.Query().Get("excluirNulas") == "true",
+	}
+	if r.Method == http.MethodPost {
+		if filtro.Codigos, err = utils.LeerCodigosSolicitud(w, r, tamanoMaximoArchivo); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
 	// Usar el servicio para obtener los datos
@@ -141,15 +187,225 @@ func (h *Handlers) GetVentasAgrupadas(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
-// GetInventario obtiene el inventario según los filtros proporcionados
-func (h *Handlers) GetInventario(w http.ResponseWriter, r *http.Request) {
+// GetSerieVentas obtiene la serie de ventas por producto y período (día, semana o mes)
+func (h *Handlers) GetSerieVentas(w http.ResponseWriter, r *http.Request) {
 	// Obtener parámetros de la consulta
-	anio := parseIntParam(r.URL.Query().Get("anio"), time.Now().Year())
-	codigoProducto := r.URL.Query().Get("codigo")
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	filtro := models.VentasFiltro{
+		FechaInicio:    r.URL.Query().Get("fechaInicio"),
+		FechaFin:       r.URL.Query().Get("fechaFin"),
+		Sucursales:     sucursales,
+		CodigoProducto: r.URL.Query().Get("codigo"),
+		ExcluirNulas:   r.URL.Query().Get("excluirNulas") == "true",
+	}
+	granularidad, err := models.ParseGranularidad(r.URL.Query().Get("granularidad"))
+	if err == nil {
+		err = filtro.Validar()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	filtro := models.InventarioFiltro{
-		Anio:           anio,
-		CodigoProducto: codigoProducto,
+	// Usar el servicio para obtener la serie
+	result, err := h.ventasService.GetSerieVentas(filtro, granularidad)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSerieNoValida) {
+			status = http.StatusBadRequest
+		}
+		log.Printf("Error al consultar serie de ventas: %v", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Devolver resultados
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetPronostico pronostica la demanda mensual de cada producto a partir de la historia de ventas
+func (h *Handlers) GetPronostico(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	params := parseParametrosPronostico(r)
+	if err := params.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// La historia termina en fechaFin (por defecto, hoy)
+	filtro := models.VentasFiltro{
+		FechaFin:       r.URL.Query().Get("fechaFin"),
+		Sucursales:     sucursales,
+		CodigoProducto: r.URL.Query().Get("codigo"),
+	}
+	if filtro.FechaFin == "" {
+		filtro.FechaFin = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", filtro.FechaFin); err != nil {
+		http.Error(w, "fecha de fin no válida, debe estar en formato YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.ventasService.GenerarPronostico(filtro, params)
+	if err != nil {
+		log.Printf("Error al generar pronóstico: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Devolver resultados
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetPromociones analiza la efectividad de precios y ofertas de cada producto en el período
+func (h *Handlers) GetPromociones(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	filtro := models.VentasFiltro{
+		FechaInicio:    r.URL.Query().Get("fechaInicio"),
+		FechaFin:       r.URL.Query().Get("fechaFin"),
+		Sucursales:     sucursales,
+		CodigoProducto: r.URL.Query().Get("codigo"),
+	}
+	params := parseParametrosPromocion(r)
+	err = filtro.Validar()
+	if err == nil {
+		err = params.Validar()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Usar el servicio para analizar los precios
+	result, err := h.ventasService.GenerarAnalisisPromociones(filtro, params)
+	if err != nil {
+		log.Printf("Error al analizar promociones: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Devolver resultados
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseParametrosPromocion lee la tolerancia, la brecha de los períodos de oferta, la ventana
+// de días base y los días de posible quiebre;
+// los valores vacíos quedan en 0 y se completan al validar
+func parseParametrosPromocion(r *http.Request) models.ParametrosPromocion {
+	tolerancia, _ := strconv.ParseFloat(r.URL.Query().Get("tolerancia"), 64)
+	return models.ParametrosPromocion{
+		ToleranciaPct:   tolerancia,
+		BrechaDias:      parseIntParam(r.URL.Query().Get("brecha"), 0),
+		VentanaBaseDias: parseIntParam(r.URL.Query().Get("ventanaBase"), 0),
+		QuiebreDias:     parseIntParam(r.URL.Query().Get("quiebre"), 0),
+	}
+}
+
+// GetClientes obtiene el ranking de clientes del período con su segmentación RFM
+func (h *Handlers) GetClientes(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	filtro := models.VentasFiltro{
+		FechaInicio: r.URL.Query().Get("fechaInicio"),
+		FechaFin:    r.URL.Query().Get("fechaFin"),
+		Sucursales:  sucursales,
+	}
+	params := models.ParametrosClientes{
+		Orden:     r.URL.Query().Get("orden"),
+		Top:       parseIntParam(r.URL.Query().Get("top"), 0),
+		Productos: parseIntParam(r.URL.Query().Get("productos"), 0),
+	}
+	err = filtro.Validar()
+	if err == nil {
+		err = params.Validar()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Usar el servicio para generar el reporte
+	result, err := h.ventasService.GenerarReporteClientes(filtro, params)
+	if err != nil {
+		log.Printf("Error al generar reporte de clientes: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Devolver resultados
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetDocumentos desglosa las ventas por tipo de documento e informa los documentos anulados
+func (h *Handlers) GetDocumentos(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	filtro := models.VentasFiltro{
+		FechaInicio:    r.URL.Query().Get("fechaInicio"),
+		FechaFin:       r.URL.Query().Get("fechaFin"),
+		Sucursales:     sucursales,
+		CodigoProducto: r.URL.Query().Get("codigo"),
+	}
+	if err := filtro.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Usar el servicio para generar el desglose
+	result, err := h.ventasService.GenerarReporteDocumentos(filtro)
+	if err != nil {
+		log.Printf("Error al generar desglose por documento: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Devolver resultados
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetInventario obtiene el inventario según los filtros proporcionados. Por POST se limita a la
+// lista de códigos exactos del cuerpo (JSON o archivo XLSX/CSV).
+func (h *Handlers) GetInventario(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	filtro := parseInventarioFiltro(r)
+	if err := filtro.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPost {
+		codigos, err := utils.LeerCodigosSolicitud(w, r, tamanoMaximoArchivo)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filtro.Codigos = codigos
 	}
 
 	// Usar el servicio para obtener los datos
@@ -165,6 +421,20 @@ func (h *Handlers) GetInventario(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// parseInventarioFiltro construye el filtro de inventario: año o rango de años de producción,
+// rango de fechas de ingreso, código y desglose por año
+func parseInventarioFiltro(r *http.Request) models.InventarioFiltro {
+	return models.InventarioFiltro{
+		Anio:              parseIntParam(r.URL.Query().Get("anio"), time.Now().Year()),
+		CodigoProducto:    r.URL.Query().Get("codigo"),
+		AnioDesde:         parseIntParam(r.URL.Query().Get("anioDesde"), 0),
+		AnioHasta:         parseIntParam(r.URL.Query().Get("anioHasta"), 0),
+		FechaIngresoDesde: r.URL.Query().Get("fechaIngresoDesde"),
+		FechaIngresoHasta: r.URL.Query().Get("fechaIngresoHasta"),
+		DesglosePorAnio:   r.URL.Query().Get("desglosePorAnio") == "true",
+	}
+}
+
 // parseIntParam convierte un string a int con valor predeterminado
 func parseIntParam(value string, defaultValue int) int {
 	if value == "" {
@@ -178,3 +448,25 @@ func parseIntParam(value string, defaultValue int) int {
 
 	return intValue
 }
+
+// parseListParam convierte una lista separada por comas en un slice, omitiendo elementos vacíos
+func parseListParam(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseFloatListParam convierte una lista de números separados por comas; ignora los valores no numéricos
+func parseFloatListParam(value string) []float64 {
+	var values []float64
+	for _, item := range parseListParam(value) {
+		if f, err := strconv.ParseFloat(item, 64); err == nil {
+			values = append(values, f)
+		}
+	}
+	return values
+}
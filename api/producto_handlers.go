@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pablojnd/rotacion/models"
+	"github.com/pablojnd/rotacion/services"
+)
+
+// ProductoHandlers contiene handlers para consultas de un producto específico
+type ProductoHandlers struct {
+	reporteService  *services.ReporteService
+	sucursalService *services.SucursalService
+}
+
+// NewProductoHandlers crea una nueva instancia de ProductoHandlers
+func NewProductoHandlers(reporteService *services.ReporteService, sucursalService *services.SucursalService) *ProductoHandlers {
+	return &ProductoHandlers{reporteService: reporteService, sucursalService: sucursalService}
+}
+
+// ObtenerLotes devuelve los lotes (Zeta) de un producto con unidades ingresadas, vendidas y restantes
+func (h *ProductoHandlers) ObtenerLotes(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	reporte, err := h.reporteService.GenerarReporteLotes(filtro, mux.Vars(r)["codigo"])
+	if err != nil {
+		if errors.Is(err, services.ErrProductoNoEncontrado) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Error al obtener lotes: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reporte)
+}
+
+// ObtenerFichaProducto devuelve la vista completa de un producto: datos maestros, ingresos, ventas,
+// serie mensual, código emparejado, rotación y documentos recientes
+func (h *ProductoHandlers) ObtenerFichaProducto(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	params := models.ParametrosFichaProducto{
+		Documentos: parseIntParam(r.URL.Query().Get("documentos"), 0),
+	}
+	if err := params.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ficha, err := h.reporteService.GenerarFichaProducto(filtro, mux.Vars(r)["codigo"], params)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProductoNoEncontrado):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrSerieNoValida):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("Error al obtener ficha de producto: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ficha)
+}
@@ -2,52 +2,47 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/pablojnd/rotacion/models"
 	"github.com/pablojnd/rotacion/services"
+	"github.com/pablojnd/rotacion/utils"
 )
 
 // ReporteHandlers contiene handlers para las operaciones de reportes combinados
 type ReporteHandlers struct {
-	reporteService *services.ReporteService
+	reporteService  *services.ReporteService
+	sucursalService *services.SucursalService
 }
 
 // NewReporteHandlers crea una nueva instancia de ReporteHandlers
-func NewReporteHandlers(reporteService *services.ReporteService) *ReporteHandlers {
-	return &ReporteHandlers{reporteService: reporteService}
+func NewReporteHandlers(reporteService *services.ReporteService, sucursalService *services.SucursalService) *ReporteHandlers {
+	return &ReporteHandlers{reporteService: reporteService, sucursalService: sucursalService}
 }
 
-// ObtenerReporteCombinado obtiene un reporte que combina datos de inventario y ventas
+// ObtenerReporteCombinado obtiene un reporte que combina datos de inventario y ventas, opcionalmente
+// filtrado por marca, categoría, dimensiones y umbrales
 func (h *ReporteHandlers) ObtenerReporteCombinado(w http.ResponseWriter, r *http.Request) {
-	// Obtener parámetros de consulta
-	anio := parseIntParam(r.URL.Query().Get("anio"), time.Now().Year())
-	fechaInicio := r.URL.Query().Get("fechaInicio")
-	fechaFin := r.URL.Query().Get("fechaFin")
-	sucursal := parseIntParam(r.URL.Query().Get("sucursal"), 211)
-	codigoProducto := r.URL.Query().Get("codigo")
-
-	// Si no se proporcionaron fechas, usar el año actual
-	if fechaInicio == "" {
-		fechaInicio = time.Date(anio, 1, 1, 0, 0, 0, 0, time.Local).Format("2006-01-02")
-	}
-	if fechaFin == "" {
-		fechaFin = time.Date(anio, 12, 31, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+	// Crear filtro a partir de los parámetros de consulta
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
 	}
 
-	// Crear filtro
-	filtro := models.ReporteFiltro{
-		Anio:           anio,
-		FechaInicio:    fechaInicio,
-		FechaFin:       fechaFin,
-		Sucursal:       sucursal,
-		CodigoProducto: codigoProducto,
+	productos, err := parseFiltroProductos(r)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
 	}
 
 	// Obtener reporte
-	reportesCoincidentes, reportesSinCoincidencia, err := h.reporteService.GenerarReporteCombinado(filtro)
+	reportesCoincidentes, reportesSinCoincidencia, err := h.reporteService.GenerarReporteCombinadoFiltrado(filtro, productos)
 	if err != nil {
 		log.Printf("Error al generar reporte combinado: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -68,14 +63,382 @@ func (h *ReporteHandlers) ObtenerReporteCombinado(w http.ResponseWriter, r *http
 	json.NewEncoder(w).Encode(result)
 }
 
-// ExportarReporteCombinado exporta un reporte combinado a Excel
+// ExportarReporteCombinado exporta un reporte combinado a Excel con los mismos filtros que el JSON
 func (h *ReporteHandlers) ExportarReporteCombinado(w http.ResponseWriter, r *http.Request) {
+	// Crear filtro a partir de los parámetros de consulta
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	productos, err := parseFiltroProductos(r)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	// Generar Excel
+	excelBytes, filename, err := h.reporteService.ExportarReporteCombinado(filtro, productos)
+	if err != nil {
+		log.Printf("Error al exportar reporte combinado: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Enviar respuesta
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// ObtenerReporteABC clasifica los productos en clases ABC (y opcionalmente XYZ)
+func (h *ReporteHandlers) ObtenerReporteABC(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	// Parámetros de clasificación; los no indicados usan la configuración del servidor
+	params := models.ParametrosABC{
+		Criterio:    r.URL.Query().Get("criterio"),
+		CalcularXYZ: r.URL.Query().Get("xyz") == "true",
+	}
+	if umbrales := parseFloatListParam(r.URL.Query().Get("umbrales")); len(umbrales) >= 2 {
+		params.UmbralA, params.UmbralB = &umbrales[0], &umbrales[1]
+	}
+	if umbrales := parseFloatListParam(r.URL.Query().Get("umbralesXYZ")); len(umbrales) >= 2 {
+		params.UmbralX, params.UmbralY = &umbrales[0], &umbrales[1]
+	}
+
+	params.Completar(h.reporteService.ParametrosABC())
+	if err := params.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reporte, err := h.reporteService.GenerarReporteABC(filtro, params)
+	if err != nil {
+		log.Printf("Error al generar reporte ABC: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reporte)
+}
+
+// ObtenerReporteInmovilizado lista los productos con stock detenido o de baja rotación
+func (h *ReporteHandlers) ObtenerReporteInmovilizado(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	params := parseParametrosInmovilizado(r)
+	if err := params.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reporte, err := h.reporteService.GenerarReporteInmovilizado(filtro, params)
+	if err != nil {
+		log.Printf("Error al generar reporte de inmovilizado: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reporte)
+}
+
+// ExportarReporteInmovilizado exporta el reporte de stock inmovilizado a Excel
+func (h *ReporteHandlers) ExportarReporteInmovilizado(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	params := parseParametrosInmovilizado(r)
+	if err := params.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	excelBytes, filename, err := h.reporteService.ExportarReporteInmovilizado(filtro, params)
+	if err != nil {
+		log.Printf("Error al exportar reporte de inmovilizado: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// ObtenerReporteReposicion calcula stock de seguridad, punto de reorden y pedido sugerido por producto
+func (h *ReporteHandlers) ObtenerReporteReposicion(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	params := parseParametrosReposicion(r)
+	if err := params.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reporte, err := h.reporteService.GenerarReporteReposicion(filtro, params)
+	if err != nil {
+		log.Printf("Error al generar reporte de reposición: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reporte)
+}
+
+// ExportarReporteReposicion exporta la sugerencia de compra agrupada por marca a Excel
+func (h *ReporteHandlers) ExportarReporteReposicion(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+	params := parseParametrosReposicion(r)
+	if err := params.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	excelBytes, filename, err := h.reporteService.ExportarReporteReposicion(filtro, params)
+	if err != nil {
+		log.Printf("Error al exportar reporte de reposición: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// ObtenerReporteRentabilidad calcula margen, markup y contribución por producto, marca, categoría y dimensiones
+func (h *ReporteHandlers) ObtenerReporteRentabilidad(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	reporte, err := h.reporteService.GenerarReporteRentabilidad(filtro)
+	if err != nil {
+		log.Printf("Error al generar reporte de rentabilidad: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("soloBajoCosto") == "true" {
+		productos := reporte.Productos[:0]
+		for _, p := range reporte.Productos {
+			if p.BajoCosto || p.LineasBajoCosto > 0 {
+				productos = append(productos, p)
+			}
+		}
+		reporte.Productos = productos
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reporte)
+}
+
+// ExportarReporteRentabilidad exporta la rentabilidad con subtotales por marca, categoría y dimensiones a Excel
+func (h *ReporteHandlers) ExportarReporteRentabilidad(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseReporteFiltro(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	excelBytes, filename, err := h.reporteService.ExportarReporteRentabilidad(filtro)
+	if err != nil {
+		log.Printf("Error al exportar reporte de rentabilidad: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// ObtenerStock calcula el stock disponible de cada producto y lote a una fecha de corte
+func (h *ReporteHandlers) ObtenerStock(w http.ResponseWriter, r *http.Request) {
+	filtro := models.StockFiltro{
+		Fecha:          r.URL.Query().Get("fecha"),
+		CodigoProducto: r.URL.Query().Get("codigo"),
+		Estrategias:    parseListParam(r.URL.Query().Get("estrategias")),
+	}
+	if err := filtro.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reporte, err := h.reporteService.GenerarReporteStock(filtro)
+	if err != nil {
+		log.Printf("Error al calcular stock a la fecha: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Opcionalmente, solo los productos con stock negativo (en total o en algún lote)
+	if r.URL.Query().Get("soloNegativos") == "true" {
+		negativos := make([]models.StockProducto, 0)
+		for _, p := range reporte.Productos {
+			conLoteNegativo := false
+			for _, lote := range p.Lotes {
+				conLoteNegativo = conLoteNegativo || lote.StockNegativo
+			}
+			if p.StockNegativo || conLoteNegativo {
+				negativos = append(negativos, p)
+			}
+		}
+		reporte.Productos = negativos
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reporte)
+}
+
+// ObtenerComparacionSucursales compara ventas y rotación de cada producto entre sucursales
+func (h *ReporteHandlers) ObtenerComparacionSucursales(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseFiltroComparacion(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	comparacion, err := h.reporteService.GenerarComparacionSucursales(filtro)
+	if err != nil {
+		log.Printf("Error al comparar sucursales: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparacion)
+}
+
+// ExportarComparacionSucursales exporta la comparación entre sucursales a Excel
+func (h *ReporteHandlers) ExportarComparacionSucursales(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseFiltroComparacion(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	excelBytes, filename, err := h.reporteService.ExportarComparacionSucursales(filtro)
+	if err != nil {
+		log.Printf("Error al exportar comparación de sucursales: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// ObtenerComparacionPeriodos compara ventas, margen y rotación de cada producto entre dos períodos
+func (h *ReporteHandlers) ObtenerComparacionPeriodos(w http.ResponseWriter, r *http.Request) {
+	filtro, params, err := parseComparacionPeriodos(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	comparacion, err := h.reporteService.GenerarComparacionPeriodos(filtro, params)
+	if err != nil {
+		log.Printf("Error al comparar períodos: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparacion)
+}
+
+// ExportarComparacionPeriodos exporta la comparación entre períodos a Excel
+func (h *ReporteHandlers) ExportarComparacionPeriodos(w http.ResponseWriter, r *http.Request) {
+	filtro, params, err := parseComparacionPeriodos(w, r, h.sucursalService)
+	if err != nil {
+		http.Error(w, err.Error(), estadoErrorFiltro(err))
+		return
+	}
+
+	excelBytes, filename, err := h.reporteService.ExportarComparacionPeriodos(filtro, params)
+	if err != nil {
+		log.Printf("Error al exportar comparación de períodos: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// parseComparacionPeriodos construye el filtro del período actual y los parámetros del anterior
+func parseComparacionPeriodos(w http.ResponseWriter, r *http.Request, sucursalService *services.SucursalService) (models.ReporteFiltro, models.ParametrosComparacionPeriodos, error) {
+	params := models.ParametrosComparacionPeriodos{
+		Modo:                r.URL.Query().Get("modo"),
+		FechaInicioAnterior: r.URL.Query().Get("fechaInicioAnterior"),
+		FechaFinAnterior:    r.URL.Query().Get("fechaFinAnterior"),
+		AnioAnterior:        parseIntParam(r.URL.Query().Get("anioAnterior"), 0),
+	}
+	filtro, err := parseReporteFiltro(w, r, sucursalService)
+	if err != nil {
+		return filtro, params, err
+	}
+	if err := params.Validar(); err != nil {
+		return filtro, params, fmt.Errorf("%w: %v", errParametroNoValido, err)
+	}
+	for _, fecha := range []string{filtro.FechaInicio, filtro.FechaFin} {
+		if _, err := time.Parse("2006-01-02", fecha); err != nil {
+			return filtro, params, fmt.Errorf("%w: fechaInicio y fechaFin deben estar en formato YYYY-MM-DD", errParametroNoValido)
+		}
+	}
+	return filtro, params, nil
+}
+
+// parseFiltroComparacion construye el filtro de la comparación; sin sucursal compara todas
+func parseFiltroComparacion(w http.ResponseWriter, r *http.Request, sucursalService *services.SucursalService) (models.ReporteFiltro, error) {
+	filtro, err := parseReporteFiltro(w, r, sucursalService)
+	if err != nil {
+		return filtro, err
+	}
+	if r.URL.Query().Get("sucursal") == "" {
+		filtro.Sucursales = nil
+	}
+	return filtro, nil
+}
+
+// parseParametrosInmovilizado obtiene los umbrales del reporte de inmovilizado; 0 usa el valor por defecto
+func parseParametrosInmovilizado(r *http.Request) models.ParametrosInmovilizado {
+	sellThrough, _ := strconv.ParseFloat(r.URL.Query().Get("sellThroughMinimo"), 64)
+	return models.ParametrosInmovilizado{
+		DiasSinVenta:      parseIntParam(r.URL.Query().Get("diasSinVenta"), 0),
+		DiasMinimos:       parseIntParam(r.URL.Query().Get("diasMinimos"), 0),
+		SellThroughMinimo: sellThrough,
+	}
+}
+
+// parseReporteFiltro construye el filtro del reporte combinado a partir de los parámetros de consulta.
+// En w se informa la fila descartada de un archivo de códigos (ver utils.LeerCodigosSolicitud).
+func parseReporteFiltro(w http.ResponseWriter, r *http.Request, sucursalService *services.SucursalService) (models.ReporteFiltro, error) {
 	// Obtener parámetros de consulta
 	anio := parseIntParam(r.URL.Query().Get("anio"), time.Now().Year())
 	fechaInicio := r.URL.Query().Get("fechaInicio")
 	fechaFin := r.URL.Query().Get("fechaFin")
-	sucursal := parseIntParam(r.URL.Query().Get("sucursal"), 211)
-	codigoProducto := r.URL.Query().Get("codigo")
+
+	// Con fechas, el año no se usa para el período ni para el inventario: debe ser coherente
+	if r.URL.Query().Get("anio") != "" {
+		if err := validarAnioPeriodo(anio, fechaInicio, fechaFin); err != nil {
+			return models.ReporteFiltro{}, fmt.Errorf("%w: %v", errParametroNoValido, err)
+		}
+	}
 
 	// Si no se proporcionaron fechas, usar el año actual
 	if fechaInicio == "" {
@@ -85,23 +448,159 @@ func (h *ReporteHandlers) ExportarReporteCombinado(w http.ResponseWriter, r *htt
 		fechaFin = time.Date(anio, 12, 31, 0, 0, 0, 0, time.Local).Format("2006-01-02")
 	}
 
-	// Crear filtro
+	sucursales, err := sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		return models.ReporteFiltro{}, err
+	}
+
 	filtro := models.ReporteFiltro{
 		Anio:           anio,
 		FechaInicio:    fechaInicio,
 		FechaFin:       fechaFin,
-		Sucursal:       sucursal,
-		CodigoProducto: codigoProducto,
+		Sucursales:     sucursales,
+		CodigoProducto: r.URL.Query().Get("codigo"),
+		Estrategias:    parseListParam(r.URL.Query().Get("estrategias")),
+		ExcluirNulas:   r.URL.Query().Get("excluirNulas") == "true",
+
+		AnioInventarioDesde: parseIntParam(r.URL.Query().Get("anioInventarioDesde"), 0),
+		AnioInventarioHasta: parseIntParam(r.URL.Query().Get("anioInventarioHasta"), 0),
+		DesglosePorAnio:     r.URL.Query().Get("desglosePorAnio") == "true",
 	}
 
-	// Generar Excel
-	excelBytes, filename, err := h.reporteService.ExportarReporteCombinado(filtro)
+	// Años de producción previos al período considerados por defecto (sin límite si se omite)
+	if valor := r.URL.Query().Get("aniosProduccionPrevios"); valor != "" {
+		previos, err := strconv.Atoi(valor)
+		if err != nil || previos < 0 {
+			return filtro, fmt.Errorf("%w: aniosProduccionPrevios debe ser un entero mayor o igual a 0", errParametroNoValido)
+		}
+		filtro.AniosProduccionPrevios = &previos
+	}
+
+	// Inventario considerado: rango explícito o lotes vendibles en el período
+	filtroInventario := filtro.FiltroInventario()
+	if err := filtroInventario.Validar(); err != nil {
+		return filtro, fmt.Errorf("%w: %v", errParametroNoValido, err)
+	}
+
+	// Stock disponible a una fecha de corte opcional
+	if fecha := r.URL.Query().Get("stockAFecha"); fecha != "" {
+		if _, err := time.Parse("2006-01-02", fecha); err != nil {
+			return filtro, fmt.Errorf("%w: stockAFecha debe estar en formato YYYY-MM-DD", errParametroNoValido)
+		}
+		filtro.StockAFecha = fecha
+	}
+
+	// Lista de códigos exactos en el cuerpo de las consultas POST
+	if r.Method == http.MethodPost {
+		codigos, err := utils.LeerCodigosSolicitud(w, r, tamanoMaximoArchivo)
+		if err != nil {
+			return filtro, fmt.Errorf("%w: %v", errParametroNoValido, err)
+		}
+		filtro.Codigos = codigos
+	}
+
+	// Pronóstico de demanda opcional
+	if r.URL.Query().Get("pronostico") == "true" {
+		params := parseParametrosPronostico(r)
+		if err := params.Validar(); err != nil {
+			return filtro, fmt.Errorf("%w: %v", errParametroNoValido, err)
+		}
+		filtro.Pronostico = &params
+	}
+
+	return filtro, nil
+}
+
+// validarAnioPeriodo verifica que el año indicado esté dentro de los años de las fechas del
+// período; las fechas vacías o no válidas no se comparan
+func validarAnioPeriodo(anio int, fechaInicio, fechaFin string) error {
+	if inicio, err := time.Parse("2006-01-02", fechaInicio); err == nil && anio < inicio.Year() {
+		return fmt.Errorf("anio %d es anterior a fechaInicio %s", anio, fechaInicio)
+	}
+	if fin, err := time.Parse("2006-01-02", fechaFin); err == nil && anio > fin.Year() {
+		return fmt.Errorf("anio %d es posterior a fechaFin %s", anio, fechaFin)
+	}
+	return nil
+}
+
+// parseFiltroProductos obtiene los filtros por atributos y umbrales del reporte combinado
+func parseFiltroProductos(r *http.Request) (models.FiltroProductos, error) {
+	q := r.URL.Query()
+	productos := models.FiltroProductos{
+		Marcas:              parseListParam(q.Get("marca")),
+		Categorias:          parseListParam(q.Get("categoria")),
+		Dimensiones:         parseListParam(q.Get("dimensiones")),
+		SoloSinCoincidencia: q.Get("soloSinCoincidencia") == "true",
+		SoloConStock:        q.Get("soloConStock") == "true",
+	}
+
+	var err error
+	if productos.DiasInventarioMin, err = parseUmbralEntero(q, "diasInventarioMin"); err != nil {
+		return productos, err
+	}
+	if productos.DiasInventarioMax, err = parseUmbralEntero(q, "diasInventarioMax"); err != nil {
+		return productos, err
+	}
+	if productos.PorcentajeVendidoMin, err = parseUmbral(q, "porcentajeVendidoMin"); err != nil {
+		return productos, err
+	}
+	if productos.PorcentajeVendidoMax, err = parseUmbral(q, "porcentajeVendidoMax"); err != nil {
+		return productos, err
+	}
+	if productos.VentaMinimaClp, err = parseUmbralEntero(q, "ventaMinimaClp"); err != nil {
+		return productos, err
+	}
+
+	if err := productos.Validar(); err != nil {
+		return productos, fmt.Errorf("%w: %v", errParametroNoValido, err)
+	}
+	return productos, nil
+}
+
+// parseUmbral interpreta un umbral numérico opcional; nil si no se indicó
+func parseUmbral(q url.Values, nombre string) (*float64, error) {
+	valor := q.Get(nombre)
+	if valor == "" {
+		return nil, nil
+	}
+	umbral, err := strconv.ParseFloat(valor, 64)
 	if err != nil {
-		log.Printf("Error al exportar reporte combinado: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("%w: %s debe ser numérico", errParametroNoValido, nombre)
 	}
+	return &umbral, nil
+}
 
-	// Enviar respuesta
-	services.SendExcelResponse(w, excelBytes, filename)
+// parseUmbralEntero interpreta un umbral entero opcional; nil si no se indicó
+func parseUmbralEntero(q url.Values, nombre string) (*int, error) {
+	valor := q.Get(nombre)
+	if valor == "" {
+		return nil, nil
+	}
+	umbral, err := strconv.Atoi(valor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s debe ser un número entero", errParametroNoValido, nombre)
+	}
+	return &umbral, nil
+}
+
+// parseParametrosReposicion obtiene los ajustes del reporte de reposición; 0 usa la configuración del servidor
+func parseParametrosReposicion(r *http.Request) models.ParametrosReposicion {
+	nivelServicio, _ := strconv.ParseFloat(r.URL.Query().Get("nivelServicio"), 64)
+	return models.ParametrosReposicion{
+		NivelServicio: nivelServicio,
+		LeadTimeDias:  parseIntParam(r.URL.Query().Get("leadTime"), 0),
+		DiasCobertura: parseIntParam(r.URL.Query().Get("diasCobertura"), 0),
+		Marca:         r.URL.Query().Get("marca"),
+	}
+}
+
+// parseParametrosPronostico obtiene los parámetros del pronóstico; 0 usa el valor por defecto
+func parseParametrosPronostico(r *http.Request) models.ParametrosPronostico {
+	nivel, _ := strconv.ParseFloat(r.URL.Query().Get("nivel"), 64)
+	return models.ParametrosPronostico{
+		Horizonte: parseIntParam(r.URL.Query().Get("horizonte"), 0),
+		Historia:  parseIntParam(r.URL.Query().Get("historia"), 0),
+		Nivel:     nivel,
+		Metodo:    r.URL.Query().Get("metodo"),
+	}
 }
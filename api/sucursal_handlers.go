@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+	"github.com/pablojnd/rotacion/services"
+)
+
+// SucursalHandlers contiene handlers para el catálogo de sucursales
+type SucursalHandlers struct {
+	sucursalService *services.SucursalService
+}
+
+// NewSucursalHandlers crea una nueva instancia de SucursalHandlers
+func NewSucursalHandlers(sucursalService *services.SucursalService) *SucursalHandlers {
+	return &SucursalHandlers{sucursalService: sucursalService}
+}
+
+// ListarSucursales devuelve las sucursales del ERP e indica cuáles tienen ventas en el período
+func (h *SucursalHandlers) ListarSucursales(w http.ResponseWriter, r *http.Request) {
+	// Si no se proporcionaron fechas, usar el año actual
+	anio := time.Now().Year()
+	fechaInicio := r.URL.Query().Get("fechaInicio")
+	fechaFin := r.URL.Query().Get("fechaFin")
+	if fechaInicio == "" {
+		fechaInicio = time.Date(anio, 1, 1, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+	}
+	if fechaFin == "" {
+		fechaFin = time.Date(anio, 12, 31, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+	}
+
+	periodo := models.VentasFiltro{FechaInicio: fechaInicio, FechaFin: fechaFin}
+	if err := periodo.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	catalogo, err := h.sucursalService.ListarSucursales(fechaInicio, fechaFin)
+	if err != nil {
+		log.Printf("Error al listar sucursales: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(catalogo)
+}
+
+// errParametroNoValido indica que un parámetro de la solicitud no es válido
+var errParametroNoValido = errors.New("parámetro no válido")
+
+// estadoErrorFiltro devuelve 400 si la sucursal u otro parámetro del filtro no es válido,
+// o 500 si falló la consulta del catálogo de sucursales
+func estadoErrorFiltro(err error) int {
+	if errors.Is(err, services.ErrSucursalNoValida) || errors.Is(err, errParametroNoValido) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pablojnd/rotacion/models"
+	"github.com/pablojnd/rotacion/services"
+)
+
+// TipoCambioHandlers contiene handlers para administrar los tipos de cambio USD→CLP
+type TipoCambioHandlers struct {
+	tipoCambioService *services.TipoCambioService
+}
+
+// TipoCambioRequest representa una solicitud de creación o actualización de tipo de cambio
+type TipoCambioRequest struct {
+	Fecha   string  `json:"fecha"`
+	Valor   float64 `json:"valor"`
+	Fuente  string  `json:"fuente"`
+	Usuario string  `json:"usuario"`
+}
+
+// NewTipoCambioHandlers crea una nueva instancia de TipoCambioHandlers
+func NewTipoCambioHandlers(tipoCambioService *services.TipoCambioService) *TipoCambioHandlers {
+	return &TipoCambioHandlers{tipoCambioService: tipoCambioService}
+}
+
+// ListarTiposCambio devuelve los tipos de cambio registrados, opcionalmente entre desde y hasta
+func (h *TipoCambioHandlers) ListarTiposCambio(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.tipoCambioService.Listar(query.Get("desde"), query.Get("hasta")))
+}
+
+// ConsultarTipoCambio devuelve el tipo de cambio aplicable a una fecha
+func (h *TipoCambioHandlers) ConsultarTipoCambio(w http.ResponseWriter, r *http.Request) {
+	aplicado, err := h.tipoCambioService.Consultar(mux.Vars(r)["fecha"])
+	if err != nil {
+		responderErrorTipoCambio(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(aplicado)
+}
+
+// GuardarTipoCambio crea o actualiza el tipo de cambio de una fecha. En PUT
+// /api/tipo-cambio/{fecha} la fecha se toma de la ruta.
+func (h *TipoCambioHandlers) GuardarTipoCambio(w http.ResponseWriter, r *http.Request) {
+	var req TipoCambioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if fecha := mux.Vars(r)["fecha"]; fecha != "" {
+		req.Fecha = fecha
+	}
+	if req.Fuente == "" {
+		req.Fuente = "manual"
+	}
+
+	tc, creado, err := h.tipoCambioService.Guardar(models.TipoCambio{
+		Fecha:  req.Fecha,
+		Valor:  req.Valor,
+		Fuente: req.Fuente,
+	}, usuarioSolicitud(r, req.Usuario))
+	if err != nil {
+		responderErrorTipoCambio(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if creado {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(tc)
+}
+
+// EliminarTipoCambio borra el tipo de cambio de una fecha
+func (h *TipoCambioHandlers) EliminarTipoCambio(w http.ResponseWriter, r *http.Request) {
+	if err := h.tipoCambioService.Eliminar(mux.Vars(r)["fecha"]); err != nil {
+		responderErrorTipoCambio(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportarTiposCambio carga tipos de cambio de forma masiva desde un archivo XLSX o CSV
+// enviado como multipart/form-data en el campo "archivo"
+func (h *TipoCambioHandlers) ImportarTiposCambio(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(tamanoMaximoArchivo); err != nil {
+		http.Error(w, "Error al leer el formulario: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	archivo, header, err := r.FormFile("archivo")
+	if err != nil {
+		http.Error(w, "Debe enviar el archivo en el campo 'archivo'", http.StatusBadRequest)
+		return
+	}
+	defer archivo.Close()
+
+	resultado, err := h.tipoCambioService.Importar(archivo, header.Filename, usuarioSolicitud(r, r.FormValue("usuario")))
+	if err != nil {
+		log.Printf("Error al importar tipos de cambio: %v", err)
+		responderErrorTipoCambio(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resultado)
+}
+
+// responderErrorTipoCambio traduce los errores del servicio de tipos de cambio a códigos HTTP
+func responderErrorTipoCambio(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrTipoCambioNoEncontrado):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrTipoCambioInvalido):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
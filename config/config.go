@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -25,6 +27,57 @@ type Config struct {
 
 	// Server
 	ServerPort string
+
+	// Origen de datos: "sql" (SQL Server + MySQL) o "fixtures" (archivos locales)
+	DataSource  string
+	FixturesDir string
+
+	// Origen del historial de ingresos: "json" (columna GROUP_CONCAT) o "filas" (consulta por ingreso)
+	HistorialModo string
+
+	// Sucursal usada cuando una consulta no indica sucursal
+	SucursalDefecto int
+
+	// Directorio para datos locales de la API (alias de códigos, etc.)
+	DataDir string
+
+	// Emparejamiento de códigos inventario-ventas
+	MatchEstrategias     []string          // Orden de estrategias a aplicar
+	MatchPrefijoMinimo   int               // Largo mínimo del prefijo común para la estrategia "prefijo"
+	MatchDistanciaMaxima int               // Distancia de edición máxima para la estrategia "distancia"
+	MatchConfianzaMinima float64           // Confianza mínima para aceptar una coincidencia
+	MatchAlias           map[string]string // Alias fijos código inventario -> código ventas
+
+	// Clasificación ABC/XYZ
+	AbcCriterio string    // Criterio por defecto: venta, cantidad o utilidad
+	AbcUmbrales []float64 // % de participación de las clases A y B (C es el resto)
+	XyzUmbrales []float64 // Coeficiente de variación máximo de las clases X e Y
+
+	// Reposición: valores por defecto y excepciones por marca o categoría (claves en mayúsculas)
+	ReposicionNivelServicio           float64            // % de ciclos sin quiebre de stock
+	ReposicionLeadTimeDias            int                // Días entre el pedido y la recepción
+	ReposicionDiasCobertura           int                // Días de demanda que cubre el pedido sugerido
+	ReposicionLeadTimeMarcas          map[string]float64 // MARCA=días
+	ReposicionLeadTimeCategorias      map[string]float64 // CATEGORIA=días
+	ReposicionNivelServicioMarcas     map[string]float64 // MARCA=%
+	ReposicionNivelServicioCategorias map[string]float64 // CATEGORIA=%
+}
+
+// Orígenes de datos soportados
+const (
+	DataSourceSQL      = "sql"
+	DataSourceFixtures = "fixtures"
+)
+
+// Modos de obtención del historial de ingresos
+const (
+	HistorialModoJSON  = "json"
+	HistorialModoFilas = "filas"
+)
+
+// UsaFixtures indica si la API debe ejecutarse sobre archivos locales en lugar de las bases de datos
+func (c *Config) UsaFixtures() bool {
+	return c.DataSource == DataSourceFixtures
 }
 
 // Load carga la configuración desde el archivo .env o variables de entorno
@@ -66,6 +119,92 @@ func Load() (*Config, error) {
 
 		// Server
 		ServerPort: serverPort,
+
+		// Origen de datos
+		DataSource:  getEnv("DATA_SOURCE", DataSourceSQL),
+		FixturesDir: getEnv("FIXTURES_DIR", "./fixtures"),
+
+		// Historial de ingresos
+		HistorialModo: getEnv("HISTORIAL_MODO", HistorialModoJSON),
+
+		// Sucursal por defecto
+		SucursalDefecto: getEnvInt("SUCURSAL_DEFECTO", 211),
+
+		// Datos locales
+		DataDir: getEnv("DATA_DIR", "./data"),
+
+		// Emparejamiento de códigos
+		MatchEstrategias:     getEnvList("MATCH_ESTRATEGIAS", []string{"alias", "exacta", "normalizada", "prefijo", "distancia"}),
+		MatchPrefijoMinimo:   getEnvInt("MATCH_PREFIJO_MINIMO", 6),
+		MatchDistanciaMaxima: getEnvInt("MATCH_DISTANCIA_MAXIMA", 1),
+		MatchConfianzaMinima: getEnvFloat("MATCH_CONFIANZA_MINIMA", 0.7),
+		MatchAlias:           getEnvPairs("MATCH_ALIAS"),
+
+		// Clasificación ABC/XYZ
+		AbcCriterio: getEnv("ABC_CRITERIO", "venta"),
+		AbcUmbrales: getEnvFloatList("ABC_UMBRALES", []float64{80, 15, 5}),
+		XyzUmbrales: getEnvFloatList("XYZ_UMBRALES", []float64{0.5, 1}),
+
+		// Reposición
+		ReposicionNivelServicio: getEnvFloat("REPOSICION_NIVEL_SERVICIO", 95),
+		ReposicionLeadTimeDias:  getEnvInt("REPOSICION_LEAD_TIME_DIAS", 30),
+		ReposicionDiasCobertura: getEnvInt("REPOSICION_DIAS_COBERTURA", 30),
+	}
+
+	// Excepciones de reposición por marca y categoría
+	var err error
+	for key, dest := range map[string]*map[string]float64{
+		"REPOSICION_LEAD_TIME_MARCAS":          &cfg.ReposicionLeadTimeMarcas,
+		"REPOSICION_LEAD_TIME_CATEGORIAS":      &cfg.ReposicionLeadTimeCategorias,
+		"REPOSICION_NIVEL_SERVICIO_MARCAS":     &cfg.ReposicionNivelServicioMarcas,
+		"REPOSICION_NIVEL_SERVICIO_CATEGORIAS": &cfg.ReposicionNivelServicioCategorias,
+	} {
+		if *dest, err = getEnvFloatPairs(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.HistorialModo != HistorialModoJSON && cfg.HistorialModo != HistorialModoFilas {
+		return nil, fmt.Errorf("HISTORIAL_MODO no válido: %q (use %q o %q)", cfg.HistorialModo, HistorialModoJSON, HistorialModoFilas)
+	}
+
+	if len(cfg.AbcUmbrales) < 2 {
+		return nil, fmt.Errorf("ABC_UMBRALES debe indicar al menos los porcentajes de las clases A y B")
+	}
+	if len(cfg.XyzUmbrales) < 2 {
+		return nil, fmt.Errorf("XYZ_UMBRALES debe indicar los coeficientes de variación de las clases X e Y")
+	}
+
+	if !nivelServicioValido(cfg.ReposicionNivelServicio) {
+		return nil, fmt.Errorf("REPOSICION_NIVEL_SERVICIO no válido: %v (use un valor entre 50 y 99.9)", cfg.ReposicionNivelServicio)
+	}
+	if cfg.ReposicionLeadTimeDias < 1 || cfg.ReposicionLeadTimeDias > 365 {
+		return nil, fmt.Errorf("REPOSICION_LEAD_TIME_DIAS no válido: %d (use de 1 a 365 días)", cfg.ReposicionLeadTimeDias)
+	}
+	if cfg.ReposicionDiasCobertura < 0 || cfg.ReposicionDiasCobertura > 365 {
+		return nil, fmt.Errorf("REPOSICION_DIAS_COBERTURA no válido: %d (use de 0 a 365 días)", cfg.ReposicionDiasCobertura)
+	}
+	for _, dias := range []map[string]float64{cfg.ReposicionLeadTimeMarcas, cfg.ReposicionLeadTimeCategorias} {
+		for clave, valor := range dias {
+			if valor != float64(int(valor)) || valor < 1 || valor > 365 {
+				return nil, fmt.Errorf("lead time no válido para %q: %v (use días enteros de 1 a 365)", clave, valor)
+			}
+		}
+	}
+	for _, niveles := range []map[string]float64{cfg.ReposicionNivelServicioMarcas, cfg.ReposicionNivelServicioCategorias} {
+		for clave, valor := range niveles {
+			if !nivelServicioValido(valor) {
+				return nil, fmt.Errorf("nivel de servicio no válido para %q: %v (use un valor entre 50 y 99.9)", clave, valor)
+			}
+		}
+	}
+
+	if cfg.SucursalDefecto <= 0 {
+		return nil, fmt.Errorf("SUCURSAL_DEFECTO no válida: %d", cfg.SucursalDefecto)
+	}
+
+	if cfg.DataSource != DataSourceSQL && cfg.DataSource != DataSourceFixtures {
+		return nil, fmt.Errorf("DATA_SOURCE no válido: %q (use %q o %q)", cfg.DataSource, DataSourceSQL, DataSourceFixtures)
 	}
 
 	return cfg, nil
@@ -79,3 +218,83 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvInt obtiene una variable de entorno entera o devuelve un valor por defecto
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat obtiene una variable de entorno decimal o devuelve un valor por defecto
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList obtiene una lista separada por comas o devuelve un valor por defecto
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvFloatList obtiene una lista de números separados por comas o devuelve un valor por defecto
+func getEnvFloatList(key string, defaultValue []float64) []float64 {
+	var values []float64
+	for _, item := range getEnvList(key, nil) {
+		value, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+// nivelServicioValido indica si un nivel de servicio (%) está en el rango admitido
+func nivelServicioValido(nivel float64) bool {
+	return nivel >= 50 && nivel < 100
+}
+
+// getEnvFloatPairs obtiene pares clave=número separados por comas, con las claves en mayúsculas
+func getEnvFloatPairs(key string) (map[string]float64, error) {
+	values := make(map[string]float64)
+	for clave, item := range getEnvPairs(key) {
+		value, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: valor no numérico para %q: %q", key, clave, item)
+		}
+		values[strings.ToUpper(clave)] = value
+	}
+	return values, nil
+}
+
+// getEnvPairs obtiene pares clave=valor separados por comas (ej. "A=B,C=D")
+func getEnvPairs(key string) map[string]string {
+	pairs := make(map[string]string)
+	for _, item := range getEnvList(key, nil) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) != "" {
+			pairs[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,39 @@
+package datasource
+
+import (
+	"github.com/pablojnd/rotacion/db"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// VentasSource define el origen de los datos de ventas.
+// Las filas devueltas usan los mismos nombres de columna que las consultas
+// de queries/sqlserver, independientemente de la implementación.
+type VentasSource interface {
+	// VentasDetalladas devuelve cada línea de venta (columnas de GetVentasQuery)
+	VentasDetalladas(filtro models.VentasFiltro) (db.Rows, error)
+
+	// VentasAgrupadas devuelve las ventas agrupadas por producto (columnas de GetVentasAgrupadasQuery)
+	VentasAgrupadas(filtro models.VentasFiltro) (db.Rows, error)
+
+	// VentasPorSucursal devuelve las ventas agrupadas por producto y sucursal (columnas de GetVentasPorSucursalQuery)
+	VentasPorSucursal(filtro models.VentasFiltro) (db.Rows, error)
+
+	// Sucursales devuelve las sucursales con sus documentos de venta en el período del filtro
+	// (columnas de GetSucursalesQuery); las sucursales del filtro se ignoran
+	Sucursales(filtro models.VentasFiltro) (db.Rows, error)
+}
+
+// InventarioSource define el origen de los datos de inventario.
+// Las filas devueltas usan los mismos nombres de columna que las consultas
+// de queries/mysql, independientemente de la implementación.
+type InventarioSource interface {
+	// Inventario devuelve el inventario agrupado por producto (columnas de GetSimplifiedDimensionsQuery)
+	Inventario(filtro models.InventarioFiltro) (db.Rows, error)
+
+	// HistorialIngresos devuelve cada ingreso como una fila (columnas de GetHistorialIngresosQuery)
+	HistorialIngresos(filtro models.InventarioFiltro) (db.Rows, error)
+
+	// IngresosHastaFecha devuelve cada ingreso de todos los años hasta la fecha del filtro
+	// (columnas de GetIngresosHastaFechaQuery)
+	IngresosHastaFecha(filtro models.StockFiltro) (db.Rows, error)
+}
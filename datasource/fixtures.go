@@ -0,0 +1,719 @@
+package datasource
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pablojnd/rotacion/db"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// Nombres de los archivos esperados dentro del directorio de fixtures
+const (
+	ArchivoFixtureVentas     = "ventas.json"
+	ArchivoFixtureInventario = "inventario.json"
+	ArchivoFixtureSucursales = "sucursales.json" // Opcional
+)
+
+// LineaVentaFixture representa una línea de boleta o factura tal como se
+// almacena en SQL Server (VENTA_BOLETA/VENTA_FACTURA y su detalle)
+type LineaVentaFixture struct {
+	Fecha             string  `json:"fecha"`
+	Sucursal          int     `json:"sucursal"`
+	TipoDocumento     string  `json:"tipoDocumento"`
+	CodigoDocumento   string  `json:"codigoDocumento"`
+	Cliente           string  `json:"cliente"`
+	CodigoProducto    string  `json:"codigoProducto"`
+	NombreProducto    string  `json:"nombreProducto"`
+	Zeta              string  `json:"zeta"`
+	CostoUnitarioUSD  float64 `json:"costoUnitarioUSD"`
+	PrecioBaseCLP     int     `json:"precioBaseCLP"`
+	PrecioOfertaCLP   int     `json:"precioOfertaCLP"`
+	PrecioVentaCLP    int     `json:"precioVentaCLP"`
+	Cantidad          float64 `json:"cantidad"`
+	TotalProductoCLP  int     `json:"totalProductoCLP"`
+	TotalDocumentoCLP int     `json:"totalDocumentoCLP"`
+	Nula              bool    `json:"nula"`
+}
+
+// IngresoFixture representa un registro de la tabla saldos de MySQL
+type IngresoFixture struct {
+	Codigo           string  `json:"codigo"`           // COD_ART
+	NombreAduanero   string  `json:"nombreAduanero"`   // DES_ADU
+	Marca            string  `json:"marca"`            // Marca
+	Categoria        string  `json:"categoria"`        // SubFamilia
+	Dimensiones      string  `json:"dimensiones"`      // NomDetSubFam
+	UnidadesPorCaja  float64 `json:"unidadesPorCaja"`  // UNI_CAJ
+	Zeta             string  `json:"zeta"`             // ZET_ART
+	AnioProduccion   string  `json:"anioProduccion"`   // ANIO_PRO
+	Unidades         float64 `json:"unidades"`         // CAN_ING
+	CifUnitarioUSD   float64 `json:"cifUnitarioUSD"`   // CIF_UNI
+	CostoUnitarioCLP float64 `json:"costoUnitarioCLP"` // COS_UNI
+	FechaIngreso     string  `json:"fechaIngreso"`     // fec_ing
+}
+
+// SucursalFixture representa un registro de la tabla SUCURSAL de SQL Server
+type SucursalFixture struct {
+	ID     int    `json:"id"`     // ID_SUCURSAL
+	Nombre string `json:"nombre"` // NOMBRE_SUCURSAL
+}
+
+// Fixtures es un origen de datos en memoria cargado desde archivos JSON.
+// Reproduce en Go las consultas de SQL Server y MySQL para poder ejecutar
+// la API completa sin acceso a las bases de datos del ERP.
+type Fixtures struct {
+	ventas     []LineaVentaFixture
+	ingresos   []IngresoFixture
+	sucursales []SucursalFixture
+}
+
+// NewFixtures carga los archivos ventas.json e inventario.json del directorio indicado
+func NewFixtures(dir string) (*Fixtures, error) {
+	f := &Fixtures{}
+
+	if err := cargarJSON(filepath.Join(dir, ArchivoFixtureVentas), &f.ventas); err != nil {
+		return nil, err
+	}
+	if err := cargarJSON(filepath.Join(dir, ArchivoFixtureInventario), &f.ingresos); err != nil {
+		return nil, err
+	}
+
+	// Sin catálogo de sucursales se usan las sucursales presentes en las ventas
+	rutaSucursales := filepath.Join(dir, ArchivoFixtureSucursales)
+	if _, err := os.Stat(rutaSucursales); err == nil {
+		if err := cargarJSON(rutaSucursales, &f.sucursales); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// NewFixturesFromData crea un origen en memoria a partir de datos ya cargados
+func NewFixturesFromData(ventas []LineaVentaFixture, ingresos []IngresoFixture) *Fixtures {
+	return &Fixtures{ventas: ventas, ingresos: ingresos}
+}
+
+// cargarJSON lee un archivo JSON y lo decodifica en target
+func cargarJSON(path string, target interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error al leer fixture %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("error al analizar fixture %s: %v", path, err)
+	}
+	return nil
+}
+
+// Columnas de GetVentasQuery
+var columnasVentasDetalladas = []string{
+	"Código Documento", "Fecha Emisión", "Tipo Documento", "Cliente",
+	"Código Producto", "Producto", "Cantidad", "Precio Unitario (CLP)",
+	"Total Venta (CLP)", "Sucursal", "Costo Unitario (USD)", "Precio Base (CLP)",
+	"Precio Oferta (CLP)", "Precio Promedio (CLP)", "Cant. Transacciones", "Zeta",
+	"Documento Nulo", "Total Anulado (CLP)",
+}
+
+// Columnas de GetVentasAgrupadasQuery
+var columnasVentasAgrupadas = []string{
+	"Código de Producto", "Nombre del Producto", "Costo Unitario (USD)",
+	"Precio Base (CLP)", "Precio de Oferta (CLP)", "Cantidad Total Vendida",
+	"Total Ventas (CLP)", "Total Líneas (CLP)", "Última Fecha de Venta", "Precio Promedio Ponderado (CLP)",
+	"Precio Mínimo (CLP)", "Precio Máximo (CLP)", "Cantidad de Ventas Registradas",
+	"Unidades Boleta", "Unidades Factura", "Ventas Boleta (CLP)", "Ventas Factura (CLP)",
+	"Documentos Boleta", "Documentos Factura", "Documentos Nulos", "Unidades Nulas",
+}
+
+// Columnas de GetVentasPorSucursalQuery
+var columnasVentasPorSucursal = []string{
+	"Código de Producto", "Sucursal", "Cantidad Total Vendida", "Total Ventas (CLP)",
+	"Última Fecha de Venta", "Cantidad de Ventas Registradas",
+}
+
+// Columnas de GetSucursalesQuery
+var columnasSucursales = []string{"Sucursal", "Nombre Sucursal", "Documentos en Período"}
+
+// Columnas de GetHistorialIngresosQuery
+var columnasHistorialIngresos = []string{
+	"Código de Producto", "Unidades por Caja", "Zeta", "Año Producción",
+	"Unidades Ingresadas", "Fecha Ingreso",
+}
+
+// Columnas de GetIngresosHastaFechaQuery
+var columnasIngresosHastaFecha = []string{
+	"Código de Producto", "Nombre Aduanero", "Marca del Producto", "Categoría Principal",
+	"Unidades por Caja", "Zeta", "Año Producción", "Unidades Ingresadas", "Fecha Ingreso",
+}
+
+// Columnas de GetSimplifiedDimensionsQuery
+var columnasInventario = []string{
+	"Código de Producto", "Nombre Aduanero", "Marca del Producto", "Categoría Principal",
+	"Subcategoría/Dimensiones", "Unidades por Caja", "Total Unidades Ingresadas",
+	"Costo Promedio CIF (USD)", "Costo Promedio Unitario (CLP)", "Fecha Primer Ingreso",
+	"Fecha Último Ingreso", "Días Desde Primer Ingreso", "Cantidad de Ingresos",
+	"Historial de Ingresos (JSON)",
+}
+
+// lineasEnPeriodo devuelve las líneas de venta del periodo y sucursales del filtro (CTE VentasBase),
+// sin las de documentos anulados si el filtro los excluye
+func (f *Fixtures) lineasEnPeriodo(filtro models.VentasFiltro) []LineaVentaFixture {
+	var lineas []LineaVentaFixture
+	for _, l := range f.ventas {
+		if !filtro.IncluyeSucursal(l.Sucursal) {
+			continue
+		}
+		if filtro.ExcluirNulas && l.Nula {
+			continue
+		}
+		if l.Fecha < filtro.FechaInicio || l.Fecha > filtro.FechaFin {
+			continue
+		}
+		lineas = append(lineas, l)
+	}
+	return lineas
+}
+
+// coincideCodigo replica el filtro por código (LIKE con comodines, vacío = todos)
+func coincideCodigo(codigo, filtro string) bool {
+	return filtro == "" || strings.Contains(codigo, filtro)
+}
+
+// VentasDetalladas reproduce GetVentasQuery sobre los datos en memoria
+func (f *Fixtures) VentasDetalladas(filtro models.VentasFiltro) (db.Rows, error) {
+	lineas := f.lineasEnPeriodo(filtro)
+
+	// CTE CalculosProducto
+	type calculo struct {
+		sumaPrecioCantidad float64
+		sumaCantidad       float64
+		transacciones      int64
+	}
+	calculos := make(map[string]*calculo)
+	for _, l := range lineas {
+		c, ok := calculos[l.CodigoProducto]
+		if !ok {
+			c = &calculo{}
+			calculos[l.CodigoProducto] = c
+		}
+		c.sumaPrecioCantidad += float64(l.PrecioVentaCLP) * l.Cantidad
+		c.sumaCantidad += l.Cantidad
+		c.transacciones++
+	}
+
+	var filtradas []LineaVentaFixture
+	for _, l := range lineas {
+		if coincideCodigo(l.CodigoProducto, filtro.CodigoProducto) && filtro.IncluyeCodigo(l.CodigoProducto) {
+			filtradas = append(filtradas, l)
+		}
+	}
+	sort.SliceStable(filtradas, func(i, j int) bool {
+		return filtradas[i].Fecha > filtradas[j].Fecha
+	})
+
+	data := make([][]interface{}, 0, len(filtradas))
+	for _, l := range filtradas {
+		c := calculos[l.CodigoProducto]
+		var precioPromedio interface{}
+		if c.sumaCantidad != 0 {
+			precioPromedio = int64(math.Round(c.sumaPrecioCantidad / c.sumaCantidad))
+		}
+		totalProducto, totalAnulado := int64(l.TotalProductoCLP), int64(0)
+		if l.Nula {
+			totalProducto, totalAnulado = 0, int64(l.TotalProductoCLP)
+		}
+
+		data = append(data, []interface{}{
+			l.CodigoDocumento,
+			parseFechaFixture(l.Fecha),
+			l.TipoDocumento,
+			clienteFixture(l.Cliente),
+			l.CodigoProducto,
+			l.NombreProducto,
+			l.Cantidad,
+			int64(l.PrecioVentaCLP),
+			totalProducto,
+			int64(l.Sucursal),
+			redondear2(l.CostoUnitarioUSD),
+			int64(l.PrecioBaseCLP),
+			int64(l.PrecioOfertaCLP),
+			precioPromedio,
+			c.transacciones,
+			l.Zeta,
+			l.Nula,
+			totalAnulado,
+		})
+	}
+
+	return db.NewMockRows(columnasVentasDetalladas, data), nil
+}
+
+// VentasAgrupadas reproduce GetVentasAgrupadasQuery sobre los datos en memoria
+func (f *Fixtures) VentasAgrupadas(filtro models.VentasFiltro) (db.Rows, error) {
+	type resumen struct {
+		codigo             string
+		nombre             string
+		costoUSD           float64
+		precioBase         int
+		precioOferta       int
+		cantidad           float64
+		totalVentas        int64
+		totalLineas        int64
+		ultimaFecha        string
+		sumaPrecioCantidad float64
+		precioMinimo       int
+		precioMaximo       int
+		ventas             int64
+
+		// Desglose por tipo de documento y anulados
+		unidadesTipo    map[string]float64
+		ventasTipo      map[string]int64
+		documentosTipo  map[string]map[string]bool
+		documentosNulos map[string]bool
+		unidadesNulas   float64
+	}
+
+	resumenes := make(map[string]*resumen)
+	for _, l := range f.lineasEnPeriodo(filtro) {
+		r, ok := resumenes[l.CodigoProducto]
+		if !ok {
+			r = &resumen{
+				codigo:          l.CodigoProducto,
+				precioMinimo:    l.PrecioVentaCLP,
+				precioMaximo:    l.PrecioVentaCLP,
+				unidadesTipo:    make(map[string]float64),
+				ventasTipo:      make(map[string]int64),
+				documentosTipo:  map[string]map[string]bool{models.TipoDocumentoBoleta: {}, models.TipoDocumentoFactura: {}},
+				documentosNulos: make(map[string]bool),
+			}
+			resumenes[l.CodigoProducto] = r
+		}
+
+		if l.NombreProducto > r.nombre {
+			r.nombre = l.NombreProducto
+		}
+		r.costoUSD = math.Max(r.costoUSD, l.CostoUnitarioUSD)
+		if l.PrecioBaseCLP > r.precioBase {
+			r.precioBase = l.PrecioBaseCLP
+		}
+		if l.PrecioOfertaCLP > r.precioOferta {
+			r.precioOferta = l.PrecioOfertaCLP
+		}
+		r.cantidad += l.Cantidad
+		if !l.Nula {
+			r.totalVentas += int64(l.TotalDocumentoCLP)
+			r.totalLineas += int64(l.TotalProductoCLP)
+			r.ventasTipo[l.TipoDocumento] += int64(l.TotalProductoCLP)
+		}
+		r.unidadesTipo[l.TipoDocumento] += l.Cantidad
+		if docs, ok := r.documentosTipo[l.TipoDocumento]; ok {
+			docs[fmt.Sprintf("%d-%s", l.Sucursal, l.CodigoDocumento)] = true
+		}
+		if l.Nula {
+			r.documentosNulos[fmt.Sprintf("%s-%d-%s", l.TipoDocumento, l.Sucursal, l.CodigoDocumento)] = true
+			r.unidadesNulas += l.Cantidad
+		}
+		if l.Fecha > r.ultimaFecha {
+			r.ultimaFecha = l.Fecha
+		}
+		r.sumaPrecioCantidad += float64(l.PrecioVentaCLP) * l.Cantidad
+		if l.PrecioVentaCLP < r.precioMinimo {
+			r.precioMinimo = l.PrecioVentaCLP
+		}
+		if l.PrecioVentaCLP > r.precioMaximo {
+			r.precioMaximo = l.PrecioVentaCLP
+		}
+		r.ventas++
+	}
+
+	codigos := make([]string, 0, len(resumenes))
+	for codigo := range resumenes {
+		if coincideCodigo(codigo, filtro.CodigoProducto) && filtro.IncluyeCodigo(codigo) {
+			codigos = append(codigos, codigo)
+		}
+	}
+	sort.Strings(codigos)
+
+	data := make([][]interface{}, 0, len(codigos))
+	for _, codigo := range codigos {
+		r := resumenes[codigo]
+		var precioPromedio interface{}
+		if r.cantidad != 0 {
+			precioPromedio = int64(math.Round(r.sumaPrecioCantidad / r.cantidad))
+		}
+
+		data = append(data, []interface{}{
+			r.codigo,
+			r.nombre,
+			redondear2(r.costoUSD),
+			int64(r.precioBase),
+			int64(r.precioOferta),
+			r.cantidad,
+			r.totalVentas,
+			r.totalLineas,
+			parseFechaFixture(r.ultimaFecha),
+			precioPromedio,
+			int64(r.precioMinimo),
+			int64(r.precioMaximo),
+			r.ventas,
+			r.unidadesTipo[models.TipoDocumentoBoleta],
+			r.unidadesTipo[models.TipoDocumentoFactura],
+			r.ventasTipo[models.TipoDocumentoBoleta],
+			r.ventasTipo[models.TipoDocumentoFactura],
+			int64(len(r.documentosTipo[models.TipoDocumentoBoleta])),
+			int64(len(r.documentosTipo[models.TipoDocumentoFactura])),
+			int64(len(r.documentosNulos)),
+			r.unidadesNulas,
+		})
+	}
+
+	return db.NewMockRows(columnasVentasAgrupadas, data), nil
+}
+
+// VentasPorSucursal reproduce GetVentasPorSucursalQuery sobre los datos en memoria
+func (f *Fixtures) VentasPorSucursal(filtro models.VentasFiltro) (db.Rows, error) {
+	type clave struct {
+		codigo   string
+		sucursal int
+	}
+	type resumen struct {
+		cantidad    float64
+		totalVentas int64
+		ultimaFecha string
+		ventas      int64
+	}
+
+	resumenes := make(map[clave]*resumen)
+	var claves []clave
+	for _, l := range f.lineasEnPeriodo(filtro) {
+		if !coincideCodigo(l.CodigoProducto, filtro.CodigoProducto) || !filtro.IncluyeCodigo(l.CodigoProducto) {
+			continue
+		}
+		k := clave{l.CodigoProducto, l.Sucursal}
+		r, ok := resumenes[k]
+		if !ok {
+			r = &resumen{}
+			resumenes[k] = r
+			claves = append(claves, k)
+		}
+		r.cantidad += l.Cantidad
+		if !l.Nula {
+			r.totalVentas += int64(l.TotalProductoCLP)
+		}
+		if l.Fecha > r.ultimaFecha {
+			r.ultimaFecha = l.Fecha
+		}
+		r.ventas++
+	}
+
+	sort.Slice(claves, func(i, j int) bool {
+		if claves[i].codigo != claves[j].codigo {
+			return claves[i].codigo < claves[j].codigo
+		}
+		return claves[i].sucursal < claves[j].sucursal
+	})
+
+	data := make([][]interface{}, 0, len(claves))
+	for _, k := range claves {
+		r := resumenes[k]
+		data = append(data, []interface{}{
+			k.codigo,
+			int64(k.sucursal),
+			r.cantidad,
+			r.totalVentas,
+			parseFechaFixture(r.ultimaFecha),
+			r.ventas,
+		})
+	}
+
+	return db.NewMockRows(columnasVentasPorSucursal, data), nil
+}
+
+// Sucursales reproduce GetSucursalesQuery sobre los datos en memoria
+func (f *Fixtures) Sucursales(filtro models.VentasFiltro) (db.Rows, error) {
+	catalogo := f.sucursales
+	if len(catalogo) == 0 {
+		vistas := make(map[int]bool)
+		for _, l := range f.ventas {
+			if !vistas[l.Sucursal] {
+				vistas[l.Sucursal] = true
+				catalogo = append(catalogo, SucursalFixture{ID: l.Sucursal, Nombre: fmt.Sprintf("Sucursal %d", l.Sucursal)})
+			}
+		}
+	}
+
+	// Documentos distintos (tipo + correlativo) por sucursal en el período
+	documentos := make(map[int]map[string]bool)
+	periodo := models.VentasFiltro{FechaInicio: filtro.FechaInicio, FechaFin: filtro.FechaFin}
+	for _, l := range f.lineasEnPeriodo(periodo) {
+		if documentos[l.Sucursal] == nil {
+			documentos[l.Sucursal] = make(map[string]bool)
+		}
+		documentos[l.Sucursal][l.TipoDocumento+"-"+l.CodigoDocumento] = true
+	}
+
+	ordenado := append([]SucursalFixture(nil), catalogo...)
+	sort.Slice(ordenado, func(i, j int) bool { return ordenado[i].ID < ordenado[j].ID })
+
+	data := make([][]interface{}, 0, len(ordenado))
+	for _, s := range ordenado {
+		data = append(data, []interface{}{int64(s.ID), s.Nombre, int64(len(documentos[s.ID]))})
+	}
+
+	return db.NewMockRows(columnasSucursales, data), nil
+}
+
+// Inventario reproduce GetSimplifiedDimensionsQuery sobre los datos en memoria
+func (f *Fixtures) Inventario(filtro models.InventarioFiltro) (db.Rows, error) {
+	type grupo struct {
+		codigo          string
+		unidadesPorCaja float64
+		ingresos        []IngresoFixture
+	}
+
+	// GROUP BY COD_ART, UNI_CAJ
+	grupos := make(map[string]*grupo)
+	var claves []string
+	for _, ing := range f.ingresosFiltrados(filtro) {
+		clave := ing.Codigo + "|" + strconv.FormatFloat(ing.UnidadesPorCaja, 'f', -1, 64)
+		g, ok := grupos[clave]
+		if !ok {
+			g = &grupo{codigo: ing.Codigo, unidadesPorCaja: ing.UnidadesPorCaja}
+			grupos[clave] = g
+			claves = append(claves, clave)
+		}
+		g.ingresos = append(g.ingresos, ing)
+	}
+	sort.Strings(claves)
+
+	hoy := time.Now()
+	data := make([][]interface{}, 0, len(claves))
+	for _, clave := range claves {
+		g := grupos[clave]
+
+		var nombre, marca, categoria, dimensiones string
+		var totalUnidades, sumaCif, sumaCosto float64
+		primerIngreso, ultimoIngreso := g.ingresos[0].FechaIngreso, g.ingresos[0].FechaIngreso
+		var historial []string
+		vistos := make(map[string]bool)
+
+		for _, ing := range g.ingresos {
+			nombre = maxString(nombre, ing.NombreAduanero)
+			marca = maxString(marca, porAsignar(ing.Marca))
+			categoria = maxString(categoria, porAsignar(ing.Categoria))
+			dimensiones = maxString(dimensiones, ing.Dimensiones)
+
+			totalUnidades += ing.Unidades
+			sumaCif += ing.CifUnitarioUSD * ing.Unidades
+			sumaCosto += ing.CostoUnitarioCLP * ing.Unidades
+
+			if ing.FechaIngreso < primerIngreso {
+				primerIngreso = ing.FechaIngreso
+			}
+			if ing.FechaIngreso > ultimoIngreso {
+				ultimoIngreso = ing.FechaIngreso
+			}
+
+			// GROUP_CONCAT(DISTINCT ...) con el mismo formato de comillas simples que MySQL
+			entrada := fmt.Sprintf("{'Zeta':'%s','Año Producción':'%s','Unidades Ingresadas':%s,'Fecha Ingreso':'%s'}",
+				ing.Zeta, ing.AnioProduccion, strconv.FormatFloat(ing.Unidades, 'f', -1, 64), ing.FechaIngreso)
+			if !vistos[entrada] {
+				vistos[entrada] = true
+				historial = append(historial, entrada)
+			}
+		}
+
+		if dimensiones == "" || dimensiones == "POR ASIGNAR" || dimensiones == "Sin Asignar" {
+			dimensiones = "POR ASIGNAR"
+		}
+
+		var cifPromedio, costoPromedio float64
+		if totalUnidades != 0 {
+			cifPromedio = redondear2(sumaCif / totalUnidades)
+			costoPromedio = redondear2(sumaCosto / totalUnidades)
+		}
+
+		var diasDesdePrimerIngreso interface{}
+		if fecha, err := time.Parse("2006-01-02", primerIngreso); err == nil {
+			diasDesdePrimerIngreso = int64(hoy.Sub(fecha).Hours() / 24)
+		}
+
+		data = append(data, []interface{}{
+			g.codigo,
+			nombre,
+			marca,
+			categoria,
+			dimensiones,
+			g.unidadesPorCaja,
+			totalUnidades,
+			cifPromedio,
+			costoPromedio,
+			primerIngreso,
+			ultimoIngreso,
+			diasDesdePrimerIngreso,
+			int64(len(historial)), // COUNT(DISTINCT ...) sobre la misma expresión del historial
+			"[" + truncarGroupConcat(strings.Join(historial, ",")) + "]",
+		})
+	}
+
+	return db.NewMockRows(columnasInventario, data), nil
+}
+
+// HistorialIngresos reproduce GetHistorialIngresosQuery sobre los datos en memoria
+func (f *Fixtures) HistorialIngresos(filtro models.InventarioFiltro) (db.Rows, error) {
+	ingresos := f.ingresosFiltrados(filtro)
+	sort.SliceStable(ingresos, func(i, j int) bool {
+		if ingresos[i].Codigo != ingresos[j].Codigo {
+			return ingresos[i].Codigo < ingresos[j].Codigo
+		}
+		return ingresos[i].FechaIngreso < ingresos[j].FechaIngreso
+	})
+
+	// SELECT DISTINCT
+	vistos := make(map[string]bool)
+	data := make([][]interface{}, 0, len(ingresos))
+	for _, ing := range ingresos {
+		fila := []interface{}{
+			ing.Codigo,
+			ing.UnidadesPorCaja,
+			ing.Zeta,
+			ing.AnioProduccion,
+			ing.Unidades,
+			ing.FechaIngreso,
+		}
+		clave := fmt.Sprint(fila...)
+		if vistos[clave] {
+			continue
+		}
+		vistos[clave] = true
+		data = append(data, fila)
+	}
+
+	return db.NewMockRows(columnasHistorialIngresos, data), nil
+}
+
+// IngresosHastaFecha reproduce GetIngresosHastaFechaQuery sobre los datos en memoria
+func (f *Fixtures) IngresosHastaFecha(filtro models.StockFiltro) (db.Rows, error) {
+	var ingresos []IngresoFixture
+	for _, ing := range f.ingresos {
+		if ing.FechaIngreso <= filtro.Fecha && coincideCodigo(ing.Codigo, filtro.CodigoProducto) {
+			ingresos = append(ingresos, ing)
+		}
+	}
+	sort.SliceStable(ingresos, func(i, j int) bool {
+		if ingresos[i].Codigo != ingresos[j].Codigo {
+			return ingresos[i].Codigo < ingresos[j].Codigo
+		}
+		return ingresos[i].FechaIngreso < ingresos[j].FechaIngreso
+	})
+
+	// SELECT DISTINCT
+	vistos := make(map[string]bool)
+	data := make([][]interface{}, 0, len(ingresos))
+	for _, ing := range ingresos {
+		fila := []interface{}{
+			ing.Codigo,
+			ing.NombreAduanero,
+			porAsignar(ing.Marca),
+			porAsignar(ing.Categoria),
+			ing.UnidadesPorCaja,
+			ing.Zeta,
+			ing.AnioProduccion,
+			ing.Unidades,
+			ing.FechaIngreso,
+		}
+		clave := fmt.Sprint(fila...)
+		if vistos[clave] {
+			continue
+		}
+		vistos[clave] = true
+		data = append(data, fila)
+	}
+
+	return db.NewMockRows(columnasIngresosHastaFecha, data), nil
+}
+
+// ingresosFiltrados devuelve los registros de saldos de los años de producción, fechas de
+// ingreso, código y lista de códigos solicitados
+func (f *Fixtures) ingresosFiltrados(filtro models.InventarioFiltro) []IngresoFixture {
+	var ingresos []IngresoFixture
+	for _, ing := range f.ingresos {
+		anio, err := strconv.Atoi(strings.TrimSpace(ing.AnioProduccion))
+		if err != nil || anio < filtro.AnioDesde || anio > filtro.AnioHasta {
+			continue
+		}
+		if filtro.FechaIngresoDesde != "" && ing.FechaIngreso < filtro.FechaIngresoDesde {
+			continue
+		}
+		if filtro.FechaIngresoHasta != "" && ing.FechaIngreso > filtro.FechaIngresoHasta {
+			continue
+		}
+		if !coincideCodigo(ing.Codigo, filtro.CodigoProducto) || !filtro.IncluyeCodigo(ing.Codigo) {
+			continue
+		}
+		ingresos = append(ingresos, ing)
+	}
+	return ingresos
+}
+
+// longitudMaximaGroupConcat es el valor por defecto de group_concat_max_len en MySQL (bytes)
+const longitudMaximaGroupConcat = 1024
+
+// truncarGroupConcat corta el resultado de GROUP_CONCAT igual que MySQL
+func truncarGroupConcat(texto string) string {
+	if len(texto) <= longitudMaximaGroupConcat {
+		return texto
+	}
+	texto = texto[:longitudMaximaGroupConcat]
+	// No dejar un carácter UTF-8 a medias
+	for len(texto) > 0 && !utf8.ValidString(texto) {
+		texto = texto[:len(texto)-1]
+	}
+	return texto
+}
+
+// parseFechaFixture convierte una fecha YYYY-MM-DD al time.Time que devolvería el driver
+func parseFechaFixture(fecha string) interface{} {
+	t, err := time.Parse("2006-01-02", fecha)
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+// clienteFixture replica ISNULL(nombre, 'Sin Cliente')
+func clienteFixture(cliente string) string {
+	if cliente == "" {
+		return "Sin Cliente"
+	}
+	return cliente
+}
+
+// porAsignar replica IFNULL(campo, 'POR ASIGNAR')
+func porAsignar(valor string) string {
+	if valor == "" {
+		return "POR ASIGNAR"
+	}
+	return valor
+}
+
+// maxString replica MAX() sobre columnas de texto
+func maxString(actual, valor string) string {
+	if valor > actual {
+		return valor
+	}
+	return actual
+}
+
+// redondear2 redondea a dos decimales como ROUND(x, 2)
+func redondear2(valor float64) float64 {
+	return math.Round(valor*100) / 100
+}
@@ -0,0 +1,54 @@
+package datasource
+
+import (
+	"github.com/pablojnd/rotacion/db"
+	"github.com/pablojnd/rotacion/models"
+	"github.com/pablojnd/rotacion/queries/mysql"
+)
+
+// MySQLInventario obtiene el inventario desde la tabla saldos de MySQL
+type MySQLInventario struct {
+	mysql *db.MySQLDB
+}
+
+// NewMySQLInventario crea un nuevo origen de inventario sobre MySQL
+func NewMySQLInventario(mysql *db.MySQLDB) *MySQLInventario {
+	return &MySQLInventario{mysql: mysql}
+}
+
+// Inventario ejecuta la consulta de inventario agrupado por producto
+func (s *MySQLInventario) Inventario(filtro models.InventarioFiltro) (db.Rows, error) {
+	return s.mysql.ExecuteQuery(mysql.GetSimplifiedDimensionsQuery(), inventarioArgs(filtro)...)
+}
+
+// HistorialIngresos ejecuta la consulta de ingresos individuales, sin GROUP_CONCAT
+func (s *MySQLInventario) HistorialIngresos(filtro models.InventarioFiltro) (db.Rows, error) {
+	return s.mysql.ExecuteQuery(mysql.GetHistorialIngresosQuery(), inventarioArgs(filtro)...)
+}
+
+// inventarioArgs devuelve los parámetros comunes de las consultas de inventario: rango de años
+// de producción, rango de fechas de ingreso, código y lista de códigos. Los textos van dos veces
+// porque la consulta primero los compara con vacío.
+func inventarioArgs(filtro models.InventarioFiltro) []interface{} {
+	codigos := filtro.CodigosTexto()
+	return []interface{}{
+		filtro.AnioDesde,
+		filtro.AnioHasta,
+		filtro.FechaIngresoDesde,
+		filtro.FechaIngresoDesde,
+		filtro.FechaIngresoHasta,
+		filtro.FechaIngresoHasta,
+		filtro.CodigoProducto,
+		filtro.CodigoProducto,
+		codigos,
+		codigos,
+	}
+}
+
+// IngresosHastaFecha ejecuta la consulta de ingresos de todos los años hasta la fecha de corte
+func (s *MySQLInventario) IngresosHastaFecha(filtro models.StockFiltro) (db.Rows, error) {
+	return s.mysql.ExecuteQuery(mysql.GetIngresosHastaFechaQuery(),
+		filtro.Fecha,
+		filtro.CodigoProducto,
+		filtro.CodigoProducto)
+}
@@ -0,0 +1,57 @@
+package datasource
+
+import (
+	"github.com/pablojnd/rotacion/db"
+	"github.com/pablojnd/rotacion/models"
+	"github.com/pablojnd/rotacion/queries/sqlserver"
+)
+
+// SQLServerVentas obtiene las ventas desde la base de datos SQL Server del ERP
+type SQLServerVentas struct {
+	sqlServer *db.SQLServerDB
+}
+
+// NewSQLServerVentas crea un nuevo origen de ventas sobre SQL Server
+func NewSQLServerVentas(sqlServer *db.SQLServerDB) *SQLServerVentas {
+	return &SQLServerVentas{sqlServer: sqlServer}
+}
+
+// VentasDetalladas ejecuta la consulta de ventas detalladas
+func (s *SQLServerVentas) VentasDetalladas(filtro models.VentasFiltro) (db.Rows, error) {
+	return s.sqlServer.ExecuteQuery(sqlserver.GetVentasQuery(), ventasArgs(filtro)...)
+}
+
+// VentasAgrupadas ejecuta la consulta de ventas agrupadas por producto
+func (s *SQLServerVentas) VentasAgrupadas(filtro models.VentasFiltro) (db.Rows, error) {
+	return s.sqlServer.ExecuteQuery(sqlserver.GetVentasAgrupadasQuery(), ventasArgs(filtro)...)
+}
+
+// VentasPorSucursal ejecuta la consulta de ventas agrupadas por producto y sucursal
+func (s *SQLServerVentas) VentasPorSucursal(filtro models.VentasFiltro) (db.Rows, error) {
+	return s.sqlServer.ExecuteQuery(sqlserver.GetVentasPorSucursalQuery(), ventasArgs(filtro)...)
+}
+
+// Sucursales ejecuta la consulta del catálogo de sucursales
+func (s *SQLServerVentas) Sucursales(filtro models.VentasFiltro) (db.Rows, error) {
+	return s.sqlServer.ExecuteQuery(sqlserver.GetSucursalesQuery(),
+		filtro.FechaInicio, filtro.FechaFin, // Para Boletas
+		filtro.FechaInicio, filtro.FechaFin, // Para Facturas
+	)
+}
+
+// ventasArgs construye los parámetros comunes de las consultas de ventas
+func ventasArgs(filtro models.VentasFiltro) []interface{} {
+	// Lista de sucursales separadas por comas; vacía = todas
+	sucursales := filtro.SucursalesTexto()
+	codigos := filtro.CodigosTexto()
+	excluirNulas := 0
+	if filtro.ExcluirNulas {
+		excluirNulas = 1
+	}
+	return []interface{}{
+		filtro.FechaInicio, filtro.FechaFin, sucursales, sucursales, excluirNulas, // Para Boletas
+		filtro.FechaInicio, filtro.FechaFin, sucursales, sucursales, excluirNulas, // Para Facturas
+		filtro.CodigoProducto, filtro.CodigoProducto, // Para filtrado por código
+		codigos, codigos, // Para la lista de códigos exactos
+	}
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formatos de fecha aceptados al convertir texto a time.Time
+var layoutsFecha = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+// campoDestino relaciona una columna del resultado con un campo del struct
+type campoDestino struct {
+	indice   int
+	columna  string
+	opcional bool
+}
+
+// ScanAll recorre rows y decodifica cada fila en un elemento del slice apuntado por dest.
+//
+// Los campos del struct se asocian a columnas con la etiqueta `db:"Nombre Columna"`;
+// `db:"Nombre Columna,opcional"` permite que la columna no exista en el resultado (el
+// campo queda en su valor cero y la ausencia se registra en el log una vez por tipo).
+// Se convierten automáticamente DECIMAL/[]byte, enteros, flotantes, fechas y NULL
+// (que deja el valor cero). Devuelve error si falta una columna obligatoria o si
+// un valor no puede convertirse al tipo del campo, incluido un valor con decimales
+// en un campo entero.
+func ScanAll(rows Rows, dest interface{}) error {
+	sliceValue := reflect.ValueOf(dest)
+	if sliceValue.Kind() != reflect.Ptr || sliceValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("ScanAll requiere un puntero a slice, se recibió %T", dest)
+	}
+	sliceValue = sliceValue.Elem()
+	elemType := sliceValue.Type().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("ScanAll requiere un slice de structs, se recibió %s", sliceValue.Type())
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	campos, err := mapearCampos(elemType, columns)
+	if err != nil {
+		return err
+	}
+
+	fila := 0
+	for rows.Next() {
+		fila++
+		values := make([]interface{}, len(columns))
+		scanArgs := make([]interface{}, len(columns))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+
+		if err := rows.Scan(scanArgs...); err != nil {
+			return err
+		}
+
+		elem := reflect.New(elemType).Elem()
+		for i, campo := range campos {
+			if campo == nil {
+				continue
+			}
+			if err := asignarValor(elem.Field(campo.indice), values[i]); err != nil {
+				return fmt.Errorf("fila %d, columna %q: %v", fila, campo.columna, err)
+			}
+		}
+
+		sliceValue.Set(reflect.Append(sliceValue, elem))
+	}
+
+	return rows.Err()
+}
+
+// mapearCampos asocia cada columna del resultado con el campo etiquetado correspondiente
+func mapearCampos(elemType reflect.Type, columns []string) ([]*campoDestino, error) {
+	porColumna := make(map[string]*campoDestino)
+	for i := 0; i < elemType.NumField(); i++ {
+		tag := elemType.Field(i).Tag.Get("db")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		partes := strings.Split(tag, ",")
+		campo := &campoDestino{indice: i, columna: partes[0]}
+		for _, opcion := range partes[1:] {
+			if opcion == "opcional" {
+				campo.opcional = true
+			}
+		}
+		porColumna[campo.columna] = campo
+	}
+
+	campos := make([]*campoDestino, len(columns))
+	encontradas := make(map[string]bool)
+	for i, col := range columns {
+		if campo, ok := porColumna[col]; ok {
+			campos[i] = campo
+			encontradas[col] = true
+		}
+	}
+
+	var faltantes []string
+	for col, campo := range porColumna {
+		switch {
+		case encontradas[col]:
+		case campo.opcional:
+			registrarOpcionalFaltante(elemType, col)
+		default:
+			faltantes = append(faltantes, col)
+		}
+	}
+	if len(faltantes) > 0 {
+		sort.Strings(faltantes)
+		return nil, fmt.Errorf("columnas faltantes para %s: %s", elemType.Name(), strings.Join(faltantes, ", "))
+	}
+
+	return campos, nil
+}
+
+// opcionalesFaltantes recuerda las columnas opcionales ya informadas como ausentes
+var opcionalesFaltantes sync.Map
+
+// registrarOpcionalFaltante informa en el log, una vez por tipo y columna, que una columna
+// opcional no vino en el resultado; así un nombre mal escrito no pasa inadvertido
+func registrarOpcionalFaltante(elemType reflect.Type, columna string) {
+	clave := elemType.String() + "|" + columna
+	if _, informada := opcionalesFaltantes.LoadOrStore(clave, true); !informada {
+		log.Printf("Columna opcional %q ausente en el resultado de %s; el campo queda en cero", columna, elemType.Name())
+	}
+}
+
+// asignarValor convierte un valor devuelto por el driver al tipo del campo destino
+func asignarValor(field reflect.Value, value interface{}) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := convertirFecha(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(convertirTexto(value))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := convertirNumero(value)
+		if err != nil {
+			return err
+		}
+		if f != math.Trunc(f) {
+			return fmt.Errorf("valor con decimales %v para un campo entero", f)
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) || field.OverflowInt(int64(f)) {
+			return fmt.Errorf("valor %v fuera del rango de %s", f, field.Type())
+		}
+		field.SetInt(int64(f))
+	case reflect.Float32, reflect.Float64:
+		f, err := convertirNumero(value)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := convertirBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("tipo de campo no soportado: %s", field.Type())
+	}
+
+	return nil
+}
+
+// convertirTexto convierte cualquier valor escalar a texto
+func convertirTexto(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format("2006-01-02 15:04:05")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// convertirNumero convierte enteros, flotantes y texto numérico (DECIMAL) a float64
+func convertirNumero(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return 0, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("valor no numérico %q", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("no se puede convertir %T a número", value)
+	}
+}
+
+// convertirFecha convierte time.Time o texto con formato de fecha a time.Time
+func convertirFecha(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return time.Time{}, nil
+		}
+		for _, layout := range layoutsFecha {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("fecha no válida %q", v)
+	default:
+		return time.Time{}, fmt.Errorf("no se puede convertir %T a fecha", value)
+	}
+}
+
+// convertirBool convierte bit/entero/texto a bool
+func convertirBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	default:
+		f, err := convertirNumero(v)
+		if err != nil {
+			return false, err
+		}
+		return f != 0, nil
+	}
+}
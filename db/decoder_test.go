@@ -0,0 +1,132 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// filaPrueba cubre los tipos de campo que convierte ScanAll
+type filaPrueba struct {
+	Codigo   string    `db:"Codigo"`
+	Unidades int       `db:"Unidades"`
+	Caja     int8      `db:"Caja,opcional"`
+	Precio   float64   `db:"Precio Unitario"`
+	Fecha    time.Time `db:"Fecha"`
+	Nula     bool      `db:"Nula"`
+	Marca    string    `db:"Marca,opcional"`
+	Interno  string
+}
+
+func TestScanAll(t *testing.T) {
+	columnas := []string{"Codigo", "Unidades", "Precio Unitario", "Fecha", "Nula"}
+	fecha := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	casos := []struct {
+		nombre   string
+		columnas []string
+		fila     []interface{}
+		esperado filaPrueba
+		err      string
+	}{
+		{
+			nombre:   "tipos nativos del driver",
+			columnas: columnas,
+			fila:     []interface{}{"CERCORBEI", int64(12), 8990.5, fecha, true},
+			esperado: filaPrueba{Codigo: "CERCORBEI", Unidades: 12, Precio: 8990.5, Fecha: fecha, Nula: true},
+		},
+		{
+			nombre:   "DECIMAL y texto como bytes",
+			columnas: columnas,
+			fila:     []interface{}{[]byte("CERCORBEI"), []byte("12.000"), []byte("8990.50"), []byte("2025-03-14"), []byte("0")},
+			esperado: filaPrueba{Codigo: "CERCORBEI", Unidades: 12, Precio: 8990.5, Fecha: fecha},
+		},
+		{
+			nombre:   "NULL deja el valor cero",
+			columnas: columnas,
+			fila:     []interface{}{nil, nil, nil, nil, nil},
+			esperado: filaPrueba{},
+		},
+		{
+			nombre:   "número y fecha con hora como texto",
+			columnas: columnas,
+			fila:     []interface{}{int64(1234), " 7 ", "", "2025-03-14 00:00:00", int64(1)},
+			esperado: filaPrueba{Codigo: "1234", Unidades: 7, Fecha: fecha, Nula: true},
+		},
+		{
+			nombre:   "columnas opcionales presentes",
+			columnas: append([]string{"Marca", "Caja"}, columnas...),
+			fila:     []interface{}{"CORONA", int64(6), "X", int64(1), 1.5, fecha, false},
+			esperado: filaPrueba{Marca: "CORONA", Caja: 6, Codigo: "X", Unidades: 1, Precio: 1.5, Fecha: fecha},
+		},
+		{
+			nombre:   "columnas adicionales se ignoran",
+			columnas: append([]string{"Otra"}, columnas...),
+			fila:     []interface{}{"?", "X", int64(1), 1.5, fecha, false},
+			esperado: filaPrueba{Codigo: "X", Unidades: 1, Precio: 1.5, Fecha: fecha},
+		},
+		{
+			nombre:   "decimales en un campo entero",
+			columnas: columnas,
+			fila:     []interface{}{"X", "2.5", 1.0, fecha, false},
+			err:      `fila 1, columna "Unidades": valor con decimales 2.5`,
+		},
+		{
+			nombre:   "entero fuera de rango",
+			columnas: append([]string{"Caja"}, columnas...),
+			fila:     []interface{}{int64(300), "X", int64(1), 1.0, fecha, false},
+			err:      "fuera del rango de int8",
+		},
+		{
+			nombre:   "texto no numérico",
+			columnas: columnas,
+			fila:     []interface{}{"X", "doce", 1.0, fecha, false},
+			err:      `valor no numérico "doce"`,
+		},
+		{
+			nombre:   "fecha no válida",
+			columnas: columnas,
+			fila:     []interface{}{"X", int64(1), 1.0, "14/03/2025", false},
+			err:      `fecha no válida "14/03/2025"`,
+		},
+		{
+			nombre:   "columnas obligatorias faltantes",
+			columnas: []string{"Codigo", "Fecha"},
+			fila:     []interface{}{"X", fecha},
+			err:      "columnas faltantes para filaPrueba: Nula, Precio Unitario, Unidades",
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			var filas []filaPrueba
+			err := ScanAll(NewMockRows(c.columnas, [][]interface{}{c.fila}), &filas)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("error = %v, se esperaba %q", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ScanAll: %v", err)
+			}
+			if len(filas) != 1 {
+				t.Fatalf("filas = %d, se esperaba 1", len(filas))
+			}
+			if filas[0] != c.esperado {
+				t.Errorf("fila = %+v, se esperaba %+v", filas[0], c.esperado)
+			}
+		})
+	}
+}
+
+func TestScanAllDestinoNoValido(t *testing.T) {
+	rows := NewMockRows([]string{"Codigo"}, nil)
+	var filas []filaPrueba
+	destinos := []interface{}{filas, &[]string{}, new(filaPrueba)}
+	for _, dest := range destinos {
+		if err := ScanAll(rows, dest); err == nil {
+			t.Errorf("ScanAll(%T) no devolvió error", dest)
+		}
+	}
+}
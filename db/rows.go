@@ -0,0 +1,12 @@
+package db
+
+// Rows abstrae un conjunto de filas de resultados.
+// La implementan tanto *sql.Rows como MockRows, lo que permite que los servicios
+// trabajen igual con las bases de datos reales y con datos en memoria.
+type Rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Close() error
+	Err() error
+}
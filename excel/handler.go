@@ -2,6 +2,7 @@ package excel
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,8 +11,12 @@ import (
 	"github.com/pablojnd/rotacion/db"
 	"github.com/pablojnd/rotacion/models"
 	"github.com/pablojnd/rotacion/services"
+	"github.com/pablojnd/rotacion/utils"
 )
 
+// tamanoMaximoArchivo limita el tamaño de las listas de códigos subidas (10 MB)
+const tamanoMaximoArchivo = 10 << 20
+
 // Handler gestiona las solicitudes relacionadas con la exportación a Excel
 type Handler struct {
 	sqlServer         *db.SQLServerDB
@@ -19,6 +24,7 @@ type Handler struct {
 	excelService      *services.ExcelService
 	ventasService     *services.VentasService
 	inventarioService *services.InventarioService
+	sucursalService   *services.SucursalService
 }
 
 // ExportRequest representa una solicitud de exportación a Excel
@@ -29,13 +35,15 @@ type ExportRequest struct {
 	Filename string        `json:"filename"`
 }
 
-// NewHandler crea un nuevo manejador para operaciones Excel
+// NewHandler crea un nuevo manejador para operaciones Excel.
+// sqlServer y mysql pueden ser nil cuando la API se ejecuta sobre fixtures.
 func NewHandler(
 	sqlServer *db.SQLServerDB,
 	mysql *db.MySQLDB,
 	excelService *services.ExcelService,
 	ventasService *services.VentasService,
 	inventarioService *services.InventarioService,
+	sucursalService *services.SucursalService,
 ) *Handler {
 	return &Handler{
 		sqlServer:         sqlServer,
@@ -43,6 +51,7 @@ func NewHandler(
 		excelService:      excelService,
 		ventasService:     ventasService,
 		inventarioService: inventarioService,
+		sucursalService:   sucursalService,
 	}
 }
 
@@ -57,10 +66,13 @@ func (h *Handler) ExportGeneric(w http.ResponseWriter, r *http.Request) {
 	var db interface{}
 
 	// Seleccionar la base de datos correcta
-	if req.Database == "sqlserver" {
+	if req.Database == "sqlserver" && h.sqlServer != nil {
 		db = h.sqlServer.DB
-	} else {
+	} else if req.Database != "sqlserver" && h.mysql != nil {
 		db = h.mysql.DB
+	} else {
+		http.Error(w, "Base de datos no disponible en modo fixtures", http.StatusServiceUnavailable)
+		return
 	}
 
 	// Generar el Excel usando el servicio
@@ -74,14 +86,31 @@ func (h *Handler) ExportGeneric(w http.ResponseWriter, r *http.Request) {
 	services.SendExcelResponse(w, excelBytes, req.Filename)
 }
 
-// ExportVentas exporta las ventas a Excel
+// ExportVentas exporta las ventas a Excel. Por POST se limita a la lista de códigos exactos del
+// cuerpo (JSON o archivo XLSX/CSV).
 func (h *Handler) ExportVentas(w http.ResponseWriter, r *http.Request) {
 	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSucursalNoValida) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
 	filtro := models.VentasFiltro{
 		FechaInicio:    r.URL.Query().Get("fechaInicio"),
 		FechaFin:       r.URL.Query().Get("fechaFin"),
-		Sucursal:       parseIntParam(r.URL.Query().Get("sucursal"), 211),
+		Sucursales:     sucursales,
 		CodigoProducto: r.URL.Query().Get("codigo"),
+		ExcluirNulas:   r.URL.Query().Get("excluirNulas") == "true",
+	}
+	if r.Method == http.MethodPost {
+		if filtro.Codigos, err = utils.LeerCodigosSolicitud(w, r, tamanoMaximoArchivo); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
 	// Determinar el tipo de consulta
@@ -101,14 +130,31 @@ func (h *Handler) ExportVentas(w http.ResponseWriter, r *http.Request) {
 	services.SendExcelResponse(w, excelBytes, filename)
 }
 
-// ExportVentasAgrupadas exporta las ventas agrupadas a Excel
+// ExportVentasAgrupadas exporta las ventas agrupadas a Excel. Por POST se limita a la lista de
+// códigos exactos del cuerpo (JSON o archivo XLSX/CSV).
 func (h *Handler) ExportVentasAgrupadas(w http.ResponseWriter, r *http.Request) {
 	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSucursalNoValida) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
 	filtro := models.VentasFiltro{
 		FechaInicio:    r.URL.Query().Get("fechaInicio"),
 		FechaFin:       r.URL.Query().Get("fechaFin"),
-		Sucursal:       parseIntParam(r.URL.Query().Get("sucursal"), 211),
+		Sucursales:     sucursales,
 		CodigoProducto: r.URL.Query().Get("codigo"),
+		ExcluirNulas:   r.URL.Query().Get("excluirNulas") == "true",
+	}
+	if r.Method == http.MethodPost {
+		if filtro.Codigos, err = utils.LeerCodigosSolicitud(w, r, tamanoMaximoArchivo); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
 	// Usar el servicio para exportar a Excel
@@ -122,15 +168,186 @@ func (h *Handler) ExportVentasAgrupadas(w http.ResponseWriter, r *http.Request)
 	services.SendExcelResponse(w, excelBytes, filename)
 }
 
-// ExportInventario exporta el inventario a Excel
-func (h *Handler) ExportInventario(w http.ResponseWriter, r *http.Request) {
+// ExportSerieVentas exporta la serie de ventas por período a Excel, con los períodos como columnas
+func (h *Handler) ExportSerieVentas(w http.ResponseWriter, r *http.Request) {
 	// Obtener parámetros de la consulta
-	anio := parseIntParam(r.URL.Query().Get("anio"), time.Now().Year())
-	codigoProducto := r.URL.Query().Get("codigo")
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSucursalNoValida) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	filtro := models.VentasFiltro{
+		FechaInicio:    r.URL.Query().Get("fechaInicio"),
+		FechaFin:       r.URL.Query().Get("fechaFin"),
+		Sucursales:     sucursales,
+		CodigoProducto: r.URL.Query().Get("codigo"),
+		ExcluirNulas:   r.URL.Query().Get("excluirNulas") == "true",
+	}
+	granularidad, err := models.ParseGranularidad(r.URL.Query().Get("granularidad"))
+	if err == nil {
+		err = filtro.Validar()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Usar el servicio para exportar a Excel
+	excelBytes, filename, err := h.ventasService.ExportarSerieVentas(filtro, granularidad)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSerieNoValida) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Error al generar Excel: %v", err), status)
+		return
+	}
+
+	// Enviar respuesta
+	services.SendExcelResponse(w, excelBytes, filename)
+}
 
-	filtro := models.InventarioFiltro{
-		Anio:           anio,
-		CodigoProducto: codigoProducto,
+// ExportPromociones exporta el análisis de efectividad de precios y ofertas a Excel
+func (h *Handler) ExportPromociones(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSucursalNoValida) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	filtro := models.VentasFiltro{
+		FechaInicio:    r.URL.Query().Get("fechaInicio"),
+		FechaFin:       r.URL.Query().Get("fechaFin"),
+		Sucursales:     sucursales,
+		CodigoProducto: r.URL.Query().Get("codigo"),
+	}
+	tolerancia, _ := strconv.ParseFloat(r.URL.Query().Get("tolerancia"), 64)
+	params := models.ParametrosPromocion{
+		ToleranciaPct:   tolerancia,
+		BrechaDias:      parseIntParam(r.URL.Query().Get("brecha"), 0),
+		VentanaBaseDias: parseIntParam(r.URL.Query().Get("ventanaBase"), 0),
+		QuiebreDias:     parseIntParam(r.URL.Query().Get("quiebre"), 0),
+	}
+	err = filtro.Validar()
+	if err == nil {
+		err = params.Validar()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Usar el servicio para exportar a Excel
+	excelBytes, filename, err := h.ventasService.ExportarAnalisisPromociones(filtro, params)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error al generar Excel: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Enviar respuesta
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// ExportClientes exporta el reporte de clientes a Excel
+func (h *Handler) ExportClientes(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSucursalNoValida) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	filtro := models.VentasFiltro{
+		FechaInicio: r.URL.Query().Get("fechaInicio"),
+		FechaFin:    r.URL.Query().Get("fechaFin"),
+		Sucursales:  sucursales,
+	}
+	params := models.ParametrosClientes{
+		Orden:     r.URL.Query().Get("orden"),
+		Top:       parseIntParam(r.URL.Query().Get("top"), 0),
+		Productos: parseIntParam(r.URL.Query().Get("productos"), 0),
+	}
+	err = filtro.Validar()
+	if err == nil {
+		err = params.Validar()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Usar el servicio para exportar a Excel
+	excelBytes, filename, err := h.ventasService.ExportarReporteClientes(filtro, params)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error al generar Excel: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Enviar respuesta
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// ExportDocumentos exporta el desglose por tipo de documento y los documentos anulados a Excel
+func (h *Handler) ExportDocumentos(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	sucursales, err := h.sucursalService.ParseSucursales(r.URL.Query().Get("sucursal"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSucursalNoValida) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	filtro := models.VentasFiltro{
+		FechaInicio:    r.URL.Query().Get("fechaInicio"),
+		FechaFin:       r.URL.Query().Get("fechaFin"),
+		Sucursales:     sucursales,
+		CodigoProducto: r.URL.Query().Get("codigo"),
+	}
+	if err := filtro.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Usar el servicio para exportar a Excel
+	excelBytes, filename, err := h.ventasService.ExportarReporteDocumentos(filtro)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error al generar Excel: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Enviar respuesta
+	services.SendExcelResponse(w, excelBytes, filename)
+}
+
+// ExportInventario exporta el inventario a Excel. Por POST se limita a la lista de códigos
+// exactos del cuerpo (JSON o archivo XLSX/CSV).
+func (h *Handler) ExportInventario(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la consulta
+	filtro := parseInventarioFiltro(r)
+	if err := filtro.Validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPost {
+		codigos, err := utils.LeerCodigosSolicitud(w, r, tamanoMaximoArchivo)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filtro.Codigos = codigos
 	}
 
 	// Usar el servicio para exportar a Excel
@@ -144,6 +361,20 @@ func (h *Handler) ExportInventario(w http.ResponseWriter, r *http.Request) {
 	services.SendExcelResponse(w, excelBytes, filename)
 }
 
+// parseInventarioFiltro construye el filtro de inventario: año o rango de años de producción,
+// rango de fechas de ingreso, código y desglose por año
+func parseInventarioFiltro(r *http.Request) models.InventarioFiltro {
+	return models.InventarioFiltro{
+		Anio:              parseIntParam(r.URL.Query().Get("anio"), time.Now().Year()),
+		CodigoProducto:    r.URL.Query().Get("codigo"),
+		AnioDesde:         parseIntParam(r.URL.Query().Get("anioDesde"), 0),
+		AnioHasta:         parseIntParam(r.URL.Query().Get("anioHasta"), 0),
+		FechaIngresoDesde: r.URL.Query().Get("fechaIngresoDesde"),
+		FechaIngresoHasta: r.URL.Query().Get("fechaIngresoHasta"),
+		DesglosePorAnio:   r.URL.Query().Get("desglosePorAnio") == "true",
+	}
+}
+
 // parseIntParam convierte un string a int con valor predeterminado
 func parseIntParam(value string, defaultValue int) int {
 	if value == "" {
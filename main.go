@@ -7,6 +7,7 @@ import (
 	"syscall"
 
 	"github.com/pablojnd/rotacion/config"
+	"github.com/pablojnd/rotacion/datasource"
 	"github.com/pablojnd/rotacion/db"
 	"github.com/pablojnd/rotacion/server"
 )
@@ -27,27 +28,52 @@ func main() {
 	}
 	log.Println("✅ Configuración cargada correctamente")
 
-	// Inicializar conexiones a bases de datos
-	log.Println("📊 Conectando a SQL Server...")
-	sqlServer, err := db.NewSQLServerConnection(cfg)
-	if err != nil {
-		log.Fatalf("❌ Error al conectar con SQL Server: %v", err)
-	}
-	log.Printf("✅ Conexión a SQL Server establecida: %s@%s:%d/%s\n",
-		cfg.SQLServerUser, cfg.SQLServerHost, cfg.SQLServerPort, cfg.SQLServerDatabase)
-	defer sqlServer.Close()
+	var (
+		sqlServer        *db.SQLServerDB
+		mysql            *db.MySQLDB
+		ventasSource     datasource.VentasSource
+		inventarioSource datasource.InventarioSource
+	)
 
-	log.Println("📊 Conectando a MySQL...")
-	mysql, err := db.NewMySQLConnection(cfg)
-	if err != nil {
-		log.Fatalf("❌ Error al conectar con MySQL: %v", err)
+	if cfg.UsaFixtures() {
+		// Ejecutar sobre archivos locales, sin conexión a las bases de datos del ERP
+		log.Printf("📁 Cargando fixtures desde %s...\n", cfg.FixturesDir)
+		fixtures, err := datasource.NewFixtures(cfg.FixturesDir)
+		if err != nil {
+			log.Fatalf("❌ Error al cargar fixtures: %v", err)
+		}
+		log.Println("✅ Fixtures cargados correctamente")
+		ventasSource = fixtures
+		inventarioSource = fixtures
+	} else {
+		// Inicializar conexiones a bases de datos
+		log.Println("📊 Conectando a SQL Server...")
+		sqlServer, err = db.NewSQLServerConnection(cfg)
+		if err != nil {
+			log.Fatalf("❌ Error al conectar con SQL Server: %v", err)
+		}
+		log.Printf("✅ Conexión a SQL Server establecida: %s@%s:%d/%s\n",
+			cfg.SQLServerUser, cfg.SQLServerHost, cfg.SQLServerPort, cfg.SQLServerDatabase)
+		defer sqlServer.Close()
+
+		log.Println("📊 Conectando a MySQL...")
+		mysql, err = db.NewMySQLConnection(cfg)
+		if err != nil {
+			log.Fatalf("❌ Error al conectar con MySQL: %v", err)
+		}
+		log.Printf("✅ Conexión a MySQL establecida: %s@%s:%d/%s\n",
+			cfg.MySQLUser, cfg.MySQLHost, cfg.MySQLPort, cfg.MySQLDatabase)
+		defer mysql.Close()
+
+		ventasSource = datasource.NewSQLServerVentas(sqlServer)
+		inventarioSource = datasource.NewMySQLInventario(mysql)
 	}
-	log.Printf("✅ Conexión a MySQL establecida: %s@%s:%d/%s\n",
-		cfg.MySQLUser, cfg.MySQLHost, cfg.MySQLPort, cfg.MySQLDatabase)
-	defer mysql.Close()
 
 	// Inicializar el servidor
-	srv := server.New(cfg, sqlServer, mysql)
+	srv, err := server.New(cfg, sqlServer, mysql, ventasSource, inventarioSource)
+	if err != nil {
+		log.Fatalf("❌ Error al inicializar el servidor: %v", err)
+	}
 
 	// Manejar señales para cerrar gracefully
 	stop := make(chan os.Signal, 1)
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Alias relaciona un código de inventario (MySQL saldos.COD_ART) con su código
+// de ventas (SQL Server PRODUCTO.CODIGO_INTERNO)
+type Alias struct {
+	CodigoInventario string    `json:"codigoInventario"`
+	CodigoVentas     string    `json:"codigoVentas"`
+	Notas            string    `json:"notas,omitempty"`
+	CreadoPor        string    `json:"creadoPor,omitempty"`
+	CreadoEn         time.Time `json:"creadoEn"`
+	ActualizadoPor   string    `json:"actualizadoPor,omitempty"`
+	ActualizadoEn    time.Time `json:"actualizadoEn"`
+}
+
+// ResultadoImportacionAlias resume una carga masiva de alias desde archivo
+type ResultadoImportacionAlias struct {
+	Creados      int      `json:"creados"`
+	Actualizados int      `json:"actualizados"`
+	Errores      []string `json:"errores,omitempty"`
+}
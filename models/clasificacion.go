@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Criterios para la clasificación ABC
+const (
+	CriterioABCVenta    = "venta"    // VentaNetaTotalClp
+	CriterioABCCantidad = "cantidad" // CantidadVendida
+	CriterioABCUtilidad = "utilidad" // UtilidadClp
+)
+
+// ParametrosABC define cómo clasificar productos en clases ABC y XYZ.
+// Los umbrales son punteros para distinguir un valor no indicado (nil), que se completa
+// con la configuración del servidor, de un cero explícito (por ejemplo, UmbralB = 0 deja
+// la clase B vacía).
+type ParametrosABC struct {
+	Criterio string   `json:"criterio"`
+	UmbralA  *float64 `json:"umbralA"` // % de participación acumulada que cubre la clase A
+	UmbralB  *float64 `json:"umbralB"` // % de participación de la clase B (C es el resto)
+
+	CalcularXYZ bool     `json:"calcularXYZ"`
+	UmbralX     *float64 `json:"umbralX"` // Coeficiente de variación máximo para la clase X
+	UmbralY     *float64 `json:"umbralY"` // Coeficiente de variación máximo para la clase Y
+}
+
+// Completar rellena los parámetros no indicados con los valores por defecto
+func (p *ParametrosABC) Completar(defecto ParametrosABC) {
+	if p.Criterio == "" {
+		p.Criterio = defecto.Criterio
+	}
+	completarUmbral(&p.UmbralA, defecto.UmbralA)
+	completarUmbral(&p.UmbralB, defecto.UmbralB)
+	completarUmbral(&p.UmbralX, defecto.UmbralX)
+	completarUmbral(&p.UmbralY, defecto.UmbralY)
+}
+
+// completarUmbral copia el valor por defecto si el umbral no fue indicado
+func completarUmbral(umbral **float64, defecto *float64) {
+	if *umbral == nil && defecto != nil {
+		valor := *defecto
+		*umbral = &valor
+	}
+}
+
+// Validar valida los parámetros de clasificación
+func (p *ParametrosABC) Validar() error {
+	switch p.Criterio {
+	case CriterioABCVenta, CriterioABCCantidad, CriterioABCUtilidad:
+	default:
+		return fmt.Errorf("criterio no válido: %q (use %q, %q o %q)",
+			p.Criterio, CriterioABCVenta, CriterioABCCantidad, CriterioABCUtilidad)
+	}
+
+	if p.UmbralA == nil || p.UmbralB == nil || p.UmbralX == nil || p.UmbralY == nil {
+		return errors.New("faltan umbrales de clasificación ABC o XYZ")
+	}
+	if *p.UmbralA <= 0 || *p.UmbralB < 0 || *p.UmbralA+*p.UmbralB > 100 {
+		return errors.New("umbrales ABC no válidos: A debe ser mayor que 0 y A + B no puede superar 100")
+	}
+	if *p.UmbralX <= 0 || *p.UmbralY < *p.UmbralX {
+		return errors.New("umbrales XYZ no válidos: X debe ser mayor que 0 e Y mayor o igual que X")
+	}
+
+	return nil
+}
+
+// ClasificacionProducto representa la clase ABC (y opcionalmente XYZ) de un producto
+type ClasificacionProducto struct {
+	CodigoProducto         string   `json:"codigoProducto"`
+	CodigoVentas           string   `json:"codigoVentas"`
+	Nombre                 string   `json:"nombre"`
+	Marca                  string   `json:"marca"`
+	Categoria              string   `json:"categoria"`
+	Valor                  float64  `json:"valor"`
+	Participacion          float64  `json:"participacion"`
+	ParticipacionAcumulada float64  `json:"participacionAcumulada"`
+	ClaseABC               string   `json:"claseABC"`
+	ClaseXYZ               string   `json:"claseXYZ,omitempty"`
+	CoeficienteVariacion   *float64 `json:"coeficienteVariacion,omitempty"`
+}
+
+// ResumenClase resume la cantidad de productos y la participación de una clase
+type ResumenClase struct {
+	Clase         string  `json:"clase"`
+	Productos     int     `json:"productos"`
+	Valor         float64 `json:"valor"`
+	Participacion float64 `json:"participacion"`
+}
+
+// ReporteABC contiene la clasificación de productos y su resumen por clase
+type ReporteABC struct {
+	Parametros ParametrosABC           `json:"parametros"`
+	Productos  []ClasificacionProducto `json:"productos"`
+	ResumenABC []ResumenClase          `json:"resumenABC"`
+	ResumenXYZ []ResumenClase          `json:"resumenXYZ,omitempty"`
+}
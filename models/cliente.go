@@ -0,0 +1,118 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ClienteSinCliente es el nombre que reciben las ventas sin cliente identificado (venta de mesón)
+const ClienteSinCliente = "Sin Cliente"
+
+// Criterios para ordenar el ranking de clientes
+const (
+	OrdenClientesVenta    = "venta"
+	OrdenClientesUnidades = "unidades"
+)
+
+// Segmentos RFM (recencia, frecuencia y monto)
+const (
+	SegmentoCampeones   = "Campeones"   // Compran seguido y hace poco
+	SegmentoLeales      = "Leales"      // Compran seguido
+	SegmentoNuevos      = "Nuevos"      // Compraron hace poco, con pocas compras
+	SegmentoEnRiesgo    = "En riesgo"   // Compraban seguido pero no recientemente
+	SegmentoPerdidos    = "Perdidos"    // Pocas compras y hace tiempo
+	SegmentoOcasionales = "Ocasionales" // El resto
+)
+
+// ParametrosClientes define el orden del ranking y el detalle de productos de los clientes clave
+type ParametrosClientes struct {
+	Orden     string `json:"orden"`     // venta o unidades
+	Top       int    `json:"top"`       // Clientes clave (primeros del ranking) con detalle de productos
+	Productos int    `json:"productos"` // Productos informados por cliente clave
+}
+
+// Validar valida los parámetros y aplica valores por defecto
+func (p *ParametrosClientes) Validar() error {
+	if p.Orden == "" {
+		p.Orden = OrdenClientesVenta
+	}
+	if p.Top == 0 {
+		p.Top = 20
+	}
+	if p.Productos == 0 {
+		p.Productos = 10
+	}
+	if p.Orden != OrdenClientesVenta && p.Orden != OrdenClientesUnidades {
+		return fmt.Errorf("orden no válido: %q (use %q o %q)", p.Orden, OrdenClientesVenta, OrdenClientesUnidades)
+	}
+	if p.Top < 1 || p.Top > 500 {
+		return errors.New("top debe estar entre 1 y 500")
+	}
+	if p.Productos < 1 || p.Productos > 100 {
+		return errors.New("productos debe estar entre 1 y 100")
+	}
+	return nil
+}
+
+// ProductoCliente resume las compras de un producto por un cliente
+type ProductoCliente struct {
+	CodigoProducto string  `json:"codigoProducto"`
+	NombreProducto string  `json:"nombreProducto"`
+	Unidades       float64 `json:"unidades"`
+	VentaClp       int     `json:"ventaClp"`
+	Documentos     int     `json:"documentos"`
+}
+
+// ResumenCliente contiene las compras de un cliente en el período y su segmentación RFM
+type ResumenCliente struct {
+	Cliente            string  `json:"cliente"`
+	RankingVenta       int     `json:"rankingVenta"`
+	RankingUnidades    int     `json:"rankingUnidades"`
+	Documentos         int     `json:"documentos"` // Boletas y facturas distintas
+	Boletas            int     `json:"boletas"`
+	Facturas           int     `json:"facturas"`
+	Unidades           float64 `json:"unidades"`
+	VentaClp           int     `json:"ventaClp"`
+	ParticipacionPct   float64 `json:"participacionPct"` // % de la venta con cliente identificado (Sin Cliente: de la venta total)
+	TicketPromedioClp  float64 `json:"ticketPromedioClp"`
+	ProductosDistintos int     `json:"productosDistintos"`
+	PrimeraCompra      string  `json:"primeraCompra"`
+	UltimaCompra       string  `json:"ultimaCompra"`
+	DiasCompra         int     `json:"diasCompra"`   // Días distintos con compras
+	RecenciaDias       int     `json:"recenciaDias"` // Días desde la última compra hasta la fecha de referencia
+	// Días promedio entre días de compra; nil con un solo día de compra
+	FrecuenciaDias *float64 `json:"frecuenciaDias"`
+
+	// Puntajes RFM de 1 a 5 (quintiles entre los clientes identificados) y segmento
+	Recencia   int    `json:"recencia,omitempty"`
+	Frecuencia int    `json:"frecuencia,omitempty"`
+	Monto      int    `json:"monto,omitempty"`
+	RFM        string `json:"rfm,omitempty"`
+	Segmento   string `json:"segmento,omitempty"`
+
+	// Productos más comprados (solo clientes clave)
+	Productos []ProductoCliente `json:"productos,omitempty"`
+}
+
+// ResumenSegmento suma los clientes de un segmento RFM
+type ResumenSegmento struct {
+	Segmento         string  `json:"segmento"`
+	Clientes         int     `json:"clientes"`
+	VentaClp         int     `json:"ventaClp"`
+	ParticipacionPct float64 `json:"participacionPct"`
+}
+
+// ReporteClientes contiene el ranking de clientes, su segmentación RFM y las ventas sin
+// cliente identificado, informadas por separado
+type ReporteClientes struct {
+	FechaInicio     string             `json:"fechaInicio"`
+	FechaFin        string             `json:"fechaFin"`
+	Sucursales      []int              `json:"sucursales"`
+	FechaReferencia string             `json:"fechaReferencia"` // Fecha de cálculo de la recencia: fin del período o hoy
+	Parametros      ParametrosClientes `json:"parametros"`
+	TotalClientes   int                `json:"totalClientes"`
+	VentaClp        int                `json:"ventaClp"` // Venta con cliente identificado
+	Clientes        []ResumenCliente   `json:"clientes"`
+	Segmentos       []ResumenSegmento  `json:"segmentos"`
+	SinCliente      *ResumenCliente    `json:"sinCliente"` // nil sin ventas de mesón
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaximoCodigosLista es la cantidad máxima de códigos de una consulta por lista
+const MaximoCodigosLista = 5000
+
+// NormalizarCodigos limpia una lista de códigos de producto: quita espacios, omite los vacíos y
+// los repetidos, y conserva el orden. Los códigos viajan a las consultas separados por comas,
+// por lo que no pueden contenerlas.
+func NormalizarCodigos(codigos []string) ([]string, error) {
+	var lista []string
+	vistos := make(map[string]bool)
+	for _, codigo := range codigos {
+		codigo = strings.TrimSpace(codigo)
+		if codigo == "" || vistos[codigo] {
+			continue
+		}
+		if strings.Contains(codigo, ",") {
+			return nil, fmt.Errorf("código no válido: %q (no puede contener comas)", codigo)
+		}
+		vistos[codigo] = true
+		lista = append(lista, codigo)
+	}
+	if len(lista) == 0 {
+		return nil, errors.New("la lista de códigos está vacía")
+	}
+	if len(lista) > MaximoCodigosLista {
+		return nil, fmt.Errorf("la lista tiene %d códigos; el máximo es %d", len(lista), MaximoCodigosLista)
+	}
+	return lista, nil
+}
+
+// incluyeCodigo indica si un código está en la lista (vacía = todos los códigos)
+func incluyeCodigo(codigos []string, codigo string) bool {
+	if len(codigos) == 0 {
+		return true
+	}
+	for _, c := range codigos {
+		if c == codigo {
+			return true
+		}
+	}
+	return false
+}
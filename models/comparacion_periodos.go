@@ -0,0 +1,128 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Modos para elegir el período anterior de la comparación
+const (
+	ModoComparacionAnioAnterior    = "anio_anterior"    // Mismas fechas del año anterior
+	ModoComparacionPeriodoAnterior = "periodo_anterior" // Período inmediatamente anterior de igual duración
+)
+
+// Estados de un producto entre los dos períodos comparados
+const (
+	EstadoPeriodoContinuo     = "continuo"     // Con ventas en ambos períodos
+	EstadoPeriodoNuevo        = "nuevo"        // Solo con ventas en el período actual
+	EstadoPeriodoDesaparecido = "desaparecido" // Solo con ventas en el período anterior
+)
+
+// ParametrosComparacionPeriodos define cómo se obtiene el período anterior. Las fechas
+// explícitas tienen prioridad sobre el modo.
+type ParametrosComparacionPeriodos struct {
+	Modo                string `json:"modo"`
+	FechaInicioAnterior string `json:"fechaInicioAnterior,omitempty"`
+	FechaFinAnterior    string `json:"fechaFinAnterior,omitempty"`
+	AnioAnterior        int    `json:"anioAnterior,omitempty"` // Año de producción del inventario anterior; 0 sigue a las fechas
+}
+
+// Validar valida los parámetros y aplica valores por defecto
+func (p *ParametrosComparacionPeriodos) Validar() error {
+	if p.Modo == "" {
+		p.Modo = ModoComparacionAnioAnterior
+	}
+	if p.Modo != ModoComparacionAnioAnterior && p.Modo != ModoComparacionPeriodoAnterior {
+		return fmt.Errorf("modo no válido: %q (use %q o %q)", p.Modo, ModoComparacionAnioAnterior, ModoComparacionPeriodoAnterior)
+	}
+	if (p.FechaInicioAnterior == "") != (p.FechaFinAnterior == "") {
+		return errors.New("fechaInicioAnterior y fechaFinAnterior deben indicarse juntas")
+	}
+	if p.FechaInicioAnterior != "" {
+		inicio, err := time.Parse("2006-01-02", p.FechaInicioAnterior)
+		if err != nil {
+			return errors.New("fechaInicioAnterior debe estar en formato YYYY-MM-DD")
+		}
+		fin, err := time.Parse("2006-01-02", p.FechaFinAnterior)
+		if err != nil {
+			return errors.New("fechaFinAnterior debe estar en formato YYYY-MM-DD")
+		}
+		if fin.Before(inicio) {
+			return errors.New("fechaFinAnterior no puede ser anterior a fechaInicioAnterior")
+		}
+	}
+	if p.AnioAnterior < 0 {
+		return errors.New("anioAnterior no puede ser negativo")
+	}
+	return nil
+}
+
+// PeriodoComparado identifica uno de los dos períodos de la comparación
+type PeriodoComparado struct {
+	FechaInicio string `json:"fechaInicio"`
+	FechaFin    string `json:"fechaFin"`
+	DiasPeriodo int    `json:"diasPeriodo"`
+
+	// Años de producción del inventario usado para la rotación; desde 0 = sin límite inferior
+	AnioInventarioDesde int `json:"anioInventarioDesde"`
+	AnioInventarioHasta int `json:"anioInventarioHasta"`
+}
+
+// MetricasPeriodo resume las ventas y la rotación de un producto (o del total) en un período
+type MetricasPeriodo struct {
+	CantidadVendida       float64 `json:"cantidadVendida"`
+	VentaNetaClp          int     `json:"ventaNetaClp"`
+	UtilidadClp           float64 `json:"utilidadClp"`
+	RotacionInventario    float64 `json:"rotacionInventario"`
+	CantidadTransacciones int     `json:"cantidadTransacciones"`
+}
+
+// Variacion es la diferencia entre el período actual y el anterior
+type Variacion struct {
+	Delta float64 `json:"delta"`
+	// Variación porcentual sobre el período anterior; nil si el anterior es 0
+	Pct *float64 `json:"pct"`
+}
+
+// VariacionesPeriodo contiene la variación de cada métrica comparada
+type VariacionesPeriodo struct {
+	CantidadVendida    Variacion `json:"cantidadVendida"`
+	VentaNetaClp       Variacion `json:"ventaNetaClp"`
+	UtilidadClp        Variacion `json:"utilidadClp"`
+	RotacionInventario Variacion `json:"rotacionInventario"`
+}
+
+// ComparacionPeriodoProducto muestra las métricas de un producto en ambos períodos y su variación
+type ComparacionPeriodoProducto struct {
+	CodigoProducto string             `json:"codigoProducto"`
+	CodigoVentas   string             `json:"codigoVentas"`
+	Nombre         string             `json:"nombre"`
+	Marca          string             `json:"marca"`
+	Categoria      string             `json:"categoria"`
+	Estado         string             `json:"estado"` // continuo, nuevo o desaparecido
+	Actual         MetricasPeriodo    `json:"actual"`
+	Anterior       MetricasPeriodo    `json:"anterior"`
+	Variaciones    VariacionesPeriodo `json:"variaciones"`
+}
+
+// TotalesComparacionPeriodos suma todos los productos de cada período
+type TotalesComparacionPeriodos struct {
+	Actual      MetricasPeriodo    `json:"actual"`
+	Anterior    MetricasPeriodo    `json:"anterior"`
+	Variaciones VariacionesPeriodo `json:"variaciones"`
+}
+
+// ComparacionPeriodos compara las ventas, el margen y la rotación de cada producto entre
+// dos períodos
+type ComparacionPeriodos struct {
+	Modo                   string                       `json:"modo,omitempty"` // Vacío si se indicaron las fechas anteriores
+	Actual                 PeriodoComparado             `json:"actual"`
+	Anterior               PeriodoComparado             `json:"anterior"`
+	Sucursales             []int                        `json:"sucursales"`
+	Totales                TotalesComparacionPeriodos   `json:"totales"`
+	ProductosContinuos     int                          `json:"productosContinuos"`
+	ProductosNuevos        int                          `json:"productosNuevos"`
+	ProductosDesaparecidos int                          `json:"productosDesaparecidos"`
+	Productos              []ComparacionPeriodoProducto `json:"productos"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// MetricasSucursal resume las ventas y la rotación de un producto (o del total) en una sucursal
+type MetricasSucursal struct {
+	Sucursal             int     `json:"sucursal"`
+	CantidadVendida      float64 `json:"cantidadVendida"`
+	VentaNetaClp         int     `json:"ventaNetaClp"`
+	CantidadVentas       int     `json:"cantidadVentas"`
+	UltimaFechaVenta     string  `json:"ultimaFechaVenta"`
+	VelocidadVentaDiaria float64 `json:"velocidadVentaDiaria"`
+	RotacionInventario   float64 `json:"rotacionInventario"`
+	Participacion        float64 `json:"participacion"` // % de las unidades vendidas del producto (o del total)
+}
+
+// ComparacionProducto muestra las métricas de un producto en cada sucursal, lado a lado
+type ComparacionProducto struct {
+	CodigoProducto     string             `json:"codigoProducto"`
+	CodigoVentas       string             `json:"codigoVentas"`
+	Nombre             string             `json:"nombre"`
+	Marca              string             `json:"marca"`
+	Categoria          string             `json:"categoria"`
+	SinInventario      bool               `json:"sinInventario"` // Vendido pero sin coincidencia en inventario
+	SaldoPeriodo       float64            `json:"saldoPeriodo"`
+	CantidadVendida    float64            `json:"cantidadVendida"`
+	VentaNetaClp       int                `json:"ventaNetaClp"`
+	RotacionInventario float64            `json:"rotacionInventario"`
+	Sucursales         []MetricasSucursal `json:"sucursales"` // Mismo orden que ComparacionSucursales.Sucursales
+}
+
+// ComparacionSucursales compara las ventas y la rotación de cada producto entre sucursales
+type ComparacionSucursales struct {
+	FechaInicio string                `json:"fechaInicio"`
+	FechaFin    string                `json:"fechaFin"`
+	DiasPeriodo int                   `json:"diasPeriodo"`
+	Sucursales  []int                 `json:"sucursales"`
+	Totales     []MetricasSucursal    `json:"totales"`
+	Productos   []ComparacionProducto `json:"productos"`
+}
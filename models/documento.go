@@ -0,0 +1,55 @@
+package models
+
+// ResumenTipoDocumento resume los documentos de venta de un tipo (boleta o factura)
+type ResumenTipoDocumento struct {
+	TipoDocumento     string  `json:"tipoDocumento"`
+	Documentos        int     `json:"documentos"`
+	Lineas            int     `json:"lineas"`
+	Unidades          float64 `json:"unidades"`
+	VentaClp          int     `json:"ventaClp"` // Suma de las líneas; en anulados, su valor original
+	TicketPromedioClp float64 `json:"ticketPromedioClp"`
+}
+
+// DocumentoAnulado es una boleta o factura anulada del período
+type DocumentoAnulado struct {
+	TipoDocumento   string  `json:"tipoDocumento"`
+	CodigoDocumento string  `json:"codigoDocumento"`
+	Sucursal        int     `json:"sucursal"`
+	Fecha           string  `json:"fecha"`
+	Cliente         string  `json:"cliente"`
+	Lineas          int     `json:"lineas"`
+	Unidades        float64 `json:"unidades"`
+	ValorClp        int     `json:"valorClp"` // Total original de sus líneas
+}
+
+// ProductoAnulado resume las líneas anuladas de un producto
+type ProductoAnulado struct {
+	CodigoProducto string  `json:"codigoProducto"`
+	NombreProducto string  `json:"nombreProducto"`
+	Documentos     int     `json:"documentos"`
+	Unidades       float64 `json:"unidades"`
+	ValorClp       int     `json:"valorClp"`
+}
+
+// ResumenAnulados contiene los documentos anulados del período, por tipo, documento y producto
+type ResumenAnulados struct {
+	Documentos    int                    `json:"documentos"`
+	Lineas        int                    `json:"lineas"`
+	Unidades      float64                `json:"unidades"`
+	ValorClp      int                    `json:"valorClp"`
+	DocumentosPct float64                `json:"documentosPct"` // % de todos los documentos del período
+	PorTipo       []ResumenTipoDocumento `json:"porTipo"`
+	Productos     []ProductoAnulado      `json:"productos"`
+	Detalle       []DocumentoAnulado     `json:"detalle"`
+}
+
+// ReporteDocumentos contiene las ventas por tipo de documento, sin los anulados, que se
+// informan por separado
+type ReporteDocumentos struct {
+	FechaInicio string                 `json:"fechaInicio"`
+	FechaFin    string                 `json:"fechaFin"`
+	Sucursales  []int                  `json:"sucursales"`
+	PorTipo     []ResumenTipoDocumento `json:"porTipo"`
+	Total       ResumenTipoDocumento   `json:"total"`
+	Anulados    ResumenAnulados        `json:"anulados"`
+}
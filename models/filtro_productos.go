@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FiltroProductos selecciona las filas del reporte combinado por sus atributos y umbrales.
+// Los criterios se combinan (todos deben cumplirse); los vacíos o nil no filtran.
+type FiltroProductos struct {
+	// Valores aceptados, sin distinguir mayúsculas; vacío = todos
+	Marcas      []string `json:"marcas,omitempty"`
+	Categorias  []string `json:"categorias,omitempty"`
+	Dimensiones []string `json:"dimensiones,omitempty"`
+
+	DiasInventarioMin    *int     `json:"diasInventarioMin,omitempty"`
+	DiasInventarioMax    *int     `json:"diasInventarioMax,omitempty"`
+	PorcentajeVendidoMin *float64 `json:"porcentajeVendidoMin,omitempty"`
+	PorcentajeVendidoMax *float64 `json:"porcentajeVendidoMax,omitempty"`
+	VentaMinimaClp       *int     `json:"ventaMinimaClp,omitempty"`
+
+	SoloSinCoincidencia bool `json:"soloSinCoincidencia,omitempty"` // Sin emparejar entre inventario y ventas
+
+	// SALDO_PERIODO mayor que 0. SALDO_PERIODO es lo ingresado en los años de producción
+	// considerados menos lo vendido en el período, sin negativos; no es el stock físico a una
+	// fecha (ver /api/stock).
+	SoloConStock bool `json:"soloConStock,omitempty"`
+}
+
+// Validar valida que los rangos sean coherentes
+func (f *FiltroProductos) Validar() error {
+	if f.DiasInventarioMin != nil && *f.DiasInventarioMin < 0 || f.DiasInventarioMax != nil && *f.DiasInventarioMax < 0 {
+		return errors.New("los días en inventario no pueden ser negativos")
+	}
+	if f.DiasInventarioMin != nil && f.DiasInventarioMax != nil && *f.DiasInventarioMin > *f.DiasInventarioMax {
+		return fmt.Errorf("diasInventarioMin (%d) no puede ser mayor que diasInventarioMax (%d)", *f.DiasInventarioMin, *f.DiasInventarioMax)
+	}
+	if f.PorcentajeVendidoMin != nil && f.PorcentajeVendidoMax != nil && *f.PorcentajeVendidoMin > *f.PorcentajeVendidoMax {
+		return fmt.Errorf("porcentajeVendidoMin (%g) no puede ser mayor que porcentajeVendidoMax (%g)", *f.PorcentajeVendidoMin, *f.PorcentajeVendidoMax)
+	}
+	return nil
+}
+
+// Activo indica si el filtro tiene algún criterio
+func (f FiltroProductos) Activo() bool {
+	return len(f.Marcas) > 0 || len(f.Categorias) > 0 || len(f.Dimensiones) > 0 ||
+		f.DiasInventarioMin != nil || f.DiasInventarioMax != nil ||
+		f.PorcentajeVendidoMin != nil || f.PorcentajeVendidoMax != nil ||
+		f.VentaMinimaClp != nil || f.SoloSinCoincidencia || f.SoloConStock
+}
+
+// Incluye indica si una fila del reporte cumple todos los criterios del filtro
+func (f FiltroProductos) Incluye(r ReporteCombinado) bool {
+	switch {
+	case !valorAceptado(f.Marcas, r.Marca),
+		!valorAceptado(f.Categorias, r.Categoria),
+		!valorAceptado(f.Dimensiones, r.Dimensiones):
+		return false
+	case f.DiasInventarioMin != nil && r.DiasEnInventario < *f.DiasInventarioMin,
+		f.DiasInventarioMax != nil && r.DiasEnInventario > *f.DiasInventarioMax:
+		return false
+	case f.PorcentajeVendidoMin != nil && r.PorcentajeVendido < *f.PorcentajeVendidoMin,
+		f.PorcentajeVendidoMax != nil && r.PorcentajeVendido > *f.PorcentajeVendidoMax:
+		return false
+	case f.VentaMinimaClp != nil && r.VentaNetaTotalClp < *f.VentaMinimaClp:
+		return false
+	case f.SoloSinCoincidencia && r.MetodoCoincidencia != "":
+		return false
+	case f.SoloConStock && r.SaldoPeriodo <= 0:
+		return false
+	}
+	return true
+}
+
+// valorAceptado indica si el valor está entre los aceptados, sin distinguir mayúsculas (vacío = todos)
+func valorAceptado(aceptados []string, valor string) bool {
+	if len(aceptados) == 0 {
+		return true
+	}
+	for _, a := range aceptados {
+		if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(valor)) {
+			return true
+		}
+	}
+	return false
+}
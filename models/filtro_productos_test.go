@@ -0,0 +1,80 @@
+package models
+
+import "testing"
+
+func TestFiltroProductosIncluye(t *testing.T) {
+	entero := func(v int) *int { return &v }
+	decimal := func(v float64) *float64 { return &v }
+	fila := ReporteCombinado{
+		Marca:              "Marca Uno",
+		Categoria:          "PISOS",
+		Dimensiones:        "60X60",
+		DiasEnInventario:   90,
+		PorcentajeVendido:  40,
+		VentaNetaTotalClp:  50000,
+		MetodoCoincidencia: "exacta",
+		SaldoPeriodo:       12,
+	}
+
+	casos := []struct {
+		nombre   string
+		filtro   FiltroProductos
+		esperado bool
+	}{
+		{"sin criterios", FiltroProductos{}, true},
+		{"marca sin distinguir mayúsculas", FiltroProductos{Marcas: []string{"otra", " marca uno "}}, true},
+		{"marca distinta", FiltroProductos{Marcas: []string{"OTRA"}}, false},
+		{"categoría y dimensiones", FiltroProductos{Categorias: []string{"pisos"}, Dimensiones: []string{"60x60"}}, true},
+		{"dimensiones distintas", FiltroProductos{Categorias: []string{"pisos"}, Dimensiones: []string{"30X30"}}, false},
+		// Los límites son inclusivos
+		{"días en el rango", FiltroProductos{DiasInventarioMin: entero(90), DiasInventarioMax: entero(90)}, true},
+		{"días bajo el mínimo", FiltroProductos{DiasInventarioMin: entero(91)}, false},
+		{"días sobre el máximo", FiltroProductos{DiasInventarioMax: entero(89)}, false},
+		{"porcentaje vendido en el rango", FiltroProductos{PorcentajeVendidoMin: decimal(40), PorcentajeVendidoMax: decimal(50)}, true},
+		{"porcentaje vendido sobre el máximo", FiltroProductos{PorcentajeVendidoMax: decimal(39.9)}, false},
+		{"venta mínima", FiltroProductos{VentaMinimaClp: entero(50000)}, true},
+		{"venta bajo el mínimo", FiltroProductos{VentaMinimaClp: entero(50001)}, false},
+		{"solo sin coincidencia", FiltroProductos{SoloSinCoincidencia: true}, false},
+		{"solo con stock", FiltroProductos{SoloConStock: true}, true},
+		// Todos los criterios deben cumplirse
+		{"un criterio falla", FiltroProductos{Marcas: []string{"marca uno"}, VentaMinimaClp: entero(60000)}, false},
+	}
+	for _, c := range casos {
+		if obtenido := c.filtro.Incluye(fila); obtenido != c.esperado {
+			t.Errorf("%s: Incluye = %v, se esperaba %v", c.nombre, obtenido, c.esperado)
+		}
+		if activo := c.filtro.Activo(); activo == (c.nombre == "sin criterios") {
+			t.Errorf("%s: Activo = %v", c.nombre, activo)
+		}
+	}
+
+	sinStock := fila
+	sinStock.SaldoPeriodo, sinStock.MetodoCoincidencia = 0, ""
+	if (FiltroProductos{SoloConStock: true}).Incluye(sinStock) {
+		t.Error("SoloConStock incluyó una fila con SALDO_PERIODO 0")
+	}
+	if !(FiltroProductos{SoloSinCoincidencia: true}).Incluye(sinStock) {
+		t.Error("SoloSinCoincidencia excluyó una fila sin método de coincidencia")
+	}
+}
+
+func TestFiltroProductosValidar(t *testing.T) {
+	entero := func(v int) *int { return &v }
+	decimal := func(v float64) *float64 { return &v }
+	casos := []struct {
+		nombre string
+		filtro FiltroProductos
+		error  bool
+	}{
+		{nombre: "vacío", filtro: FiltroProductos{}},
+		{nombre: "rangos iguales", filtro: FiltroProductos{DiasInventarioMin: entero(30), DiasInventarioMax: entero(30), PorcentajeVendidoMin: decimal(10), PorcentajeVendidoMax: decimal(10)}},
+		{nombre: "días negativos", filtro: FiltroProductos{DiasInventarioMax: entero(-1)}, error: true},
+		{nombre: "días invertidos", filtro: FiltroProductos{DiasInventarioMin: entero(60), DiasInventarioMax: entero(30)}, error: true},
+		{nombre: "porcentaje invertido", filtro: FiltroProductos{PorcentajeVendidoMin: decimal(50), PorcentajeVendidoMax: decimal(20)}, error: true},
+	}
+	for _, c := range casos {
+		if err := c.filtro.Validar(); (err != nil) != c.error {
+			t.Errorf("%s: Validar = %v, se esperaba error %v", c.nombre, err, c.error)
+		}
+	}
+}
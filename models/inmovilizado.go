@@ -0,0 +1,76 @@
+package models
+
+import "errors"
+
+// Tramos de antigüedad del stock, según días en inventario
+const (
+	TramoHasta90    = "0-90"
+	TramoHasta180   = "91-180"
+	TramoHasta365   = "181-365"
+	TramoMasDe365   = ">365"
+	MotivoSinVenta  = "sin_ventas"
+	MotivoBajaVenta = "bajo_sell_through"
+)
+
+// ParametrosInmovilizado define cuándo se considera inmovilizado un producto con stock
+type ParametrosInmovilizado struct {
+	DiasSinVenta      int     `json:"diasSinVenta"`      // Sin ventas en los últimos N días
+	DiasMinimos       int     `json:"diasMinimos"`       // Días en inventario desde los que se evalúa el sell-through
+	SellThroughMinimo float64 `json:"sellThroughMinimo"` // % vendido mínimo esperado tras DiasMinimos
+}
+
+// Validar valida los parámetros y aplica valores por defecto
+func (p *ParametrosInmovilizado) Validar() error {
+	if p.DiasSinVenta < 0 || p.DiasMinimos < 0 || p.SellThroughMinimo < 0 || p.SellThroughMinimo > 100 {
+		return errors.New("parámetros no válidos: los días deben ser positivos y el sell-through entre 0 y 100")
+	}
+
+	if p.DiasSinVenta == 0 {
+		p.DiasSinVenta = 90
+	}
+	if p.DiasMinimos == 0 {
+		p.DiasMinimos = 180
+	}
+	if p.SellThroughMinimo == 0 {
+		p.SellThroughMinimo = 20
+	}
+
+	return nil
+}
+
+// ProductoInmovilizado representa un producto con stock detenido o de baja rotación
+type ProductoInmovilizado struct {
+	CodigoProducto         string   `json:"codigoProducto"`
+	CodigoVentas           string   `json:"codigoVentas"`
+	Nombre                 string   `json:"nombre"`
+	Marca                  string   `json:"marca"`
+	Categoria              string   `json:"categoria"`
+	SaldoPeriodo           float64  `json:"saldoPeriodo"`
+	DiasEnInventario       int      `json:"diasEnInventario"`
+	FechaUltimoIngreso     string   `json:"fechaUltimoIngreso"`
+	UltimaFechaVenta       string   `json:"ultimaFechaVenta"`
+	DiasSinVenta           *int     `json:"diasSinVenta"` // nil sin ventas en el período ni en los últimos DiasSinVenta días
+	PorcentajeVendido      float64  `json:"porcentajeVendido"`
+	CapitalInmovilizadoClp float64  `json:"capitalInmovilizadoClp"`
+	CapitalInmovilizadoUsd float64  `json:"capitalInmovilizadoUsd"`
+	Tramo                  string   `json:"tramo"`
+	Motivos                []string `json:"motivos"`
+}
+
+// ResumenTramo resume el stock inmovilizado de un tramo de antigüedad
+type ResumenTramo struct {
+	Tramo                  string  `json:"tramo"`
+	Productos              int     `json:"productos"`
+	Unidades               float64 `json:"unidades"`
+	CapitalInmovilizadoClp float64 `json:"capitalInmovilizadoClp"`
+	CapitalInmovilizadoUsd float64 `json:"capitalInmovilizadoUsd"`
+}
+
+// ReporteInmovilizado contiene los productos inmovilizados y su resumen por antigüedad
+type ReporteInmovilizado struct {
+	Parametros      ParametrosInmovilizado `json:"parametros"`
+	FechaReferencia string                 `json:"fechaReferencia"`
+	Productos       []ProductoInmovilizado `json:"productos"`
+	ResumenTramos   []ResumenTramo         `json:"resumenTramos"`
+	Total           ResumenTramo           `json:"total"`
+}
@@ -1,25 +1,45 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
 // Inventario representa un elemento de inventario con nombres en español
 type Inventario struct {
-	CodigoProducto          string    `json:"codigoProducto"`
-	NombreAduanero          string    `json:"nombreAduanero"`
-	MarcaProducto           string    `json:"marcaProducto"`
-	CategoriaPrincipal      string    `json:"categoriaPrincipal"`
-	SubcategoriaDimensiones string    `json:"subcategoriaDimensiones"`
-	UnidadesPorCaja         float64   `json:"unidadesPorCaja"`
-	TotalUnidadesIngresadas float64   `json:"totalUnidadesIngresadas"`
-	CostoPromedioCIF        float64   `json:"costoPromedioCIF"`
-	CostoPromedioUnitario   float64   `json:"costoPromedioUnitario"`
-	FechaPrimerIngreso      time.Time `json:"fechaPrimerIngreso"`
-	FechaUltimoIngreso      time.Time `json:"fechaUltimoIngreso"`
-	DiasDesdePrimerIngreso  int       `json:"diasDesdePrimerIngreso"`
-	CantidadIngresos        int       `json:"cantidadIngresos"`
-	HistorialIngresos       string    `json:"historialIngresos"`
+	CodigoProducto          string    `json:"codigoProducto" db:"Código de Producto"`
+	NombreAduanero          string    `json:"nombreAduanero" db:"Nombre Aduanero"`
+	MarcaProducto           string    `json:"marcaProducto" db:"Marca del Producto"`
+	CategoriaPrincipal      string    `json:"categoriaPrincipal" db:"Categoría Principal"`
+	SubcategoriaDimensiones string    `json:"subcategoriaDimensiones" db:"Subcategoría/Dimensiones"`
+	UnidadesPorCaja         float64   `json:"unidadesPorCaja" db:"Unidades por Caja"`
+	TotalUnidadesIngresadas float64   `json:"totalUnidadesIngresadas" db:"Total Unidades Ingresadas"`
+	CostoPromedioCIF        float64   `json:"costoPromedioCIF" db:"Costo Promedio CIF (USD)"`
+	CostoPromedioUnitario   float64   `json:"costoPromedioUnitario" db:"Costo Promedio Unitario (CLP)"`
+	FechaPrimerIngreso      time.Time `json:"fechaPrimerIngreso" db:"Fecha Primer Ingreso"`
+	FechaUltimoIngreso      time.Time `json:"fechaUltimoIngreso" db:"Fecha Último Ingreso"`
+	DiasDesdePrimerIngreso  int       `json:"diasDesdePrimerIngreso" db:"Días Desde Primer Ingreso"`
+	CantidadIngresos        int       `json:"cantidadIngresos" db:"Cantidad de Ingresos"`
+	HistorialIngresos       string    `json:"historialIngresos" db:"Historial de Ingresos (JSON)"`
+
+	// Historial interpretado y advertencias del proceso (texto truncado, ingresos inválidos)
+	Ingresos              []MetadatoProducto `json:"ingresos"`
+	AdvertenciasHistorial []string           `json:"advertenciasHistorial,omitempty"`
+
+	// Unidades ingresadas por año de producción; solo si se solicita el desglose
+	UnidadesPorAnio []UnidadesAnio `json:"unidadesPorAnio,omitempty"`
+}
+
+// IngresoSaldo representa una fila de GetHistorialIngresosQuery
+type IngresoSaldo struct {
+	CodigoProducto     string    `db:"Código de Producto"`
+	UnidadesPorCaja    float64   `db:"Unidades por Caja"`
+	Zeta               string    `db:"Zeta"`
+	AnioProduccion     string    `db:"Año Producción"`
+	UnidadesIngresadas float64   `db:"Unidades Ingresadas"`
+	FechaIngreso       time.Time `db:"Fecha Ingreso"`
 }
 
 // MetadatoProducto representa un elemento dentro del array de historial de ingresos
@@ -30,10 +50,28 @@ type MetadatoProducto struct {
 	FechaIngreso       time.Time `json:"Fecha Ingreso"`
 }
 
+// AnioProduccionSinLimite es el año de producción desde el que se consulta cuando el rango no
+// tiene límite inferior
+const AnioProduccionSinLimite = 1
+
 // InventarioFiltro define los filtros para consultar inventario
 type InventarioFiltro struct {
 	Anio           int    `json:"anio"`
 	CodigoProducto string `json:"codigoProducto"` // Nuevo campo para filtrar por código
+
+	// Lista de códigos exactos (ver NormalizarCodigos); vacía = todos
+	Codigos []string `json:"codigos,omitempty"`
+
+	// Rango de años de producción (ANIO_PRO); sin rango se usa solo Anio
+	AnioDesde int `json:"anioDesde,omitempty"`
+	AnioHasta int `json:"anioHasta,omitempty"`
+
+	// Rango de fechas de ingreso (YYYY-MM-DD); vacío no limita
+	FechaIngresoDesde string `json:"fechaIngresoDesde,omitempty"`
+	FechaIngresoHasta string `json:"fechaIngresoHasta,omitempty"`
+
+	// Agrega las unidades ingresadas por año de producción
+	DesglosePorAnio bool `json:"desglosePorAnio,omitempty"`
 }
 
 // Validar valida los parámetros del filtro
@@ -43,5 +81,80 @@ func (f *InventarioFiltro) Validar() error {
 		f.Anio = time.Now().Year() // Usar año actual como predeterminado
 	}
 
+	// Sin rango se consulta un solo año; con un solo extremo, el rango es de un año
+	switch {
+	case f.AnioDesde == 0 && f.AnioHasta == 0:
+		f.AnioDesde, f.AnioHasta = f.Anio, f.Anio
+	case f.AnioDesde == 0:
+		f.AnioDesde = f.AnioHasta
+	case f.AnioHasta == 0:
+		f.AnioHasta = f.AnioDesde
+	}
+	if f.AnioDesde < 0 || f.AnioDesde > f.AnioHasta {
+		return fmt.Errorf("rango de años de producción no válido: %d a %d", f.AnioDesde, f.AnioHasta)
+	}
+
+	var desde, hasta time.Time
+	var err error
+	if f.FechaIngresoDesde != "" {
+		if desde, err = time.Parse("2006-01-02", f.FechaIngresoDesde); err != nil {
+			return errors.New("fechaIngresoDesde debe estar en formato YYYY-MM-DD")
+		}
+	}
+	if f.FechaIngresoHasta != "" {
+		if hasta, err = time.Parse("2006-01-02", f.FechaIngresoHasta); err != nil {
+			return errors.New("fechaIngresoHasta debe estar en formato YYYY-MM-DD")
+		}
+	}
+	if !desde.IsZero() && !hasta.IsZero() && hasta.Before(desde) {
+		return errors.New("fechaIngresoHasta no puede ser anterior a fechaIngresoDesde")
+	}
+
 	return nil
 }
+
+// IncluyeCodigo indica si un código está en la lista de códigos del filtro (vacía = todos)
+func (f InventarioFiltro) IncluyeCodigo(codigo string) bool {
+	return incluyeCodigo(f.Codigos, codigo)
+}
+
+// CodigosTexto devuelve la lista de códigos separados por comas, o vacío si no hay lista
+func (f InventarioFiltro) CodigosTexto() string {
+	return strings.Join(f.Codigos, ",")
+}
+
+// RangoAnios indica si el filtro abarca más de un año de producción
+func (f InventarioFiltro) RangoAnios() bool {
+	return f.AnioDesde != 0 && f.AnioDesde != f.AnioHasta
+}
+
+// UnidadesAnio resume los ingresos de un producto de un año de producción
+type UnidadesAnio struct {
+	Anio     string  `json:"anio"`
+	Unidades float64 `json:"unidades"`
+	Ingresos int     `json:"ingresos"`
+}
+
+// LoteProducto representa el movimiento de un lote (Zeta) de un producto:
+// unidades recibidas según el historial de ingresos y unidades vendidas según las ventas con ese Zeta
+type LoteProducto struct {
+	Zeta               string  `json:"zeta"`
+	AnioProduccion     string  `json:"anioProduccion"`
+	FechaIngreso       string  `json:"fechaIngreso"`
+	DiasEnInventario   int     `json:"diasEnInventario"`
+	UnidadesIngresadas float64 `json:"unidadesIngresadas"`
+	UnidadesVendidas   float64 `json:"unidadesVendidas"`
+	UnidadesRestantes  float64 `json:"unidadesRestantes"`
+	PorcentajeVendido  float64 `json:"porcentajeVendido"`
+	VentaNetaClp       int     `json:"ventaNetaClp"`
+	UltimaFechaVenta   string  `json:"ultimaFechaVenta"`
+	SinIngreso         bool    `json:"sinIngreso,omitempty"` // Zeta vendido que no aparece en el historial de ingresos
+}
+
+// ReporteLotes agrupa los lotes de un producto
+type ReporteLotes struct {
+	CodigoProducto string         `json:"codigoProducto"`
+	CodigoVentas   string         `json:"codigoVentas"`
+	Nombre         string         `json:"nombre"`
+	Lotes          []LoteProducto `json:"lotes"`
+}
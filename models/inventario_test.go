@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestInventarioFiltroValidar(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		filtro   InventarioFiltro
+		esperado string // desde-hasta
+		error    bool
+	}{
+		{nombre: "sin rango usa el año", filtro: InventarioFiltro{Anio: 2024}, esperado: "2024-2024"},
+		{nombre: "solo desde", filtro: InventarioFiltro{Anio: 2024, AnioDesde: 2022}, esperado: "2022-2022"},
+		{nombre: "solo hasta", filtro: InventarioFiltro{Anio: 2024, AnioHasta: 2023}, esperado: "2023-2023"},
+		{nombre: "rango", filtro: InventarioFiltro{Anio: 2024, AnioDesde: 2021, AnioHasta: 2023}, esperado: "2021-2023"},
+		{nombre: "rango invertido", filtro: InventarioFiltro{AnioDesde: 2024, AnioHasta: 2022}, error: true},
+		{nombre: "fecha de ingreso no válida", filtro: InventarioFiltro{Anio: 2024, FechaIngresoDesde: "01-03-2024"}, error: true},
+		{nombre: "fechas de ingreso invertidas", filtro: InventarioFiltro{Anio: 2024, FechaIngresoDesde: "2024-03-01", FechaIngresoHasta: "2024-02-01"}, error: true},
+		{nombre: "fechas de ingreso", filtro: InventarioFiltro{Anio: 2024, FechaIngresoDesde: "2024-02-01", FechaIngresoHasta: "2024-02-01"}, esperado: "2024-2024"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			filtro := c.filtro
+			err := filtro.Validar()
+			if c.error {
+				if err == nil {
+					t.Errorf("Validar(%+v) no devolvió error", c.filtro)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validar: %v", err)
+			}
+			if obtenido := fmt.Sprintf("%d-%d", filtro.AnioDesde, filtro.AnioHasta); obtenido != c.esperado {
+				t.Errorf("rango = %s, se esperaba %s", obtenido, c.esperado)
+			}
+		})
+	}
+
+	// Sin año se usa el actual
+	filtro := InventarioFiltro{}
+	if err := filtro.Validar(); err != nil || filtro.Anio != time.Now().Year() || filtro.RangoAnios() {
+		t.Errorf("filtro vacío = %+v, %v; se esperaba el año actual", filtro, err)
+	}
+}
+
+func TestReporteFiltroFiltroInventario(t *testing.T) {
+	previos := func(n int) *int { return &n }
+	casos := []struct {
+		nombre   string
+		filtro   ReporteFiltro
+		esperado string // desde-hasta ingresados hasta
+	}{
+		{
+			// Sin rango: todos los lotes producidos hasta el año de fin e ingresados hasta la fecha de fin
+			nombre:   "lotes vendibles en el período",
+			filtro:   ReporteFiltro{Anio: 2025, FechaInicio: "2024-11-01", FechaFin: "2025-01-31"},
+			esperado: fmt.Sprintf("%d-2025 hasta 2025-01-31", AnioProduccionSinLimite),
+		},
+		{
+			nombre:   "años previos al inicio",
+			filtro:   ReporteFiltro{Anio: 2025, FechaInicio: "2024-11-01", FechaFin: "2025-01-31", AniosProduccionPrevios: previos(2)},
+			esperado: "2022-2025 hasta 2025-01-31",
+		},
+		{
+			nombre:   "sin años previos",
+			filtro:   ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31", AniosProduccionPrevios: previos(0)},
+			esperado: "2025-2025 hasta 2025-01-31",
+		},
+		{
+			// El rango explícito no limita la fecha de ingreso
+			nombre:   "rango explícito",
+			filtro:   ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31", AnioInventarioDesde: 2023, AniosProduccionPrevios: previos(0)},
+			esperado: "2023-0 hasta ",
+		},
+		{
+			// Sin fechas válidas queda el año del reporte (ver InventarioFiltro.Validar)
+			nombre:   "fechas no válidas",
+			filtro:   ReporteFiltro{Anio: 2025, FechaInicio: "enero", FechaFin: "2025-01-31"},
+			esperado: "0-0 hasta ",
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			filtro := c.filtro.FiltroInventario()
+			obtenido := fmt.Sprintf("%d-%d hasta %s", filtro.AnioDesde, filtro.AnioHasta, filtro.FechaIngresoHasta)
+			if obtenido != c.esperado {
+				t.Errorf("filtro de inventario = %q, se esperaba %q", obtenido, c.esperado)
+			}
+			if filtro.Anio != c.filtro.Anio {
+				t.Errorf("Anio = %d, se esperaba %d", filtro.Anio, c.filtro.Anio)
+			}
+		})
+	}
+}
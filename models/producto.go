@@ -0,0 +1,111 @@
+package models
+
+import "errors"
+
+// ParametrosFichaProducto define el detalle de la ficha de un producto
+type ParametrosFichaProducto struct {
+	Documentos int `json:"documentos"` // Documentos recientes informados
+}
+
+// Validar valida los parámetros y aplica valores por defecto
+func (p *ParametrosFichaProducto) Validar() error {
+	if p.Documentos == 0 {
+		p.Documentos = 10
+	}
+	if p.Documentos < 1 || p.Documentos > 100 {
+		return errors.New("documentos debe estar entre 1 y 100")
+	}
+	return nil
+}
+
+// MaestroProducto contiene los datos del producto en el inventario (MySQL)
+type MaestroProducto struct {
+	CodigoProducto     string  `json:"codigoProducto"`
+	Nombre             string  `json:"nombre"`
+	Marca              string  `json:"marca"`
+	Categoria          string  `json:"categoria"`
+	Dimensiones        string  `json:"dimensiones"`
+	Packing            float64 `json:"packing"`
+	CifPromedioUsd     float64 `json:"cifPromedioUsd"`
+	CifPromedioClp     float64 `json:"cifPromedioClp"`
+	CantidadIngresada  float64 `json:"cantidadIngresada"`
+	CantidadIngresos   int     `json:"cantidadIngresos"`
+	FechaPrimerIngreso string  `json:"fechaPrimerIngreso"`
+	FechaUltimoIngreso string  `json:"fechaUltimoIngreso"`
+	DiasEnInventario   int     `json:"diasEnInventario"`
+}
+
+// CoincidenciaProducto indica con qué código se emparejó el producto en el otro sistema
+type CoincidenciaProducto struct {
+	CodigoInventario string  `json:"codigoInventario"` // Vacío si solo existe en ventas
+	CodigoVentas     string  `json:"codigoVentas"`     // Vacío si no tuvo ventas en el período
+	Metodo           string  `json:"metodo"`
+	Confianza        float64 `json:"confianza"`
+
+	// Otros códigos de inventario emparejados con el mismo código de ventas
+	OtrosCodigosInventario []string `json:"otrosCodigosInventario,omitempty"`
+}
+
+// IngresosProducto contiene el historial de ingresos del producto y sus lotes
+type IngresosProducto struct {
+	Historial    []MetadatoProducto `json:"historial"`
+	PorAnio      []UnidadesAnio     `json:"porAnio"`
+	Lotes        []LoteProducto     `json:"lotes"`
+	Advertencias []string           `json:"advertencias,omitempty"`
+}
+
+// ResumenVentasProducto resume las ventas del producto en el período (SQL Server)
+type ResumenVentasProducto struct {
+	CantidadVendida        float64         `json:"cantidadVendida"`
+	VentaNetaClp           int             `json:"ventaNetaClp"`
+	UtilidadClp            float64         `json:"utilidadClp"`
+	Lineas                 int             `json:"lineas"`
+	Documentos             int             `json:"documentos"`
+	Clientes               int             `json:"clientes"` // Clientes identificados distintos
+	PrecioProductoClp      int             `json:"precioProductoClp"`
+	PrecioOfertaClp        int             `json:"precioOfertaClp"`
+	PrecioVentaPromedioClp int             `json:"precioVentaPromedioClp"`
+	PrimeraFechaVenta      string          `json:"primeraFechaVenta"`
+	UltimaFechaVenta       string          `json:"ultimaFechaVenta"`
+	PorSucursal            []VentaSucursal `json:"porSucursal"`
+}
+
+// RotacionProducto contiene los indicadores de rotación del producto en el período
+type RotacionProducto struct {
+	SaldoPeriodo         float64  `json:"saldoPeriodo"`
+	DiasPeriodo          int      `json:"diasPeriodo"`
+	VelocidadVentaDiaria float64  `json:"velocidadVentaDiaria"`
+	DiasDeStock          *float64 `json:"diasDeStock"`
+	RotacionInventario   float64  `json:"rotacionInventario"`
+	Gmroi                float64  `json:"gmroi"`
+	PorcentajeVendido    float64  `json:"porcentajeVendido"`
+}
+
+// DocumentoProducto es un documento de venta que incluye el producto
+type DocumentoProducto struct {
+	Fecha           string  `json:"fecha"`
+	TipoDocumento   string  `json:"tipoDocumento"`
+	CodigoDocumento string  `json:"codigoDocumento"`
+	Sucursal        int     `json:"sucursal"`
+	Cliente         string  `json:"cliente"`
+	Cantidad        float64 `json:"cantidad"`
+	VentaClp        int     `json:"ventaClp"`
+	Zeta            string  `json:"zeta"`
+	Nulo            bool    `json:"nulo,omitempty"`
+}
+
+// FichaProducto reúne en una sola consulta todo lo que se sabe de un producto en inventario y
+// ventas durante el período
+type FichaProducto struct {
+	Codigo       string                `json:"codigo"` // Código consultado
+	FechaInicio  string                `json:"fechaInicio"`
+	FechaFin     string                `json:"fechaFin"`
+	Sucursales   []int                 `json:"sucursales"`
+	Maestro      *MaestroProducto      `json:"maestro"` // nil si el producto no está en inventario
+	Coincidencia CoincidenciaProducto  `json:"coincidencia"`
+	Ingresos     IngresosProducto      `json:"ingresos"`
+	Ventas       ResumenVentasProducto `json:"ventas"`
+	SerieMensual []PuntoSerie          `json:"serieMensual"`
+	Rotacion     RotacionProducto      `json:"rotacion"`
+	Documentos   []DocumentoProducto   `json:"documentosRecientes"`
+}
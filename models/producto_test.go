@@ -0,0 +1,16 @@
+package models
+
+import "testing"
+
+func TestParametrosFichaProductoValidar(t *testing.T) {
+	params := ParametrosFichaProducto{}
+	if err := params.Validar(); err != nil || params.Documentos != 10 {
+		t.Errorf("Validar = %v con %d documentos, se esperaban 10 por defecto", err, params.Documentos)
+	}
+	for _, documentos := range []int{-1, 101} {
+		params := ParametrosFichaProducto{Documentos: documentos}
+		if err := params.Validar(); err == nil {
+			t.Errorf("Validar con %d documentos no devolvió error", documentos)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package models
+
+import "errors"
+
+// Tipos de precio de una línea de venta
+const (
+	TipoPrecioLista     = "lista"     // Precio de lista observado (o superior)
+	TipoPrecioOferta    = "oferta"    // Bajo el precio de lista: precio de oferta actual o precio repetido en varios días
+	TipoPrecioDescuento = "descuento" // Bajo el precio de lista sin ser de oferta: descuento puntual
+)
+
+// ParametrosPromocion define cómo se clasifican las líneas y se forman los períodos de oferta
+type ParametrosPromocion struct {
+	ToleranciaPct   float64 `json:"toleranciaPct"`   // Diferencia % aceptada para considerar un precio igual al de lista o al de oferta
+	BrechaDias      int     `json:"brechaDias"`      // Días sin ventas en oferta que separan dos períodos de oferta
+	VentanaBaseDias int     `json:"ventanaBaseDias"` // Días antes y después de cada período de oferta que forman la base regular
+	QuiebreDias     int     `json:"quiebreDias"`     // Días seguidos sin ventas que se consideran posible quiebre de stock
+}
+
+// Validar valida los parámetros y aplica valores por defecto
+func (p *ParametrosPromocion) Validar() error {
+	if p.ToleranciaPct == 0 {
+		p.ToleranciaPct = 1
+	}
+	if p.BrechaDias == 0 {
+		p.BrechaDias = 7
+	}
+	if p.VentanaBaseDias == 0 {
+		p.VentanaBaseDias = 28
+	}
+	if p.QuiebreDias == 0 {
+		p.QuiebreDias = 14
+	}
+	if p.ToleranciaPct < 0 || p.ToleranciaPct > 20 {
+		return errors.New("la tolerancia debe estar entre 0 y 20 %")
+	}
+	if p.BrechaDias < 1 || p.BrechaDias > 90 {
+		return errors.New("la brecha debe estar entre 1 y 90 días")
+	}
+	if p.VentanaBaseDias < 7 || p.VentanaBaseDias > 180 {
+		return errors.New("la ventana base debe estar entre 7 y 180 días")
+	}
+	if p.QuiebreDias < 3 || p.QuiebreDias > 180 {
+		return errors.New("los días de quiebre deben estar entre 3 y 180")
+	}
+	return nil
+}
+
+// ResumenTipoPrecio resume las líneas vendidas con un tipo de precio
+type ResumenTipoPrecio struct {
+	Lineas                  int     `json:"lineas"`
+	Unidades                float64 `json:"unidades"`
+	VentaClp                int     `json:"ventaClp"`
+	PrecioPromedioClp       float64 `json:"precioPromedioClp"`       // Ponderado por unidades
+	ProfundidadDescuentoPct float64 `json:"profundidadDescuentoPct"` // Descuento promedio sobre el precio de lista observado, ponderado por unidades
+}
+
+// PeriodoOferta es un período con ventas a precio de oferta: fechas con ventas en oferta
+// separadas por hasta la brecha configurada y sin ventas a precio de lista entre ellas
+type PeriodoOferta struct {
+	Desde    string  `json:"desde"`
+	Hasta    string  `json:"hasta"`
+	Dias     int     `json:"dias"`
+	Unidades float64 `json:"unidades"` // Todas las unidades vendidas en el período, a cualquier precio
+}
+
+// ProductoPromocion contiene la efectividad de precios y ofertas de un producto
+type ProductoPromocion struct {
+	CodigoProducto  string `json:"codigoProducto"`
+	NombreProducto  string `json:"nombreProducto"`
+	PrecioBaseClp   int    `json:"precioBaseClp"`   // Precio base actual del maestro
+	PrecioOfertaClp int    `json:"precioOfertaClp"` // Precio de oferta actual del maestro, 0 sin oferta vigente
+	PrecioMinimoClp int    `json:"precioMinimoClp"`
+	PrecioMaximoClp int    `json:"precioMaximoClp"`
+
+	Lista     ResumenTipoPrecio `json:"lista"`
+	Oferta    ResumenTipoPrecio `json:"oferta"`
+	Descuento ResumenTipoPrecio `json:"descuento"`
+
+	ProfundidadDescuentoPct float64 `json:"profundidadDescuentoPct"` // Sobre todas las unidades vendidas
+	UnidadesOfertaPct       float64 `json:"unidadesOfertaPct"`       // % de unidades vendidas en oferta
+	VentaOfertaPct          float64 `json:"ventaOfertaPct"`          // % de la venta en oferta
+
+	// Velocidad diaria en los períodos de oferta y en los días regulares comparables, y su diferencia %
+	PeriodosOferta   []PeriodoOferta `json:"periodosOferta"`
+	DiasOferta       int             `json:"diasOferta"`
+	DiasRegulares    int             `json:"diasRegulares"` // Días del período fuera de oferta
+	DiasBase         int             `json:"diasBase"`      // Días regulares cercanos a una oferta y sin posible quiebre
+	DiasQuiebre      int             `json:"diasQuiebre"`   // Días regulares excluidos por posible quiebre de stock
+	VelocidadOferta  float64         `json:"velocidadOferta"`
+	VelocidadRegular float64         `json:"velocidadRegular"` // Sobre los días base
+	LiftPct          *float64        `json:"liftPct"`          // nil sin días en oferta o sin ventas en los días base
+}
+
+// ReportePromociones contiene la efectividad de precios y ofertas por producto
+type ReportePromociones struct {
+	FechaInicio string              `json:"fechaInicio"`
+	FechaFin    string              `json:"fechaFin"`
+	Sucursales  []int               `json:"sucursales"`
+	DiasPeriodo int                 `json:"diasPeriodo"`
+	Parametros  ParametrosPromocion `json:"parametros"`
+	Productos   []ProductoPromocion `json:"productos"`
+
+	// Supuestos del análisis que condicionan la lectura del lift
+	Limitaciones []string `json:"limitaciones"`
+
+	// Totales de todos los productos por tipo de precio
+	Lista     ResumenTipoPrecio `json:"lista"`
+	Oferta    ResumenTipoPrecio `json:"oferta"`
+	Descuento ResumenTipoPrecio `json:"descuento"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Métodos de pronóstico de demanda
+const (
+	MetodoPromedioMovil = "promedio_movil"        // Promedio de los últimos meses
+	MetodoSuavizado     = "suavizado_exponencial" // Holt: nivel y tendencia
+	MetodoHoltWinters   = "holt_winters"          // Holt-Winters aditivo con estacionalidad anual
+	MetodoCroston       = "croston"               // Demanda intermitente
+	MetodoSinDatos      = "sin_datos"             // Sin ventas en la historia: pronóstico 0
+)
+
+// MetodosPronostico lista los métodos evaluados, en orden de preferencia ante empates
+var MetodosPronostico = []string{MetodoPromedioMovil, MetodoSuavizado, MetodoCroston, MetodoHoltWinters}
+
+// nivelesConfianza asocia cada nivel de confianza admitido con su valor z de la normal
+var nivelesConfianza = map[float64]float64{80: 1.2816, 90: 1.6449, 95: 1.96, 99: 2.5758}
+
+// ParametrosPronostico define el pronóstico mensual de demanda
+type ParametrosPronostico struct {
+	Horizonte int     `json:"horizonte"` // Meses a pronosticar
+	Historia  int     `json:"historia"`  // Meses completos de historia usados para ajustar
+	Nivel     float64 `json:"nivel"`     // Nivel de confianza del intervalo (80, 90, 95 o 99)
+	Metodo    string  `json:"metodo"`    // Fuerza un método; vacío elige por backtest
+}
+
+// Validar valida los parámetros y aplica valores por defecto
+func (p *ParametrosPronostico) Validar() error {
+	if p.Horizonte == 0 {
+		p.Horizonte = 3
+	}
+	if p.Historia == 0 {
+		p.Historia = 24
+	}
+	if p.Nivel == 0 {
+		p.Nivel = 95
+	}
+
+	if p.Horizonte < 1 || p.Horizonte > 24 {
+		return errors.New("el horizonte debe estar entre 1 y 24 meses")
+	}
+	if p.Historia < 3 || p.Historia > 120 {
+		return errors.New("la historia debe estar entre 3 y 120 meses")
+	}
+	if _, ok := nivelesConfianza[p.Nivel]; !ok {
+		return fmt.Errorf("nivel de confianza no válido: %v (use 80, 90, 95 o 99)", p.Nivel)
+	}
+	if p.Metodo != "" {
+		valido := false
+		for _, m := range MetodosPronostico {
+			valido = valido || m == p.Metodo
+		}
+		if !valido {
+			return fmt.Errorf("método de pronóstico no válido: %q", p.Metodo)
+		}
+	}
+	return nil
+}
+
+// ValorZ devuelve el valor z de la normal para el nivel de confianza
+func (p ParametrosPronostico) ValorZ() float64 {
+	return nivelesConfianza[p.Nivel]
+}
+
+// PuntoPronostico es la demanda pronosticada de un mes con su intervalo de predicción
+type PuntoPronostico struct {
+	Periodo  string  `json:"periodo"` // 2025-01
+	Cantidad float64 `json:"cantidad"`
+	Inferior float64 `json:"inferior"`
+	Superior float64 `json:"superior"`
+}
+
+// ErrorBacktest es el error de un método al pronosticar los últimos meses de la historia
+type ErrorBacktest struct {
+	Metodo string  `json:"metodo"`
+	Mae    float64 `json:"mae"` // Error absoluto medio, en unidades por mes
+}
+
+// PronosticoProducto es el pronóstico mensual de un producto
+type PronosticoProducto struct {
+	CodigoProducto          string            `json:"codigoProducto"`
+	NombreProducto          string            `json:"nombreProducto"`
+	Metodo                  string            `json:"metodo"`
+	MaeBacktest             *float64          `json:"maeBacktest"` // nil si la historia no alcanza para el backtest
+	Backtest                []ErrorBacktest   `json:"backtest,omitempty"`
+	DemandaPromedioHistoria float64           `json:"demandaPromedioHistoria"`
+	MesesSinVenta           int               `json:"mesesSinVenta"`
+	TotalPronosticado       float64           `json:"totalPronosticado"`
+	Historia                []float64         `json:"historia"`
+	Pronostico              []PuntoPronostico `json:"pronostico"`
+}
+
+// ReportePronostico contiene los pronósticos de todos los productos
+type ReportePronostico struct {
+	Parametros     ParametrosPronostico `json:"parametros"`
+	Sucursales     []int                `json:"sucursales"`
+	InicioHistoria string               `json:"inicioHistoria"`
+	FinHistoria    string               `json:"finHistoria"`
+	Periodos       []string             `json:"periodos"` // Meses pronosticados
+	Productos      []PronosticoProducto `json:"productos"`
+}
+
+// ResumenPronostico es el pronóstico de un producto incorporado al reporte combinado
+type ResumenPronostico struct {
+	Metodo              string   `json:"METODO"`
+	Horizonte           int      `json:"HORIZONTE_MESES"`
+	DemandaPronosticada float64  `json:"DEMANDA_PRONOSTICADA"`
+	Inferior            float64  `json:"DEMANDA_INFERIOR"`
+	Superior            float64  `json:"DEMANDA_SUPERIOR"`
+	CoberturaMeses      *float64 `json:"COBERTURA_MESES"` // Saldo del período / demanda mensual pronosticada; nil sin demanda
+	StockFaltante       float64  `json:"STOCK_FALTANTE"`  // Demanda pronosticada - saldo del período (mínimo 0)
+}
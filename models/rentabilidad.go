@@ -0,0 +1,63 @@
+package models
+
+// IndicadoresMargen contiene el margen de un producto o grupo con una base de costo
+type IndicadoresMargen struct {
+	Venta          float64 `json:"venta"`          // Venta neta CLP sobre la que se calcula el margen
+	Unidades       float64 `json:"unidades"`       // Unidades vendidas correspondientes
+	Costo          float64 `json:"costo"`          // Costo de lo vendido en CLP
+	Margen         float64 `json:"margen"`         // Venta neta - costo
+	MargenPct      float64 `json:"margenPct"`      // Margen bruto % sobre la venta neta
+	Markup         float64 `json:"markup"`         // Margen % sobre el costo
+	MargenUnitario float64 `json:"margenUnitario"` // Margen por unidad vendida
+	Contribucion   float64 `json:"contribucion"`   // % del margen total del reporte
+	BajoCosto      bool    `json:"bajoCosto"`      // Margen negativo: se vendió bajo costo
+}
+
+// ProductoRentabilidad contiene la rentabilidad de un producto en el período, con dos bases
+// de costo: el CIF promedio del inventario (MySQL) y el costo unitario USD de las líneas de
+// venta (SQL Server STOCKS), convertido a CLP al tipo de cambio de cada fecha. La base STOCKS
+// se calcula sobre las mismas líneas que MARGEN_MONEDA del reporte combinado, por lo que su
+// venta y unidades pueden diferir de las del producto (sin anuladas ni líneas sin tipo de cambio).
+type ProductoRentabilidad struct {
+	CodigoProducto    string  `json:"codigoProducto"`
+	CodigoVentas      string  `json:"codigoVentas"`
+	Nombre            string  `json:"nombre"`
+	Marca             string  `json:"marca"`
+	Categoria         string  `json:"categoria"`
+	Dimensiones       string  `json:"dimensiones"`
+	Unidades          float64 `json:"unidades"`
+	VentaNetaClp      float64 `json:"ventaNetaClp"`
+	PrecioPromedioClp float64 `json:"precioPromedioClp"` // Venta neta / unidades
+	CostoCifUsd       float64 `json:"costoCifUsd"`       // CIF promedio unitario
+	CostoStocksUsd    float64 `json:"costoStocksUsd"`    // Costo unitario promedio de las líneas de venta (CIF si no lo traen)
+
+	Cif    IndicadoresMargen  `json:"cif"`
+	Stocks *IndicadoresMargen `json:"stocks,omitempty"` // nil sin tipos de cambio registrados
+
+	SinInventario   bool `json:"sinInventario"`   // Sin inventario emparejado: el costo CIF es 0
+	LineasBajoCosto int  `json:"lineasBajoCosto"` // Líneas vendidas a un precio unitario menor al costo
+	LineasSinCosto  int  `json:"lineasSinCosto"`  // Líneas sin costo de STOCKS, costeadas al CIF promedio
+	BajoCosto       bool `json:"bajoCosto"`       // Margen negativo con alguna de las bases de costo
+}
+
+// GrupoRentabilidad suma la rentabilidad de los productos de una marca, categoría o dimensión
+type GrupoRentabilidad struct {
+	Grupo              string             `json:"grupo"`
+	Productos          int                `json:"productos"`
+	ProductosBajoCosto int                `json:"productosBajoCosto"`
+	Unidades           float64            `json:"unidades"`
+	VentaNetaClp       float64            `json:"ventaNetaClp"`
+	Cif                IndicadoresMargen  `json:"cif"`
+	Stocks             *IndicadoresMargen `json:"stocks,omitempty"`
+}
+
+// ReporteRentabilidad contiene la rentabilidad por producto y sus totales por marca,
+// categoría y dimensiones
+type ReporteRentabilidad struct {
+	ConCostoStocks bool                   `json:"conCostoStocks"` // Hay tipos de cambio para la base de costo STOCKS
+	Productos      []ProductoRentabilidad `json:"productos"`
+	PorMarca       []GrupoRentabilidad    `json:"porMarca"`
+	PorCategoria   []GrupoRentabilidad    `json:"porCategoria"`
+	PorDimensiones []GrupoRentabilidad    `json:"porDimensiones"`
+	Total          GrupoRentabilidad      `json:"total"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // ReporteCombinado representa la unión de datos de inventario y ventas
 type ReporteCombinado struct {
 	// Datos generales del producto
@@ -20,6 +22,13 @@ type ReporteCombinado struct {
 	CantidadIngresos   int     `json:"CANTIDAD_INGRESOS"`
 	HistorialIngresos  string  `json:"HISTORIAL_INGRESOS"`
 
+	// Historial interpretado (uso interno) y advertencias al interpretarlo
+	Ingresos              []MetadatoProducto `json:"-"`
+	AdvertenciasHistorial []string           `json:"ADVERTENCIAS_HISTORIAL,omitempty"`
+
+	// Unidades ingresadas por año de producción; solo si se solicita el desglose
+	IngresadaPorAnio []UnidadesAnio `json:"INGRESADA_POR_ANIO,omitempty"`
+
 	// Datos de ventas (SQL Server)
 	PrecioProductoClp      int     `json:"PRECIO_PRODUCTO_CLP"`
 	PrecioOfertaClp        int     `json:"PRECIO_OFERTA_CLP"`
@@ -30,10 +39,52 @@ type ReporteCombinado struct {
 	UltimaFechaVenta       string  `json:"ULTIMA_FECHA_VENTA"`
 	CantidadTransacciones  int     `json:"CANTIDAD_TRANSACCIONES"`
 
+	// Emparejamiento inventario-ventas
+	CodigoVentas          string  `json:"CODIGO_VENTAS"`
+	MetodoCoincidencia    string  `json:"METODO_COINCIDENCIA"`
+	ConfianzaCoincidencia float64 `json:"CONFIANZA_COINCIDENCIA"`
+
 	// Campos calculados
 	PorcentajeVendido float64 `json:"PORCENTAJE_VENDIDO"`
 	UtilidadClp       float64 `json:"UTILIDAD_CLP"`
 	RankingVenta      int     `json:"RANKING_VENTA"`
+
+	// Indicadores de rotación
+	SaldoPeriodo         float64           `json:"SALDO_PERIODO"` // Ingresado - vendido en el período (mínimo 0); no es el stock físico
+	DiasPeriodo          int               `json:"DIAS_PERIODO"`
+	VelocidadVentaDiaria float64           `json:"VELOCIDAD_VENTA_DIARIA"`
+	DiasDeStock          *float64          `json:"DIAS_DE_STOCK"` // nil si no hubo ventas en el período
+	RotacionInventario   float64           `json:"ROTACION_INVENTARIO"`
+	Gmroi                float64           `json:"GMROI"`
+	SellThroughLotes     []SellThroughLote `json:"SELL_THROUGH_LOTES,omitempty"`
+
+	// Clasificación ABC con los parámetros por defecto del servidor
+	ClaseABC string `json:"CLASE_ABC"`
+
+	// Desglose por sucursal; solo se completa al consultar varias sucursales
+	VentasPorSucursal []VentaSucursal `json:"VENTAS_POR_SUCURSAL,omitempty"`
+
+	// Pronóstico de demanda; solo se completa si se solicita
+	Pronostico *ResumenPronostico `json:"PRONOSTICO,omitempty"`
+
+	// Stock disponible a una fecha de corte (ingresos acumulados menos ventas acumuladas);
+	// solo se completa si se solicita
+	StockAFecha   *float64 `json:"STOCK_A_FECHA,omitempty"`
+	StockNegativo bool     `json:"STOCK_NEGATIVO,omitempty"`
+
+	// Margen en USD y CLP al tipo de cambio de cada fecha; solo se completa si hay
+	// tipos de cambio registrados
+	MargenMoneda *MargenMoneda `json:"MARGEN_MONEDA,omitempty"`
+}
+
+// SellThroughLote indica qué parte de un lote de ingreso se ha vendido,
+// asignando las ventas a los lotes en orden de ingreso (FIFO)
+type SellThroughLote struct {
+	Zeta               string  `json:"ZETA"`
+	FechaIngreso       string  `json:"FECHA_INGRESO"`
+	UnidadesIngresadas float64 `json:"UNIDADES_INGRESADAS"`
+	UnidadesVendidas   float64 `json:"UNIDADES_VENDIDAS"`
+	PorcentajeVendido  float64 `json:"PORCENTAJE_VENDIDO"`
 }
 
 // ReporteFiltro define los filtros para consultar el reporte combinado
@@ -41,6 +92,81 @@ type ReporteFiltro struct {
 	Anio           int    `json:"anio"`
 	FechaInicio    string `json:"fechaInicio"`
 	FechaFin       string `json:"fechaFin"`
-	Sucursal       int    `json:"sucursal"`
+	Sucursales     []int  `json:"sucursales"` // Vacío = todas las sucursales
 	CodigoProducto string `json:"codigoProducto"`
+
+	// Lista de códigos exactos, de inventario o de ventas (ver NormalizarCodigos); vacía = todos.
+	// Se aplica sobre el resultado del emparejamiento, no en las consultas.
+	Codigos []string `json:"codigos,omitempty"`
+
+	// Orden de estrategias de coincidencia; vacío usa la configuración del servidor
+	Estrategias []string `json:"estrategias,omitempty"`
+
+	// Pronóstico de demanda a incorporar; nil no pronostica
+	Pronostico *ParametrosPronostico `json:"pronostico,omitempty"`
+
+	// Fecha de corte del stock disponible a incorporar; vacío no lo calcula
+	StockAFecha string `json:"stockAFecha,omitempty"`
+
+	// Excluye las líneas de documentos anulados de las ventas
+	ExcluirNulas bool `json:"excluirNulas,omitempty"`
+
+	// Rango de años de producción del inventario; 0 usa los lotes que pudieron venderse en el
+	// período (ver FiltroInventario)
+	AnioInventarioDesde int `json:"anioInventarioDesde,omitempty"`
+	AnioInventarioHasta int `json:"anioInventarioHasta,omitempty"`
+
+	// Años de producción anteriores al inicio del período que se consideran sin rango explícito;
+	// nil no limita los años anteriores
+	AniosProduccionPrevios *int `json:"aniosProduccionPrevios,omitempty"`
+
+	// Agrega las unidades ingresadas por año de producción
+	DesglosePorAnio bool `json:"desglosePorAnio,omitempty"`
+}
+
+// FiltroInventario devuelve el filtro de inventario del reporte. Sin rango de años explícito
+// considera los lotes que pudieron venderse en el período: producidos hasta el año de fin
+// (desde AniosProduccionPrevios años antes del inicio si se indica) e ingresados hasta la
+// fecha de fin.
+func (f ReporteFiltro) FiltroInventario() InventarioFiltro {
+	filtro := InventarioFiltro{
+		Anio:            f.Anio,
+		CodigoProducto:  f.CodigoProducto,
+		AnioDesde:       f.AnioInventarioDesde,
+		AnioHasta:       f.AnioInventarioHasta,
+		DesglosePorAnio: f.DesglosePorAnio,
+	}
+	if filtro.AnioDesde != 0 || filtro.AnioHasta != 0 {
+		return filtro
+	}
+
+	inicio, errInicio := time.Parse("2006-01-02", f.FechaInicio)
+	fin, errFin := time.Parse("2006-01-02", f.FechaFin)
+	if errInicio != nil || errFin != nil {
+		return filtro
+	}
+	filtro.AnioDesde = AnioProduccionSinLimite
+	if f.AniosProduccionPrevios != nil {
+		filtro.AnioDesde = inicio.Year() - *f.AniosProduccionPrevios
+	}
+	filtro.AnioHasta = fin.Year()
+	filtro.FechaIngresoHasta = f.FechaFin
+	return filtro
+}
+
+// FiltroVentas devuelve el filtro de ventas equivalente (período, sucursales, código y anulados)
+func (f ReporteFiltro) FiltroVentas() VentasFiltro {
+	return VentasFiltro{
+		FechaInicio:    f.FechaInicio,
+		FechaFin:       f.FechaFin,
+		Sucursales:     f.Sucursales,
+		CodigoProducto: f.CodigoProducto,
+		ExcluirNulas:   f.ExcluirNulas,
+	}
+}
+
+// IncluyeProducto indica si una fila del reporte está en la lista de códigos del filtro, por su
+// código de inventario o de ventas (lista vacía = todas)
+func (f ReporteFiltro) IncluyeProducto(r ReporteCombinado) bool {
+	return incluyeCodigo(f.Codigos, r.CodigoProducto) || (r.CodigoVentas != "" && incluyeCodigo(f.Codigos, r.CodigoVentas))
 }
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// PoliticaReposicion define el nivel de servicio y el plazo de reposición de un producto
+type PoliticaReposicion struct {
+	NivelServicio float64 `json:"nivelServicio"` // % de ciclos de reposición sin quiebre de stock
+	LeadTimeDias  int     `json:"leadTimeDias"`  // Días entre el pedido al proveedor y la recepción
+}
+
+// ConfigReposicion contiene la política por defecto y las excepciones por marca y categoría.
+// Las claves de los mapas están en mayúsculas.
+type ConfigReposicion struct {
+	Defecto       PoliticaReposicion
+	DiasCobertura int // Días de demanda que debe cubrir el pedido además del punto de reorden
+
+	LeadTimeMarca          map[string]int
+	LeadTimeCategoria      map[string]int
+	NivelServicioMarca     map[string]float64
+	NivelServicioCategoria map[string]float64
+}
+
+// Politica devuelve la política de un producto: la marca tiene prioridad sobre la categoría,
+// y esta sobre el valor por defecto. Cada valor se resuelve por separado.
+func (c ConfigReposicion) Politica(marca, categoria string) PoliticaReposicion {
+	marca = strings.ToUpper(strings.TrimSpace(marca))
+	categoria = strings.ToUpper(strings.TrimSpace(categoria))
+
+	politica := c.Defecto
+	if dias, ok := c.LeadTimeMarca[marca]; ok {
+		politica.LeadTimeDias = dias
+	} else if dias, ok := c.LeadTimeCategoria[categoria]; ok {
+		politica.LeadTimeDias = dias
+	}
+	if nivel, ok := c.NivelServicioMarca[marca]; ok {
+		politica.NivelServicio = nivel
+	} else if nivel, ok := c.NivelServicioCategoria[categoria]; ok {
+		politica.NivelServicio = nivel
+	}
+	return politica
+}
+
+// ParametrosReposicion permite ajustar la política en una consulta.
+// Los valores en cero usan la configuración del servidor (por marca, categoría o por defecto).
+type ParametrosReposicion struct {
+	NivelServicio float64 `json:"nivelServicio"` // Si se indica, se aplica a todos los productos
+	LeadTimeDias  int     `json:"leadTimeDias"`  // Si se indica, se aplica a todos los productos
+	DiasCobertura int     `json:"diasCobertura"`
+	Marca         string  `json:"marca,omitempty"` // Limita el reporte a una marca (proveedor)
+}
+
+// Validar valida los parámetros indicados
+func (p *ParametrosReposicion) Validar() error {
+	if p.NivelServicio != 0 && (p.NivelServicio < 50 || p.NivelServicio >= 100) {
+		return errors.New("el nivel de servicio debe estar entre 50 y 99.9")
+	}
+	if p.LeadTimeDias < 0 || p.LeadTimeDias > 365 {
+		return errors.New("el lead time debe estar entre 1 y 365 días")
+	}
+	if p.DiasCobertura < 0 || p.DiasCobertura > 365 {
+		return errors.New("los días de cobertura deben estar entre 0 y 365")
+	}
+	return nil
+}
+
+// ProductoReposicion contiene el cálculo de reposición de un producto
+type ProductoReposicion struct {
+	CodigoProducto     string   `json:"codigoProducto"`
+	CodigoVentas       string   `json:"codigoVentas"`
+	Nombre             string   `json:"nombre"`
+	Marca              string   `json:"marca"`
+	Categoria          string   `json:"categoria"`
+	Dimensiones        string   `json:"dimensiones"`
+	Packing            float64  `json:"packing"`     // Unidades por caja
+	StockActual        float64  `json:"stockActual"` // Stock disponible a la fecha de corte (ver /api/stock)
+	DemandaDiaria      float64  `json:"demandaDiaria"`
+	DesviacionDiaria   float64  `json:"desviacionDiaria"`
+	NivelServicio      float64  `json:"nivelServicio"`
+	LeadTimeDias       int      `json:"leadTimeDias"`
+	StockSeguridad     float64  `json:"stockSeguridad"`
+	PuntoReorden       float64  `json:"puntoReorden"`
+	DiasDeStock        *float64 `json:"diasDeStock"`
+	RequiereReposicion bool     `json:"requiereReposicion"` // Stock actual en o bajo el punto de reorden
+	CantidadSugerida   float64  `json:"cantidadSugerida"`   // Unidades a pedir, redondeadas al packing
+	Cajas              float64  `json:"cajas"`
+	CostoUnitarioUsd   float64  `json:"costoUnitarioUsd"`
+	CostoPedidoUsd     float64  `json:"costoPedidoUsd"`
+	CostoPedidoClp     float64  `json:"costoPedidoClp"`
+}
+
+// ResumenReposicionMarca resume el pedido sugerido de una marca
+type ResumenReposicionMarca struct {
+	Marca          string  `json:"marca"`
+	Productos      int     `json:"productos"` // Productos a reponer
+	Unidades       float64 `json:"unidades"`
+	Cajas          float64 `json:"cajas"`
+	CostoPedidoUsd float64 `json:"costoPedidoUsd"`
+	CostoPedidoClp float64 `json:"costoPedidoClp"`
+}
+
+// ReporteReposicion contiene el cálculo de reposición y la sugerencia de compra por marca
+type ReporteReposicion struct {
+	Parametros   ParametrosReposicion     `json:"parametros"`
+	DiasPeriodo  int                      `json:"diasPeriodo"`
+	Productos    []ProductoReposicion     `json:"productos"`
+	ResumenMarca []ResumenReposicionMarca `json:"resumenMarca"`
+	Total        ResumenReposicionMarca   `json:"total"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Granularidades de la serie de ventas
+const (
+	GranularidadDia    = "dia"
+	GranularidadSemana = "semana"
+	GranularidadMes    = "mes"
+)
+
+// ParseGranularidad valida la granularidad de la serie; vacío usa la mensual
+func ParseGranularidad(valor string) (string, error) {
+	switch g := strings.ToLower(strings.TrimSpace(valor)); g {
+	case "":
+		return GranularidadMes, nil
+	case GranularidadDia, GranularidadSemana, GranularidadMes:
+		return g, nil
+	default:
+		return "", fmt.Errorf("granularidad no válida: %q (use %q, %q o %q)", valor, GranularidadDia, GranularidadSemana, GranularidadMes)
+	}
+}
+
+// PeriodoSerie identifica un período de la serie
+type PeriodoSerie struct {
+	Periodo     string `json:"periodo"`     // 2025-01-31, 2025-W05 o 2025-01
+	FechaInicio string `json:"fechaInicio"` // Primer día del período
+	FechaFin    string `json:"fechaFin"`    // Último día del período
+}
+
+// PuntoSerie contiene las ventas de un producto en un período
+type PuntoSerie struct {
+	Periodo       string  `json:"periodo"`
+	Cantidad      float64 `json:"cantidad"`
+	VentaClp      int     `json:"ventaClp"`
+	Transacciones int     `json:"transacciones"`
+}
+
+// SerieProducto es la serie de ventas de un producto, con un punto por período (incluidos los vacíos)
+type SerieProducto struct {
+	CodigoProducto string       `json:"codigoProducto"`
+	NombreProducto string       `json:"nombreProducto"`
+	Cantidad       float64      `json:"cantidad"`
+	VentaClp       int          `json:"ventaClp"`
+	Transacciones  int          `json:"transacciones"`
+	Puntos         []PuntoSerie `json:"puntos"`
+}
+
+// SerieVentas contiene la serie de ventas por producto en el rango solicitado
+type SerieVentas struct {
+	FechaInicio  string          `json:"fechaInicio"`
+	FechaFin     string          `json:"fechaFin"`
+	Granularidad string          `json:"granularidad"`
+	Sucursales   []int           `json:"sucursales"`
+	Periodos     []PeriodoSerie  `json:"periodos"`
+	Productos    []SerieProducto `json:"productos"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestParseGranularidad(t *testing.T) {
+	casos := []struct {
+		valor    string
+		esperado string
+		error    bool
+	}{
+		{valor: "", esperado: GranularidadMes},
+		{valor: " Semana ", esperado: GranularidadSemana},
+		{valor: "dia", esperado: GranularidadDia},
+		{valor: "anio", error: true},
+	}
+	for _, c := range casos {
+		granularidad, err := ParseGranularidad(c.valor)
+		switch {
+		case c.error && err == nil:
+			t.Errorf("ParseGranularidad(%q) = %q, se esperaba error", c.valor, granularidad)
+		case !c.error && (err != nil || granularidad != c.esperado):
+			t.Errorf("ParseGranularidad(%q) = %q, %v; se esperaba %q", c.valor, granularidad, err, c.esperado)
+		}
+	}
+}
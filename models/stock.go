@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// StockFiltro define el cálculo del stock disponible a una fecha de corte
+type StockFiltro struct {
+	Fecha          string `json:"fecha"` // Fecha de corte (incluida); por defecto, hoy
+	CodigoProducto string `json:"codigoProducto"`
+
+	// Orden de estrategias de coincidencia; vacío usa la configuración del servidor
+	Estrategias []string `json:"estrategias,omitempty"`
+}
+
+// Validar valida el filtro y aplica la fecha por defecto
+func (f *StockFiltro) Validar() error {
+	if f.Fecha == "" {
+		f.Fecha = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", f.Fecha); err != nil {
+		return errors.New("fecha de corte no válida, debe estar en formato YYYY-MM-DD")
+	}
+	return nil
+}
+
+// IngresoStock representa una fila de GetIngresosHastaFechaQuery
+type IngresoStock struct {
+	CodigoProducto     string    `db:"Código de Producto"`
+	NombreAduanero     string    `db:"Nombre Aduanero"`
+	Marca              string    `db:"Marca del Producto"`
+	Categoria          string    `db:"Categoría Principal"`
+	UnidadesPorCaja    float64   `db:"Unidades por Caja"`
+	Zeta               string    `db:"Zeta"`
+	AnioProduccion     string    `db:"Año Producción"`
+	UnidadesIngresadas float64   `db:"Unidades Ingresadas"`
+	FechaIngreso       time.Time `db:"Fecha Ingreso"`
+}
+
+// StockLote es el stock disponible de un lote (Zeta) a la fecha de corte
+type StockLote struct {
+	Zeta               string  `json:"zeta"`
+	AnioProduccion     string  `json:"anioProduccion"`
+	FechaIngreso       string  `json:"fechaIngreso"`
+	UnidadesIngresadas float64 `json:"unidadesIngresadas"`
+	UnidadesVendidas   float64 `json:"unidadesVendidas"` // Sin contar documentos anulados
+	UnidadesAnuladas   float64 `json:"unidadesAnuladas"`
+	Stock              float64 `json:"stock"`
+	StockNegativo      bool    `json:"stockNegativo"`
+	SinIngreso         bool    `json:"sinIngreso,omitempty"` // Zeta vendido que no figura en los ingresos
+}
+
+// StockProducto es el stock disponible de un producto a la fecha de corte:
+// ingresos acumulados menos ventas acumuladas, sin contar las líneas de documentos anulados
+type StockProducto struct {
+	CodigoProducto     string      `json:"codigoProducto"`
+	CodigoVentas       string      `json:"codigoVentas"`
+	Nombre             string      `json:"nombre"`
+	Marca              string      `json:"marca"`
+	Categoria          string      `json:"categoria"`
+	MetodoCoincidencia string      `json:"metodoCoincidencia,omitempty"`
+	PrimerIngreso      string      `json:"primerIngreso"`
+	UltimoIngreso      string      `json:"ultimoIngreso"`
+	UltimaVenta        string      `json:"ultimaVenta"`
+	UnidadesIngresadas float64     `json:"unidadesIngresadas"`
+	UnidadesVendidas   float64     `json:"unidadesVendidas"`
+	UnidadesAnuladas   float64     `json:"unidadesAnuladas"`
+	Stock              float64     `json:"stock"`
+	StockNegativo      bool        `json:"stockNegativo"` // Se vendió más de lo ingresado: problema de emparejamiento o de datos
+	SinIngresos        bool        `json:"sinIngresos"`   // Producto vendido sin ingresos emparejados
+	Lotes              []StockLote `json:"lotes"`
+}
+
+// ReporteStock contiene el stock disponible de cada producto a la fecha de corte
+type ReporteStock struct {
+	Fecha                string          `json:"fecha"`
+	InicioVentas         string          `json:"inicioVentas"` // Primera fecha de venta considerada
+	Productos            []StockProducto `json:"productos"`
+	UnidadesEnStock      float64         `json:"unidadesEnStock"` // Suma de los stocks positivos
+	ProductosNegativos   int             `json:"productosNegativos"`
+	LotesNegativos       int             `json:"lotesNegativos"`
+	ProductosSinIngresos int             `json:"productosSinIngresos"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// Sucursal representa una sucursal del ERP y su actividad de ventas en un período
+type Sucursal struct {
+	ID                 int    `json:"id" db:"Sucursal"`
+	Nombre             string `json:"nombre" db:"Nombre Sucursal"`
+	DocumentosPeriodo  int    `json:"documentosPeriodo" db:"Documentos en Período"`
+	TieneVentas        bool   `json:"tieneVentas"`
+	SucursalPorDefecto bool   `json:"sucursalPorDefecto"`
+}
+
+// CatalogoSucursales es la respuesta del catálogo de sucursales
+type CatalogoSucursales struct {
+	FechaInicio        string     `json:"fechaInicio"`
+	FechaFin           string     `json:"fechaFin"`
+	SucursalPorDefecto int        `json:"sucursalPorDefecto"`
+	Sucursales         []Sucursal `json:"sucursales"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// TipoCambio es el valor del dólar en pesos chilenos (CLP por USD) vigente desde una fecha
+type TipoCambio struct {
+	Fecha          string    `json:"fecha"` // YYYY-MM-DD
+	Valor          float64   `json:"valor"`
+	Fuente         string    `json:"fuente,omitempty"` // Origen del valor (archivo, manual, etc.)
+	ActualizadoPor string    `json:"actualizadoPor,omitempty"`
+	ActualizadoEn  time.Time `json:"actualizadoEn"`
+}
+
+// TipoCambioAplicado indica el tipo de cambio usado para convertir una fecha: el de la
+// misma fecha o, si no existe (fines de semana, feriados), el último anterior
+type TipoCambioAplicado struct {
+	Fecha       string  `json:"fecha"`       // Fecha consultada
+	FechaValor  string  `json:"fechaValor"`  // Fecha del valor aplicado
+	Valor       float64 `json:"valor"`       // CLP por USD
+	Aproximado  bool    `json:"aproximado"`  // La fecha es anterior al primer valor registrado y se usó ese
+	DiasDesfase int     `json:"diasDesfase"` // Días entre la fecha consultada y la del valor aplicado
+}
+
+// ResultadoImportacionTipoCambio resume una carga masiva de tipos de cambio desde archivo
+type ResultadoImportacionTipoCambio struct {
+	Creados      int      `json:"creados"`
+	Actualizados int      `json:"actualizados"`
+	Errores      []string `json:"errores,omitempty"`
+}
+
+// MargenMoneda contiene el margen de las ventas de un producto en USD y CLP, convirtiendo
+// cada línea de venta al tipo de cambio de su fecha de emisión. El costo es el costo
+// unitario en USD de la venta; si no viene informado se usa el CIF promedio del inventario.
+type MargenMoneda struct {
+	VentaNetaClp float64 `json:"VENTA_NETA_CLP"`
+	VentaNetaUsd float64 `json:"VENTA_NETA_USD"`
+	CostoUsd     float64 `json:"COSTO_USD"`
+	CostoClp     float64 `json:"COSTO_CLP"`
+	MargenUsd    float64 `json:"MARGEN_USD"`
+	MargenClp    float64 `json:"MARGEN_CLP"`
+	MargenPct    float64 `json:"MARGEN_PORCENTAJE"` // Sobre la venta neta en CLP
+
+	// Tipo de cambio promedio de las ventas (venta CLP / venta USD) y de los ingresos
+	// (ponderado por unidades); el CIF en CLP se recalcula con el de los ingresos
+	TipoCambioVentas   float64 `json:"TIPO_CAMBIO_VENTAS"`
+	TipoCambioIngresos float64 `json:"TIPO_CAMBIO_INGRESOS"`
+	CifPromedioClp     float64 `json:"CIF_PROMEDIO_CLP_TC"`
+
+	// Líneas de venta cuya fecha es anterior al primer tipo de cambio registrado
+	LineasAproximadas int `json:"LINEAS_TC_APROXIMADO,omitempty"`
+
+	// Líneas de venta sin costo unitario de STOCKS, costeadas al CIF promedio
+	LineasSinCosto int `json:"LINEAS_SIN_COSTO,omitempty"`
+}
@@ -2,6 +2,9 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,26 +29,133 @@ type Venta struct {
 
 // VentaAgrupada representa la información consolidada de ventas por producto
 type VentaAgrupada struct {
-	CodigoProducto             string  `json:"codigoProducto"`
-	NombreProducto             string  `json:"nombreProducto"`
-	CostoUnitarioUSD           float64 `json:"costoUnitarioUSD"`
-	PrecioBaseCLP              int     `json:"precioBaseCLP"`
-	PrecioOfertaCLP            int     `json:"precioOfertaCLP"`
-	CantidadTotalVendida       float64 `json:"cantidadTotalVendida"`
-	TotalVentasCLP             int     `json:"totalVentasCLP"`
-	UltimaFechaVenta           string  `json:"ultimaFechaVenta"`
-	PrecioPromedioPonderadoCLP int     `json:"precioPromedioPonderadoCLP"`
-	PrecioMinimoCLP            int     `json:"precioMinimoCLP"`
-	PrecioMaximoCLP            int     `json:"precioMaximoCLP"`
-	CantidadDeVentas           int     `json:"cantidadDeVentas"`
+	CodigoProducto             string  `json:"codigoProducto" db:"Código de Producto"`
+	NombreProducto             string  `json:"nombreProducto" db:"Nombre del Producto"`
+	CostoUnitarioUSD           float64 `json:"costoUnitarioUSD" db:"Costo Unitario (USD)"`
+	PrecioBaseCLP              int     `json:"precioBaseCLP" db:"Precio Base (CLP)"`
+	PrecioOfertaCLP            int     `json:"precioOfertaCLP" db:"Precio de Oferta (CLP)"`
+	CantidadTotalVendida       float64 `json:"cantidadTotalVendida" db:"Cantidad Total Vendida"`
+	TotalVentasCLP             int     `json:"totalVentasCLP" db:"Total Ventas (CLP)"` // Suma del total del documento de cada línea
+	TotalLineasCLP             int     `json:"totalLineasCLP" db:"Total Líneas (CLP)"` // Suma del total de cada línea
+	UltimaFechaVenta           string  `json:"ultimaFechaVenta" db:"Última Fecha de Venta"`
+	PrecioPromedioPonderadoCLP int     `json:"precioPromedioPonderadoCLP" db:"Precio Promedio Ponderado (CLP)"`
+	PrecioMinimoCLP            int     `json:"precioMinimoCLP" db:"Precio Mínimo (CLP)"`
+	PrecioMaximoCLP            int     `json:"precioMaximoCLP" db:"Precio Máximo (CLP)"`
+	CantidadDeVentas           int     `json:"cantidadDeVentas" db:"Cantidad de Ventas Registradas"`
+
+	// Desglose por tipo de documento; la venta suma el total de cada línea, como TotalLineasCLP
+	UnidadesBoleta    float64 `json:"unidadesBoleta" db:"Unidades Boleta"`
+	UnidadesFactura   float64 `json:"unidadesFactura" db:"Unidades Factura"`
+	VentasBoletaCLP   int     `json:"ventasBoletaCLP" db:"Ventas Boleta (CLP)"`
+	VentasFacturaCLP  int     `json:"ventasFacturaCLP" db:"Ventas Factura (CLP)"`
+	DocumentosBoleta  int     `json:"documentosBoleta" db:"Documentos Boleta"`
+	DocumentosFactura int     `json:"documentosFactura" db:"Documentos Factura"`
+
+	// Documentos anulados (su venta es 0, pero sus unidades se cuentan salvo con ExcluirNulas)
+	DocumentosNulos int     `json:"documentosNulos" db:"Documentos Nulos"`
+	UnidadesNulas   float64 `json:"unidadesNulas" db:"Unidades Nulas"`
+
+	// Desglose por sucursal; solo se completa al consultar varias sucursales
+	VentasPorSucursal []VentaSucursal `json:"ventasPorSucursal,omitempty"`
+}
+
+// VentaSucursal representa las ventas de un producto en una sucursal
+type VentaSucursal struct {
+	CodigoProducto       string  `json:"-" db:"Código de Producto"`
+	Sucursal             int     `json:"sucursal" db:"Sucursal"`
+	CantidadTotalVendida float64 `json:"cantidadTotalVendida" db:"Cantidad Total Vendida"`
+	TotalVentasCLP       int     `json:"totalVentasCLP" db:"Total Ventas (CLP)"`
+	UltimaFechaVenta     string  `json:"ultimaFechaVenta" db:"Última Fecha de Venta"`
+	CantidadDeVentas     int     `json:"cantidadDeVentas" db:"Cantidad de Ventas Registradas"`
 }
 
 // VentasFiltro define los filtros para consultar ventas
 type VentasFiltro struct {
 	FechaInicio    string `json:"fechaInicio"`
 	FechaFin       string `json:"fechaFin"`
-	Sucursal       int    `json:"sucursal"`
+	Sucursales     []int  `json:"sucursales"`     // Vacío = todas las sucursales
 	CodigoProducto string `json:"codigoProducto"` // Nuevo campo para filtrar por código
+
+	// Lista de códigos exactos (ver NormalizarCodigos); vacía = todos
+	Codigos []string `json:"codigos,omitempty"`
+
+	// Excluye las líneas de documentos anulados de todas las métricas (por defecto solo su venta es 0)
+	ExcluirNulas bool `json:"excluirNulas"`
+}
+
+// TodasLasSucursales indica si el filtro abarca todas las sucursales
+func (f VentasFiltro) TodasLasSucursales() bool {
+	return len(f.Sucursales) == 0
+}
+
+// VariasSucursales indica si el filtro abarca más de una sucursal (y corresponde desglosar)
+func (f VentasFiltro) VariasSucursales() bool {
+	return len(f.Sucursales) != 1
+}
+
+// IncluyeSucursal indica si una sucursal está dentro del filtro
+func (f VentasFiltro) IncluyeSucursal(sucursal int) bool {
+	if f.TodasLasSucursales() {
+		return true
+	}
+	for _, s := range f.Sucursales {
+		if s == sucursal {
+			return true
+		}
+	}
+	return false
+}
+
+// SucursalesTexto devuelve las sucursales separadas por comas, o vacío si son todas
+func (f VentasFiltro) SucursalesTexto() string {
+	textos := make([]string, len(f.Sucursales))
+	for i, s := range f.Sucursales {
+		textos[i] = strconv.Itoa(s)
+	}
+	return strings.Join(textos, ",")
+}
+
+// IncluyeCodigo indica si un código está en la lista de códigos del filtro (vacía = todos)
+func (f VentasFiltro) IncluyeCodigo(codigo string) bool {
+	return incluyeCodigo(f.Codigos, codigo)
+}
+
+// CodigosTexto devuelve la lista de códigos separados por comas, o vacío si no hay lista
+func (f VentasFiltro) CodigosTexto() string {
+	return strings.Join(f.Codigos, ",")
+}
+
+// ParseSucursales interpreta el parámetro sucursal: vacío usa la sucursal por defecto,
+// "todas" (o "all") abarca todas y una lista separada por comas selecciona varias
+func ParseSucursales(valor string, defecto int) ([]int, error) {
+	valor = strings.TrimSpace(valor)
+	switch strings.ToLower(valor) {
+	case "":
+		return []int{defecto}, nil
+	case "todas", "all":
+		return nil, nil
+	}
+
+	var sucursales []int
+	vistas := make(map[int]bool)
+	for _, item := range strings.Split(valor, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		sucursal, err := strconv.Atoi(item)
+		if err != nil || sucursal <= 0 {
+			return nil, fmt.Errorf("%q no es un ID de sucursal", item)
+		}
+		if !vistas[sucursal] {
+			vistas[sucursal] = true
+			sucursales = append(sucursales, sucursal)
+		}
+	}
+	if len(sucursales) == 0 {
+		return []int{defecto}, nil
+	}
+	return sucursales, nil
 }
 
 // Validar valida los parámetros del filtro
@@ -65,9 +175,11 @@ func (f *VentasFiltro) Validar() error {
 		return errors.New("fecha de fin no válida, debe estar en formato YYYY-MM-DD")
 	}
 
-	// Validar sucursal
-	if f.Sucursal <= 0 {
-		f.Sucursal = 211 // Usando sucursal por defecto si no se especifica
+	// Validar sucursales
+	for _, s := range f.Sucursales {
+		if s <= 0 {
+			return fmt.Errorf("sucursal no válida: %d", s)
+		}
 	}
 
 	return nil
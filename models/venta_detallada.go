@@ -1,20 +1,29 @@
 package models
 
+// Tipos de documento de venta
+const (
+	TipoDocumentoBoleta  = "BOLETA"
+	TipoDocumentoFactura = "FACTURA"
+)
+
 // VentaDetallada representa una venta individual con detalles completos
 type VentaDetallada struct {
-	CodigoDocumento       string  `json:"codigoDocumento"`
-	FechaEmision          string  `json:"fechaEmision"`
-	TipoDocumento         string  `json:"tipoDocumento"`
-	Cliente               string  `json:"cliente"`
-	CodigoProducto        string  `json:"codigoProducto"`
-	NombreProducto        string  `json:"nombreProducto"`
-	Cantidad              float64 `json:"cantidad"`
-	PrecioUnitarioCLP     int     `json:"precioUnitarioCLP"`
-	TotalVentaCLP         int     `json:"totalVentaCLP"`
-	Sucursal              int     `json:"sucursal"`
-	CostoUnitarioUSD      float64 `json:"costoUnitarioUSD"`
-	PrecioBaseCLP         int     `json:"precioBaseCLP"`
-	PrecioOfertaCLP       int     `json:"precioOfertaCLP"`
-	PrecioPromedioCLP     int     `json:"precioPromedioCLP"`
-	CantidadTransacciones int     `json:"cantidadTransacciones"`
+	CodigoDocumento       string  `json:"codigoDocumento" db:"Código Documento"`
+	FechaEmision          string  `json:"fechaEmision" db:"Fecha Emisión"`
+	TipoDocumento         string  `json:"tipoDocumento" db:"Tipo Documento"`
+	Cliente               string  `json:"cliente" db:"Cliente"`
+	CodigoProducto        string  `json:"codigoProducto" db:"Código Producto"`
+	NombreProducto        string  `json:"nombreProducto" db:"Producto"`
+	Cantidad              float64 `json:"cantidad" db:"Cantidad"`
+	PrecioUnitarioCLP     int     `json:"precioUnitarioCLP" db:"Precio Unitario (CLP)"`
+	TotalVentaCLP         int     `json:"totalVentaCLP" db:"Total Venta (CLP)"`
+	Sucursal              int     `json:"sucursal" db:"Sucursal"`
+	CostoUnitarioUSD      float64 `json:"costoUnitarioUSD" db:"Costo Unitario (USD)"`
+	PrecioBaseCLP         int     `json:"precioBaseCLP" db:"Precio Base (CLP)"`
+	PrecioOfertaCLP       int     `json:"precioOfertaCLP" db:"Precio Oferta (CLP)"`
+	PrecioPromedioCLP     int     `json:"precioPromedioCLP" db:"Precio Promedio (CLP)"`
+	CantidadTransacciones int     `json:"cantidadTransacciones" db:"Cant. Transacciones"`
+	Zeta                  string  `json:"zeta" db:"Zeta"`
+	Nula                  bool    `json:"nula" db:"Documento Nulo"`                 // Documento anulado: su total es 0
+	TotalAnuladoCLP       int     `json:"totalAnuladoCLP" db:"Total Anulado (CLP)"` // Total original de la línea anulada
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseSucursales(t *testing.T) {
+	casos := []struct {
+		valor    string
+		esperado string
+		error    bool
+	}{
+		{valor: "", esperado: "[211]"},
+		{valor: " , ", esperado: "[211]"},
+		{valor: "212", esperado: "[212]"},
+		// Se conserva el orden y se descartan las repetidas
+		{valor: "213, 211,213", esperado: "[213 211]"},
+		{valor: "todas", esperado: "[]"},
+		{valor: " ALL ", esperado: "[]"},
+		{valor: "211,centro", error: true},
+		{valor: "0", error: true},
+		{valor: "-5", error: true},
+	}
+	for _, c := range casos {
+		sucursales, err := ParseSucursales(c.valor, 211)
+		switch {
+		case c.error && err == nil:
+			t.Errorf("ParseSucursales(%q) = %v, se esperaba error", c.valor, sucursales)
+		case !c.error && (err != nil || fmt.Sprint(sucursales) != c.esperado):
+			t.Errorf("ParseSucursales(%q) = %v, %v; se esperaba %s", c.valor, sucursales, err, c.esperado)
+		}
+	}
+}
+
+func TestVentasFiltroSucursales(t *testing.T) {
+	todas := VentasFiltro{}
+	una := VentasFiltro{Sucursales: []int{211}}
+	varias := VentasFiltro{Sucursales: []int{211, 213}}
+
+	if !todas.TodasLasSucursales() || !todas.VariasSucursales() || !todas.IncluyeSucursal(999) || todas.SucursalesTexto() != "" {
+		t.Errorf("todas las sucursales: %+v", todas)
+	}
+	if una.TodasLasSucursales() || una.VariasSucursales() || !una.IncluyeSucursal(211) || una.IncluyeSucursal(212) {
+		t.Errorf("una sucursal: %+v", una)
+	}
+	if !varias.VariasSucursales() || !varias.IncluyeSucursal(213) || varias.IncluyeSucursal(212) || varias.SucursalesTexto() != "211,213" {
+		t.Errorf("varias sucursales: %+v", varias)
+	}
+
+	invalido := VentasFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-31", Sucursales: []int{211, 0}}
+	if err := invalido.Validar(); err == nil {
+		t.Error("Validar con la sucursal 0 no devolvió error")
+	}
+}
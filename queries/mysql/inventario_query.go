@@ -32,8 +32,13 @@ SELECT
     MAX(fec_ing) AS "Fecha Último Ingreso",
     DATEDIFF(CURDATE(), MIN(fec_ing)) AS "Días Desde Primer Ingreso",
 
-    -- Conteo de registros
-    COUNT(*) AS "Cantidad de Ingresos",
+    -- Conteo de registros: los mismos ingresos distintos que el historial
+    COUNT(DISTINCT CONCAT(
+        '{\'Zeta\':\'', ZET_ART, '\',',
+        '\'Año Producción\':\'', ANIO_PRO, '\',',
+        '\'Unidades Ingresadas\':', CAN_ING, ',',
+        '\'Fecha Ingreso\':\'', DATE_FORMAT(fec_ing, '%Y-%m-%d'), '\'}'
+    )) AS "Cantidad de Ingresos",
 
     -- Metadatos en JSON
     CONCAT(
@@ -50,8 +55,11 @@ SELECT
     ) AS "Historial de Ingresos (JSON)"
 FROM saldos s
 WHERE
-    CAST(ANIO_PRO AS SIGNED) = ?
+    CAST(ANIO_PRO AS SIGNED) BETWEEN ? AND ?
+    AND (? = '' OR fec_ing >= ?)
+    AND (? = '' OR fec_ing < DATE_ADD(?, INTERVAL 1 DAY))
     AND (? = '' OR COD_ART LIKE CONCAT('%', ?, '%'))
+    AND (? = '' OR FIND_IN_SET(COD_ART, ?) > 0)
 GROUP BY 
     COD_ART, 
     UNI_CAJ
@@ -150,8 +158,13 @@ SELECT
     MAX(fec_ing) AS "Fecha Último Ingreso",
     DATEDIFF(CURDATE(), MIN(fec_ing)) AS "Días Desde Primer Ingreso",
 
-    -- Conteo de registros
-    COUNT(*) AS "Cantidad de Ingresos",
+    -- Conteo de registros: los mismos ingresos distintos que el historial
+    COUNT(DISTINCT CONCAT(
+        '{\'Zeta\':\'', ZET_ART, '\',',
+        '\'Año Producción\':\'', ANIO_PRO, '\',',
+        '\'Unidades Ingresadas\':', CAN_ING, ',',
+        '\'Fecha Ingreso\':\'', DATE_FORMAT(fec_ing, '%Y-%m-%d'), '\'}'
+    )) AS "Cantidad de Ingresos",
 
     -- Metadatos en JSON
     CONCAT('[', GROUP_CONCAT(DISTINCT CONCAT(
@@ -162,11 +175,59 @@ SELECT
     ) SEPARATOR ','), ']') AS "Historial de Ingresos (JSON)"
 FROM saldos s
 WHERE
-    CAST(ANIO_PRO AS SIGNED) = ?
+    CAST(ANIO_PRO AS SIGNED) BETWEEN ? AND ?
+    AND (? = '' OR fec_ing >= ?)
+    AND (? = '' OR fec_ing < DATE_ADD(?, INTERVAL 1 DAY))
     AND (? = '' OR COD_ART LIKE CONCAT('%', ?, '%'))
+    AND (? = '' OR FIND_IN_SET(COD_ART, ?) > 0)
 GROUP BY
     COD_ART,
     UNI_CAJ
 ORDER BY "Código de Producto"
 `
 }
+
+// GetHistorialIngresosQuery devuelve cada ingreso de la tabla saldos como una fila.
+// Es la alternativa a "Historial de Ingresos (JSON)" que no depende de
+// GROUP_CONCAT (y por lo tanto no se trunca con group_concat_max_len).
+func GetHistorialIngresosQuery() string {
+	return `
+SELECT DISTINCT
+    COD_ART AS "Código de Producto",
+    UNI_CAJ AS "Unidades por Caja",
+    ZET_ART AS "Zeta",
+    ANIO_PRO AS "Año Producción",
+    CAN_ING AS "Unidades Ingresadas",
+    DATE_FORMAT(fec_ing, '%Y-%m-%d') AS "Fecha Ingreso"
+FROM saldos s
+WHERE
+    CAST(ANIO_PRO AS SIGNED) BETWEEN ? AND ?
+    AND (? = '' OR fec_ing >= ?)
+    AND (? = '' OR fec_ing < DATE_ADD(?, INTERVAL 1 DAY))
+    AND (? = '' OR COD_ART LIKE CONCAT('%', ?, '%'))
+    AND (? = '' OR FIND_IN_SET(COD_ART, ?) > 0)
+ORDER BY COD_ART, fec_ing
+`
+}
+
+// GetIngresosHastaFechaQuery devuelve cada ingreso de la tabla saldos hasta una fecha de corte,
+// de todos los años de producción, para calcular el stock acumulado a esa fecha
+func GetIngresosHastaFechaQuery() string {
+	return `
+SELECT DISTINCT
+    COD_ART AS "Código de Producto",
+    DES_ADU AS "Nombre Aduanero",
+    IFNULL(Marca, 'POR ASIGNAR') AS "Marca del Producto",
+    IFNULL(SubFamilia, 'POR ASIGNAR') AS "Categoría Principal",
+    UNI_CAJ AS "Unidades por Caja",
+    ZET_ART AS "Zeta",
+    ANIO_PRO AS "Año Producción",
+    CAN_ING AS "Unidades Ingresadas",
+    DATE_FORMAT(fec_ing, '%Y-%m-%d') AS "Fecha Ingreso"
+FROM saldos s
+WHERE
+    fec_ing <= ?
+    AND (? = '' OR COD_ART LIKE CONCAT('%', ?, '%'))
+ORDER BY COD_ART, fec_ing
+`
+}
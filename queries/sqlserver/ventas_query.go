@@ -17,7 +17,10 @@ WITH VentasBase AS (
         DB.CANTIDAD AS CantidadVendida,
         CASE WHEN VB.NULA = 1 THEN 0 ELSE DB.TOTAL END AS TotalProductoCLP,
         CASE WHEN VB.NULA = 1 THEN 0 ELSE VB.TOTAL END AS TotalDocumentoCLP,
+        CASE WHEN VB.NULA = 1 THEN DB.TOTAL ELSE 0 END AS TotalAnuladoCLP,
+        DB.ZETA AS Zeta,
         'BOLETA' AS TipoDocumento,
+        VB.NULA AS Nula,
         CAST(VB.CORRELATIVO AS VARCHAR(20)) AS CodigoDocumento,
         ISNULL(PCLI.NOMBRE_P + ' ' + PCLI.APELLIDOPATERNO_P, 'Sin Cliente') AS Cliente
     FROM VENTA_BOLETA VB
@@ -32,7 +35,8 @@ WITH VentasBase AS (
         z.ANIO = YEAR(VB.FECHA_VENTA)
     WHERE 
         VB.FECHA_VENTA BETWEEN ? AND ? AND
-        VB.ID_SUCURSAL = ?
+        (? = '' OR ',' + ? + ',' LIKE '%,' + CAST(VB.ID_SUCURSAL AS VARCHAR(10)) + ',%') AND
+        (? = 0 OR VB.NULA = 0)
     
     UNION ALL
     
@@ -49,7 +53,10 @@ WITH VentasBase AS (
         DF.CANTIDAD AS CantidadVendida,
         CASE WHEN VF.NULA = 1 THEN 0 ELSE DF.TOTAL END AS TotalProductoCLP,
         CASE WHEN VF.NULA = 1 THEN 0 ELSE VF.TOTAL END AS TotalDocumentoCLP,
+        CASE WHEN VF.NULA = 1 THEN DF.TOTAL ELSE 0 END AS TotalAnuladoCLP,
+        DF.ZETA AS Zeta,
         'FACTURA' AS TipoDocumento,
+        VF.NULA AS Nula,
         CAST(VF.CORRELATIVO AS VARCHAR(20)) AS CodigoDocumento,
         ISNULL(PCLIF.NOMBRE_P + ' ' + PCLIF.APELLIDOPATERNO_P, 'Sin Cliente') AS Cliente
     FROM VENTA_FACTURA VF
@@ -64,7 +71,8 @@ WITH VentasBase AS (
         z.ANIO = YEAR(VF.FECHA_EMISION)
     WHERE 
         VF.FECHA_EMISION BETWEEN ? AND ? AND
-        VF.ID_SUCURSAL = ?
+        (? = '' OR ',' + ? + ',' LIKE '%,' + CAST(VF.ID_SUCURSAL AS VARCHAR(10)) + ',%') AND
+        (? = 0 OR VF.NULA = 0)
 ),
 CalculosProducto AS (
     SELECT 
@@ -94,17 +102,24 @@ SELECT
     v.PrecioBaseCLP AS "Precio Base (CLP)",
     v.PrecioOfertaCLP AS "Precio Oferta (CLP)",
     c.PrecioPromedioCLP AS "Precio Promedio (CLP)",
-    c.CantidadTransacciones AS "Cant. Transacciones"
+    c.CantidadTransacciones AS "Cant. Transacciones",
+    v.Zeta AS "Zeta",
+    v.Nula AS "Documento Nulo",
+    v.TotalAnuladoCLP AS "Total Anulado (CLP)"
 FROM VentasBase v
 INNER JOIN CalculosProducto c ON v.CodigoProducto = c.CodigoProducto
 WHERE (? = '' OR v.CodigoProducto LIKE '%' + ? + '%')
+  AND (? = '' OR CHARINDEX(',' + v.CodigoProducto + ',', ',' + ? + ',') > 0)
 ORDER BY v.FechaDocumento DESC
 `
 }
 
-// GetVentasAgrupadasQuery devuelve la consulta SQL para obtener ventas agrupadas por producto
-func GetVentasAgrupadasQuery() string {
-	return `
+// ventasBaseResumen es la CTE VentasBase común a las consultas de ventas resumidas:
+// una fila por línea de boleta o factura con su total, el total del documento, su tipo y si
+// está anulado. El total del documento se repite en cada línea, por lo que sumarlo acredita el
+// documento completo a cada producto; los desgloses nuevos suman el total de la línea
+// (TotalProductoCLP).
+const ventasBaseResumen = `
 WITH VentasBase AS (
     -- BOLETAS
     SELECT 
@@ -117,7 +132,10 @@ WITH VentasBase AS (
         PR.PRECIO_OFERTA AS PrecioOfertaCLP, -- Precio de oferta en CLP
         DB.VALORUNITARIO AS PrecioVentaCLP, -- Precio real de venta en CLP
         DB.CANTIDAD AS CantidadVendida,
-        CASE WHEN VB.NULA = 1 THEN 0 ELSE VB.TOTAL END AS TotalDocumentoCLP
+        CASE WHEN VB.NULA = 1 THEN 0 ELSE DB.TOTAL END AS TotalProductoCLP,
+        'BOLETA' AS TipoDocumento,
+        VB.NULA AS Nula,
+        CAST(VB.CORRELATIVO AS VARCHAR(20)) AS CodigoDocumento
     FROM VENTA_BOLETA VB
     INNER JOIN DETALLE_VENTA_BOLETA DB ON VB.ID_VENTA_BOLETA = DB.ID_VENTA_BOLETA
     INNER JOIN PRODUCTO PR ON DB.ID_PRODUCTO = PR.ID_PRODUCTO
@@ -128,7 +146,8 @@ WITH VentasBase AS (
         z.ANIO = YEAR(VB.FECHA_VENTA)
     WHERE 
         VB.FECHA_VENTA BETWEEN ? AND ? AND
-        VB.ID_SUCURSAL = ?
+        (? = '' OR ',' + ? + ',' LIKE '%,' + CAST(VB.ID_SUCURSAL AS VARCHAR(10)) + ',%') AND
+        (? = 0 OR VB.NULA = 0)
 
     UNION ALL
 
@@ -143,7 +162,10 @@ WITH VentasBase AS (
         PR.PRECIO_OFERTA AS PrecioOfertaCLP,
         DF.VALORUNITARIO AS PrecioVentaCLP,
         DF.CANTIDAD AS CantidadVendida,
-        CASE WHEN VF.NULA = 1 THEN 0 ELSE VF.TOTAL END AS TotalDocumentoCLP
+        CASE WHEN VF.NULA = 1 THEN 0 ELSE DF.TOTAL END AS TotalProductoCLP,
+        'FACTURA' AS TipoDocumento,
+        VF.NULA AS Nula,
+        CAST(VF.CORRELATIVO AS VARCHAR(20)) AS CodigoDocumento
     FROM VENTA_FACTURA VF
     INNER JOIN DETALLE_FAC_E DF ON VF.ID_VENTA_FACTURA = DF.ID_VENTA_FACTURA
     INNER JOIN PRODUCTO PR ON DF.ID_PRODUCTO = PR.ID_PRODUCTO
@@ -154,8 +176,13 @@ WITH VentasBase AS (
         z.ANIO = YEAR(VF.FECHA_EMISION)
     WHERE 
         VF.FECHA_EMISION BETWEEN ? AND ? AND
-        VF.ID_SUCURSAL = ?
-),
+        (? = '' OR ',' + ? + ',' LIKE '%,' + CAST(VF.ID_SUCURSAL AS VARCHAR(10)) + ',%') AND
+        (? = 0 OR VF.NULA = 0)
+)`
+
+// GetVentasAgrupadasQuery devuelve la consulta SQL para obtener ventas agrupadas por producto
+func GetVentasAgrupadasQuery() string {
+	return ventasBaseResumen + `,
 Resumen AS (
     SELECT 
         CodigoProducto,
@@ -165,6 +192,7 @@ Resumen AS (
         MAX(PrecioOfertaCLP) AS PrecioOfertaCLP,
         SUM(CantidadVendida) AS CantidadTotalVendida,
         SUM(TotalDocumentoCLP) AS TotalVentasCLP,
+        SUM(TotalProductoCLP) AS TotalLineasCLP,
         MAX(FechaDocumento) AS UltimaFechaVenta,
         CAST(
             ROUND(
@@ -174,7 +202,18 @@ Resumen AS (
         ) AS PrecioPromedioPonderadoCLP,
         MIN(PrecioVentaCLP) AS PrecioMinimoCLP,
         MAX(PrecioVentaCLP) AS PrecioMaximoCLP,
-        COUNT(*) AS CantidadDeVentas
+        COUNT(*) AS CantidadDeVentas,
+        SUM(CASE WHEN TipoDocumento = 'BOLETA' THEN CantidadVendida ELSE 0 END) AS UnidadesBoleta,
+        SUM(CASE WHEN TipoDocumento = 'FACTURA' THEN CantidadVendida ELSE 0 END) AS UnidadesFactura,
+        SUM(CASE WHEN TipoDocumento = 'BOLETA' THEN TotalProductoCLP ELSE 0 END) AS VentasBoletaCLP,
+        SUM(CASE WHEN TipoDocumento = 'FACTURA' THEN TotalProductoCLP ELSE 0 END) AS VentasFacturaCLP,
+        COUNT(DISTINCT CASE WHEN TipoDocumento = 'BOLETA'
+            THEN CAST(Sucursal AS VARCHAR(10)) + '-' + CodigoDocumento END) AS DocumentosBoleta,
+        COUNT(DISTINCT CASE WHEN TipoDocumento = 'FACTURA'
+            THEN CAST(Sucursal AS VARCHAR(10)) + '-' + CodigoDocumento END) AS DocumentosFactura,
+        COUNT(DISTINCT CASE WHEN Nula = 1
+            THEN TipoDocumento + '-' + CAST(Sucursal AS VARCHAR(10)) + '-' + CodigoDocumento END) AS DocumentosNulos,
+        SUM(CASE WHEN Nula = 1 THEN CantidadVendida ELSE 0 END) AS UnidadesNulas
     FROM VentasBase
     GROUP BY CodigoProducto
 )
@@ -186,13 +225,69 @@ SELECT
     PrecioOfertaCLP AS "Precio de Oferta (CLP)",
     CantidadTotalVendida AS "Cantidad Total Vendida",
     TotalVentasCLP AS "Total Ventas (CLP)",
+    TotalLineasCLP AS "Total Líneas (CLP)",
     UltimaFechaVenta AS "Última Fecha de Venta",
     PrecioPromedioPonderadoCLP AS "Precio Promedio Ponderado (CLP)",
     PrecioMinimoCLP AS "Precio Mínimo (CLP)",
     PrecioMaximoCLP AS "Precio Máximo (CLP)",
-    CantidadDeVentas AS "Cantidad de Ventas Registradas"
+    CantidadDeVentas AS "Cantidad de Ventas Registradas",
+    UnidadesBoleta AS "Unidades Boleta",
+    UnidadesFactura AS "Unidades Factura",
+    VentasBoletaCLP AS "Ventas Boleta (CLP)",
+    VentasFacturaCLP AS "Ventas Factura (CLP)",
+    DocumentosBoleta AS "Documentos Boleta",
+    DocumentosFactura AS "Documentos Factura",
+    DocumentosNulos AS "Documentos Nulos",
+    UnidadesNulas AS "Unidades Nulas"
 FROM Resumen
 WHERE (? = '' OR CodigoProducto LIKE '%' + ? + '%')
+  AND (? = '' OR CHARINDEX(',' + CodigoProducto + ',', ',' + ? + ',') > 0)
 ORDER BY CodigoProducto
 `
 }
+
+// GetVentasPorSucursalQuery devuelve la consulta SQL para obtener las ventas de cada producto por sucursal
+func GetVentasPorSucursalQuery() string {
+	return ventasBaseResumen + `
+SELECT 
+    CodigoProducto AS "Código de Producto",
+    Sucursal AS "Sucursal",
+    SUM(CantidadVendida) AS "Cantidad Total Vendida",
+    SUM(TotalProductoCLP) AS "Total Ventas (CLP)",
+    MAX(FechaDocumento) AS "Última Fecha de Venta",
+    COUNT(*) AS "Cantidad de Ventas Registradas"
+FROM VentasBase
+WHERE (? = '' OR CodigoProducto LIKE '%' + ? + '%')
+  AND (? = '' OR CHARINDEX(',' + CodigoProducto + ',', ',' + ? + ',') > 0)
+GROUP BY CodigoProducto, Sucursal
+ORDER BY CodigoProducto, Sucursal
+`
+}
+
+// GetSucursalesQuery devuelve la consulta SQL para obtener las sucursales y la cantidad
+// de documentos de venta (boletas y facturas) de cada una en el período
+func GetSucursalesQuery() string {
+	return `
+WITH DocumentosPeriodo AS (
+    SELECT VB.ID_SUCURSAL AS Sucursal, COUNT(*) AS Documentos
+    FROM VENTA_BOLETA VB
+    WHERE VB.FECHA_VENTA BETWEEN ? AND ?
+    GROUP BY VB.ID_SUCURSAL
+
+    UNION ALL
+
+    SELECT VF.ID_SUCURSAL AS Sucursal, COUNT(*) AS Documentos
+    FROM VENTA_FACTURA VF
+    WHERE VF.FECHA_EMISION BETWEEN ? AND ?
+    GROUP BY VF.ID_SUCURSAL
+)
+SELECT 
+    S.ID_SUCURSAL AS "Sucursal",
+    S.NOMBRE_SUCURSAL AS "Nombre Sucursal",
+    ISNULL(SUM(D.Documentos), 0) AS "Documentos en Período"
+FROM SUCURSAL S
+LEFT JOIN DocumentosPeriodo D ON D.Sucursal = S.ID_SUCURSAL
+GROUP BY S.ID_SUCURSAL, S.NOMBRE_SUCURSAL
+ORDER BY S.ID_SUCURSAL
+`
+}
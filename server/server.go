@@ -2,57 +2,129 @@ package server
 
 import (
 	"net/http"
+	"path/filepath"
 
 	"github.com/gorilla/mux"
 	"github.com/pablojnd/rotacion/api"
 	"github.com/pablojnd/rotacion/config"
+	"github.com/pablojnd/rotacion/datasource"
 	"github.com/pablojnd/rotacion/db"
 	"github.com/pablojnd/rotacion/excel"
+	"github.com/pablojnd/rotacion/models"
 	"github.com/pablojnd/rotacion/services"
 )
 
 // Server representa el servidor HTTP
 type Server struct {
-	config    *config.Config
-	router    *mux.Router
-	sqlServer *db.SQLServerDB
-	mysql     *db.MySQLDB
+	config           *config.Config
+	router           *mux.Router
+	sqlServer        *db.SQLServerDB
+	mysql            *db.MySQLDB
+	ventasSource     datasource.VentasSource
+	inventarioSource datasource.InventarioSource
 }
 
-// New crea una nueva instancia del servidor
-func New(cfg *config.Config, sqlServer *db.SQLServerDB, mysql *db.MySQLDB) *Server {
+// New crea una nueva instancia del servidor.
+// sqlServer y mysql son opcionales (nil en modo fixtures) y solo se usan
+// para las consultas SQL directas; el resto de la API usa los orígenes de datos.
+func New(
+	cfg *config.Config,
+	sqlServer *db.SQLServerDB,
+	mysql *db.MySQLDB,
+	ventasSource datasource.VentasSource,
+	inventarioSource datasource.InventarioSource,
+) (*Server, error) {
 	s := &Server{
-		config:    cfg,
-		router:    mux.NewRouter(),
-		sqlServer: sqlServer,
-		mysql:     mysql,
+		config:           cfg,
+		router:           mux.NewRouter(),
+		sqlServer:        sqlServer,
+		mysql:            mysql,
+		ventasSource:     ventasSource,
+		inventarioSource: inventarioSource,
 	}
 
-	s.setupRoutes()
-	return s
+	if err := s.setupRoutes(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 // setupRoutes configura las rutas del API
-func (s *Server) setupRoutes() {
+func (s *Server) setupRoutes() error {
 	// Crear servicios compartidos
 	excelService := services.NewExcelService()
-	ventasService := services.NewVentasService(s.sqlServer, excelService)
-	inventarioService := services.NewInventarioService(s.mysql, excelService)
+	ventasService := services.NewVentasService(s.ventasSource, excelService)
+	inventarioService := services.NewInventarioService(s.inventarioSource, excelService,
+		s.config.HistorialModo == config.HistorialModoFilas)
+
+	// Crear el catálogo de sucursales
+	sucursalService := services.NewSucursalService(s.ventasSource, s.config.SucursalDefecto)
+
+	// Crear la tabla persistente de alias de códigos
+	aliasService, err := services.NewAliasService(filepath.Join(s.config.DataDir, "alias.json"))
+	if err != nil {
+		return err
+	}
+
+	// Crear la tabla persistente de tipos de cambio USD→CLP
+	tipoCambioService, err := services.NewTipoCambioService(filepath.Join(s.config.DataDir, "tipo_cambio.json"))
+	if err != nil {
+		return err
+	}
+
+	// Crear el emparejador de códigos inventario-ventas.
+	// Los alias registrados vía API tienen prioridad sobre los fijos de MATCH_ALIAS.
+	matcher, err := services.NewMatcher(services.MatcherConfig{
+		Estrategias:     s.config.MatchEstrategias,
+		PrefijoMinimo:   s.config.MatchPrefijoMinimo,
+		DistanciaMaxima: s.config.MatchDistanciaMaxima,
+		ConfianzaMinima: s.config.MatchConfianzaMinima,
+	}, services.AliasEncadenados{aliasService, services.MapaAlias(s.config.MatchAlias)})
+	if err != nil {
+		return err
+	}
 
 	// Crear el servicio de reportes combinados
 	reporteService := services.NewReporteService(
-		s.sqlServer,
-		s.mysql,
 		ventasService,
 		inventarioService,
 		excelService,
+		matcher,
+		models.ParametrosABC{
+			Criterio: s.config.AbcCriterio,
+			UmbralA:  &s.config.AbcUmbrales[0],
+			UmbralB:  &s.config.AbcUmbrales[1],
+			UmbralX:  &s.config.XyzUmbrales[0],
+			UmbralY:  &s.config.XyzUmbrales[1],
+		},
+		models.ConfigReposicion{
+			Defecto: models.PoliticaReposicion{
+				NivelServicio: s.config.ReposicionNivelServicio,
+				LeadTimeDias:  s.config.ReposicionLeadTimeDias,
+			},
+			DiasCobertura:          s.config.ReposicionDiasCobertura,
+			LeadTimeMarca:          diasPorClave(s.config.ReposicionLeadTimeMarcas),
+			LeadTimeCategoria:      diasPorClave(s.config.ReposicionLeadTimeCategorias),
+			NivelServicioMarca:     s.config.ReposicionNivelServicioMarcas,
+			NivelServicioCategoria: s.config.ReposicionNivelServicioCategorias,
+		},
+		tipoCambioService,
 	)
 
 	// Crear handlers para la API
-	handlers := api.NewHandlers(s.sqlServer, s.mysql)
+	handlers := api.NewHandlers(s.sqlServer, s.mysql, ventasService, inventarioService, sucursalService)
 
 	// Crear handler para reportes combinados
-	reporteHandlers := api.NewReporteHandlers(reporteService)
+	reporteHandlers := api.NewReporteHandlers(reporteService, sucursalService)
+
+	// Crear handler para consultas por producto
+	productoHandlers := api.NewProductoHandlers(reporteService, sucursalService)
+
+	// Crear handler para alias de códigos
+	aliasHandlers := api.NewAliasHandlers(aliasService)
+
+	// Crear handler para tipos de cambio
+	tipoCambioHandlers := api.NewTipoCambioHandlers(tipoCambioService)
 
 	// Crear handler para Excel
 	excelHandler := excel.NewHandler(
@@ -61,8 +133,12 @@ func (s *Server) setupRoutes() {
 		excelService,
 		ventasService,
 		inventarioService,
+		sucursalService,
 	)
 
+	// Crear handler para el catálogo de sucursales
+	sucursalHandlers := api.NewSucursalHandlers(sucursalService)
+
 	// Ruta de estado del servidor
 	s.router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -75,24 +151,78 @@ func (s *Server) setupRoutes() {
 	// Consultas SQL Server
 	apiRouter.HandleFunc("/sqlserver/query", handlers.SQLServerQuery).Methods("POST")
 
-	// Consulta específica de ventas
-	apiRouter.HandleFunc("/ventas", handlers.GetVentas).Methods("GET")
-	apiRouter.HandleFunc("/ventas/excel", excelHandler.ExportVentas).Methods("GET")
+	// Consulta específica de ventas (POST: lista de códigos exactos en JSON o XLSX/CSV)
+	apiRouter.HandleFunc("/ventas", handlers.GetVentas).Methods("GET", "POST")
+	apiRouter.HandleFunc("/ventas/excel", excelHandler.ExportVentas).Methods("GET", "POST")
 
 	// Nueva ruta para ventas agrupadas
-	apiRouter.HandleFunc("/ventas/agrupadas", handlers.GetVentasAgrupadas).Methods("GET")
-	apiRouter.HandleFunc("/ventas/agrupadas/excel", excelHandler.ExportVentasAgrupadas).Methods("GET")
+	apiRouter.HandleFunc("/ventas/agrupadas", handlers.GetVentasAgrupadas).Methods("GET", "POST")
+	apiRouter.HandleFunc("/ventas/agrupadas/excel", excelHandler.ExportVentasAgrupadas).Methods("GET", "POST")
+
+	// Serie de ventas por período
+	apiRouter.HandleFunc("/ventas/serie", handlers.GetSerieVentas).Methods("GET")
+	apiRouter.HandleFunc("/ventas/serie/excel", excelHandler.ExportSerieVentas).Methods("GET")
+
+	// Pronóstico de demanda
+	apiRouter.HandleFunc("/pronostico", handlers.GetPronostico).Methods("GET")
+
+	// Ventas por tipo de documento y documentos anulados
+	apiRouter.HandleFunc("/ventas/documentos", handlers.GetDocumentos).Methods("GET")
+	apiRouter.HandleFunc("/ventas/documentos/excel", excelHandler.ExportDocumentos).Methods("GET")
+
+	// Efectividad de precios y ofertas
+	apiRouter.HandleFunc("/ventas/promociones", handlers.GetPromociones).Methods("GET")
+	apiRouter.HandleFunc("/ventas/promociones/excel", excelHandler.ExportPromociones).Methods("GET")
+
+	// Clientes: ranking y segmentación RFM
+	apiRouter.HandleFunc("/clientes", handlers.GetClientes).Methods("GET")
+	apiRouter.HandleFunc("/clientes/excel", excelHandler.ExportClientes).Methods("GET")
+
+	// Catálogo de sucursales
+	apiRouter.HandleFunc("/sucursales", sucursalHandlers.ListarSucursales).Methods("GET")
 
 	// Consultas MySQL
 	apiRouter.HandleFunc("/mysql/query", handlers.MySQLQuery).Methods("POST")
 
-	// Ruta para inventario
-	apiRouter.HandleFunc("/inventario", handlers.GetInventario).Methods("GET")
-	apiRouter.HandleFunc("/inventario/excel", excelHandler.ExportInventario).Methods("GET")
-
-	// Nuevas rutas para reporte combinado
-	apiRouter.HandleFunc("/reporte/combinado", reporteHandlers.ObtenerReporteCombinado).Methods("GET")
-	apiRouter.HandleFunc("/reporte/combinado/excel", reporteHandlers.ExportarReporteCombinado).Methods("GET")
+	// Ruta para inventario (POST: lista de códigos exactos en JSON o XLSX/CSV)
+	apiRouter.HandleFunc("/inventario", handlers.GetInventario).Methods("GET", "POST")
+	apiRouter.HandleFunc("/inventario/excel", excelHandler.ExportInventario).Methods("GET", "POST")
+
+	// Nuevas rutas para reporte combinado (POST: lista de códigos exactos en JSON o XLSX/CSV)
+	apiRouter.HandleFunc("/reporte/combinado", reporteHandlers.ObtenerReporteCombinado).Methods("GET", "POST")
+	apiRouter.HandleFunc("/reporte/combinado/excel", reporteHandlers.ExportarReporteCombinado).Methods("GET", "POST")
+	apiRouter.HandleFunc("/reporte/abc", reporteHandlers.ObtenerReporteABC).Methods("GET")
+	apiRouter.HandleFunc("/reporte/inmovilizado", reporteHandlers.ObtenerReporteInmovilizado).Methods("GET")
+	apiRouter.HandleFunc("/reporte/inmovilizado/excel", reporteHandlers.ExportarReporteInmovilizado).Methods("GET")
+	apiRouter.HandleFunc("/reporte/reposicion", reporteHandlers.ObtenerReporteReposicion).Methods("GET")
+	apiRouter.HandleFunc("/reporte/reposicion/excel", reporteHandlers.ExportarReporteReposicion).Methods("GET")
+	apiRouter.HandleFunc("/reporte/rentabilidad", reporteHandlers.ObtenerReporteRentabilidad).Methods("GET")
+	apiRouter.HandleFunc("/reporte/rentabilidad/excel", reporteHandlers.ExportarReporteRentabilidad).Methods("GET")
+	apiRouter.HandleFunc("/stock", reporteHandlers.ObtenerStock).Methods("GET")
+	apiRouter.HandleFunc("/reporte/sucursales", reporteHandlers.ObtenerComparacionSucursales).Methods("GET")
+	apiRouter.HandleFunc("/reporte/sucursales/excel", reporteHandlers.ExportarComparacionSucursales).Methods("GET")
+	apiRouter.HandleFunc("/reporte/periodos", reporteHandlers.ObtenerComparacionPeriodos).Methods("GET")
+	apiRouter.HandleFunc("/reporte/periodos/excel", reporteHandlers.ExportarComparacionPeriodos).Methods("GET")
+
+	// Consultas por producto
+	apiRouter.HandleFunc("/productos/{codigo}", productoHandlers.ObtenerFichaProducto).Methods("GET")
+	apiRouter.HandleFunc("/productos/{codigo}/lotes", productoHandlers.ObtenerLotes).Methods("GET")
+
+	// Alias de códigos inventario-ventas
+	apiRouter.HandleFunc("/alias", aliasHandlers.ListarAlias).Methods("GET")
+	apiRouter.HandleFunc("/alias", aliasHandlers.GuardarAlias).Methods("POST")
+	apiRouter.HandleFunc("/alias/importar", aliasHandlers.ImportarAlias).Methods("POST")
+	apiRouter.HandleFunc("/alias/{codigo}", aliasHandlers.ObtenerAlias).Methods("GET")
+	apiRouter.HandleFunc("/alias/{codigo}", aliasHandlers.GuardarAlias).Methods("PUT")
+	apiRouter.HandleFunc("/alias/{codigo}", aliasHandlers.EliminarAlias).Methods("DELETE")
+
+	// Tipos de cambio USD→CLP por fecha
+	apiRouter.HandleFunc("/tipo-cambio", tipoCambioHandlers.ListarTiposCambio).Methods("GET")
+	apiRouter.HandleFunc("/tipo-cambio", tipoCambioHandlers.GuardarTipoCambio).Methods("POST")
+	apiRouter.HandleFunc("/tipo-cambio/importar", tipoCambioHandlers.ImportarTiposCambio).Methods("POST")
+	apiRouter.HandleFunc("/tipo-cambio/{fecha}", tipoCambioHandlers.ConsultarTipoCambio).Methods("GET")
+	apiRouter.HandleFunc("/tipo-cambio/{fecha}", tipoCambioHandlers.GuardarTipoCambio).Methods("PUT")
+	apiRouter.HandleFunc("/tipo-cambio/{fecha}", tipoCambioHandlers.EliminarTipoCambio).Methods("DELETE")
 
 	// Exportar a Excel
 	apiRouter.HandleFunc("/export/excel", excelHandler.ExportGeneric).Methods("POST")
@@ -105,9 +235,20 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/static/docs/index.html", http.StatusMovedPermanently)
 	})
+
+	return nil
 }
 
 // Start inicia el servidor HTTP
 func (s *Server) Start() error {
 	return http.ListenAndServe(":"+s.config.ServerPort, s.router)
 }
+
+// diasPorClave convierte los lead times configurados (días enteros validados al cargar) a enteros
+func diasPorClave(valores map[string]float64) map[string]int {
+	dias := make(map[string]int, len(valores))
+	for clave, valor := range valores {
+		dias[clave] = int(valor)
+	}
+	return dias
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+	"github.com/pablojnd/rotacion/utils"
+)
+
+// Errores devueltos por AliasService
+var (
+	ErrAliasNoEncontrado = errors.New("alias no encontrado")
+	ErrAliasInvalido     = errors.New("alias no válido")
+)
+
+// AliasService administra la tabla persistente de alias de códigos inventario-ventas.
+// Los alias se guardan en un archivo JSON local, sin requerir escritura en las bases del ERP.
+type AliasService struct {
+	ruta  string
+	mu    sync.RWMutex
+	alias map[string]models.Alias // código inventario -> alias
+}
+
+// NewAliasService crea el servicio y carga los alias existentes desde ruta
+func NewAliasService(ruta string) (*AliasService, error) {
+	s := &AliasService{
+		ruta:  ruta,
+		alias: make(map[string]models.Alias),
+	}
+
+	data, err := os.ReadFile(ruta)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer alias: %v", err)
+	}
+
+	var lista []models.Alias
+	if err := json.Unmarshal(data, &lista); err != nil {
+		return nil, fmt.Errorf("error al leer alias de %s: %v", ruta, err)
+	}
+	for _, a := range lista {
+		s.alias[a.CodigoInventario] = a
+	}
+
+	return s, nil
+}
+
+// BuscarAlias implementa TablaAlias para el emparejador del reporte combinado
+func (s *AliasService) BuscarAlias(codigoInventario string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.alias[codigoInventario]
+	return a.CodigoVentas, ok
+}
+
+// Listar devuelve todos los alias ordenados por código de inventario
+func (s *AliasService) Listar() []models.Alias {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.listar()
+}
+
+// Obtener devuelve el alias de un código de inventario
+func (s *AliasService) Obtener(codigoInventario string) (models.Alias, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.alias[strings.TrimSpace(codigoInventario)]
+	if !ok {
+		return models.Alias{}, fmt.Errorf("%w: %s", ErrAliasNoEncontrado, codigoInventario)
+	}
+	return a, nil
+}
+
+// Guardar crea o actualiza el alias de un código de inventario.
+// Devuelve el alias guardado e indica si fue creado.
+func (s *AliasService) Guardar(alias models.Alias, usuario string) (models.Alias, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	anterior, existia := s.alias[strings.TrimSpace(alias.CodigoInventario)]
+	guardado, creado, err := s.guardar(alias, usuario, time.Now())
+	if err != nil {
+		return models.Alias{}, false, err
+	}
+	if err := s.persistir(); err != nil {
+		// Revertir el cambio en memoria para mantenerla igual al archivo
+		if existia {
+			s.alias[guardado.CodigoInventario] = anterior
+		} else {
+			delete(s.alias, guardado.CodigoInventario)
+		}
+		return models.Alias{}, false, err
+	}
+	return guardado, creado, nil
+}
+
+// Eliminar borra el alias de un código de inventario
+func (s *AliasService) Eliminar(codigoInventario string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codigoInventario = strings.TrimSpace(codigoInventario)
+	anterior, ok := s.alias[codigoInventario]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAliasNoEncontrado, codigoInventario)
+	}
+	delete(s.alias, codigoInventario)
+
+	if err := s.persistir(); err != nil {
+		s.alias[codigoInventario] = anterior
+		return err
+	}
+	return nil
+}
+
+// Importar carga alias de forma masiva desde un archivo XLSX o CSV.
+// El archivo debe tener encabezados con el código de inventario (CODIGO_INVENTARIO o COD_ART)
+// y el de ventas (CODIGO_VENTAS o CODIGO_INTERNO); la columna NOTAS es opcional.
+// Las filas con errores se informan y no detienen la carga.
+func (s *AliasService) Importar(r io.Reader, nombreArchivo string, usuario string) (models.ResultadoImportacionAlias, error) {
+	var resultado models.ResultadoImportacionAlias
+
+	filas, err := utils.LeerTablaArchivo(r, nombreArchivo)
+	if err != nil {
+		return resultado, err
+	}
+	if len(filas) == 0 {
+		return resultado, fmt.Errorf("%w: el archivo está vacío", ErrAliasInvalido)
+	}
+
+	encabezados := filas[0]
+	colInventario := utils.BuscarColumna(encabezados, "codigoInventario", "COD_ART", "codigo_inv")
+	colVentas := utils.BuscarColumna(encabezados, "codigoVentas", "CODIGO_INTERNO", "codigo_ven")
+	colNotas := utils.BuscarColumna(encabezados, "notas", "nota", "observacion")
+	if colInventario < 0 || colVentas < 0 {
+		return resultado, fmt.Errorf("%w: el archivo debe tener las columnas CODIGO_INVENTARIO y CODIGO_VENTAS", ErrAliasInvalido)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ahora := time.Now()
+	for i, fila := range filas[1:] {
+		alias := models.Alias{
+			CodigoInventario: utils.ValorCelda(fila, colInventario),
+			CodigoVentas:     utils.ValorCelda(fila, colVentas),
+			Notas:            utils.ValorCelda(fila, colNotas),
+		}
+		if alias.CodigoInventario == "" && alias.CodigoVentas == "" {
+			continue // Fila en blanco
+		}
+
+		_, creado, err := s.guardar(alias, usuario, ahora)
+		if err != nil {
+			// i+2: la fila 1 es el encabezado
+			resultado.Errores = append(resultado.Errores, fmt.Sprintf("fila %d: %v", i+2, err))
+			continue
+		}
+		if creado {
+			resultado.Creados++
+		} else {
+			resultado.Actualizados++
+		}
+	}
+
+	if resultado.Creados+resultado.Actualizados > 0 {
+		if err := s.persistir(); err != nil {
+			return resultado, err
+		}
+	}
+
+	return resultado, nil
+}
+
+// guardar valida y registra un alias en memoria; requiere tener el bloqueo de escritura
+func (s *AliasService) guardar(alias models.Alias, usuario string, ahora time.Time) (models.Alias, bool, error) {
+	alias.CodigoInventario = strings.TrimSpace(alias.CodigoInventario)
+	alias.CodigoVentas = strings.TrimSpace(alias.CodigoVentas)
+	alias.Notas = strings.TrimSpace(alias.Notas)
+
+	if alias.CodigoInventario == "" || alias.CodigoVentas == "" {
+		return models.Alias{}, false, fmt.Errorf("%w: se requieren codigoInventario y codigoVentas", ErrAliasInvalido)
+	}
+
+	// Un código de ventas solo puede corresponder a un código de inventario
+	for _, existente := range s.alias {
+		if existente.CodigoVentas == alias.CodigoVentas && existente.CodigoInventario != alias.CodigoInventario {
+			return models.Alias{}, false, fmt.Errorf("%w: el código de ventas %s ya está asociado a %s",
+				ErrAliasInvalido, alias.CodigoVentas, existente.CodigoInventario)
+		}
+	}
+
+	existente, existe := s.alias[alias.CodigoInventario]
+	if existe {
+		alias.CreadoPor = existente.CreadoPor
+		alias.CreadoEn = existente.CreadoEn
+	} else {
+		alias.CreadoPor = usuario
+		alias.CreadoEn = ahora
+	}
+	alias.ActualizadoPor = usuario
+	alias.ActualizadoEn = ahora
+
+	s.alias[alias.CodigoInventario] = alias
+	return alias, !existe, nil
+}
+
+// listar devuelve los alias ordenados; requiere tener el bloqueo
+func (s *AliasService) listar() []models.Alias {
+	lista := make([]models.Alias, 0, len(s.alias))
+	for _, a := range s.alias {
+		lista = append(lista, a)
+	}
+	sort.Slice(lista, func(i, j int) bool {
+		return lista[i].CodigoInventario < lista[j].CodigoInventario
+	})
+	return lista
+}
+
+// persistir escribe los alias en disco de forma atómica; requiere tener el bloqueo
+func (s *AliasService) persistir() error {
+	data, err := json.MarshalIndent(s.listar(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error al serializar alias: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.ruta), 0o755); err != nil {
+		return fmt.Errorf("error al crear directorio de alias: %v", err)
+	}
+
+	tmp := s.ruta + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error al guardar alias: %v", err)
+	}
+	if err := os.Rename(tmp, s.ruta); err != nil {
+		return fmt.Errorf("error al guardar alias: %v", err)
+	}
+	return nil
+}
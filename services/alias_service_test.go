@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// describirAlias resume los alias como "INV>VEN", ordenados por código de inventario
+func describirAlias(lista []models.Alias) string {
+	var partes []string
+	for _, a := range lista {
+		partes = append(partes, a.CodigoInventario+">"+a.CodigoVentas)
+	}
+	return strings.Join(partes, " ")
+}
+
+func TestAliasServiceGuardarYEliminar(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "alias.json")
+	s, err := NewAliasService(ruta)
+	if err != nil {
+		t.Fatalf("NewAliasService: %v", err)
+	}
+
+	guardado, creado, err := s.Guardar(models.Alias{CodigoInventario: " CER-OLD ", CodigoVentas: "CER 2025", Notas: "cambio de código"}, "ana")
+	if err != nil || !creado {
+		t.Fatalf("Guardar = %v, %v; se esperaba un alias creado", creado, err)
+	}
+	if guardado.CodigoInventario != "CER-OLD" || guardado.CreadoPor != "ana" || guardado.ActualizadoPor != "ana" {
+		t.Errorf("alias guardado = %+v", guardado)
+	}
+
+	// Al actualizar se conservan el autor y la fecha de creación
+	actualizado, creado, err := s.Guardar(models.Alias{CodigoInventario: "CER-OLD", CodigoVentas: "CER-2025"}, "luis")
+	if err != nil || creado {
+		t.Fatalf("Guardar = %v, %v; se esperaba un alias actualizado", creado, err)
+	}
+	if actualizado.CreadoPor != "ana" || !actualizado.CreadoEn.Equal(guardado.CreadoEn) || actualizado.ActualizadoPor != "luis" {
+		t.Errorf("alias actualizado = %+v", actualizado)
+	}
+
+	invalidos := []models.Alias{
+		{CodigoInventario: "SIN-VENTAS"},
+		{CodigoVentas: "SIN-INVENTARIO"},
+		// El código de ventas ya está asociado a CER-OLD
+		{CodigoInventario: "OTRO", CodigoVentas: "CER-2025"},
+	}
+	for _, alias := range invalidos {
+		if _, _, err := s.Guardar(alias, "ana"); !errors.Is(err, ErrAliasInvalido) {
+			t.Errorf("Guardar(%+v) = %v, se esperaba ErrAliasInvalido", alias, err)
+		}
+	}
+
+	if _, _, err := s.Guardar(models.Alias{CodigoInventario: "ADH-25", CodigoVentas: "ADHPEG25"}, "ana"); err != nil {
+		t.Fatalf("Guardar: %v", err)
+	}
+
+	// Se relee del archivo para verificar que se persistió
+	s, err = NewAliasService(ruta)
+	if err != nil {
+		t.Fatalf("NewAliasService: %v", err)
+	}
+	if obtenido := describirAlias(s.Listar()); obtenido != "ADH-25>ADHPEG25 CER-OLD>CER-2025" {
+		t.Errorf("Listar = %s", obtenido)
+	}
+	if a, err := s.Obtener(" CER-OLD"); err != nil || a.Notas != "" || a.CreadoPor != "ana" {
+		t.Errorf("Obtener = %+v, %v", a, err)
+	}
+	if codigo, ok := s.BuscarAlias("ADH-25"); !ok || codigo != "ADHPEG25" {
+		t.Errorf("BuscarAlias = %q, %v; se esperaba ADHPEG25", codigo, ok)
+	}
+
+	if err := s.Eliminar("ADH-25"); err != nil {
+		t.Fatalf("Eliminar: %v", err)
+	}
+	if err := s.Eliminar("ADH-25"); !errors.Is(err, ErrAliasNoEncontrado) {
+		t.Errorf("Eliminar de un alias inexistente = %v, se esperaba ErrAliasNoEncontrado", err)
+	}
+	if _, err := s.Obtener("ADH-25"); !errors.Is(err, ErrAliasNoEncontrado) {
+		t.Errorf("Obtener de un alias eliminado = %v, se esperaba ErrAliasNoEncontrado", err)
+	}
+	// Liberado el alias, su código de ventas se puede asociar a otro producto
+	if _, _, err := s.Guardar(models.Alias{CodigoInventario: "ADH-NUEVO", CodigoVentas: "ADHPEG25"}, "ana"); err != nil {
+		t.Errorf("Guardar tras eliminar: %v", err)
+	}
+}
+
+func TestAliasServiceImportar(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "alias.json")
+	s, err := NewAliasService(ruta)
+	if err != nil {
+		t.Fatalf("NewAliasService: %v", err)
+	}
+	if _, _, err := s.Guardar(models.Alias{CodigoInventario: "CER-OLD", CodigoVentas: "CER 2024"}, "ana"); err != nil {
+		t.Fatalf("Guardar: %v", err)
+	}
+
+	csv := "COD_ART;CODIGO_INTERNO;NOTAS\n" +
+		"CER-OLD;CER 2025;nuevo código\n" +
+		"ADH-25;ADHPEG25;\n" +
+		";;\n" +
+		"SIN-VENTAS;;\n" +
+		"OTRO;ADHPEG25;\n"
+	resultado, err := s.Importar(strings.NewReader(csv), "alias.csv", "luis")
+	if err != nil {
+		t.Fatalf("Importar: %v", err)
+	}
+	errores := strings.Join(resultado.Errores, " | ")
+	if resultado.Creados != 1 || resultado.Actualizados != 1 || len(resultado.Errores) != 2 ||
+		!strings.HasPrefix(resultado.Errores[0], "fila 5:") || !strings.HasPrefix(resultado.Errores[1], "fila 6:") {
+		t.Errorf("Importar = %d creados, %d actualizados, errores %s; se esperaba 1, 1 y errores en las filas 5 y 6",
+			resultado.Creados, resultado.Actualizados, errores)
+	}
+
+	s, err = NewAliasService(ruta)
+	if err != nil {
+		t.Fatalf("NewAliasService: %v", err)
+	}
+	if obtenido := describirAlias(s.Listar()); obtenido != "ADH-25>ADHPEG25 CER-OLD>CER 2025" {
+		t.Errorf("Listar = %s", obtenido)
+	}
+
+	for _, csv := range []string{"", "CODIGO;DESCRIPCION\nA;B\n"} {
+		if _, err := s.Importar(strings.NewReader(csv), "alias.csv", "luis"); !errors.Is(err, ErrAliasInvalido) {
+			t.Errorf("Importar(%q) = %v, se esperaba ErrAliasInvalido", csv, err)
+		}
+	}
+}
+
+func TestReporteCombinadoAliasAntesQueHeuristicas(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{
+		ventaPrueba("2025-01-05", "CER-OLD", 1, 1000), // Coincidencia exacta que el alias debe ignorar
+		ventaPrueba("2025-01-05", "CER 2025", 2, 1000),
+		ventaPrueba("2025-01-05", "ADH25", 3, 1000), // Coincidencia normalizada
+		ventaPrueba("2025-01-05", "ADHPEG25", 4, 1000),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("CER-OLD", "Z1", "2024-12-01", 10, 400),
+		ingresoPrueba("ADH-25", "Z1", "2024-12-01", 10, 400),
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+
+	aliasService, err := NewAliasService(filepath.Join(t.TempDir(), "alias.json"))
+	if err != nil {
+		t.Fatalf("NewAliasService: %v", err)
+	}
+	for inventario, ventas := range map[string]string{"CER-OLD": "CER 2025", "ADH-25": "ADHPEG25"} {
+		if _, _, err := aliasService.Guardar(models.Alias{CodigoInventario: inventario, CodigoVentas: ventas}, "ana"); err != nil {
+			t.Fatalf("Guardar: %v", err)
+		}
+	}
+	s.matcher, err = NewMatcher(MatcherConfig{Estrategias: []string{EstrategiaAlias, EstrategiaExacta, EstrategiaNormalizada}}, aliasService)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10"})
+	if err != nil {
+		t.Fatalf("GenerarReporteCombinado: %v", err)
+	}
+
+	obtenidos := make(map[string]string)
+	for _, r := range coincidentes {
+		obtenidos[r.CodigoProducto] = fmt.Sprintf("%s %s %v", r.CodigoVentas, r.MetodoCoincidencia, r.CantidadVendida)
+	}
+	esperados := map[string]string{
+		"CER-OLD": "CER 2025 alias 2",
+		"ADH-25":  "ADHPEG25 alias 4",
+	}
+	for codigo, esperado := range esperados {
+		if obtenidos[codigo] != esperado {
+			t.Errorf("%s = %q, se esperaba %q", codigo, obtenidos[codigo], esperado)
+		}
+	}
+	// Los códigos que habrían ganado por coincidencia exacta o normalizada quedan sin pareja
+	var sinPareja []string
+	for _, r := range sinCoincidencia {
+		sinPareja = append(sinPareja, r.CodigoVentas)
+	}
+	if obtenido := strings.Join(sinPareja, ","); obtenido != "ADH25,CER-OLD" && obtenido != "CER-OLD,ADH25" {
+		t.Errorf("sin coincidencia = %s, se esperaba ADH25 y CER-OLD", obtenido)
+	}
+}
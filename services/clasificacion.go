@@ -0,0 +1,288 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// Clases de la clasificación ABC y XYZ
+var (
+	clasesABC = []string{"A", "B", "C"}
+	clasesXYZ = []string{"X", "Y", "Z"}
+)
+
+// valorCriterio devuelve el valor de un producto según el criterio ABC
+func valorCriterio(reporte models.ReporteCombinado, criterio string) float64 {
+	switch criterio {
+	case models.CriterioABCCantidad:
+		return reporte.CantidadVendida
+	case models.CriterioABCUtilidad:
+		return reporte.UtilidadClp
+	default:
+		return float64(reporte.VentaNetaTotalClp)
+	}
+}
+
+// clasificarABC asigna clases ABC según la participación acumulada en el criterio indicado.
+// Un producto pertenece a la clase A mientras la participación acumulada de los productos
+// anteriores sea menor que UmbralA (así el producto que cruza el umbral también es A);
+// lo mismo para B con UmbralA + UmbralB. Los productos sin valor positivo son C, con
+// participación 0, y no suman al total.
+// El resultado queda ordenado de mayor a menor valor.
+func clasificarABC(reportes []models.ReporteCombinado, params models.ParametrosABC) []models.ClasificacionProducto {
+	productos := make([]models.ClasificacionProducto, 0, len(reportes))
+	total := 0.0
+	for _, r := range reportes {
+		valor := valorCriterio(r, params.Criterio)
+		if valor > 0 {
+			total += valor
+		}
+		productos = append(productos, models.ClasificacionProducto{
+			CodigoProducto: r.CodigoProducto,
+			CodigoVentas:   r.CodigoVentas,
+			Nombre:         r.Nombre,
+			Marca:          r.Marca,
+			Categoria:      r.Categoria,
+			Valor:          valor,
+		})
+	}
+
+	sort.SliceStable(productos, func(i, j int) bool {
+		return productos[i].Valor > productos[j].Valor
+	})
+
+	acumulado := 0.0
+	for i := range productos {
+		p := &productos[i]
+		if p.Valor <= 0 || total == 0 {
+			p.ClaseABC = "C"
+			p.ParticipacionAcumulada = acumulado
+			continue
+		}
+
+		p.Participacion = p.Valor / total * 100
+		switch {
+		case acumulado < *params.UmbralA:
+			p.ClaseABC = "A"
+		case acumulado < *params.UmbralA+*params.UmbralB:
+			p.ClaseABC = "B"
+		default:
+			p.ClaseABC = "C"
+		}
+		acumulado += p.Participacion
+		p.ParticipacionAcumulada = acumulado
+	}
+
+	return productos
+}
+
+// clasificarXYZ asigna la clase XYZ según el coeficiente de variación de la demanda mensual.
+// Los productos sin ventas en el período son Z.
+func clasificarXYZ(productos []models.ClasificacionProducto, coeficientes map[string]float64, params models.ParametrosABC) {
+	for i := range productos {
+		p := &productos[i]
+		cv, ok := coeficientes[p.CodigoVentas]
+		if !ok {
+			p.ClaseXYZ = "Z"
+			continue
+		}
+
+		p.CoeficienteVariacion = &cv
+		switch {
+		case cv <= *params.UmbralX:
+			p.ClaseXYZ = "X"
+		case cv <= *params.UmbralY:
+			p.ClaseXYZ = "Y"
+		default:
+			p.ClaseXYZ = "Z"
+		}
+	}
+}
+
+// coeficientesVariacion calcula, por código de ventas, el coeficiente de variación
+// (desviación estándar / promedio) de las unidades vendidas por mes en el período.
+// Los meses sin ventas cuentan como cero; los días futuros no se consideran.
+func coeficientesVariacion(ventas []models.VentaDetallada, fechaInicio, fechaFin string, hoy time.Time) (map[string]float64, error) {
+	inicio, err := time.Parse("2006-01-02", fechaInicio)
+	if err != nil {
+		return nil, fmt.Errorf("fecha de inicio no válida: %v", err)
+	}
+	fin, err := time.Parse("2006-01-02", fechaFin)
+	if err != nil {
+		return nil, fmt.Errorf("fecha de fin no válida: %v", err)
+	}
+	if fin.After(hoy) {
+		fin = hoy
+	}
+
+	indiceMes := func(fecha time.Time) int {
+		return (fecha.Year()-inicio.Year())*12 + int(fecha.Month()) - int(inicio.Month())
+	}
+	meses := indiceMes(fin) + 1
+	if meses < 1 {
+		meses = 1
+	}
+
+	demanda := make(map[string][]float64)
+	for _, venta := range ventas {
+		fecha, ok := parsearFechaVenta(venta.FechaEmision)
+		if !ok {
+			continue
+		}
+		mes := indiceMes(fecha)
+		if mes < 0 || mes >= meses {
+			continue
+		}
+		if demanda[venta.CodigoProducto] == nil {
+			demanda[venta.CodigoProducto] = make([]float64, meses)
+		}
+		demanda[venta.CodigoProducto][mes] += venta.Cantidad
+	}
+
+	coeficientes := make(map[string]float64, len(demanda))
+	for codigo, serie := range demanda {
+		promedio, desviacion := promedioYDesviacion(serie)
+		if promedio > 0 {
+			coeficientes[codigo] = desviacion / promedio
+		}
+	}
+	return coeficientes, nil
+}
+
+// promedioYDesviacion calcula el promedio y la desviación estándar poblacional
+func promedioYDesviacion(valores []float64) (float64, float64) {
+	if len(valores) == 0 {
+		return 0, 0
+	}
+
+	suma := 0.0
+	for _, v := range valores {
+		suma += v
+	}
+	promedio := suma / float64(len(valores))
+
+	varianza := 0.0
+	for _, v := range valores {
+		varianza += (v - promedio) * (v - promedio)
+	}
+	return promedio, math.Sqrt(varianza / float64(len(valores)))
+}
+
+// resumirClases agrupa cantidad de productos, valor y participación por clase. Igual que en
+// clasificarABC, los valores negativos (devoluciones, notas de crédito) no suman al valor de
+// la clase ni al total, de modo que las participaciones suman 100 %.
+func resumirClases(productos []models.ClasificacionProducto, clases []string, claseDe func(models.ClasificacionProducto) string) []models.ResumenClase {
+	resumen := make([]models.ResumenClase, len(clases))
+	indice := make(map[string]int, len(clases))
+	for i, clase := range clases {
+		resumen[i].Clase = clase
+		indice[clase] = i
+	}
+
+	total := 0.0
+	for _, p := range productos {
+		i, ok := indice[claseDe(p)]
+		if !ok {
+			continue
+		}
+		resumen[i].Productos++
+		if p.Valor > 0 {
+			resumen[i].Valor += p.Valor
+			total += p.Valor
+		}
+	}
+
+	for i := range resumen {
+		if total > 0 {
+			resumen[i].Participacion = resumen[i].Valor / total * 100
+		}
+	}
+	return resumen
+}
+
+// ParametrosABC devuelve los parámetros de clasificación configurados en el servidor
+func (s *ReporteService) ParametrosABC() models.ParametrosABC {
+	return s.parametrosABC
+}
+
+// GenerarReporteABC clasifica los productos del reporte combinado en clases ABC
+// y, si se solicita, XYZ según la variabilidad de la demanda mensual
+func (s *ReporteService) GenerarReporteABC(filtro models.ReporteFiltro, params models.ParametrosABC) (*models.ReporteABC, error) {
+	params.Completar(s.parametrosABC)
+	if err := params.Validar(); err != nil {
+		return nil, err
+	}
+
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	productos := clasificarABC(append(coincidentes, sinCoincidencia...), params)
+	reporte := &models.ReporteABC{
+		Parametros: params,
+		Productos:  productos,
+		ResumenABC: resumirClases(productos, clasesABC, func(p models.ClasificacionProducto) string { return p.ClaseABC }),
+	}
+
+	if params.CalcularXYZ {
+		ventas, err := s.ventasService.GetVentasDetalladas(filtro.FiltroVentas())
+		if err != nil {
+			return nil, fmt.Errorf("error al obtener ventas detalladas: %v", err)
+		}
+
+		coeficientes, err := coeficientesVariacion(ventas, filtro.FechaInicio, filtro.FechaFin, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		clasificarXYZ(reporte.Productos, coeficientes, params)
+		reporte.ResumenXYZ = resumirClases(productos, clasesXYZ, func(p models.ClasificacionProducto) string { return p.ClaseXYZ })
+	}
+
+	return reporte, nil
+}
+
+// asignarClasesABC completa la clase ABC de cada fila del reporte combinado
+// según los parámetros por defecto del servidor
+func (s *ReporteService) asignarClasesABC(coincidentes, sinCoincidencia []models.ReporteCombinado) {
+	todos := append(append([]models.ReporteCombinado(nil), coincidentes...), sinCoincidencia...)
+	productos := clasificarABC(todos, s.parametrosABC)
+
+	clases := make(map[string]string, len(productos))
+	for _, p := range productos {
+		clases[p.CodigoProducto+"|"+p.CodigoVentas] = p.ClaseABC
+	}
+	for _, lista := range [][]models.ReporteCombinado{coincidentes, sinCoincidencia} {
+		for i := range lista {
+			lista[i].ClaseABC = clases[lista[i].CodigoProducto+"|"+lista[i].CodigoVentas]
+		}
+	}
+}
+
+// resumenABC clasifica las filas del reporte combinado y resume por clase
+func (s *ReporteService) resumenABC(coincidentes, sinCoincidencia []models.ReporteCombinado) []models.ResumenClase {
+	todos := append(append([]models.ReporteCombinado(nil), coincidentes...), sinCoincidencia...)
+	productos := clasificarABC(todos, s.parametrosABC)
+	return resumirClases(productos, clasesABC, func(p models.ClasificacionProducto) string { return p.ClaseABC })
+}
+
+// hojaResumenABC genera la hoja de resumen con productos y participación por clase
+func hojaResumenABC(resumen []models.ResumenClase, params models.ParametrosABC) HojaExcel {
+	hoja := HojaExcel{
+		Nombre: "Resumen ABC",
+		Columnas: []ColumnaExcel{
+			{Titulo: "CLASE"},
+			{Titulo: "PRODUCTOS"},
+			{Titulo: "VALOR (" + params.Criterio + ")", Ancho: 20, Decimal: true},
+			{Titulo: "% PARTICIPACION", Decimal: true},
+		},
+	}
+	for _, r := range resumen {
+		hoja.Filas = append(hoja.Filas, []interface{}{r.Clase, r.Productos, r.Valor, r.Participacion})
+	}
+	return hoja
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+func TestGenerarReporteABC(t *testing.T) {
+	// Demanda mensual de enero a marzo: ESTABLE 10/10/10, VARIABLE 10/0/20 y ERRATICO 30/0/0
+	ventas := []datasource.LineaVentaFixture{
+		ventaPrueba("2025-01-10", "ESTABLE", 10, 5000),
+		ventaPrueba("2025-02-10", "ESTABLE", 10, 5000),
+		ventaPrueba("2025-03-10", "ESTABLE", 10, 5000),
+		ventaPrueba("2025-01-15", "VARIABLE", 10, 1000),
+		ventaPrueba("2025-03-05", "VARIABLE", 8, 1000),
+		ventaPrueba("2025-03-25", "VARIABLE", 12, 1000),
+		ventaPrueba("2025-01-20", "ERRATICO", 30, 500),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("ESTABLE", "Z1", "2024-12-01", 100, 1000),
+		ingresoPrueba("VARIABLE", "Z1", "2024-12-01", 100, 600),
+		ingresoPrueba("ERRATICO", "Z1", "2024-12-01", 100, 100),
+		ingresoPrueba("SIN-VENTA", "Z1", "2024-12-01", 100, 100),
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+	filtro := models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-03-31"}
+
+	casos := []struct {
+		nombre   string
+		criterio string
+		abc      map[string]string
+	}{
+		{
+			// Venta 150.000, 30.000 y 15.000: VARIABLE cruza el 80 % y sigue siendo A
+			nombre:   "por venta",
+			criterio: models.CriterioABCVenta,
+			abc:      map[string]string{"ESTABLE": "A", "VARIABLE": "A", "ERRATICO": "B", "SIN-VENTA": "C"},
+		},
+		{
+			nombre:   "por cantidad",
+			criterio: models.CriterioABCCantidad,
+			abc:      map[string]string{"ESTABLE": "A", "VARIABLE": "A", "ERRATICO": "A", "SIN-VENTA": "C"},
+		},
+		{
+			// Utilidad 120.000, 12.000 y 12.000
+			nombre:   "por utilidad",
+			criterio: models.CriterioABCUtilidad,
+			abc:      map[string]string{"ESTABLE": "A", "VARIABLE": "B", "ERRATICO": "B", "SIN-VENTA": "C"},
+		},
+	}
+	xyz := map[string]string{"ESTABLE": "X", "VARIABLE": "Y", "ERRATICO": "Z", "SIN-VENTA": "Z"}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			reporte, err := s.GenerarReporteABC(filtro, models.ParametrosABC{Criterio: c.criterio, CalcularXYZ: true})
+			if err != nil {
+				t.Fatalf("GenerarReporteABC: %v", err)
+			}
+
+			productos := 0
+			for _, p := range reporte.Productos {
+				productos++
+				if p.ClaseABC != c.abc[p.CodigoProducto] {
+					t.Errorf("%s: ClaseABC = %q, se esperaba %q", p.CodigoProducto, p.ClaseABC, c.abc[p.CodigoProducto])
+				}
+				if p.ClaseXYZ != xyz[p.CodigoProducto] {
+					t.Errorf("%s: ClaseXYZ = %q, se esperaba %q", p.CodigoProducto, p.ClaseXYZ, xyz[p.CodigoProducto])
+				}
+			}
+			if productos != len(c.abc) {
+				t.Errorf("productos = %d, se esperaban %d", productos, len(c.abc))
+			}
+
+			var resumen int
+			for _, r := range reporte.ResumenABC {
+				resumen += r.Productos
+			}
+			if resumen != len(c.abc) {
+				t.Errorf("el resumen ABC suma %d productos, se esperaban %d", resumen, len(c.abc))
+			}
+		})
+	}
+}
+
+func TestResumirClasesConValoresNegativos(t *testing.T) {
+	// DEVUELTO tiene más notas de crédito que ventas en el período
+	reportes := []models.ReporteCombinado{
+		{CodigoProducto: "MAYOR", VentaNetaTotalClp: 600},
+		{CodigoProducto: "MEDIO", VentaNetaTotalClp: 300},
+		{CodigoProducto: "MENOR", VentaNetaTotalClp: 100},
+		{CodigoProducto: "DEVUELTO", VentaNetaTotalClp: -200},
+	}
+	params := models.ParametrosABC{Criterio: models.CriterioABCVenta, UmbralA: umbral(60), UmbralB: umbral(30)}
+
+	productos := clasificarABC(reportes, params)
+	resumen := resumirClases(productos, clasesABC, func(p models.ClasificacionProducto) string { return p.ClaseABC })
+
+	obtenido := make([]string, 0, len(resumen))
+	suma := 0.0
+	for _, r := range resumen {
+		obtenido = append(obtenido, fmt.Sprintf("%s %d %v %v%%", r.Clase, r.Productos, r.Valor, r.Participacion))
+		suma += r.Participacion
+	}
+	// La clase C cuenta al producto negativo, pero solo suma el valor positivo de MENOR
+	esperado := "A 1 600 60%, B 1 300 30%, C 2 100 10%"
+	if strings.Join(obtenido, ", ") != esperado {
+		t.Errorf("resumen = %s, se esperaba %s", strings.Join(obtenido, ", "), esperado)
+	}
+	if !aproximado(suma, 100) {
+		t.Errorf("las participaciones suman %v, se esperaba 100", suma)
+	}
+}
+
+func TestParametrosABCUmbralCeroExplicito(t *testing.T) {
+	defecto := models.ParametrosABC{Criterio: models.CriterioABCVenta, UmbralA: umbral(80), UmbralB: umbral(15), UmbralX: umbral(0.5), UmbralY: umbral(1)}
+
+	params := models.ParametrosABC{UmbralA: umbral(90), UmbralB: umbral(0)}
+	params.Completar(defecto)
+	if err := params.Validar(); err != nil {
+		t.Fatalf("Validar: %v", err)
+	}
+	if *params.UmbralA != 90 || *params.UmbralB != 0 || *params.UmbralX != 0.5 || *params.UmbralY != 1 {
+		t.Errorf("umbrales = %v/%v/%v/%v, se esperaba 90/0/0.5/1", *params.UmbralA, *params.UmbralB, *params.UmbralX, *params.UmbralY)
+	}
+	// Completar copia los valores por defecto sin compartirlos
+	*params.UmbralX = 0.7
+	if *defecto.UmbralX != 0.5 {
+		t.Errorf("Completar modificó el umbral X por defecto: %v", *defecto.UmbralX)
+	}
+
+	// Con B = 0 no hay clase B: lo que queda fuera de A es C
+	reportes := []models.ReporteCombinado{
+		{CodigoProducto: "MAYOR", VentaNetaTotalClp: 900},
+		{CodigoProducto: "MENOR", VentaNetaTotalClp: 100},
+	}
+	clases := make(map[string]string)
+	for _, p := range clasificarABC(reportes, params) {
+		clases[p.CodigoProducto] = p.ClaseABC
+	}
+	if clases["MAYOR"] != "A" || clases["MENOR"] != "C" {
+		t.Errorf("clases = %v, se esperaba MAYOR A y MENOR C", clases)
+	}
+
+	if err := (&models.ParametrosABC{Criterio: models.CriterioABCVenta, UmbralA: umbral(80)}).Validar(); err == nil {
+		t.Error("Validar sin umbrales B, X e Y no devolvió error")
+	}
+}
+
+func TestCoeficientesVariacion(t *testing.T) {
+	ventas := []models.VentaDetallada{
+		{CodigoProducto: "A", FechaEmision: "2025-01-10", Cantidad: 10},
+		{CodigoProducto: "A", FechaEmision: "2025-03-10", Cantidad: 20},
+		{CodigoProducto: "B", FechaEmision: "2025-02-01T00:00:00Z", Cantidad: 6},
+		{CodigoProducto: "B", FechaEmision: "2025-02-20", Cantidad: 0},
+		{CodigoProducto: "C", FechaEmision: "2024-12-31", Cantidad: 5}, // Fuera del período
+		{CodigoProducto: "D", FechaEmision: "sin fecha", Cantidad: 5},
+	}
+
+	casos := []struct {
+		nombre   string
+		fin      string
+		esperado map[string]float64
+	}{
+		// A: 10/0/20 (promedio 10, desviación 8,165); B: 0/6/0
+		{nombre: "tres meses", fin: "2025-03-31", esperado: map[string]float64{"A": 0.8165, "B": 1.4142}},
+		// Marzo queda fuera: A 10/0, B 0/6
+		{nombre: "dos meses", fin: "2025-02-28", esperado: map[string]float64{"A": 1, "B": 1}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			coeficientes, err := coeficientesVariacion(ventas, "2025-01-01", c.fin, fechaPrueba(t, "2025-12-31"))
+			if err != nil {
+				t.Fatalf("coeficientesVariacion: %v", err)
+			}
+			if len(coeficientes) != len(c.esperado) {
+				t.Errorf("coeficientes = %v, se esperaba %v", coeficientes, c.esperado)
+			}
+			for codigo, cv := range c.esperado {
+				if !aproximado(coeficientes[codigo], cv) {
+					t.Errorf("%s: coeficiente = %v, se esperaba %v", codigo, coeficientes[codigo], cv)
+				}
+			}
+		})
+	}
+
+	if _, err := coeficientesVariacion(ventas, "inicio", "2025-03-31", fechaPrueba(t, "2025-12-31")); err == nil {
+		t.Error("coeficientesVariacion con una fecha no válida no devolvió error")
+	}
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// esSinCliente indica si una línea de venta no tiene cliente identificado (venta de mesón)
+func esSinCliente(cliente string) bool {
+	cliente = strings.TrimSpace(cliente)
+	return cliente == "" || strings.EqualFold(cliente, models.ClienteSinCliente)
+}
+
+// acumuladoCliente suma las líneas de venta de un cliente
+type acumuladoCliente struct {
+	resumen    models.ResumenCliente
+	documentos map[string]string // claveDocumento -> tipo
+	dias       map[string]bool
+	productos  map[string]*models.ProductoCliente
+	docsProd   map[string]map[string]bool
+}
+
+func nuevoAcumuladoCliente(cliente string) *acumuladoCliente {
+	return &acumuladoCliente{
+		resumen:    models.ResumenCliente{Cliente: cliente},
+		documentos: make(map[string]string),
+		dias:       make(map[string]bool),
+		productos:  make(map[string]*models.ProductoCliente),
+		docsProd:   make(map[string]map[string]bool),
+	}
+}
+
+// agregar suma una línea de venta (sin documentos anulados)
+func (a *acumuladoCliente) agregar(v models.VentaDetallada) {
+	documento := claveDocumento(v)
+	a.documentos[documento] = v.TipoDocumento
+	a.dias[v.FechaEmision[:10]] = true
+	a.resumen.Unidades += v.Cantidad
+	a.resumen.VentaClp += v.TotalVentaCLP
+
+	p, ok := a.productos[v.CodigoProducto]
+	if !ok {
+		p = &models.ProductoCliente{CodigoProducto: v.CodigoProducto, NombreProducto: v.NombreProducto}
+		a.productos[v.CodigoProducto] = p
+		a.docsProd[v.CodigoProducto] = make(map[string]bool)
+	}
+	p.Unidades += v.Cantidad
+	p.VentaClp += v.TotalVentaCLP
+	a.docsProd[v.CodigoProducto][documento] = true
+}
+
+// cerrar completa documentos, fechas de compra, recencia y frecuencia a la fecha de referencia
+func (a *acumuladoCliente) cerrar(referencia time.Time) models.ResumenCliente {
+	r := a.resumen
+	r.Documentos = len(a.documentos)
+	for _, tipo := range a.documentos {
+		switch tipo {
+		case models.TipoDocumentoBoleta:
+			r.Boletas++
+		case models.TipoDocumentoFactura:
+			r.Facturas++
+		}
+	}
+	if r.Documentos > 0 {
+		r.TicketPromedioClp = redondear(float64(r.VentaClp)/float64(r.Documentos), 2)
+	}
+	r.ProductosDistintos = len(a.productos)
+
+	dias := make([]string, 0, len(a.dias))
+	for dia := range a.dias {
+		dias = append(dias, dia)
+	}
+	sort.Strings(dias)
+	r.DiasCompra = len(dias)
+	if len(dias) > 0 {
+		r.PrimeraCompra, r.UltimaCompra = dias[0], dias[len(dias)-1]
+		primera, _ := time.Parse("2006-01-02", r.PrimeraCompra)
+		ultima, _ := time.Parse("2006-01-02", r.UltimaCompra)
+		if recencia := int(referencia.Sub(ultima).Hours() / 24); recencia > 0 {
+			r.RecenciaDias = recencia
+		}
+		if len(dias) > 1 {
+			frecuencia := redondear(ultima.Sub(primera).Hours()/24/float64(len(dias)-1), 2)
+			r.FrecuenciaDias = &frecuencia
+		}
+	}
+	return r
+}
+
+// productosPrincipales devuelve los productos más comprados por el cliente según el orden del reporte
+func (a *acumuladoCliente) productosPrincipales(orden string, limite int) []models.ProductoCliente {
+	productos := make([]models.ProductoCliente, 0, len(a.productos))
+	for codigo, p := range a.productos {
+		p.Documentos = len(a.docsProd[codigo])
+		productos = append(productos, *p)
+	}
+	sort.Slice(productos, func(i, j int) bool {
+		pi, pj := productos[i], productos[j]
+		if orden == models.OrdenClientesUnidades && pi.Unidades != pj.Unidades {
+			return pi.Unidades > pj.Unidades
+		}
+		if pi.VentaClp != pj.VentaClp {
+			return pi.VentaClp > pj.VentaClp
+		}
+		return pi.CodigoProducto < pj.CodigoProducto
+	})
+	if len(productos) > limite {
+		productos = productos[:limite]
+	}
+	return productos
+}
+
+// puntajesQuintil asigna a cada valor un puntaje de 1 (el menor) a 5 (el mayor) según su posición
+// entre todos los valores. Los valores iguales reciben el mismo puntaje; un único valor recibe 3.
+func puntajesQuintil(valores []float64) []int {
+	puntajes := make([]int, len(valores))
+	if len(valores) == 1 {
+		puntajes[0] = 3
+		return puntajes
+	}
+	for i, v := range valores {
+		menores := 0
+		for _, otro := range valores {
+			if otro < v {
+				menores++
+			}
+		}
+		puntajes[i] = 1 + int(math.Round(float64(menores)*4/float64(len(valores)-1)))
+	}
+	return puntajes
+}
+
+// segmentoRFM asigna el segmento a partir de los puntajes de recencia y frecuencia
+func segmentoRFM(recencia, frecuencia int) string {
+	switch {
+	case recencia >= 4 && frecuencia >= 4:
+		return models.SegmentoCampeones
+	case recencia <= 2 && frecuencia >= 3:
+		return models.SegmentoEnRiesgo
+	case recencia >= 3 && frecuencia >= 3:
+		return models.SegmentoLeales
+	case recencia >= 4:
+		return models.SegmentoNuevos
+	case recencia <= 2:
+		return models.SegmentoPerdidos
+	default:
+		return models.SegmentoOcasionales
+	}
+}
+
+// segmentosRFM define el orden de los segmentos en el resumen
+var segmentosRFM = []string{
+	models.SegmentoCampeones, models.SegmentoLeales, models.SegmentoNuevos,
+	models.SegmentoOcasionales, models.SegmentoEnRiesgo, models.SegmentoPerdidos,
+}
+
+// aplicarRFM completa los puntajes RFM y el segmento de cada cliente
+func aplicarRFM(clientes []models.ResumenCliente) {
+	recencia := make([]float64, len(clientes))
+	frecuencia := make([]float64, len(clientes))
+	monto := make([]float64, len(clientes))
+	for i, c := range clientes {
+		recencia[i] = -float64(c.RecenciaDias) // Más reciente, mayor puntaje
+		frecuencia[i] = float64(c.Documentos)
+		monto[i] = float64(c.VentaClp)
+	}
+	r, f, m := puntajesQuintil(recencia), puntajesQuintil(frecuencia), puntajesQuintil(monto)
+	for i := range clientes {
+		clientes[i].Recencia, clientes[i].Frecuencia, clientes[i].Monto = r[i], f[i], m[i]
+		clientes[i].RFM = fmt.Sprintf("%d%d%d", r[i], f[i], m[i])
+		clientes[i].Segmento = segmentoRFM(r[i], f[i])
+	}
+}
+
+// ordenarClientes ordena por venta o por unidades, de mayor a menor
+func ordenarClientes(clientes []models.ResumenCliente, orden string) {
+	sort.Slice(clientes, func(i, j int) bool {
+		ci, cj := clientes[i], clientes[j]
+		if orden == models.OrdenClientesUnidades && ci.Unidades != cj.Unidades {
+			return ci.Unidades > cj.Unidades
+		}
+		if ci.VentaClp != cj.VentaClp {
+			return ci.VentaClp > cj.VentaClp
+		}
+		if ci.Unidades != cj.Unidades {
+			return ci.Unidades > cj.Unidades
+		}
+		return ci.Cliente < cj.Cliente
+	})
+}
+
+// GenerarReporteClientes agrupa las líneas de boletas y facturas del período por cliente: ranking por
+// venta y unidades, frecuencia y recencia de compra, segmentación RFM y productos de los clientes
+// clave. Las ventas sin cliente identificado se informan por separado y no entran al ranking ni a
+// la segmentación. Las líneas de documentos anulados se excluyen.
+func (s *VentasService) GenerarReporteClientes(filtro models.VentasFiltro, params models.ParametrosClientes) (*models.ReporteClientes, error) {
+	ventas, err := s.GetVentasDetalladas(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	porCliente := make(map[string]*acumuladoCliente)
+	var sinCliente *acumuladoCliente
+	for _, v := range ventas {
+		if v.Nula {
+			continue
+		}
+		if _, ok := parsearFechaVenta(v.FechaEmision); !ok {
+			continue
+		}
+		if esSinCliente(v.Cliente) {
+			if sinCliente == nil {
+				sinCliente = nuevoAcumuladoCliente(models.ClienteSinCliente)
+			}
+			sinCliente.agregar(v)
+			continue
+		}
+		nombre := strings.TrimSpace(v.Cliente)
+		acumulado, ok := porCliente[nombre]
+		if !ok {
+			acumulado = nuevoAcumuladoCliente(nombre)
+			porCliente[nombre] = acumulado
+		}
+		acumulado.agregar(v)
+	}
+
+	// La recencia se mide al fin del período, o a hoy si el período no ha terminado
+	hoy := time.Now().UTC().Truncate(24 * time.Hour)
+	referencia, err := time.Parse("2006-01-02", filtro.FechaFin)
+	if err != nil || referencia.After(hoy) {
+		referencia = hoy
+	}
+
+	reporte := &models.ReporteClientes{
+		FechaInicio:     filtro.FechaInicio,
+		FechaFin:        filtro.FechaFin,
+		Sucursales:      filtro.Sucursales,
+		FechaReferencia: referencia.Format("2006-01-02"),
+		Parametros:      params,
+		TotalClientes:   len(porCliente),
+		Clientes:        make([]models.ResumenCliente, 0, len(porCliente)),
+		Segmentos:       []models.ResumenSegmento{},
+	}
+	for _, acumulado := range porCliente {
+		cliente := acumulado.cerrar(referencia)
+		reporte.VentaClp += cliente.VentaClp
+		reporte.Clientes = append(reporte.Clientes, cliente)
+	}
+	aplicarRFM(reporte.Clientes)
+
+	// Rankings por unidades y por venta
+	ordenarClientes(reporte.Clientes, models.OrdenClientesUnidades)
+	for i := range reporte.Clientes {
+		reporte.Clientes[i].RankingUnidades = i + 1
+	}
+	ordenarClientes(reporte.Clientes, models.OrdenClientesVenta)
+	for i := range reporte.Clientes {
+		reporte.Clientes[i].RankingVenta = i + 1
+		if reporte.VentaClp > 0 {
+			reporte.Clientes[i].ParticipacionPct = redondear(float64(reporte.Clientes[i].VentaClp)/float64(reporte.VentaClp)*100, 2)
+		}
+	}
+	ordenarClientes(reporte.Clientes, params.Orden)
+
+	// Productos de los clientes clave
+	for i := range reporte.Clientes {
+		if i >= params.Top {
+			break
+		}
+		reporte.Clientes[i].Productos = porCliente[reporte.Clientes[i].Cliente].productosPrincipales(params.Orden, params.Productos)
+	}
+
+	segmentos := make(map[string]*models.ResumenSegmento)
+	for _, c := range reporte.Clientes {
+		segmento, ok := segmentos[c.Segmento]
+		if !ok {
+			segmento = &models.ResumenSegmento{Segmento: c.Segmento}
+			segmentos[c.Segmento] = segmento
+		}
+		segmento.Clientes++
+		segmento.VentaClp += c.VentaClp
+	}
+	for _, nombre := range segmentosRFM {
+		segmento, ok := segmentos[nombre]
+		if !ok {
+			continue
+		}
+		if reporte.VentaClp > 0 {
+			segmento.ParticipacionPct = redondear(float64(segmento.VentaClp)/float64(reporte.VentaClp)*100, 2)
+		}
+		reporte.Segmentos = append(reporte.Segmentos, *segmento)
+	}
+
+	if sinCliente != nil {
+		resumen := sinCliente.cerrar(referencia)
+		if total := reporte.VentaClp + resumen.VentaClp; total > 0 {
+			resumen.ParticipacionPct = redondear(float64(resumen.VentaClp)/float64(total)*100, 2)
+		}
+		resumen.Productos = sinCliente.productosPrincipales(params.Orden, params.Productos)
+		reporte.SinCliente = &resumen
+	}
+	return reporte, nil
+}
+
+// ExportarReporteClientes exporta el reporte de clientes a Excel, con el ranking (y la venta sin
+// cliente en una fila resaltada al final), los productos de los clientes clave y los segmentos RFM
+func (s *VentasService) ExportarReporteClientes(filtro models.VentasFiltro, params models.ParametrosClientes) ([]byte, string, error) {
+	reporte, err := s.GenerarReporteClientes(filtro, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	clientes := HojaExcel{
+		Nombre: "Clientes",
+		Columnas: []ColumnaExcel{
+			{Titulo: "RANKING VENTA"},
+			{Titulo: "RANKING UNIDADES"},
+			{Titulo: "Cliente", Ancho: 40},
+			{Titulo: "DOCUMENTOS"},
+			{Titulo: "BOLETAS"},
+			{Titulo: "FACTURAS"},
+			{Titulo: "UNIDADES", Decimal: true},
+			{Titulo: "VENTA CLP"},
+			{Titulo: "PARTICIPACION %", Decimal: true},
+			{Titulo: "TICKET PROMEDIO CLP", Decimal: true},
+			{Titulo: "PRODUCTOS DISTINTOS"},
+			{Titulo: "PRIMERA COMPRA"},
+			{Titulo: "ULTIMA COMPRA"},
+			{Titulo: "DIAS CON COMPRA"},
+			{Titulo: "RECENCIA (DIAS)"},
+			{Titulo: "FRECUENCIA (DIAS)", Decimal: true},
+			{Titulo: "RFM"},
+			{Titulo: "SEGMENTO"},
+		},
+	}
+	productos := HojaExcel{
+		Nombre: "Productos Clientes Clave",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Cliente", Ancho: 40},
+			{Titulo: "Código de Producto"},
+			{Titulo: "Nombre del Producto", Ancho: 40},
+			{Titulo: "UNIDADES", Decimal: true},
+			{Titulo: "VENTA CLP"},
+			{Titulo: "DOCUMENTOS"},
+		},
+	}
+	segmentos := HojaExcel{
+		Nombre: "Segmentos RFM",
+		Columnas: []ColumnaExcel{
+			{Titulo: "SEGMENTO", Ancho: 20},
+			{Titulo: "CLIENTES"},
+			{Titulo: "VENTA CLP"},
+			{Titulo: "PARTICIPACION %", Decimal: true},
+		},
+	}
+
+	filaCliente := func(c models.ResumenCliente, rankingVenta, rankingUnidades interface{}) []interface{} {
+		var frecuencia interface{}
+		if c.FrecuenciaDias != nil {
+			frecuencia = *c.FrecuenciaDias
+		}
+		return []interface{}{
+			rankingVenta, rankingUnidades, c.Cliente,
+			c.Documentos, c.Boletas, c.Facturas, c.Unidades, c.VentaClp, c.ParticipacionPct, c.TicketPromedioClp,
+			c.ProductosDistintos, c.PrimeraCompra, c.UltimaCompra, c.DiasCompra, c.RecenciaDias, frecuencia,
+			c.RFM, c.Segmento,
+		}
+	}
+	filasProductos := func(c models.ResumenCliente) {
+		for _, p := range c.Productos {
+			productos.Filas = append(productos.Filas, []interface{}{
+				c.Cliente, p.CodigoProducto, p.NombreProducto, p.Unidades, p.VentaClp, p.Documentos,
+			})
+		}
+	}
+
+	for _, c := range reporte.Clientes {
+		clientes.Filas = append(clientes.Filas, filaCliente(c, c.RankingVenta, c.RankingUnidades))
+		filasProductos(c)
+	}
+	if reporte.SinCliente != nil {
+		clientes.FilasResaltadas = append(clientes.FilasResaltadas, len(clientes.Filas))
+		clientes.Filas = append(clientes.Filas, filaCliente(*reporte.SinCliente, nil, nil))
+		for i := range reporte.SinCliente.Productos {
+			productos.FilasResaltadas = append(productos.FilasResaltadas, len(productos.Filas)+i)
+		}
+		filasProductos(*reporte.SinCliente)
+	}
+	for _, seg := range reporte.Segmentos {
+		segmentos.Filas = append(segmentos.Filas, []interface{}{seg.Segmento, seg.Clientes, seg.VentaClp, seg.ParticipacionPct})
+	}
+
+	excelBytes, err := s.excelService.GenerarLibro(clientes, productos, segmentos)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("Clientes_%s_%s_al_%s.xlsx",
+		nombreSucursales(filtro), filtro.FechaInicio, filtro.FechaFin)
+	return excelBytes, filename, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ventaCliente crea una línea de boleta del cliente en su propio documento
+func ventaCliente(cliente, fecha, codigo string, cantidad float64, precio int) datasource.LineaVentaFixture {
+	v := ventaPrueba(fecha, codigo, cantidad, precio)
+	v.Cliente = cliente
+	v.CodigoDocumento = fmt.Sprintf("%s-%s", cliente, v.CodigoDocumento)
+	return v
+}
+
+func TestPuntajesQuintil(t *testing.T) {
+	casos := []struct {
+		valores  []float64
+		esperado string
+	}{
+		{[]float64{7}, "[3]"},
+		{[]float64{10, 50, 30, 20, 40}, "[1 5 3 2 4]"},
+		// Los valores iguales reciben el mismo puntaje
+		{[]float64{5, 5, 1}, "[3 3 1]"},
+		{[]float64{2, 2}, "[1 1]"},
+	}
+	for _, c := range casos {
+		if obtenido := fmt.Sprint(puntajesQuintil(c.valores)); obtenido != c.esperado {
+			t.Errorf("puntajesQuintil(%v) = %s, se esperaba %s", c.valores, obtenido, c.esperado)
+		}
+	}
+}
+
+func TestSegmentoRFM(t *testing.T) {
+	casos := []struct {
+		recencia, frecuencia int
+		esperado             string
+	}{
+		{5, 5, models.SegmentoCampeones},
+		{4, 4, models.SegmentoCampeones},
+		{3, 5, models.SegmentoLeales},
+		{4, 3, models.SegmentoLeales},
+		{2, 3, models.SegmentoEnRiesgo},
+		{1, 5, models.SegmentoEnRiesgo},
+		{5, 1, models.SegmentoNuevos},
+		{4, 2, models.SegmentoNuevos},
+		{2, 2, models.SegmentoPerdidos},
+		{1, 1, models.SegmentoPerdidos},
+		{3, 2, models.SegmentoOcasionales},
+		{3, 1, models.SegmentoOcasionales},
+	}
+	for _, c := range casos {
+		if obtenido := segmentoRFM(c.recencia, c.frecuencia); obtenido != c.esperado {
+			t.Errorf("segmentoRFM(%d, %d) = %s, se esperaba %s", c.recencia, c.frecuencia, obtenido, c.esperado)
+		}
+	}
+}
+
+func TestGenerarReporteClientes(t *testing.T) {
+	anulada := ventaCliente("ANA", "2025-01-20", "PISO", 10, 1000)
+	anulada.Nula = true
+	carla := ventaCliente("CARLA", "2025-01-29", "MURO", 2, 1000)
+	carla.CodigoDocumento = "CARLA-2025-01-29-PISO" // Misma boleta que su compra de PISO
+	ventas := []datasource.LineaVentaFixture{
+		// ANA compra seguido y hace poco
+		ventaCliente("ANA", "2025-01-05", "PISO", 1, 1000),
+		ventaCliente("ANA", "2025-01-15", "PISO", 1, 1000),
+		ventaCliente("ANA", "2025-01-30", "MURO", 1, 1000),
+		anulada,
+		// BETO compró dos veces a comienzos de mes
+		ventaCliente("BETO", "2025-01-02", "PISO", 2, 1000),
+		ventaCliente("BETO", "2025-01-03", "PISO", 2, 1000),
+		// CARLA compró una vez hace poco
+		ventaCliente("CARLA", "2025-01-29", "PISO", 3, 1000),
+		carla,
+		// DIEGO compró una vez hace tiempo
+		ventaCliente("DIEGO", "2025-01-01", "MURO", 1, 500),
+		// Venta de mesón
+		ventaCliente("", "2025-01-10", "PISO", 1, 1000),
+	}
+	s := NewVentasService(datasource.NewFixturesFromData(ventas, nil), NewExcelService())
+
+	params := models.ParametrosClientes{Top: 1, Productos: 1}
+	if err := params.Validar(); err != nil {
+		t.Fatalf("Validar: %v", err)
+	}
+	reporte, err := s.GenerarReporteClientes(models.VentasFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-31", Sucursales: []int{211}}, params)
+	if err != nil {
+		t.Fatalf("GenerarReporteClientes: %v", err)
+	}
+
+	if reporte.FechaReferencia != "2025-01-31" || reporte.TotalClientes != 4 || reporte.VentaClp != 12500 {
+		t.Errorf("referencia %s, %d clientes, $%d; se esperaba 2025-01-31, 4 clientes, $12500",
+			reporte.FechaReferencia, reporte.TotalClientes, reporte.VentaClp)
+	}
+
+	var partes []string
+	for _, c := range reporte.Clientes {
+		frecuencia := "-"
+		if c.FrecuenciaDias != nil {
+			frecuencia = fmt.Sprint(*c.FrecuenciaDias)
+		}
+		partes = append(partes, fmt.Sprintf("%d %s %d docs $%d %v%% recencia %d frecuencia %s RFM %s %s",
+			c.RankingVenta, c.Cliente, c.Documentos, c.VentaClp, c.ParticipacionPct, c.RecenciaDias, frecuencia, c.RFM, c.Segmento))
+	}
+	// La línea anulada de ANA no cuenta y CARLA suma sus dos líneas en un solo documento
+	esperado := strings.Join([]string{
+		"1 CARLA 1 docs $5000 40% recencia 2 frecuencia - RFM 415 Nuevos",
+		"2 BETO 2 docs $4000 32% recencia 28 frecuencia 1 RFM 244 En riesgo",
+		"3 ANA 3 docs $3000 24% recencia 1 frecuencia 12.5 RFM 552 Campeones",
+		"4 DIEGO 1 docs $500 4% recencia 30 frecuencia - RFM 111 Perdidos",
+	}, "\n")
+	if obtenido := strings.Join(partes, "\n"); obtenido != esperado {
+		t.Errorf("clientes:\n%s\nse esperaba:\n%s", obtenido, esperado)
+	}
+
+	// Solo los clientes clave llevan el detalle de productos
+	if productos := reporte.Clientes[0].Productos; len(productos) != 1 || productos[0].CodigoProducto != "PISO" {
+		t.Errorf("productos de CARLA = %+v, se esperaba solo PISO", productos)
+	}
+	if productos := reporte.Clientes[1].Productos; productos != nil {
+		t.Errorf("productos de BETO = %+v, se esperaba ninguno", productos)
+	}
+
+	var segmentos []string
+	for _, seg := range reporte.Segmentos {
+		segmentos = append(segmentos, fmt.Sprintf("%s %d %v%%", seg.Segmento, seg.Clientes, seg.ParticipacionPct))
+	}
+	if obtenido, esperado := strings.Join(segmentos, ", "), "Campeones 1 24%, Nuevos 1 40%, En riesgo 1 32%, Perdidos 1 4%"; obtenido != esperado {
+		t.Errorf("segmentos = %s, se esperaba %s", obtenido, esperado)
+	}
+
+	// La venta sin cliente queda fuera del ranking y su participación es sobre la venta total
+	sin := reporte.SinCliente
+	if sin == nil || sin.VentaClp != 1000 || sin.ParticipacionPct != 7.41 || sin.Segmento != "" {
+		t.Errorf("sin cliente = %+v, se esperaba $1000 con 7.41%% y sin segmento", sin)
+	}
+}
+
+func TestGenerarReporteClientesOrdenUnidades(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{
+		ventaCliente("ANA", "2025-01-05", "PISO", 1, 5000),
+		ventaCliente("BETO", "2025-01-05", "MURO", 10, 100),
+	}
+	s := NewVentasService(datasource.NewFixturesFromData(ventas, nil), NewExcelService())
+
+	params := models.ParametrosClientes{Orden: models.OrdenClientesUnidades}
+	if err := params.Validar(); err != nil {
+		t.Fatalf("Validar: %v", err)
+	}
+	reporte, err := s.GenerarReporteClientes(models.VentasFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-31", Sucursales: []int{211}}, params)
+	if err != nil {
+		t.Fatalf("GenerarReporteClientes: %v", err)
+	}
+
+	var partes []string
+	for _, c := range reporte.Clientes {
+		partes = append(partes, fmt.Sprintf("%s venta %d unidades %d", c.Cliente, c.RankingVenta, c.RankingUnidades))
+	}
+	if obtenido, esperado := strings.Join(partes, ", "), "BETO venta 2 unidades 1, ANA venta 1 unidades 2"; obtenido != esperado {
+		t.Errorf("clientes = %s, se esperaba %s", obtenido, esperado)
+	}
+}
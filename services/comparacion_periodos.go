@@ -0,0 +1,379 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// GenerarComparacionPeriodos genera el reporte combinado del período del filtro y de un período
+// anterior, y compara cada producto entre ambos: unidades, venta, utilidad y rotación, con su
+// variación absoluta y porcentual. Los productos se emparejan por su código de ventas, de modo
+// que un cambio de inventario entre años no los separe; los que solo venden en uno de los
+// períodos se informan como nuevos o desaparecidos.
+func (s *ReporteService) GenerarComparacionPeriodos(filtro models.ReporteFiltro, params models.ParametrosComparacionPeriodos) (*models.ComparacionPeriodos, error) {
+	anterior, err := filtroAnterior(filtro, params)
+	if err != nil {
+		return nil, err
+	}
+	// La comparación no usa el pronóstico ni el stock a fecha
+	filtro.Pronostico, anterior.Pronostico = nil, nil
+	filtro.StockAFecha, anterior.StockAFecha = "", ""
+
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, err
+	}
+	coincidentesAnt, sinCoincidenciaAnt, err := s.GenerarReporteCombinado(anterior)
+	if err != nil {
+		return nil, fmt.Errorf("error al generar el período anterior: %v", err)
+	}
+
+	hoy := time.Now()
+	comparacion := &models.ComparacionPeriodos{
+		Modo:       params.Modo,
+		Actual:     periodoComparado(filtro, hoy),
+		Anterior:   periodoComparado(anterior, hoy),
+		Sucursales: filtro.Sucursales,
+		Productos:  []models.ComparacionPeriodoProducto{},
+	}
+	if params.FechaInicioAnterior != "" {
+		comparacion.Modo = ""
+	}
+
+	actuales, ordenActual := productosConVenta(coincidentes, sinCoincidencia)
+	anteriores, ordenAnterior := productosConVenta(coincidentesAnt, sinCoincidenciaAnt)
+
+	for _, clave := range ordenActual {
+		r := actuales[clave]
+		producto := models.ComparacionPeriodoProducto{
+			CodigoProducto: r.CodigoProducto,
+			CodigoVentas:   r.CodigoVentas,
+			Nombre:         r.Nombre,
+			Marca:          r.Marca,
+			Categoria:      r.Categoria,
+			Estado:         models.EstadoPeriodoNuevo,
+			Actual:         metricasPeriodo(r),
+		}
+		if ant, ok := anteriores[clave]; ok {
+			producto.Estado = models.EstadoPeriodoContinuo
+			producto.Anterior = metricasPeriodo(ant)
+		}
+		comparacion.Productos = append(comparacion.Productos, producto)
+	}
+	for _, clave := range ordenAnterior {
+		if _, ok := actuales[clave]; ok {
+			continue
+		}
+		r := anteriores[clave]
+		comparacion.Productos = append(comparacion.Productos, models.ComparacionPeriodoProducto{
+			CodigoProducto: r.CodigoProducto,
+			CodigoVentas:   r.CodigoVentas,
+			Nombre:         r.Nombre,
+			Marca:          r.Marca,
+			Categoria:      r.Categoria,
+			Estado:         models.EstadoPeriodoDesaparecido,
+			Anterior:       metricasPeriodo(r),
+		})
+	}
+
+	for i := range comparacion.Productos {
+		p := &comparacion.Productos[i]
+		p.Variaciones = variacionesPeriodo(p.Actual, p.Anterior)
+		switch p.Estado {
+		case models.EstadoPeriodoNuevo:
+			comparacion.ProductosNuevos++
+		case models.EstadoPeriodoDesaparecido:
+			comparacion.ProductosDesaparecidos++
+		default:
+			comparacion.ProductosContinuos++
+		}
+	}
+
+	// Primero las mayores caídas de venta, para revisar qué se dejó de vender
+	sort.SliceStable(comparacion.Productos, func(i, j int) bool {
+		pi, pj := comparacion.Productos[i], comparacion.Productos[j]
+		if pi.Variaciones.VentaNetaClp.Delta != pj.Variaciones.VentaNetaClp.Delta {
+			return pi.Variaciones.VentaNetaClp.Delta < pj.Variaciones.VentaNetaClp.Delta
+		}
+		return pi.CodigoVentas < pj.CodigoVentas
+	})
+
+	comparacion.Totales.Actual = totalPeriodo(coincidentes, sinCoincidencia)
+	comparacion.Totales.Anterior = totalPeriodo(coincidentesAnt, sinCoincidenciaAnt)
+	comparacion.Totales.Variaciones = variacionesPeriodo(comparacion.Totales.Actual, comparacion.Totales.Anterior)
+	return comparacion, nil
+}
+
+// filtroAnterior construye el filtro del período anterior: las fechas explícitas o, según el
+// modo, las mismas fechas del año anterior o el período inmediatamente anterior de igual
+// duración.
+func filtroAnterior(filtro models.ReporteFiltro, params models.ParametrosComparacionPeriodos) (models.ReporteFiltro, error) {
+	inicio, err := time.Parse("2006-01-02", filtro.FechaInicio)
+	if err != nil {
+		return filtro, fmt.Errorf("error al interpretar la fecha de inicio: %v", err)
+	}
+	fin, err := time.Parse("2006-01-02", filtro.FechaFin)
+	if err != nil {
+		return filtro, fmt.Errorf("error al interpretar la fecha de fin: %v", err)
+	}
+
+	anterior := filtro
+	var finAnterior time.Time
+	switch {
+	case params.FechaInicioAnterior != "":
+		finAnterior, err = time.Parse("2006-01-02", params.FechaFinAnterior)
+		if err != nil {
+			return filtro, fmt.Errorf("error al interpretar la fecha de fin anterior: %v", err)
+		}
+		anterior.FechaInicio = params.FechaInicioAnterior
+		anterior.FechaFin = params.FechaFinAnterior
+	case params.Modo == models.ModoComparacionPeriodoAnterior:
+		dias := int(fin.Sub(inicio).Hours() / 24)
+		finAnterior = inicio.AddDate(0, 0, -1)
+		anterior.FechaInicio = formatearFecha(finAnterior.AddDate(0, 0, -dias))
+		anterior.FechaFin = formatearFecha(finAnterior)
+	default:
+		finAnterior = restarAnio(fin)
+		anterior.FechaInicio = formatearFecha(restarAnio(inicio))
+		anterior.FechaFin = formatearFecha(finAnterior)
+	}
+
+	// El inventario sigue a las fechas, salvo un año anterior explícito o un rango de años
+	// explícito, que se desplaza los mismos años
+	desplazamiento := fin.Year() - finAnterior.Year()
+	anterior.Anio = filtro.Anio - desplazamiento
+	switch {
+	case params.AnioAnterior != 0:
+		anterior.Anio = params.AnioAnterior
+		anterior.AnioInventarioDesde = params.AnioAnterior
+		anterior.AnioInventarioHasta = params.AnioAnterior
+	case filtro.AnioInventarioDesde != 0 || filtro.AnioInventarioHasta != 0:
+		if anterior.AnioInventarioDesde != 0 {
+			anterior.AnioInventarioDesde -= desplazamiento
+		}
+		if anterior.AnioInventarioHasta != 0 {
+			anterior.AnioInventarioHasta -= desplazamiento
+		}
+	}
+	return anterior, nil
+}
+
+// restarAnio devuelve la misma fecha del año anterior; el 29 de febrero pasa al 28
+func restarAnio(fecha time.Time) time.Time {
+	anterior := fecha.AddDate(-1, 0, 0)
+	if anterior.Month() != fecha.Month() {
+		anterior = anterior.AddDate(0, 0, -anterior.Day())
+	}
+	return anterior
+}
+
+// periodoComparado describe el período de un filtro y los años de producción de su inventario
+func periodoComparado(filtro models.ReporteFiltro, hoy time.Time) models.PeriodoComparado {
+	inventario := filtro.FiltroInventario()
+	inventario.Validar()
+	if inventario.AnioDesde == models.AnioProduccionSinLimite {
+		inventario.AnioDesde = 0
+	}
+	return models.PeriodoComparado{
+		FechaInicio:         filtro.FechaInicio,
+		FechaFin:            filtro.FechaFin,
+		DiasPeriodo:         diasPeriodo(filtro.FechaInicio, filtro.FechaFin, hoy),
+		AnioInventarioDesde: inventario.AnioDesde,
+		AnioInventarioHasta: inventario.AnioHasta,
+	}
+}
+
+// claveProductoPeriodo identifica un producto entre períodos: su código de ventas o, si no
+// tiene ventas, su código de inventario
+func claveProductoPeriodo(r models.ReporteCombinado) string {
+	if r.CodigoVentas != "" {
+		return r.CodigoVentas
+	}
+	return r.CodigoProducto
+}
+
+// productosConVenta indexa los productos con ventas en el período, en el orden del reporte
+func productosConVenta(coincidentes, sinCoincidencia []models.ReporteCombinado) (map[string]models.ReporteCombinado, []string) {
+	productos := make(map[string]models.ReporteCombinado, len(coincidentes)+len(sinCoincidencia))
+	var orden []string
+	for _, reportes := range [][]models.ReporteCombinado{coincidentes, sinCoincidencia} {
+		for _, r := range reportes {
+			if r.CantidadTransacciones == 0 && r.VentaNetaTotalClp == 0 {
+				continue
+			}
+			clave := claveProductoPeriodo(r)
+			if _, ok := productos[clave]; ok {
+				continue
+			}
+			productos[clave] = r
+			orden = append(orden, clave)
+		}
+	}
+	return productos, orden
+}
+
+// metricasPeriodo extrae las métricas comparables de una fila del reporte combinado
+func metricasPeriodo(r models.ReporteCombinado) models.MetricasPeriodo {
+	return models.MetricasPeriodo{
+		CantidadVendida:       r.CantidadVendida,
+		VentaNetaClp:          r.VentaNetaTotalClp,
+		UtilidadClp:           redondear(r.UtilidadClp, 2),
+		RotacionInventario:    redondear(r.RotacionInventario, 4),
+		CantidadTransacciones: r.CantidadTransacciones,
+	}
+}
+
+// totalPeriodo suma las métricas de todos los productos del período. La rotación total
+// solo considera los productos con inventario.
+func totalPeriodo(coincidentes, sinCoincidencia []models.ReporteCombinado) models.MetricasPeriodo {
+	var total models.MetricasPeriodo
+	var promedio, vendidaConInventario, utilidad float64
+	for _, r := range coincidentes {
+		promedio += inventarioPromedio(r)
+		vendidaConInventario += r.CantidadVendida
+	}
+	for _, reportes := range [][]models.ReporteCombinado{coincidentes, sinCoincidencia} {
+		for _, r := range reportes {
+			total.CantidadVendida += r.CantidadVendida
+			total.VentaNetaClp += r.VentaNetaTotalClp
+			total.CantidadTransacciones += r.CantidadTransacciones
+			utilidad += r.UtilidadClp
+		}
+	}
+	total.UtilidadClp = redondear(utilidad, 2)
+	if promedio > 0 {
+		total.RotacionInventario = redondear(vendidaConInventario/promedio, 4)
+	}
+	return total
+}
+
+// variacion calcula la diferencia entre dos valores y su porcentaje sobre el anterior
+func variacion(actual, anterior float64) models.Variacion {
+	v := models.Variacion{Delta: redondear(actual-anterior, 4)}
+	if anterior != 0 {
+		pct := redondear((actual-anterior)/anterior*100, 2)
+		v.Pct = &pct
+	}
+	return v
+}
+
+// variacionesPeriodo compara las métricas de ambos períodos
+func variacionesPeriodo(actual, anterior models.MetricasPeriodo) models.VariacionesPeriodo {
+	return models.VariacionesPeriodo{
+		CantidadVendida:    variacion(actual.CantidadVendida, anterior.CantidadVendida),
+		VentaNetaClp:       variacion(float64(actual.VentaNetaClp), float64(anterior.VentaNetaClp)),
+		UtilidadClp:        variacion(actual.UtilidadClp, anterior.UtilidadClp),
+		RotacionInventario: variacion(actual.RotacionInventario, anterior.RotacionInventario),
+	}
+}
+
+// valorPct devuelve el porcentaje para Excel; vacío si no se puede calcular
+func valorPct(pct *float64) interface{} {
+	if pct == nil {
+		return ""
+	}
+	return *pct
+}
+
+// ExportarComparacionPeriodos exporta la comparación entre períodos a Excel, con las variaciones
+// coloreadas en verde (alza) o rojo (baja)
+func (s *ReporteService) ExportarComparacionPeriodos(filtro models.ReporteFiltro, params models.ParametrosComparacionPeriodos) ([]byte, string, error) {
+	comparacion, err := s.GenerarComparacionPeriodos(filtro, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Un bloque de columnas por métrica: actual, anterior, variación y variación %
+	metricas := []struct {
+		titulo  string
+		decimal bool
+		valores func(m models.MetricasPeriodo) interface{}
+		delta   func(v models.VariacionesPeriodo) models.Variacion
+	}{
+		{"CANTIDAD VENDIDA", true,
+			func(m models.MetricasPeriodo) interface{} { return m.CantidadVendida },
+			func(v models.VariacionesPeriodo) models.Variacion { return v.CantidadVendida }},
+		{"VENTA NETA CLP", false,
+			func(m models.MetricasPeriodo) interface{} { return m.VentaNetaClp },
+			func(v models.VariacionesPeriodo) models.Variacion { return v.VentaNetaClp }},
+		{"UTILIDAD CLP", true,
+			func(m models.MetricasPeriodo) interface{} { return m.UtilidadClp },
+			func(v models.VariacionesPeriodo) models.Variacion { return v.UtilidadClp }},
+		{"ROTACION", true,
+			func(m models.MetricasPeriodo) interface{} { return m.RotacionInventario },
+			func(v models.VariacionesPeriodo) models.Variacion { return v.RotacionInventario }},
+	}
+	columnasMetricas := func() []ColumnaExcel {
+		var columnas []ColumnaExcel
+		for _, m := range metricas {
+			columnas = append(columnas,
+				ColumnaExcel{Titulo: m.titulo + " ACTUAL", Decimal: m.decimal},
+				ColumnaExcel{Titulo: m.titulo + " ANTERIOR", Decimal: m.decimal},
+				ColumnaExcel{Titulo: "VAR " + m.titulo, Decimal: true, ColorSigno: true},
+				ColumnaExcel{Titulo: "VAR % " + m.titulo, Decimal: true, ColorSigno: true},
+			)
+		}
+		return columnas
+	}
+	valoresMetricas := func(actual, anterior models.MetricasPeriodo, v models.VariacionesPeriodo) []interface{} {
+		var valores []interface{}
+		for _, m := range metricas {
+			delta := m.delta(v)
+			valores = append(valores, m.valores(actual), m.valores(anterior), delta.Delta, valorPct(delta.Pct))
+		}
+		return valores
+	}
+
+	productos := HojaExcel{
+		Nombre: "Comparación Períodos",
+		Columnas: append([]ColumnaExcel{
+			{Titulo: "Codigo_Producto"},
+			{Titulo: "CODIGO VENTAS"},
+			{Titulo: "NOMBRE", Ancho: 40},
+			{Titulo: "MARCA", Ancho: 20},
+			{Titulo: "CATEGORIA", Ancho: 20},
+			{Titulo: "ESTADO"},
+		}, columnasMetricas()...),
+	}
+	for _, p := range comparacion.Productos {
+		fila := []interface{}{p.CodigoProducto, p.CodigoVentas, p.Nombre, p.Marca, p.Categoria, p.Estado}
+		productos.Filas = append(productos.Filas, append(fila, valoresMetricas(p.Actual, p.Anterior, p.Variaciones)...))
+		if p.Estado == models.EstadoPeriodoDesaparecido {
+			productos.FilasAlerta = append(productos.FilasAlerta, len(productos.Filas)-1)
+		}
+	}
+
+	// Hoja de resumen: períodos comparados y totales
+	resumen := HojaExcel{
+		Nombre: "Resumen",
+		Columnas: append([]ColumnaExcel{
+			{Titulo: "PERIODO ACTUAL", Ancho: 24},
+			{Titulo: "PERIODO ANTERIOR", Ancho: 24},
+			{Titulo: "PRODUCTOS CONTINUOS"},
+			{Titulo: "PRODUCTOS NUEVOS"},
+			{Titulo: "PRODUCTOS DESAPARECIDOS"},
+		}, columnasMetricas()...),
+	}
+	fila := []interface{}{
+		comparacion.Actual.FechaInicio + " al " + comparacion.Actual.FechaFin,
+		comparacion.Anterior.FechaInicio + " al " + comparacion.Anterior.FechaFin,
+		comparacion.ProductosContinuos,
+		comparacion.ProductosNuevos,
+		comparacion.ProductosDesaparecidos,
+	}
+	totales := comparacion.Totales
+	resumen.Filas = append(resumen.Filas, append(fila, valoresMetricas(totales.Actual, totales.Anterior, totales.Variaciones)...))
+
+	excelBytes, err := s.excelService.GenerarLibro(productos, resumen)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("Comparacion_Periodos_%s_%s_al_%s_vs_%s_al_%s.xlsx",
+		nombreSucursales(filtro.FiltroVentas()), comparacion.Actual.FechaInicio, comparacion.Actual.FechaFin,
+		comparacion.Anterior.FechaInicio, comparacion.Anterior.FechaFin)
+	return excelBytes, filename, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+func TestFiltroAnterior(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		filtro   models.ReporteFiltro
+		params   models.ParametrosComparacionPeriodos
+		esperado string // inicio..fin año desde-hasta
+	}{
+		{
+			nombre:   "año anterior",
+			filtro:   models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31"},
+			params:   models.ParametrosComparacionPeriodos{Modo: models.ModoComparacionAnioAnterior},
+			esperado: "2024-01-01..2024-01-31 2024 0-0",
+		},
+		{
+			// El 29 de febrero pasa al 28
+			nombre:   "año anterior desde un año bisiesto",
+			filtro:   models.ReporteFiltro{Anio: 2024, FechaInicio: "2024-02-01", FechaFin: "2024-02-29"},
+			params:   models.ParametrosComparacionPeriodos{Modo: models.ModoComparacionAnioAnterior},
+			esperado: "2023-02-01..2023-02-28 2023 0-0",
+		},
+		{
+			// Marzo tiene 31 días: el período anterior también
+			nombre:   "período anterior de igual duración",
+			filtro:   models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-03-01", FechaFin: "2025-03-31"},
+			params:   models.ParametrosComparacionPeriodos{Modo: models.ModoComparacionPeriodoAnterior},
+			esperado: "2025-01-29..2025-02-28 2025 0-0",
+		},
+		{
+			nombre:   "período anterior en el año previo",
+			filtro:   models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-10"},
+			params:   models.ParametrosComparacionPeriodos{Modo: models.ModoComparacionPeriodoAnterior},
+			esperado: "2024-12-22..2024-12-31 2024 0-0",
+		},
+		{
+			nombre:   "rango de años de inventario desplazado",
+			filtro:   models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31", AnioInventarioDesde: 2023, AnioInventarioHasta: 2024},
+			params:   models.ParametrosComparacionPeriodos{Modo: models.ModoComparacionAnioAnterior},
+			esperado: "2024-01-01..2024-01-31 2024 2022-2023",
+		},
+		{
+			// Las fechas explícitas tienen prioridad sobre el modo
+			nombre:   "fechas y año explícitos",
+			filtro:   models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31"},
+			params:   models.ParametrosComparacionPeriodos{Modo: models.ModoComparacionPeriodoAnterior, FechaInicioAnterior: "2023-06-01", FechaFinAnterior: "2023-06-30", AnioAnterior: 2022},
+			esperado: "2023-06-01..2023-06-30 2022 2022-2022",
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			anterior, err := filtroAnterior(c.filtro, c.params)
+			if err != nil {
+				t.Fatalf("filtroAnterior: %v", err)
+			}
+			obtenido := fmt.Sprintf("%s..%s %d %d-%d", anterior.FechaInicio, anterior.FechaFin, anterior.Anio, anterior.AnioInventarioDesde, anterior.AnioInventarioHasta)
+			if obtenido != c.esperado {
+				t.Errorf("período anterior = %s, se esperaba %s", obtenido, c.esperado)
+			}
+		})
+	}
+
+	if _, err := filtroAnterior(models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "enero"}, models.ParametrosComparacionPeriodos{}); err == nil {
+		t.Error("filtroAnterior con una fecha de fin no válida no devolvió error")
+	}
+}
+
+func TestVariacion(t *testing.T) {
+	casos := []struct {
+		actual, anterior float64
+		esperado         string
+	}{
+		{150, 100, "50 50%"},
+		{80, 100, "-20 -20%"},
+		{1.23456, 1, "0.2346 23.46%"},
+		// Sin valor anterior no hay porcentaje
+		{10, 0, "10 -"},
+		{0, 0, "0 -"},
+	}
+	for _, c := range casos {
+		v := variacion(c.actual, c.anterior)
+		pct := "-"
+		if v.Pct != nil {
+			pct = fmt.Sprintf("%v%%", *v.Pct)
+		}
+		if obtenido := fmt.Sprintf("%v %s", v.Delta, pct); obtenido != c.esperado {
+			t.Errorf("variacion(%v, %v) = %s, se esperaba %s", c.actual, c.anterior, obtenido, c.esperado)
+		}
+	}
+}
+
+func TestGenerarComparacionPeriodos(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{
+		// Enero de 2024
+		ventaPrueba("2024-01-10", "PISO", 10, 1000),
+		ventaPrueba("2024-01-12", "TECHO", 3, 2000),
+		// Enero de 2025
+		ventaPrueba("2025-01-10", "PISO", 15, 1000),
+		ventaPrueba("2025-01-11", "MURO", 4, 500),
+		// Fuera de ambos períodos
+		ventaPrueba("2025-02-10", "TECHO", 9, 2000),
+	}
+	s := servicioPrueba(t, ventas, nil)
+
+	params := models.ParametrosComparacionPeriodos{}
+	if err := params.Validar(); err != nil {
+		t.Fatalf("Validar: %v", err)
+	}
+	comparacion, err := s.GenerarComparacionPeriodos(models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31"}, params)
+	if err != nil {
+		t.Fatalf("GenerarComparacionPeriodos: %v", err)
+	}
+
+	if comparacion.Anterior.FechaInicio != "2024-01-01" || comparacion.Anterior.FechaFin != "2024-01-31" {
+		t.Errorf("período anterior %s..%s, se esperaba 2024-01-01..2024-01-31", comparacion.Anterior.FechaInicio, comparacion.Anterior.FechaFin)
+	}
+
+	var partes []string
+	for _, p := range comparacion.Productos {
+		pct := "-"
+		if p.Variaciones.VentaNetaClp.Pct != nil {
+			pct = fmt.Sprintf("%v%%", *p.Variaciones.VentaNetaClp.Pct)
+		}
+		partes = append(partes, fmt.Sprintf("%s %s %v/%v u $%d/$%d %v %s",
+			p.CodigoVentas, p.Estado, p.Actual.CantidadVendida, p.Anterior.CantidadVendida,
+			p.Actual.VentaNetaClp, p.Anterior.VentaNetaClp, p.Variaciones.VentaNetaClp.Delta, pct))
+	}
+	// Primero las mayores caídas de venta
+	esperado := strings.Join([]string{
+		"TECHO desaparecido 0/3 u $0/$6000 -6000 -100%",
+		"MURO nuevo 4/0 u $2000/$0 2000 -",
+		"PISO continuo 15/10 u $15000/$10000 5000 50%",
+	}, "\n")
+	if obtenido := strings.Join(partes, "\n"); obtenido != esperado {
+		t.Errorf("productos:\n%s\nse esperaba:\n%s", obtenido, esperado)
+	}
+
+	if comparacion.ProductosContinuos != 1 || comparacion.ProductosNuevos != 1 || comparacion.ProductosDesaparecidos != 1 {
+		t.Errorf("continuos %d, nuevos %d, desaparecidos %d; se esperaba 1 de cada uno",
+			comparacion.ProductosContinuos, comparacion.ProductosNuevos, comparacion.ProductosDesaparecidos)
+	}
+
+	totales := comparacion.Totales
+	if totales.Actual.VentaNetaClp != 17000 || totales.Anterior.VentaNetaClp != 16000 || totales.Variaciones.VentaNetaClp.Delta != 1000 {
+		t.Errorf("totales $%d/$%d variación %v, se esperaba $17000/$16000 variación 1000",
+			totales.Actual.VentaNetaClp, totales.Anterior.VentaNetaClp, totales.Variaciones.VentaNetaClp.Delta)
+	}
+}
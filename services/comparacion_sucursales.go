@@ -0,0 +1,258 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// GenerarComparacionSucursales compara, para cada producto, las unidades, la venta y la
+// rotación en cada sucursal del filtro. El inventario no se lleva por sucursal, por lo que
+// la rotación de cada sucursal usa el inventario promedio total del producto: la suma de
+// las rotaciones por sucursal es la rotación total.
+func (s *ReporteService) GenerarComparacionSucursales(filtro models.ReporteFiltro) (*models.ComparacionSucursales, error) {
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	// Con una sola sucursal no hay desglose: todas las ventas son de esa sucursal
+	if len(filtro.Sucursales) == 1 {
+		for _, reportes := range [][]models.ReporteCombinado{coincidentes, sinCoincidencia} {
+			for i := range reportes {
+				reportes[i].VentasPorSucursal = desgloseUnico(reportes[i], filtro.Sucursales[0])
+			}
+		}
+	}
+
+	sucursales := filtro.Sucursales
+	if len(sucursales) == 0 {
+		desgloses := make([][]models.VentaSucursal, 0, len(coincidentes)+len(sinCoincidencia))
+		for _, r := range coincidentes {
+			desgloses = append(desgloses, r.VentasPorSucursal)
+		}
+		for _, r := range sinCoincidencia {
+			desgloses = append(desgloses, r.VentasPorSucursal)
+		}
+		sucursales = SucursalesConVentas(desgloses...)
+	}
+
+	dias := diasPeriodo(filtro.FechaInicio, filtro.FechaFin, time.Now())
+	comparacion := &models.ComparacionSucursales{
+		FechaInicio: filtro.FechaInicio,
+		FechaFin:    filtro.FechaFin,
+		DiasPeriodo: dias,
+		Sucursales:  sucursales,
+		Productos:   make([]models.ComparacionProducto, 0, len(coincidentes)+len(sinCoincidencia)),
+	}
+
+	for _, r := range coincidentes {
+		comparacion.Productos = append(comparacion.Productos, compararProducto(r, sucursales, dias, false))
+	}
+	for _, r := range sinCoincidencia {
+		comparacion.Productos = append(comparacion.Productos, compararProducto(r, sucursales, dias, true))
+	}
+
+	sort.SliceStable(comparacion.Productos, func(i, j int) bool {
+		return comparacion.Productos[i].VentaNetaClp > comparacion.Productos[j].VentaNetaClp
+	})
+
+	comparacion.Totales = totalesPorSucursal(coincidentes, sinCoincidencia, sucursales, dias)
+	return comparacion, nil
+}
+
+// desgloseUnico asigna todas las ventas de la fila a una sucursal
+func desgloseUnico(r models.ReporteCombinado, sucursal int) []models.VentaSucursal {
+	if r.CantidadTransacciones == 0 {
+		return nil
+	}
+	return []models.VentaSucursal{{
+		CodigoProducto:       r.CodigoVentas,
+		Sucursal:             sucursal,
+		CantidadTotalVendida: r.CantidadVendida,
+		TotalVentasCLP:       r.VentaNetaTotalClp,
+		UltimaFechaVenta:     r.UltimaFechaVenta,
+		CantidadDeVentas:     r.CantidadTransacciones,
+	}}
+}
+
+// inventarioPromedio replica el inventario promedio usado por calcularRotacion
+func inventarioPromedio(r models.ReporteCombinado) float64 {
+	return (r.CantidadIngresada + r.SaldoPeriodo) / 2
+}
+
+// compararProducto calcula las métricas de un producto en cada sucursal
+func compararProducto(r models.ReporteCombinado, sucursales []int, dias int, sinInventario bool) models.ComparacionProducto {
+	producto := models.ComparacionProducto{
+		CodigoProducto:     r.CodigoProducto,
+		CodigoVentas:       r.CodigoVentas,
+		Nombre:             r.Nombre,
+		Marca:              r.Marca,
+		Categoria:          r.Categoria,
+		SinInventario:      sinInventario,
+		SaldoPeriodo:       r.SaldoPeriodo,
+		CantidadVendida:    r.CantidadVendida,
+		VentaNetaClp:       r.VentaNetaTotalClp,
+		RotacionInventario: r.RotacionInventario,
+		Sucursales:         make([]models.MetricasSucursal, 0, len(sucursales)),
+	}
+
+	promedio := inventarioPromedio(r)
+	for _, suc := range sucursales {
+		venta, _ := ventaEnSucursal(r.VentasPorSucursal, suc)
+		metricas := metricasSucursal(venta, dias)
+		if promedio > 0 {
+			metricas.RotacionInventario = venta.CantidadTotalVendida / promedio
+		}
+		if r.CantidadVendida > 0 {
+			metricas.Participacion = venta.CantidadTotalVendida / r.CantidadVendida * 100
+		}
+		producto.Sucursales = append(producto.Sucursales, metricas)
+	}
+	return producto
+}
+
+// metricasSucursal convierte las ventas de una sucursal en métricas comparables
+func metricasSucursal(venta models.VentaSucursal, dias int) models.MetricasSucursal {
+	return models.MetricasSucursal{
+		Sucursal:             venta.Sucursal,
+		CantidadVendida:      venta.CantidadTotalVendida,
+		VentaNetaClp:         venta.TotalVentasCLP,
+		CantidadVentas:       venta.CantidadDeVentas,
+		UltimaFechaVenta:     venta.UltimaFechaVenta,
+		VelocidadVentaDiaria: venta.CantidadTotalVendida / float64(dias),
+	}
+}
+
+// totalesPorSucursal suma las ventas de todos los productos en cada sucursal. La rotación
+// total de la sucursal solo considera los productos con inventario.
+func totalesPorSucursal(coincidentes, sinCoincidencia []models.ReporteCombinado, sucursales []int, dias int) []models.MetricasSucursal {
+	var promedioTotal float64
+	for _, r := range coincidentes {
+		promedioTotal += inventarioPromedio(r)
+	}
+
+	totales := make([]models.MetricasSucursal, 0, len(sucursales))
+	var cantidadTotal float64
+	for _, suc := range sucursales {
+		total := models.VentaSucursal{Sucursal: suc}
+		var vendidaConInventario float64
+		for _, reportes := range [][]models.ReporteCombinado{coincidentes, sinCoincidencia} {
+			for _, r := range reportes {
+				venta, ok := ventaEnSucursal(r.VentasPorSucursal, suc)
+				if !ok {
+					continue
+				}
+				total.CantidadTotalVendida += venta.CantidadTotalVendida
+				total.TotalVentasCLP += venta.TotalVentasCLP
+				total.CantidadDeVentas += venta.CantidadDeVentas
+				if venta.UltimaFechaVenta > total.UltimaFechaVenta {
+					total.UltimaFechaVenta = venta.UltimaFechaVenta
+				}
+			}
+		}
+		for _, r := range coincidentes {
+			venta, _ := ventaEnSucursal(r.VentasPorSucursal, suc)
+			vendidaConInventario += venta.CantidadTotalVendida
+		}
+
+		metricas := metricasSucursal(total, dias)
+		if promedioTotal > 0 {
+			metricas.RotacionInventario = vendidaConInventario / promedioTotal
+		}
+		cantidadTotal += total.CantidadTotalVendida
+		totales = append(totales, metricas)
+	}
+
+	if cantidadTotal > 0 {
+		for i := range totales {
+			totales[i].Participacion = totales[i].CantidadVendida / cantidadTotal * 100
+		}
+	}
+	return totales
+}
+
+// ExportarComparacionSucursales exporta la comparación entre sucursales a Excel
+func (s *ReporteService) ExportarComparacionSucursales(filtro models.ReporteFiltro) ([]byte, string, error) {
+	comparacion, err := s.GenerarComparacionSucursales(filtro)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Hoja principal: una fila por producto con un bloque de columnas por sucursal
+	productos := HojaExcel{
+		Nombre: "Comparación Sucursales",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Codigo_Producto"},
+			{Titulo: "NOMBRE", Ancho: 40},
+			{Titulo: "MARCA", Ancho: 20},
+			{Titulo: "CATEGORIA", Ancho: 20},
+			{Titulo: "SALDO PERIODO", Decimal: true},
+			{Titulo: "CANTIDAD VENDIDA TOTAL", Decimal: true},
+			{Titulo: "VENTA NETA TOTAL CLP"},
+			{Titulo: "ROTACION TOTAL", Decimal: true},
+		},
+	}
+	for _, suc := range comparacion.Sucursales {
+		productos.Columnas = append(productos.Columnas,
+			ColumnaExcel{Titulo: fmt.Sprintf("CANTIDAD SUC %d", suc), Decimal: true},
+			ColumnaExcel{Titulo: fmt.Sprintf("VENTA CLP SUC %d", suc)},
+			ColumnaExcel{Titulo: fmt.Sprintf("ROTACION SUC %d", suc), Decimal: true},
+			ColumnaExcel{Titulo: fmt.Sprintf("%% UNIDADES SUC %d", suc), Decimal: true},
+		)
+	}
+	for _, p := range comparacion.Productos {
+		fila := []interface{}{
+			p.CodigoProducto,
+			p.Nombre,
+			p.Marca,
+			p.Categoria,
+			p.SaldoPeriodo,
+			p.CantidadVendida,
+			p.VentaNetaClp,
+			p.RotacionInventario,
+		}
+		for _, m := range p.Sucursales {
+			fila = append(fila, m.CantidadVendida, m.VentaNetaClp, m.RotacionInventario, m.Participacion)
+		}
+		productos.Filas = append(productos.Filas, fila)
+	}
+
+	// Hoja de totales por sucursal
+	totales := HojaExcel{
+		Nombre: "Totales por Sucursal",
+		Columnas: []ColumnaExcel{
+			{Titulo: "SUCURSAL"},
+			{Titulo: "CANTIDAD VENDIDA", Decimal: true},
+			{Titulo: "VENTA NETA CLP"},
+			{Titulo: "CANTIDAD VENTAS"},
+			{Titulo: "ULTIMA FECHA VENTA"},
+			{Titulo: "VELOCIDAD VENTA DIARIA", Decimal: true},
+			{Titulo: "ROTACION INVENTARIO", Decimal: true},
+			{Titulo: "% UNIDADES", Decimal: true},
+		},
+	}
+	for _, t := range comparacion.Totales {
+		totales.Filas = append(totales.Filas, []interface{}{
+			t.Sucursal,
+			t.CantidadVendida,
+			t.VentaNetaClp,
+			t.CantidadVentas,
+			t.UltimaFechaVenta,
+			t.VelocidadVentaDiaria,
+			t.RotacionInventario,
+			t.Participacion,
+		})
+	}
+
+	excelBytes, err := s.excelService.GenerarLibro(productos, totales)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("Comparacion_%s_%s_al_%s.xlsx",
+		nombreSucursales(filtro.FiltroVentas()), filtro.FechaInicio, filtro.FechaFin)
+	return excelBytes, filename, nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ventaSucursal crea una línea de boleta de la sucursal indicada
+func ventaSucursal(sucursal int, codigo string, cantidad float64, precio int) datasource.LineaVentaFixture {
+	v := ventaPrueba("2025-01-05", codigo, cantidad, precio)
+	v.Sucursal = sucursal
+	v.CodigoDocumento = fmt.Sprintf("%d-%s", sucursal, v.CodigoDocumento)
+	return v
+}
+
+// describirMetricas resume sucursal, unidades, venta, participación y velocidad diaria
+func describirMetricas(metricas []models.MetricasSucursal) string {
+	var partes []string
+	for _, m := range metricas {
+		partes = append(partes, fmt.Sprintf("%d: %v u $%d %v%% %v/día", m.Sucursal, m.CantidadVendida, m.VentaNetaClp, redondear(m.Participacion, 2), m.VelocidadVentaDiaria))
+	}
+	return strings.Join(partes, ", ")
+}
+
+// ventasSucursalesPrueba vende PISO en 211, 212 y 213, MURO solo en 212 y SOLO-VENTA, sin
+// inventario, en 211
+func ventasSucursalesPrueba() ([]datasource.LineaVentaFixture, []datasource.IngresoFixture) {
+	ventas := []datasource.LineaVentaFixture{
+		ventaSucursal(211, "PISO", 6, 1000),
+		ventaSucursal(212, "PISO", 4, 1000),
+		ventaSucursal(213, "PISO", 10, 1000),
+		ventaSucursal(212, "MURO", 2, 500),
+		ventaSucursal(211, "SOLO-VENTA", 1, 1000),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("PISO", "Z1", "2024-12-01", 100, 400),
+		ingresoPrueba("MURO", "Z1", "2024-12-01", 50, 200),
+	}
+	return ventas, ingresos
+}
+
+func TestGetVentasAgrupadasPorSucursal(t *testing.T) {
+	ventas, _ := ventasSucursalesPrueba()
+	s := NewVentasService(datasource.NewFixturesFromData(ventas, nil), NewExcelService())
+
+	casos := []struct {
+		nombre     string
+		sucursales []int
+		esperado   string
+	}{
+		// Una sola sucursal no lleva desglose
+		{"una sucursal", []int{211}, "PISO 6 [], SOLO-VENTA 1 []"},
+		{"lista de sucursales", []int{211, 212}, "MURO 2 [212:2], PISO 10 [211:6 212:4], SOLO-VENTA 1 [211:1]"},
+		{"todas", nil, "MURO 2 [212:2], PISO 20 [211:6 212:4 213:10], SOLO-VENTA 1 [211:1]"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			agrupadas, err := s.GetVentasAgrupadas(models.VentasFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10", Sucursales: c.sucursales})
+			if err != nil {
+				t.Fatalf("GetVentasAgrupadas: %v", err)
+			}
+			var partes []string
+			for _, v := range agrupadas {
+				var desglose []string
+				for _, suc := range v.VentasPorSucursal {
+					desglose = append(desglose, fmt.Sprintf("%d:%v", suc.Sucursal, suc.CantidadTotalVendida))
+				}
+				partes = append(partes, fmt.Sprintf("%s %v [%s]", v.CodigoProducto, v.CantidadTotalVendida, strings.Join(desglose, " ")))
+			}
+			if obtenido := strings.Join(partes, ", "); obtenido != c.esperado {
+				t.Errorf("ventas = %s, se esperaba %s", obtenido, c.esperado)
+			}
+		})
+	}
+}
+
+func TestGenerarComparacionSucursales(t *testing.T) {
+	ventas, ingresos := ventasSucursalesPrueba()
+	s := servicioPrueba(t, ventas, ingresos)
+
+	comparacion, err := s.GenerarComparacionSucursales(models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10", Sucursales: []int{211, 212}})
+	if err != nil {
+		t.Fatalf("GenerarComparacionSucursales: %v", err)
+	}
+	if fmt.Sprint(comparacion.Sucursales) != "[211 212]" || comparacion.DiasPeriodo != 10 {
+		t.Errorf("sucursales %v en %d días, se esperaba [211 212] en 10", comparacion.Sucursales, comparacion.DiasPeriodo)
+	}
+
+	productos := make(map[string]models.ComparacionProducto)
+	for _, p := range comparacion.Productos {
+		productos[p.CodigoVentas] = p
+	}
+	esperados := map[string]string{
+		"PISO":       "211: 6 u $6000 60% 0.6/día, 212: 4 u $4000 40% 0.4/día",
+		"MURO":       "211: 0 u $0 0% 0/día, 212: 2 u $1000 100% 0.2/día",
+		"SOLO-VENTA": "211: 1 u $1000 100% 0.1/día, 212: 0 u $0 0% 0/día",
+	}
+	if len(productos) != len(esperados) {
+		t.Errorf("productos = %d, se esperaban %d", len(productos), len(esperados))
+	}
+	for clave, esperado := range esperados {
+		if obtenido := describirMetricas(productos[clave].Sucursales); obtenido != esperado {
+			t.Errorf("%s:\n%s\nse esperaba:\n%s", clave, obtenido, esperado)
+		}
+	}
+	if !productos["SOLO-VENTA"].SinInventario {
+		t.Error("SOLO-VENTA debería quedar sin inventario")
+	}
+
+	// La rotación de cada sucursal usa el inventario promedio total: las rotaciones suman la total
+	piso := productos["PISO"]
+	if !aproximado(piso.Sucursales[0].RotacionInventario+piso.Sucursales[1].RotacionInventario, piso.RotacionInventario) {
+		t.Errorf("rotación por sucursal %v + %v, se esperaba %v", piso.Sucursales[0].RotacionInventario, piso.Sucursales[1].RotacionInventario, piso.RotacionInventario)
+	}
+
+	// Los totales incluyen las ventas sin inventario
+	if obtenido, esperado := describirMetricas(comparacion.Totales), "211: 7 u $7000 53.85% 0.7/día, 212: 6 u $5000 46.15% 0.6/día"; obtenido != esperado {
+		t.Errorf("totales:\n%s\nse esperaba:\n%s", obtenido, esperado)
+	}
+
+	// Con todas las sucursales se comparan las que tienen ventas
+	todas, err := s.GenerarComparacionSucursales(models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10"})
+	if err != nil {
+		t.Fatalf("GenerarComparacionSucursales: %v", err)
+	}
+	if fmt.Sprint(todas.Sucursales) != "[211 212 213]" {
+		t.Errorf("sucursales = %v, se esperaba [211 212 213]", todas.Sucursales)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// acumuladoDocumentos suma líneas de venta contando sus documentos distintos
+type acumuladoDocumentos struct {
+	resumen    models.ResumenTipoDocumento
+	documentos map[string]bool
+}
+
+// agregar suma una línea con su valor (venta o, en anulados, el total original)
+func (a *acumuladoDocumentos) agregar(v models.VentaDetallada, valor int) {
+	if a.documentos == nil {
+		a.documentos = make(map[string]bool)
+	}
+	a.documentos[claveDocumento(v)] = true
+	a.resumen.Lineas++
+	a.resumen.Unidades += v.Cantidad
+	a.resumen.VentaClp += valor
+}
+
+// cerrar devuelve el resumen con los documentos distintos y el ticket promedio
+func (a *acumuladoDocumentos) cerrar(tipo string) models.ResumenTipoDocumento {
+	r := a.resumen
+	r.TipoDocumento = tipo
+	r.Documentos = len(a.documentos)
+	if r.Documentos > 0 {
+		r.TicketPromedioClp = redondear(float64(r.VentaClp)/float64(r.Documentos), 2)
+	}
+	return r
+}
+
+// claveDocumento identifica un documento de venta: tipo, sucursal y correlativo
+func claveDocumento(v models.VentaDetallada) string {
+	return fmt.Sprintf("%s-%d-%s", v.TipoDocumento, v.Sucursal, v.CodigoDocumento)
+}
+
+// tiposDocumento devuelve los tipos de documento presentes, boletas y facturas primero
+func tiposDocumento(porTipo map[string]*acumuladoDocumentos) []string {
+	tipos := []string{models.TipoDocumentoBoleta, models.TipoDocumentoFactura}
+	var otros []string
+	for tipo := range porTipo {
+		if tipo != models.TipoDocumentoBoleta && tipo != models.TipoDocumentoFactura {
+			otros = append(otros, tipo)
+		}
+	}
+	sort.Strings(otros)
+	return append(tipos, otros...)
+}
+
+// GenerarReporteDocumentos desglosa las unidades, la venta y los documentos del período por tipo de
+// documento. Los documentos anulados no se incluyen en el desglose: se informan por separado con su
+// valor original, por tipo, por documento y por producto afectado.
+func (s *VentasService) GenerarReporteDocumentos(filtro models.VentasFiltro) (*models.ReporteDocumentos, error) {
+	// Los anulados se informan siempre, aunque el filtro los excluya de las demás consultas
+	filtro.ExcluirNulas = false
+	ventas, err := s.GetVentasDetalladas(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	vigentes := make(map[string]*acumuladoDocumentos)
+	anulados := make(map[string]*acumuladoDocumentos)
+	var total, totalAnulados acumuladoDocumentos
+	documentosAnulados := make(map[string]*models.DocumentoAnulado)
+	productosAnulados := make(map[string]*models.ProductoAnulado)
+	docsProducto := make(map[string]map[string]bool)
+	for _, v := range ventas {
+		if !v.Nula {
+			if vigentes[v.TipoDocumento] == nil {
+				vigentes[v.TipoDocumento] = &acumuladoDocumentos{}
+			}
+			vigentes[v.TipoDocumento].agregar(v, v.TotalVentaCLP)
+			total.agregar(v, v.TotalVentaCLP)
+			continue
+		}
+
+		if anulados[v.TipoDocumento] == nil {
+			anulados[v.TipoDocumento] = &acumuladoDocumentos{}
+		}
+		anulados[v.TipoDocumento].agregar(v, v.TotalAnuladoCLP)
+		totalAnulados.agregar(v, v.TotalAnuladoCLP)
+
+		clave := claveDocumento(v)
+		doc, ok := documentosAnulados[clave]
+		if !ok {
+			fecha, _ := parsearFechaVenta(v.FechaEmision)
+			doc = &models.DocumentoAnulado{
+				TipoDocumento:   v.TipoDocumento,
+				CodigoDocumento: v.CodigoDocumento,
+				Sucursal:        v.Sucursal,
+				Fecha:           formatearFecha(fecha),
+				Cliente:         v.Cliente,
+			}
+			documentosAnulados[clave] = doc
+		}
+		doc.Lineas++
+		doc.Unidades += v.Cantidad
+		doc.ValorClp += v.TotalAnuladoCLP
+
+		producto, ok := productosAnulados[v.CodigoProducto]
+		if !ok {
+			producto = &models.ProductoAnulado{CodigoProducto: v.CodigoProducto, NombreProducto: v.NombreProducto}
+			productosAnulados[v.CodigoProducto] = producto
+			docsProducto[v.CodigoProducto] = make(map[string]bool)
+		}
+		docsProducto[v.CodigoProducto][clave] = true
+		producto.Unidades += v.Cantidad
+		producto.ValorClp += v.TotalAnuladoCLP
+	}
+
+	reporte := &models.ReporteDocumentos{
+		FechaInicio: filtro.FechaInicio,
+		FechaFin:    filtro.FechaFin,
+		Sucursales:  filtro.Sucursales,
+		PorTipo:     []models.ResumenTipoDocumento{},
+		Total:       total.cerrar("TOTAL"),
+	}
+	for _, tipo := range tiposDocumento(vigentes) {
+		if acumulado, ok := vigentes[tipo]; ok {
+			reporte.PorTipo = append(reporte.PorTipo, acumulado.cerrar(tipo))
+		}
+	}
+
+	resumenAnulados := totalAnulados.cerrar("TOTAL")
+	reporte.Anulados = models.ResumenAnulados{
+		Documentos: resumenAnulados.Documentos,
+		Lineas:     resumenAnulados.Lineas,
+		Unidades:   resumenAnulados.Unidades,
+		ValorClp:   resumenAnulados.VentaClp,
+		PorTipo:    []models.ResumenTipoDocumento{},
+		Productos:  make([]models.ProductoAnulado, 0, len(productosAnulados)),
+		Detalle:    make([]models.DocumentoAnulado, 0, len(documentosAnulados)),
+	}
+	if todos := reporte.Total.Documentos + resumenAnulados.Documentos; todos > 0 {
+		reporte.Anulados.DocumentosPct = redondear(float64(resumenAnulados.Documentos)/float64(todos)*100, 2)
+	}
+	for _, tipo := range tiposDocumento(anulados) {
+		if acumulado, ok := anulados[tipo]; ok {
+			reporte.Anulados.PorTipo = append(reporte.Anulados.PorTipo, acumulado.cerrar(tipo))
+		}
+	}
+	for codigo, producto := range productosAnulados {
+		producto.Documentos = len(docsProducto[codigo])
+		reporte.Anulados.Productos = append(reporte.Anulados.Productos, *producto)
+	}
+	sort.Slice(reporte.Anulados.Productos, func(i, j int) bool {
+		pi, pj := reporte.Anulados.Productos[i], reporte.Anulados.Productos[j]
+		if pi.ValorClp != pj.ValorClp {
+			return pi.ValorClp > pj.ValorClp
+		}
+		return pi.CodigoProducto < pj.CodigoProducto
+	})
+	for _, doc := range documentosAnulados {
+		reporte.Anulados.Detalle = append(reporte.Anulados.Detalle, *doc)
+	}
+	sort.Slice(reporte.Anulados.Detalle, func(i, j int) bool {
+		di, dj := reporte.Anulados.Detalle[i], reporte.Anulados.Detalle[j]
+		if di.Fecha != dj.Fecha {
+			return di.Fecha < dj.Fecha
+		}
+		if di.TipoDocumento != dj.TipoDocumento {
+			return di.TipoDocumento < dj.TipoDocumento
+		}
+		return di.CodigoDocumento < dj.CodigoDocumento
+	})
+	return reporte, nil
+}
+
+// ExportarReporteDocumentos exporta el desglose por tipo de documento y los anulados a Excel
+func (s *VentasService) ExportarReporteDocumentos(filtro models.VentasFiltro) ([]byte, string, error) {
+	reporte, err := s.GenerarReporteDocumentos(filtro)
+	if err != nil {
+		return nil, "", err
+	}
+
+	porTipo := HojaExcel{
+		Nombre: "Por Tipo de Documento",
+		Columnas: []ColumnaExcel{
+			{Titulo: "TIPO DOCUMENTO", Ancho: 24},
+			{Titulo: "DOCUMENTOS"},
+			{Titulo: "LINEAS"},
+			{Titulo: "UNIDADES", Decimal: true},
+			{Titulo: "VENTA CLP"},
+			{Titulo: "TICKET PROMEDIO CLP", Decimal: true},
+		},
+	}
+	filaTipo := func(nombre string, r models.ResumenTipoDocumento) []interface{} {
+		return []interface{}{nombre, r.Documentos, r.Lineas, r.Unidades, r.VentaClp, r.TicketPromedioClp}
+	}
+	for _, r := range reporte.PorTipo {
+		porTipo.Filas = append(porTipo.Filas, filaTipo(r.TipoDocumento, r))
+	}
+	porTipo.FilasResaltadas = append(porTipo.FilasResaltadas, len(porTipo.Filas))
+	porTipo.Filas = append(porTipo.Filas, filaTipo("TOTAL VIGENTES", reporte.Total))
+	for _, r := range reporte.Anulados.PorTipo {
+		porTipo.FilasAlerta = append(porTipo.FilasAlerta, len(porTipo.Filas))
+		porTipo.Filas = append(porTipo.Filas, filaTipo(r.TipoDocumento+" ANULADA", r))
+	}
+
+	documentos := HojaExcel{
+		Nombre: "Documentos Anulados",
+		Columnas: []ColumnaExcel{
+			{Titulo: "FECHA"},
+			{Titulo: "TIPO DOCUMENTO"},
+			{Titulo: "CODIGO DOCUMENTO"},
+			{Titulo: "SUCURSAL"},
+			{Titulo: "Cliente", Ancho: 40},
+			{Titulo: "LINEAS"},
+			{Titulo: "UNIDADES", Decimal: true},
+			{Titulo: "VALOR ORIGINAL CLP"},
+		},
+	}
+	for _, d := range reporte.Anulados.Detalle {
+		documentos.Filas = append(documentos.Filas, []interface{}{
+			d.Fecha, d.TipoDocumento, d.CodigoDocumento, d.Sucursal, d.Cliente, d.Lineas, d.Unidades, d.ValorClp,
+		})
+	}
+
+	productos := HojaExcel{
+		Nombre: "Productos Anulados",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Código de Producto"},
+			{Titulo: "Nombre del Producto", Ancho: 40},
+			{Titulo: "DOCUMENTOS"},
+			{Titulo: "UNIDADES", Decimal: true},
+			{Titulo: "VALOR ORIGINAL CLP"},
+		},
+	}
+	for _, p := range reporte.Anulados.Productos {
+		productos.Filas = append(productos.Filas, []interface{}{
+			p.CodigoProducto, p.NombreProducto, p.Documentos, p.Unidades, p.ValorClp,
+		})
+	}
+
+	excelBytes, err := s.excelService.GenerarLibro(porTipo, documentos, productos)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("Documentos_Venta_%s_%s_al_%s.xlsx",
+		nombreSucursales(filtro), filtro.FechaInicio, filtro.FechaFin)
+	return excelBytes, filename, nil
+}
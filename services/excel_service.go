@@ -42,24 +42,10 @@ func (s *ExcelService) GenerateExcelFromQuery(db interface{}, query string, args
 }
 
 // GenerateExcel genera un archivo Excel a partir de filas SQL o MockRows
-func (s *ExcelService) GenerateExcel(rows interface{}, filename string) ([]byte, error) {
-	var columns []string
-	var err error
-
-	// Determinar el tipo de rows y obtener las columnas
-	switch r := rows.(type) {
-	case *sql.Rows:
-		columns, err = r.Columns()
-		if err != nil {
-			return nil, err
-		}
-	case *db.MockRows:
-		columns, err = r.Columns()
-		if err != nil {
-			return nil, err
-		}
-	default:
-		return nil, fmt.Errorf("tipo de filas no soportado: %T", rows)
+func (s *ExcelService) GenerateExcel(rows db.Rows, filename string) ([]byte, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
 	}
 
 	// Crear un nuevo archivo Excel
@@ -106,42 +92,24 @@ func (s *ExcelService) GenerateExcel(rows interface{}, filename string) ([]byte,
 	// Agregar datos
 	rowIndex := 2 // comenzamos desde la fila 2
 
-	// Procesar filas según el tipo
-	switch r := rows.(type) {
-	case *sql.Rows:
-		for r.Next() {
-			values := make([]interface{}, len(columns))
-			scanArgs := make([]interface{}, len(columns))
-
-			for i := range values {
-				scanArgs[i] = &values[i]
-			}
-
-			if err := r.Scan(scanArgs...); err != nil {
-				return nil, err
-			}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		scanArgs := make([]interface{}, len(columns))
 
-			// Escribir valores en el Excel
-			s.setCellValues(f, sheetName, rowIndex, columns, values)
-			rowIndex++
+		for i := range values {
+			scanArgs[i] = &values[i]
 		}
-	case *db.MockRows:
-		for r.Next() {
-			values := make([]interface{}, len(columns))
-			scanArgs := make([]interface{}, len(columns))
-
-			for i := range values {
-				scanArgs[i] = &values[i]
-			}
 
-			if err := r.Scan(scanArgs...); err != nil {
-				return nil, err
-			}
-
-			// Escribir valores en el Excel
-			s.setCellValues(f, sheetName, rowIndex, columns, values)
-			rowIndex++
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
 		}
+
+		// Escribir valores en el Excel
+		s.setCellValues(f, sheetName, rowIndex, columns, values)
+		rowIndex++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
 	// Auto-ajustar columnas
@@ -159,6 +127,214 @@ func (s *ExcelService) GenerateExcel(rows interface{}, filename string) ([]byte,
 	return buffer.Bytes(), nil
 }
 
+// ColumnaExcel describe el encabezado y el formato de una columna de una hoja
+type ColumnaExcel struct {
+	Titulo  string
+	Ancho   float64 // 0 usa el ancho por defecto
+	Decimal bool    // Aplica formato numérico con 2 decimales
+
+	// Colorea los valores positivos en verde y los negativos en rojo (formato condicional)
+	ColorSigno bool
+}
+
+// HojaExcel describe una hoja generada a partir de datos en memoria
+type HojaExcel struct {
+	Nombre   string
+	Columnas []ColumnaExcel
+	Filas    [][]interface{}
+
+	// Índices (desde 0) de las filas a destacar en negrita, como subtotales
+	FilasResaltadas []int
+
+	// Índices (desde 0) de las filas a marcar como alerta en rojo, como ventas bajo costo
+	FilasAlerta []int
+}
+
+// EscribirHoja crea una hoja en el libro con encabezados destacados y los datos indicados
+func (s *ExcelService) EscribirHoja(f *excelize.File, hoja HojaExcel) error {
+	if _, err := f.NewSheet(hoja.Nombre); err != nil {
+		return err
+	}
+
+	styleHeader, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+			Size: 11,
+		},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{"#DCE6F1"},
+			Pattern: 1,
+		},
+		Border: []excelize.Border{
+			{Type: "bottom", Color: "#000000", Style: 1},
+		},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+			Vertical:   "center",
+			WrapText:   true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Estilo para números
+	styleNumber, err := f.NewStyle(&excelize.Style{
+		NumFmt: 2, // Formato numérico con 2 decimales
+	})
+	if err != nil {
+		return err
+	}
+
+	// Estilos para filas destacadas (subtotales)
+	styleResaltado, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#F2F2F2"}, Pattern: 1},
+	})
+	if err != nil {
+		return err
+	}
+	styleResaltadoNumber, err := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true},
+		Fill:   excelize.Fill{Type: "pattern", Color: []string{"#F2F2F2"}, Pattern: 1},
+		NumFmt: 2,
+	})
+	if err != nil {
+		return err
+	}
+	// Estilos para filas de alerta
+	styleAlerta, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "#9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#FFC7CE"}, Pattern: 1},
+	})
+	if err != nil {
+		return err
+	}
+	styleAlertaNumber, err := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Color: "#9C0006"},
+		Fill:   excelize.Fill{Type: "pattern", Color: []string{"#FFC7CE"}, Pattern: 1},
+		NumFmt: 2,
+	})
+	if err != nil {
+		return err
+	}
+	resaltadas := make(map[int]bool, len(hoja.FilasResaltadas))
+	for _, i := range hoja.FilasResaltadas {
+		resaltadas[i] = true
+	}
+	alertas := make(map[int]bool, len(hoja.FilasAlerta))
+	for _, i := range hoja.FilasAlerta {
+		alertas[i] = true
+	}
+
+	// Escribir encabezados y ajustar anchos
+	for i, col := range hoja.Columnas {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(hoja.Nombre, cell, col.Titulo)
+		f.SetCellStyle(hoja.Nombre, cell, cell, styleHeader)
+
+		width := col.Ancho
+		if width == 0 {
+			width = 15.0 // Ancho base
+		}
+		colName, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetColWidth(hoja.Nombre, colName, colName, width)
+	}
+
+	// Escribir datos
+	for i, fila := range hoja.Filas {
+		row := i + 2 // La fila 1 es para encabezados
+		for j, val := range fila {
+			cell, _ := excelize.CoordinatesToCellName(j+1, row)
+			f.SetCellValue(hoja.Nombre, cell, val)
+
+			// Aplicar estilo numérico a columnas apropiadas
+			decimal := j < len(hoja.Columnas) && hoja.Columnas[j].Decimal
+			switch {
+			case resaltadas[i] && decimal:
+				f.SetCellStyle(hoja.Nombre, cell, cell, styleResaltadoNumber)
+			case resaltadas[i]:
+				f.SetCellStyle(hoja.Nombre, cell, cell, styleResaltado)
+			case alertas[i] && decimal:
+				f.SetCellStyle(hoja.Nombre, cell, cell, styleAlertaNumber)
+			case alertas[i]:
+				f.SetCellStyle(hoja.Nombre, cell, cell, styleAlerta)
+			case decimal:
+				f.SetCellStyle(hoja.Nombre, cell, cell, styleNumber)
+			}
+		}
+	}
+
+	return s.aplicarColorSigno(f, hoja)
+}
+
+// aplicarColorSigno agrega el formato condicional de las columnas con ColorSigno: verde si el
+// valor es positivo y rojo si es negativo
+func (s *ExcelService) aplicarColorSigno(f *excelize.File, hoja HojaExcel) error {
+	if len(hoja.Filas) == 0 {
+		return nil
+	}
+	positivo, err := f.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "#006100"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#C6EFCE"}, Pattern: 1},
+	})
+	if err != nil {
+		return err
+	}
+	negativo, err := f.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "#9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#FFC7CE"}, Pattern: 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, col := range hoja.Columnas {
+		if !col.ColorSigno {
+			continue
+		}
+		colName, _ := excelize.ColumnNumberToName(i + 1)
+		rango := fmt.Sprintf("%s2:%s%d", colName, colName, len(hoja.Filas)+1)
+		err := f.SetConditionalFormat(hoja.Nombre, rango, []excelize.ConditionalFormatOptions{
+			{Type: "cell", Criteria: ">", Format: positivo, Value: "0"},
+			{Type: "cell", Criteria: "<", Format: negativo, Value: "0"},
+		})
+		if err != nil {
+			return fmt.Errorf("error al aplicar formato condicional: %v", err)
+		}
+	}
+	return nil
+}
+
+// GenerarLibro crea un libro Excel con las hojas indicadas y lo devuelve como bytes
+func (s *ExcelService) GenerarLibro(hojas ...HojaExcel) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for _, hoja := range hojas {
+		if err := s.EscribirHoja(f, hoja); err != nil {
+			return nil, err
+		}
+	}
+
+	return LibroABytes(f)
+}
+
+// LibroABytes elimina la hoja predeterminada "Sheet1", activa la primera hoja y serializa el libro
+func LibroABytes(f *excelize.File) ([]byte, error) {
+	if len(f.GetSheetList()) > 1 {
+		f.DeleteSheet("Sheet1")
+	}
+	f.SetActiveSheet(0)
+
+	var buffer bytes.Buffer
+	if err := f.Write(&buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
 // setCellValues escribe los valores en las celdas del Excel con formato adecuado
 func (s *ExcelService) setCellValues(f *excelize.File, sheetName string, rowIndex int, columns []string, values []interface{}) {
 	for i := range columns {
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ventaPrueba crea una línea de boleta de la sucursal 211 con cantidad unidades a precio
+func ventaPrueba(fecha, codigo string, cantidad float64, precio int) datasource.LineaVentaFixture {
+	total := int(cantidad * float64(precio))
+	return datasource.LineaVentaFixture{
+		Fecha:             fecha,
+		Sucursal:          211,
+		TipoDocumento:     models.TipoDocumentoBoleta,
+		CodigoDocumento:   fmt.Sprintf("%s-%s", fecha, codigo),
+		CodigoProducto:    codigo,
+		NombreProducto:    "PRODUCTO " + codigo,
+		PrecioBaseCLP:     precio,
+		PrecioVentaCLP:    precio,
+		Cantidad:          cantidad,
+		TotalProductoCLP:  total,
+		TotalDocumentoCLP: total,
+	}
+}
+
+// ingresoPrueba crea un ingreso de inventario de producción 2024
+func ingresoPrueba(codigo, zeta, fecha string, unidades, costoClp float64) datasource.IngresoFixture {
+	return datasource.IngresoFixture{
+		Codigo:           codigo,
+		NombreAduanero:   "PRODUCTO " + codigo,
+		Marca:            "MARCA",
+		Categoria:        "CATEGORIA",
+		UnidadesPorCaja:  1,
+		Zeta:             zeta,
+		AnioProduccion:   "2024",
+		Unidades:         unidades,
+		CostoUnitarioCLP: costoClp,
+		FechaIngreso:     fecha,
+	}
+}
+
+// servicioPrueba arma el servicio de reportes sobre fixtures en memoria: emparejamiento exacto y
+// normalizado, ABC por venta 80/15, XYZ 0,5/1 y sin tipos de cambio
+func servicioPrueba(t *testing.T, ventas []datasource.LineaVentaFixture, ingresos []datasource.IngresoFixture) *ReporteService {
+	t.Helper()
+
+	fixtures := datasource.NewFixturesFromData(ventas, ingresos)
+	excelService := NewExcelService()
+	matcher, err := NewMatcher(MatcherConfig{Estrategias: []string{EstrategiaExacta, EstrategiaNormalizada}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	tipoCambio, err := NewTipoCambioService(filepath.Join(t.TempDir(), "tipo_cambio.json"))
+	if err != nil {
+		t.Fatalf("NewTipoCambioService: %v", err)
+	}
+
+	return NewReporteService(
+		NewVentasService(fixtures, excelService),
+		NewInventarioService(fixtures, excelService, false),
+		excelService,
+		matcher,
+		models.ParametrosABC{Criterio: models.CriterioABCVenta, UmbralA: umbral(80), UmbralB: umbral(15), UmbralX: umbral(0.5), UmbralY: umbral(1)},
+		models.ConfigReposicion{},
+		tipoCambio,
+	)
+}
+
+// fechaPrueba convierte una fecha YYYY-MM-DD
+func fechaPrueba(t *testing.T, fecha string) time.Time {
+	t.Helper()
+	f, err := time.Parse("2006-01-02", fecha)
+	if err != nil {
+		t.Fatalf("fecha no válida %q: %v", fecha, err)
+	}
+	return f
+}
+
+// aproximado indica si dos valores coinciden a 4 decimales
+func aproximado(a, b float64) bool {
+	return redondear(a, 4) == redondear(b, 4)
+}
+
+// umbral devuelve un puntero al umbral de clasificación indicado
+func umbral(valor float64) *float64 {
+	return &valor
+}
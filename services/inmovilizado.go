@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// tramosAntiguedad en el orden en que se presentan
+var tramosAntiguedad = []string{
+	models.TramoHasta90,
+	models.TramoHasta180,
+	models.TramoHasta365,
+	models.TramoMasDe365,
+}
+
+// tramoAntiguedad devuelve el tramo de antigüedad según los días en inventario
+func tramoAntiguedad(dias int) string {
+	switch {
+	case dias <= 90:
+		return models.TramoHasta90
+	case dias <= 180:
+		return models.TramoHasta180
+	case dias <= 365:
+		return models.TramoHasta365
+	default:
+		return models.TramoMasDe365
+	}
+}
+
+// diasDesde devuelve los días entre una fecha (YYYY-MM-DD, con o sin hora) y la referencia
+func diasDesde(fecha string, referencia time.Time) (int, bool) {
+	t, ok := parsearFechaVenta(fecha)
+	if !ok {
+		return 0, false
+	}
+	return int(referencia.Sub(t).Hours() / 24), true
+}
+
+// ultimasVentas obtiene la última fecha de venta por código de ventas en los DiasSinVenta días
+// anteriores a la referencia, sin depender del período del reporte
+func (s *ReporteService) ultimasVentas(filtro models.ReporteFiltro, referencia time.Time, diasSinVenta int) (map[string]string, error) {
+	ventasFiltro := filtro.FiltroVentas()
+	ventasFiltro.FechaInicio = referencia.AddDate(0, 0, -diasSinVenta).Format("2006-01-02")
+	ventasFiltro.FechaFin = referencia.Format("2006-01-02")
+	ventas, err := s.ventasService.GetVentasAgrupadas(ventasFiltro)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las últimas ventas: %v", err)
+	}
+
+	ultimas := make(map[string]string, len(ventas))
+	for _, v := range ventas {
+		if v.UltimaFechaVenta > ultimas[v.CodigoProducto] {
+			ultimas[v.CodigoProducto] = v.UltimaFechaVenta
+		}
+	}
+	return ultimas, nil
+}
+
+// GenerarReporteInmovilizado detecta productos con stock y sin ventas en los últimos
+// DiasSinVenta días, o con sell-through bajo SellThroughMinimo tras DiasMinimos días
+// en inventario. El capital inmovilizado es saldo del período × costo promedio (CIF USD y CLP).
+// La última venta se busca en los DiasSinVenta días anteriores a la referencia aunque queden
+// fuera del período, y los días en inventario se cuentan desde el primer ingreso hasta la
+// referencia.
+func (s *ReporteService) GenerarReporteInmovilizado(filtro models.ReporteFiltro, params models.ParametrosInmovilizado) (*models.ReporteInmovilizado, error) {
+	if err := params.Validar(); err != nil {
+		return nil, err
+	}
+
+	coincidentes, _, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	// Las ventas posteriores a la fecha de fin (o a hoy) no se consideran
+	referencia := fechaReferencia(filtro.FechaFin)
+	ultimas, err := s.ultimasVentas(filtro, referencia, params.DiasSinVenta)
+	if err != nil {
+		return nil, err
+	}
+
+	reporte := &models.ReporteInmovilizado{
+		Parametros:      params,
+		FechaReferencia: referencia.Format("2006-01-02"),
+		Productos:       []models.ProductoInmovilizado{},
+	}
+
+	for _, r := range coincidentes {
+		if r.SaldoPeriodo <= 0 {
+			continue
+		}
+
+		diasEnInventario, ok := diasDesde(r.FechaPrimerIngreso, referencia)
+		if !ok {
+			diasEnInventario = r.DiasEnInventario
+		}
+		ultimaVenta := r.UltimaFechaVenta
+		codigoVentas := r.CodigoVentas
+		if codigoVentas == "" {
+			codigoVentas = r.CodigoProducto
+		}
+		if fecha := ultimas[codigoVentas]; fecha > ultimaVenta {
+			ultimaVenta = fecha
+		}
+
+		producto := models.ProductoInmovilizado{
+			CodigoProducto:         r.CodigoProducto,
+			CodigoVentas:           r.CodigoVentas,
+			Nombre:                 r.Nombre,
+			Marca:                  r.Marca,
+			Categoria:              r.Categoria,
+			SaldoPeriodo:           r.SaldoPeriodo,
+			DiasEnInventario:       diasEnInventario,
+			FechaUltimoIngreso:     r.FechaUltimoIngreso,
+			UltimaFechaVenta:       ultimaVenta,
+			PorcentajeVendido:      r.PorcentajeVendido,
+			CapitalInmovilizadoClp: r.SaldoPeriodo * r.CifPromedioClp,
+			CapitalInmovilizadoUsd: r.SaldoPeriodo * r.CifPromedioUsd,
+			Tramo:                  tramoAntiguedad(diasEnInventario),
+		}
+
+		// Sin ventas en el período ni en los últimos DiasSinVenta días: el stock está detenido
+		// desde el primer ingreso
+		diasDetenido := diasEnInventario
+		if dias, ok := diasDesde(ultimaVenta, referencia); ok {
+			producto.DiasSinVenta = &dias
+			diasDetenido = dias
+		}
+
+		if diasDetenido >= params.DiasSinVenta {
+			producto.Motivos = append(producto.Motivos, models.MotivoSinVenta)
+		}
+		if diasEnInventario >= params.DiasMinimos && r.PorcentajeVendido < params.SellThroughMinimo {
+			producto.Motivos = append(producto.Motivos, models.MotivoBajaVenta)
+		}
+		if len(producto.Motivos) == 0 {
+			continue
+		}
+
+		reporte.Productos = append(reporte.Productos, producto)
+	}
+
+	// Mayor capital inmovilizado primero
+	sort.SliceStable(reporte.Productos, func(i, j int) bool {
+		return reporte.Productos[i].CapitalInmovilizadoClp > reporte.Productos[j].CapitalInmovilizadoClp
+	})
+
+	reporte.ResumenTramos, reporte.Total = resumirTramos(reporte.Productos)
+	return reporte, nil
+}
+
+// parsearFechaVenta interpreta la última fecha de venta (YYYY-MM-DD, con o sin hora)
+func parsearFechaVenta(fecha string) (time.Time, bool) {
+	if len(fecha) < 10 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01-02", fecha[:10])
+	return t, err == nil
+}
+
+// resumirTramos agrupa productos, unidades y capital por tramo de antigüedad
+func resumirTramos(productos []models.ProductoInmovilizado) ([]models.ResumenTramo, models.ResumenTramo) {
+	resumen := make([]models.ResumenTramo, len(tramosAntiguedad))
+	indice := make(map[string]int, len(tramosAntiguedad))
+	for i, tramo := range tramosAntiguedad {
+		resumen[i].Tramo = tramo
+		indice[tramo] = i
+	}
+
+	total := models.ResumenTramo{Tramo: "Total"}
+	for _, p := range productos {
+		t := &resumen[indice[p.Tramo]]
+		for _, r := range []*models.ResumenTramo{t, &total} {
+			r.Productos++
+			r.Unidades += p.SaldoPeriodo
+			r.CapitalInmovilizadoClp += p.CapitalInmovilizadoClp
+			r.CapitalInmovilizadoUsd += p.CapitalInmovilizadoUsd
+		}
+	}
+	return resumen, total
+}
+
+// ExportarReporteInmovilizado exporta el reporte de stock inmovilizado a Excel
+func (s *ReporteService) ExportarReporteInmovilizado(filtro models.ReporteFiltro, params models.ParametrosInmovilizado) ([]byte, string, error) {
+	reporte, err := s.GenerarReporteInmovilizado(filtro, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	productos := HojaExcel{
+		Nombre: "Inmovilizado",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Codigo_Producto"},
+			{Titulo: "CODIGO VENTAS"},
+			{Titulo: "NOMBRE", Ancho: 40},
+			{Titulo: "MARCA", Ancho: 20},
+			{Titulo: "CATEGORIA", Ancho: 20},
+			{Titulo: "SALDO PERIODO", Decimal: true},
+			{Titulo: "DIAS EN INVENTARIO"},
+			{Titulo: "TRAMO"},
+			{Titulo: "FECHA ULTIMO INGRESO"},
+			{Titulo: "ULTIMA FECHA VENTA"},
+			{Titulo: "DIAS SIN VENTA"},
+			{Titulo: "% VENDIDO", Decimal: true},
+			{Titulo: "CAPITAL INMOVILIZADO CLP", Ancho: 20, Decimal: true},
+			{Titulo: "CAPITAL INMOVILIZADO USD", Ancho: 20, Decimal: true},
+			{Titulo: "MOTIVOS", Ancho: 30},
+		},
+	}
+	for _, p := range reporte.Productos {
+		var diasSinVenta interface{}
+		if p.DiasSinVenta != nil {
+			diasSinVenta = *p.DiasSinVenta
+		}
+		productos.Filas = append(productos.Filas, []interface{}{
+			p.CodigoProducto,
+			p.CodigoVentas,
+			p.Nombre,
+			p.Marca,
+			p.Categoria,
+			p.SaldoPeriodo,
+			p.DiasEnInventario,
+			p.Tramo,
+			p.FechaUltimoIngreso,
+			p.UltimaFechaVenta,
+			diasSinVenta,
+			p.PorcentajeVendido,
+			p.CapitalInmovilizadoClp,
+			p.CapitalInmovilizadoUsd,
+			strings.Join(p.Motivos, ", "),
+		})
+	}
+
+	resumen := HojaExcel{
+		Nombre: "Resumen por Antigüedad",
+		Columnas: []ColumnaExcel{
+			{Titulo: "TRAMO (DIAS)"},
+			{Titulo: "PRODUCTOS"},
+			{Titulo: "UNIDADES", Decimal: true},
+			{Titulo: "CAPITAL INMOVILIZADO CLP", Ancho: 20, Decimal: true},
+			{Titulo: "CAPITAL INMOVILIZADO USD", Ancho: 20, Decimal: true},
+		},
+	}
+	for _, t := range append(reporte.ResumenTramos, reporte.Total) {
+		resumen.Filas = append(resumen.Filas, []interface{}{
+			t.Tramo,
+			t.Productos,
+			t.Unidades,
+			t.CapitalInmovilizadoClp,
+			t.CapitalInmovilizadoUsd,
+		})
+	}
+
+	excelBytes, err := s.excelService.GenerarLibro(productos, resumen)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("Reporte_Inmovilizado_%s.xlsx", reporte.FechaReferencia)
+	return excelBytes, filename, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// describirInmovilizado resume motivos, días sin venta, días en inventario y tramo de un producto
+func describirInmovilizado(p models.ProductoInmovilizado) string {
+	sinVenta := "nil"
+	if p.DiasSinVenta != nil {
+		sinVenta = fmt.Sprint(*p.DiasSinVenta)
+	}
+	return fmt.Sprintf("%s | sin venta %s (%s) | %d días %s", strings.Join(p.Motivos, ","), sinVenta, p.UltimaFechaVenta, p.DiasEnInventario, p.Tramo)
+}
+
+func TestGenerarReporteInmovilizado(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{
+		ventaPrueba("2025-02-19", "RECIENTE", 50, 1000), // Antes del período, dentro de los 90 días
+		ventaPrueba("2024-10-01", "DETENIDO", 50, 1000), // Antes de los 90 días
+		ventaPrueba("2025-03-10", "MEDIO", 5, 1000),
+		ventaPrueba("2025-03-20", "SANO", 50, 1000),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("RECIENTE", "Z1", "2024-01-01", 100, 500),
+		ingresoPrueba("DETENIDO", "Z1", "2024-01-01", 100, 500),
+		ingresoPrueba("MEDIO", "Z1", "2024-09-01", 100, 500),
+		ingresoPrueba("NUEVO", "Z1", "2025-03-01", 100, 500),
+		ingresoPrueba("SANO", "Z1", "2024-12-01", 100, 500),
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+
+	// La fecha de fin ya pasó: los días se cuentan hasta el 31 de marzo y no hasta hoy
+	filtro := models.ReporteFiltro{FechaInicio: "2025-03-01", FechaFin: "2025-03-31"}
+	params := models.ParametrosInmovilizado{DiasSinVenta: 90, DiasMinimos: 200, SellThroughMinimo: 20}
+	reporte, err := s.GenerarReporteInmovilizado(filtro, params)
+	if err != nil {
+		t.Fatalf("GenerarReporteInmovilizado: %v", err)
+	}
+	if reporte.FechaReferencia != "2025-03-31" {
+		t.Errorf("FechaReferencia = %s, se esperaba 2025-03-31", reporte.FechaReferencia)
+	}
+
+	obtenidos := make(map[string]string)
+	for _, p := range reporte.Productos {
+		obtenidos[p.CodigoProducto] = describirInmovilizado(p)
+	}
+	esperados := map[string]string{
+		// Vendió hace 40 días fuera del período: no está detenido, pero su venta del período es 0 %
+		"RECIENTE": "bajo_sell_through | sin venta 40 (2025-02-19) | 455 días >365",
+		"DETENIDO": "sin_ventas,bajo_sell_through | sin venta nil () | 455 días >365",
+		"MEDIO":    "bajo_sell_through | sin venta 21 (2025-03-10) | 211 días 181-365",
+	}
+	if len(obtenidos) != len(esperados) {
+		t.Errorf("productos = %v, se esperaba %v", obtenidos, esperados)
+	}
+	for codigo, esperado := range esperados {
+		if obtenidos[codigo] != esperado {
+			t.Errorf("%s:\n%s\nse esperaba:\n%s", codigo, obtenidos[codigo], esperado)
+		}
+	}
+}
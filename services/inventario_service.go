@@ -1,86 +1,217 @@
 package services
 
 import (
-	"encoding/json"
+	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/pablojnd/rotacion/datasource"
 	"github.com/pablojnd/rotacion/db"
 	"github.com/pablojnd/rotacion/models"
-	"github.com/pablojnd/rotacion/queries/mysql"
 	"github.com/pablojnd/rotacion/utils"
 )
 
 // InventarioService proporciona métodos para trabajar con datos de inventario
 type InventarioService struct {
-	mysql        *db.MySQLDB
-	excelService *ExcelService
+	inventarioSource  datasource.InventarioSource
+	excelService      *ExcelService
+	historialPorFilas bool
 }
 
-// NewInventarioService crea un nuevo servicio de inventario
-func NewInventarioService(mysql *db.MySQLDB, excelService *ExcelService) *InventarioService {
+// NewInventarioService crea un nuevo servicio de inventario.
+// Si historialPorFilas es true, el historial de ingresos se obtiene siempre con la
+// consulta por filas en lugar de interpretar la columna concatenada.
+func NewInventarioService(inventarioSource datasource.InventarioSource, excelService *ExcelService, historialPorFilas bool) *InventarioService {
 	return &InventarioService{
-		mysql:        mysql,
-		excelService: excelService,
+		inventarioSource:  inventarioSource,
+		excelService:      excelService,
+		historialPorFilas: historialPorFilas,
 	}
 }
 
 // GetInventario obtiene el inventario según los filtros proporcionados
-func (s *InventarioService) GetInventario(filtro models.InventarioFiltro) ([]map[string]interface{}, error) {
+func (s *InventarioService) GetInventario(filtro models.InventarioFiltro) ([]models.Inventario, error) {
 	// Validar filtros
 	if err := filtro.Validar(); err != nil {
 		return nil, err
 	}
 
-	// Obtener la consulta SQL
-	query := mysql.GetSimplifiedDimensionsQuery()
-
 	// Ejecutar la consulta con los parámetros
-	rows, err := s.mysql.ExecuteQuery(query,
-		filtro.Anio,
-		filtro.CodigoProducto,
-		filtro.CodigoProducto)
+	rows, err := s.inventarioSource.Inventario(filtro)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	// Convertir resultados a JSON
-	result, err := utils.RowsToJSON(rows)
-	if err != nil {
-		return nil, err
+	// Decodificar resultados
+	inventario := make([]models.Inventario, 0)
+	if err := db.ScanAll(rows, &inventario); err != nil {
+		return nil, fmt.Errorf("error al decodificar inventario: %v", err)
 	}
 
-	// Procesar los metadatos JSON
-	for i := range result {
-		// Procesar metadatos JSON (ahora llamado "Historial de Ingresos (JSON)")
-		if metadataJSON, ok := result[i]["Historial de Ingresos (JSON)"].(string); ok {
-			// Arreglar las comillas simples en el JSON
-			fixedJSON := utils.FixJSONQuotes(metadataJSON)
-
-			// Intentar analizar como JSON válido para verificar
-			var metadata []interface{}
-			if err := json.Unmarshal([]byte(fixedJSON), &metadata); err == nil {
-				// Si el análisis tiene éxito, reemplazar el string con el objeto JSON analizado
-				result[i]["Historial de Ingresos (JSON)"] = metadata
-			} else {
-				// Si hay error, mantener el string arreglado
-				result[i]["Historial de Ingresos (JSON)"] = fixedJSON
+	// Interpretar el historial de ingresos concatenado; si viene truncado se completa por filas,
+	// consultando solo los productos afectados
+	usarFilas := s.historialPorFilas
+	truncados := make(map[int]bool)
+	if !usarFilas {
+		for i := range inventario {
+			historial := utils.ParseHistorialIngresos(inventario[i].HistorialIngresos, inventario[i].CantidadIngresos)
+			inventario[i].Ingresos = historial.Ingresos
+			inventario[i].AdvertenciasHistorial = historial.Advertencias
+			if historial.Truncado {
+				truncados[i] = true
 			}
 		}
+	}
 
-		// Procesar dimensiones si es necesario (adaptado a los nuevos nombres)
-		if dimensiones, ok := result[i]["Subcategoría/Dimensiones"].(string); ok {
-			if dimensiones == "POR ASIGNAR" || dimensiones == "Sin Asignar" || dimensiones == "" {
-				if nombreProducto, ok := result[i]["Nombre Aduanero"].(string); ok {
-					// Extraer dimensiones del nombre del producto
-					result[i]["Subcategoría/Dimensiones"] = extractDimensionsFromName(nombreProducto)
+	if usarFilas || len(truncados) > 0 {
+		filtroFilas := filtro
+		if !usarFilas {
+			filtroFilas.Codigos = codigosTruncados(inventario, truncados)
+		}
+		porProducto, err := s.consultarHistorialPorFilas(filtroFilas)
+		switch {
+		case err != nil && usarFilas:
+			return nil, err
+		case err != nil:
+			for i := range truncados {
+				inventario[i].AdvertenciasHistorial = append(inventario[i].AdvertenciasHistorial,
+					fmt.Sprintf("no se pudo completar el historial por filas: %v", err))
+			}
+		default:
+			for i := range inventario {
+				if !usarFilas && !truncados[i] {
+					continue
+				}
+				inventario[i].Ingresos = porProducto[claveHistorial(inventario[i].CodigoProducto, inventario[i].UnidadesPorCaja)]
+				if truncados[i] {
+					inventario[i].AdvertenciasHistorial = append(inventario[i].AdvertenciasHistorial,
+						"historial completo obtenido con la consulta por filas")
 				}
 			}
 		}
 	}
 
-	return result, nil
+	for i := range inventario {
+		if inventario[i].Ingresos == nil {
+			inventario[i].Ingresos = []models.MetadatoProducto{}
+		}
+
+		// Extraer dimensiones del nombre del producto si no están asignadas
+		dimensiones := inventario[i].SubcategoriaDimensiones
+		if dimensiones == "POR ASIGNAR" || dimensiones == "Sin Asignar" || dimensiones == "" {
+			inventario[i].SubcategoriaDimensiones = extractDimensionsFromName(inventario[i].NombreAduanero)
+		}
+
+		if filtro.DesglosePorAnio {
+			inventario[i].UnidadesPorAnio = unidadesPorAnio(inventario[i].Ingresos)
+		}
+	}
+
+	return inventario, nil
+}
+
+// unidadesPorAnio suma los ingresos de un producto por año de producción, del más antiguo al más reciente
+func unidadesPorAnio(ingresos []models.MetadatoProducto) []models.UnidadesAnio {
+	indice := make(map[string]int)
+	var anios []models.UnidadesAnio
+	for _, ing := range ingresos {
+		anio := strings.TrimSpace(ing.AnioProduccion)
+		i, ok := indice[anio]
+		if !ok {
+			i = len(anios)
+			indice[anio] = i
+			anios = append(anios, models.UnidadesAnio{Anio: anio})
+		}
+		anios[i].Unidades += ing.UnidadesIngresadas
+		anios[i].Ingresos++
+	}
+	sort.Slice(anios, func(i, j int) bool { return anios[i].Anio < anios[j].Anio })
+	return anios
+}
+
+// AniosDesglose devuelve los años de producción presentes en los desgloses, ordenados
+func AniosDesglose(desgloses ...[]models.UnidadesAnio) []string {
+	vistos := make(map[string]bool)
+	var anios []string
+	for _, desglose := range desgloses {
+		for _, u := range desglose {
+			if !vistos[u.Anio] {
+				vistos[u.Anio] = true
+				anios = append(anios, u.Anio)
+			}
+		}
+	}
+	sort.Strings(anios)
+	return anios
+}
+
+// unidadesEnAnio devuelve las unidades ingresadas de un año de producción del desglose
+func unidadesEnAnio(desglose []models.UnidadesAnio, anio string) float64 {
+	for _, u := range desglose {
+		if u.Anio == anio {
+			return u.Unidades
+		}
+	}
+	return 0
+}
+
+// consultarHistorialPorFilas obtiene el historial de ingresos con una fila por ingreso,
+// agrupado por código de producto y unidades por caja (igual que la consulta de inventario)
+func (s *InventarioService) consultarHistorialPorFilas(filtro models.InventarioFiltro) (map[string][]models.MetadatoProducto, error) {
+	rows, err := s.inventarioSource.HistorialIngresos(filtro)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener historial de ingresos: %v", err)
+	}
+	defer rows.Close()
+
+	var ingresos []models.IngresoSaldo
+	if err := db.ScanAll(rows, &ingresos); err != nil {
+		return nil, fmt.Errorf("error al decodificar historial de ingresos: %v", err)
+	}
+
+	porProducto := make(map[string][]models.MetadatoProducto)
+	for _, ing := range ingresos {
+		clave := claveHistorial(ing.CodigoProducto, ing.UnidadesPorCaja)
+		porProducto[clave] = append(porProducto[clave], models.MetadatoProducto{
+			Zeta:               ing.Zeta,
+			AnioProduccion:     ing.AnioProduccion,
+			UnidadesIngresadas: ing.UnidadesIngresadas,
+			FechaIngreso:       ing.FechaIngreso,
+		})
+	}
+	for _, lista := range porProducto {
+		utils.OrdenarIngresos(lista)
+	}
+
+	return porProducto, nil
+}
+
+// codigosTruncados devuelve los códigos de los productos con el historial truncado, para
+// consultar por filas solo esos productos. Si alguno contiene comas no puede filtrarse por
+// lista y se devuelve nil (todos los productos del filtro).
+func codigosTruncados(inventario []models.Inventario, truncados map[int]bool) []string {
+	vistos := make(map[string]bool, len(truncados))
+	codigos := make([]string, 0, len(truncados))
+	for i := range truncados {
+		codigo := inventario[i].CodigoProducto
+		if strings.Contains(codigo, ",") {
+			return nil
+		}
+		if !vistos[codigo] {
+			vistos[codigo] = true
+			codigos = append(codigos, codigo)
+		}
+	}
+	sort.Strings(codigos)
+	return codigos
+}
+
+// claveHistorial identifica un grupo de la consulta de inventario (COD_ART, UNI_CAJ)
+func claveHistorial(codigo string, unidadesPorCaja float64) string {
+	return codigo + "|" + strconv.FormatFloat(unidadesPorCaja, 'f', -1, 64)
 }
 
 // extractDimensionsFromName extrae las dimensiones del nombre del producto
@@ -107,12 +238,21 @@ func (s *InventarioService) ExportInventarioToExcel(filtro models.InventarioFilt
 	// Nombre del archivo
 	filename := generateInventarioFilename(filtro)
 
+	// Con desglose por año se arma la hoja desde el inventario procesado
+	if filtro.DesglosePorAnio {
+		inventario, err := s.GetInventario(filtro)
+		if err != nil {
+			return nil, "", err
+		}
+		excelBytes, err := s.excelService.GenerarLibro(hojaInventarioPorAnio(inventario))
+		if err != nil {
+			return nil, "", err
+		}
+		return excelBytes, filename, nil
+	}
+
 	// Obtener y ejecutar la consulta
-	query := mysql.GetInventarioQuery()
-	rows, err := s.mysql.ExecuteQuery(query,
-		filtro.Anio,
-		filtro.CodigoProducto,
-		filtro.CodigoProducto) // pasamos dos veces para el CONCAT en la consulta
+	rows, err := s.inventarioSource.Inventario(filtro)
 	if err != nil {
 		return nil, "", err
 	}
@@ -127,11 +267,88 @@ func (s *InventarioService) ExportInventarioToExcel(filtro models.InventarioFilt
 	return excelBytes, filename, nil
 }
 
+// hojaInventarioPorAnio genera la hoja de inventario con una columna de unidades por año de producción
+func hojaInventarioPorAnio(inventario []models.Inventario) HojaExcel {
+	desgloses := make([][]models.UnidadesAnio, len(inventario))
+	for i, inv := range inventario {
+		desgloses[i] = inv.UnidadesPorAnio
+	}
+	anios := AniosDesglose(desgloses...)
+
+	hoja := HojaExcel{
+		Nombre: "Datos",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Código de Producto"},
+			{Titulo: "Nombre Aduanero", Ancho: 40},
+			{Titulo: "Marca del Producto", Ancho: 20},
+			{Titulo: "Categoría Principal", Ancho: 20},
+			{Titulo: "Subcategoría/Dimensiones"},
+			{Titulo: "Unidades por Caja", Decimal: true},
+			{Titulo: "Total Unidades Ingresadas", Decimal: true},
+			{Titulo: "Costo Promedio CIF (USD)", Decimal: true},
+			{Titulo: "Costo Promedio Unitario (CLP)", Decimal: true},
+			{Titulo: "Fecha Primer Ingreso"},
+			{Titulo: "Fecha Último Ingreso"},
+			{Titulo: "Días Desde Primer Ingreso"},
+			{Titulo: "Cantidad de Ingresos"},
+		},
+	}
+	for _, anio := range anios {
+		hoja.Columnas = append(hoja.Columnas, ColumnaExcel{Titulo: "Unidades " + anio, Decimal: true})
+	}
+	for _, inv := range inventario {
+		fila := []interface{}{
+			inv.CodigoProducto,
+			inv.NombreAduanero,
+			inv.MarcaProducto,
+			inv.CategoriaPrincipal,
+			inv.SubcategoriaDimensiones,
+			inv.UnidadesPorCaja,
+			inv.TotalUnidadesIngresadas,
+			inv.CostoPromedioCIF,
+			inv.CostoPromedioUnitario,
+			formatearFecha(inv.FechaPrimerIngreso),
+			formatearFecha(inv.FechaUltimoIngreso),
+			inv.DiasDesdePrimerIngreso,
+			inv.CantidadIngresos,
+		}
+		for _, anio := range anios {
+			fila = append(fila, unidadesEnAnio(inv.UnidadesPorAnio, anio))
+		}
+		hoja.Filas = append(hoja.Filas, fila)
+	}
+	return hoja
+}
+
 // generateInventarioFilename genera un nombre de archivo para el reporte de inventario
 func generateInventarioFilename(filtro models.InventarioFiltro) string {
-	base := "Inventario_" + string(filtro.Anio)
+	base := "Inventario_" + strconv.Itoa(filtro.AnioDesde)
+	if filtro.RangoAnios() {
+		base = fmt.Sprintf("Inventario_%d-%d", filtro.AnioDesde, filtro.AnioHasta)
+	}
+	if filtro.FechaIngresoDesde != "" || filtro.FechaIngresoHasta != "" {
+		base += "_Ingresos_" + filtro.FechaIngresoDesde + "_al_" + filtro.FechaIngresoHasta
+	}
 	if filtro.CodigoProducto != "" {
 		base += "_" + filtro.CodigoProducto
 	}
+	if len(filtro.Codigos) > 0 {
+		base += fmt.Sprintf("_Lista_%d_Codigos", len(filtro.Codigos))
+	}
 	return base + ".xlsx"
 }
+
+// GetIngresosHastaFecha obtiene cada ingreso de todos los años hasta la fecha de corte del filtro
+func (s *InventarioService) GetIngresosHastaFecha(filtro models.StockFiltro) ([]models.IngresoStock, error) {
+	rows, err := s.inventarioSource.IngresosHastaFecha(filtro)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener ingresos hasta la fecha: %v", err)
+	}
+	defer rows.Close()
+
+	ingresos := make([]models.IngresoStock, 0)
+	if err := db.ScanAll(rows, &ingresos); err != nil {
+		return nil, fmt.Errorf("error al decodificar ingresos hasta la fecha: %v", err)
+	}
+	return ingresos, nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+func TestGetInventarioIngresosDuplicados(t *testing.T) {
+	// La misma fila de ingreso repetida en saldos aparece una sola vez en el historial
+	ingreso := ingresoPrueba("DUPLICADO", "Z1", "2024-03-01", 10, 400)
+	fixtures := datasource.NewFixturesFromData(nil, []datasource.IngresoFixture{
+		ingreso,
+		ingreso,
+		ingresoPrueba("DUPLICADO", "Z2", "2024-04-01", 5, 400),
+	})
+	s := NewInventarioService(fixtures, NewExcelService(), false)
+
+	inventario, err := s.GetInventario(models.InventarioFiltro{Anio: 2024})
+	if err != nil {
+		t.Fatalf("GetInventario: %v", err)
+	}
+	if len(inventario) != 1 {
+		t.Fatalf("inventario = %d productos, se esperaba 1", len(inventario))
+	}
+	p := inventario[0]
+	if p.CantidadIngresos != 2 || len(p.Ingresos) != 2 {
+		t.Errorf("CantidadIngresos = %d con %d ingresos, se esperaban 2 y 2", p.CantidadIngresos, len(p.Ingresos))
+	}
+	// Sin advertencia de historial truncado ni consulta por filas
+	if len(p.AdvertenciasHistorial) != 0 {
+		t.Errorf("advertencias = %v, no se esperaba ninguna", p.AdvertenciasHistorial)
+	}
+}
+
+// ingresoAnio crea un ingreso de PISO del año de producción indicado
+func ingresoAnio(zeta, anio, fecha string, unidades float64) datasource.IngresoFixture {
+	ing := ingresoPrueba("PISO", zeta, fecha, unidades, 400)
+	ing.AnioProduccion = anio
+	return ing
+}
+
+// ingresosVariosAnios ingresa PISO con producción 2022, 2023 y 2024, y un lote 2024 ingresado
+// en febrero de 2025
+func ingresosVariosAnios() []datasource.IngresoFixture {
+	return []datasource.IngresoFixture{
+		ingresoAnio("Z22", "2022", "2022-06-01", 10),
+		ingresoAnio("Z23", "2023", "2023-06-01", 20),
+		ingresoAnio("Z24", "2024", "2024-06-01", 30),
+		ingresoAnio("Z24B", "2024", "2025-02-15", 40),
+	}
+}
+
+func TestGetInventarioRangos(t *testing.T) {
+	s := NewInventarioService(datasource.NewFixturesFromData(nil, ingresosVariosAnios()), NewExcelService(), false)
+
+	casos := []struct {
+		nombre   string
+		filtro   models.InventarioFiltro
+		esperado string // unidades [año:unidades/ingresos]
+	}{
+		{"un año", models.InventarioFiltro{Anio: 2024}, "70 [2024:70/2]"},
+		{"rango de años", models.InventarioFiltro{AnioDesde: 2022, AnioHasta: 2023}, "30 [2022:10/1 2023:20/1]"},
+		{"ingresados hasta", models.InventarioFiltro{AnioDesde: 2023, AnioHasta: 2024, FechaIngresoHasta: "2024-12-31"}, "50 [2023:20/1 2024:30/1]"},
+		{"ingresados desde", models.InventarioFiltro{AnioDesde: 2022, AnioHasta: 2024, FechaIngresoDesde: "2024-01-01"}, "70 [2024:70/2]"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			filtro := c.filtro
+			filtro.DesglosePorAnio = true
+			inventario, err := s.GetInventario(filtro)
+			if err != nil {
+				t.Fatalf("GetInventario: %v", err)
+			}
+			if len(inventario) != 1 {
+				t.Fatalf("inventario = %d productos, se esperaba 1", len(inventario))
+			}
+			var anios []string
+			for _, u := range inventario[0].UnidadesPorAnio {
+				anios = append(anios, fmt.Sprintf("%s:%v/%d", u.Anio, u.Unidades, u.Ingresos))
+			}
+			obtenido := fmt.Sprintf("%v [%s]", inventario[0].TotalUnidadesIngresadas, strings.Join(anios, " "))
+			if obtenido != c.esperado {
+				t.Errorf("inventario = %s, se esperaba %s", obtenido, c.esperado)
+			}
+		})
+	}
+
+	// Sin desglose solicitado no se informa por año
+	inventario, err := s.GetInventario(models.InventarioFiltro{Anio: 2024})
+	if err != nil {
+		t.Fatalf("GetInventario: %v", err)
+	}
+	if len(inventario) != 1 || inventario[0].UnidadesPorAnio != nil {
+		t.Errorf("inventario = %+v, se esperaba sin desglose por año", inventario)
+	}
+}
+
+func TestGenerarReporteCombinadoLotesVendibles(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{ventaPrueba("2025-01-10", "PISO", 5, 1000)}
+	previos := 1
+
+	casos := []struct {
+		nombre   string
+		filtro   models.ReporteFiltro
+		esperado float64
+	}{
+		// Todos los lotes ingresados hasta el fin del período, sin el ingresado en febrero
+		{"por defecto", models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31"}, 60},
+		{"un año previo", models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31", AniosProduccionPrevios: &previos}, 30},
+		// El rango explícito incluye el lote ingresado después del período
+		{"rango explícito", models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31", AnioInventarioDesde: 2024, AnioInventarioHasta: 2024}, 70},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			s := servicioPrueba(t, ventas, ingresosVariosAnios())
+			coincidentes, _, err := s.GenerarReporteCombinado(c.filtro)
+			if err != nil {
+				t.Fatalf("GenerarReporteCombinado: %v", err)
+			}
+			if len(coincidentes) != 1 || coincidentes[0].CantidadIngresada != c.esperado {
+				t.Errorf("coincidentes = %+v, se esperaba PISO con %v unidades ingresadas", coincidentes, c.esperado)
+			}
+		})
+	}
+}
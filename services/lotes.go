@@ -0,0 +1,292 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ErrProductoNoEncontrado indica que el código no existe en inventario ni en ventas
+var ErrProductoNoEncontrado = errors.New("producto no encontrado")
+
+// calcularLotes cruza los lotes del historial de ingresos con las ventas por Zeta. Las ventas
+// deben incluir todas las anteriores al período desde el primer ingreso (ver filtroLotes), de
+// modo que las unidades restantes sean las del lote y no solo las del período; las ventas
+// anteriores al primer ingreso no se consideran. Las ventas sin Zeta se asignan en orden de
+// ingreso (FIFO) a los lotes ya ingresados a la fecha de la venta, y las que exceden lo
+// ingresado o cuyo Zeta no figura en el historial se informan como lotes sin ingreso.
+// La antigüedad de cada lote se cuenta hasta la fecha de referencia.
+func calcularLotes(ingresos []models.MetadatoProducto, ventas []models.VentaDetallada, referencia time.Time) []models.LoteProducto {
+	lotes := make([]models.LoteProducto, 0, len(ingresos))
+	indice := make(map[string]int, len(ingresos))
+	for _, ingreso := range ingresos {
+		zeta := strings.ToUpper(strings.TrimSpace(ingreso.Zeta))
+		if i, ok := indice[zeta]; ok {
+			// El mismo Zeta ingresado más de una vez se acumula en un solo lote
+			lotes[i].UnidadesIngresadas += ingreso.UnidadesIngresadas
+			continue
+		}
+
+		lote := models.LoteProducto{
+			Zeta:               ingreso.Zeta,
+			AnioProduccion:     ingreso.AnioProduccion,
+			FechaIngreso:       formatearFecha(ingreso.FechaIngreso),
+			UnidadesIngresadas: ingreso.UnidadesIngresadas,
+		}
+		if !ingreso.FechaIngreso.IsZero() {
+			lote.DiasEnInventario = int(referencia.Sub(ingreso.FechaIngreso).Hours() / 24)
+		}
+		indice[zeta] = len(lotes)
+		lotes = append(lotes, lote)
+	}
+
+	desde := ""
+	for _, lote := range lotes {
+		if lote.FechaIngreso != "" && (desde == "" || lote.FechaIngreso < desde) {
+			desde = lote.FechaIngreso
+		}
+	}
+
+	var sinZeta []models.VentaDetallada
+	for _, venta := range ventas {
+		if desde != "" && venta.FechaEmision < desde {
+			continue
+		}
+		zeta := strings.ToUpper(strings.TrimSpace(venta.Zeta))
+		if zeta == "" && len(ingresos) > 0 {
+			sinZeta = append(sinZeta, venta)
+			continue
+		}
+		i, ok := indice[zeta]
+		if !ok {
+			indice[zeta] = len(lotes)
+			i = len(lotes)
+			lotes = append(lotes, models.LoteProducto{Zeta: venta.Zeta, SinIngreso: true})
+		}
+		sumarVentaLote(&lotes[i], venta, venta.Cantidad, venta.TotalVentaCLP)
+	}
+	lotes = asignarFIFO(lotes, sinZeta)
+
+	for i := range lotes {
+		lote := &lotes[i]
+		lote.UnidadesRestantes = lote.UnidadesIngresadas - lote.UnidadesVendidas
+		if lote.UnidadesIngresadas > 0 {
+			lote.PorcentajeVendido = lote.UnidadesVendidas / lote.UnidadesIngresadas * 100
+		}
+	}
+
+	return lotes
+}
+
+// sumarVentaLote suma al lote una parte (o el total) de una línea de venta
+func sumarVentaLote(lote *models.LoteProducto, venta models.VentaDetallada, cantidad float64, ventaClp int) {
+	lote.UnidadesVendidas += cantidad
+	lote.VentaNetaClp += ventaClp
+	if venta.FechaEmision > lote.UltimaFechaVenta {
+		lote.UltimaFechaVenta = venta.FechaEmision
+	}
+}
+
+// asignarFIFO reparte las ventas sin Zeta entre los lotes con ingreso, del más antiguo al más
+// reciente y solo entre los ingresados hasta la fecha de cada venta. La venta neta se reparte en
+// proporción a las unidades; lo que no alcanza a cubrir ningún lote queda en un lote sin ingreso.
+func asignarFIFO(lotes []models.LoteProducto, ventas []models.VentaDetallada) []models.LoteProducto {
+	if len(ventas) == 0 {
+		return lotes
+	}
+	ventas = append([]models.VentaDetallada(nil), ventas...)
+	sort.SliceStable(ventas, func(i, j int) bool { return ventas[i].FechaEmision < ventas[j].FechaEmision })
+
+	var orden []int
+	for i, lote := range lotes {
+		if !lote.SinIngreso {
+			orden = append(orden, i)
+		}
+	}
+	sort.SliceStable(orden, func(i, j int) bool { return lotes[orden[i]].FechaIngreso < lotes[orden[j]].FechaIngreso })
+
+	sinIngreso := -1
+	for _, venta := range ventas {
+		pendientes, pendienteClp := venta.Cantidad, venta.TotalVentaCLP
+		for _, i := range orden {
+			lote := &lotes[i]
+			if pendientes <= 0 || lote.FechaIngreso > venta.FechaEmision {
+				break
+			}
+			asignadas := math.Min(pendientes, lote.UnidadesIngresadas-lote.UnidadesVendidas)
+			if asignadas <= 0 {
+				continue
+			}
+			asignadaClp := pendienteClp
+			if asignadas < pendientes {
+				asignadaClp = int(math.Round(float64(pendienteClp) * asignadas / pendientes))
+			}
+			sumarVentaLote(lote, venta, asignadas, asignadaClp)
+			pendientes -= asignadas
+			pendienteClp -= asignadaClp
+		}
+		if pendientes == 0 && pendienteClp == 0 {
+			continue
+		}
+		if sinIngreso < 0 {
+			sinIngreso = len(lotes)
+			lotes = append(lotes, models.LoteProducto{SinIngreso: true})
+		}
+		sumarVentaLote(&lotes[sinIngreso], venta, pendientes, pendienteClp)
+	}
+	return lotes
+}
+
+// filtroLotes adelanta el inicio del período al primer ingreso de los productos, para que las
+// ventas de cada lote se cuenten desde su ingreso y no solo dentro del período
+func filtroLotes(filtro models.ReporteFiltro, reportes ...models.ReporteCombinado) models.ReporteFiltro {
+	for _, r := range reportes {
+		for _, ingreso := range r.Ingresos {
+			if fecha := formatearFecha(ingreso.FechaIngreso); fecha != "" && fecha < filtro.FechaInicio {
+				filtro.FechaInicio = fecha
+			}
+		}
+	}
+	return filtro
+}
+
+// fechaReferencia devuelve la fecha de fin del filtro, o hoy si es posterior
+func fechaReferencia(fechaFin string) time.Time {
+	referencia := time.Now()
+	if fin, err := time.Parse("2006-01-02", fechaFin); err == nil && fin.Before(referencia) {
+		referencia = fin
+	}
+	return time.Date(referencia.Year(), referencia.Month(), referencia.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ventasPorCodigo obtiene las líneas de venta del período agrupadas por código de ventas
+func (s *ReporteService) ventasPorCodigo(filtro models.ReporteFiltro, codigo string) (map[string][]models.VentaDetallada, error) {
+	ventasFiltro := filtro.FiltroVentas()
+	ventasFiltro.CodigoProducto = codigo
+	ventas, err := s.ventasService.GetVentasDetalladas(ventasFiltro)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener ventas detalladas: %v", err)
+	}
+
+	porCodigo := make(map[string][]models.VentaDetallada)
+	for _, venta := range ventas {
+		porCodigo[venta.CodigoProducto] = append(porCodigo[venta.CodigoProducto], venta)
+	}
+	return porCodigo, nil
+}
+
+// buscarProducto busca en el reporte combinado la fila del código exacto, de inventario o de ventas
+func buscarProducto(coincidentes, sinCoincidencia []models.ReporteCombinado, codigo string) (*models.ReporteCombinado, error) {
+	for _, lista := range [][]models.ReporteCombinado{coincidentes, sinCoincidencia} {
+		for i := range lista {
+			if lista[i].CodigoProducto == codigo || lista[i].CodigoVentas == codigo {
+				return &lista[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrProductoNoEncontrado, codigo)
+}
+
+// GenerarReporteLotes devuelve los lotes de un producto, buscado por su código
+// de inventario o de ventas
+func (s *ReporteService) GenerarReporteLotes(filtro models.ReporteFiltro, codigo string) (*models.ReporteLotes, error) {
+	// El código de ventas puede diferir del de inventario, por lo que se empareja todo el catálogo
+	filtro.CodigoProducto = ""
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	encontrado, err := buscarProducto(coincidentes, sinCoincidencia, codigo)
+	if err != nil {
+		return nil, err
+	}
+
+	var ventas []models.VentaDetallada
+	if encontrado.CodigoVentas != "" {
+		porCodigo, err := s.ventasPorCodigo(filtroLotes(filtro, *encontrado), encontrado.CodigoVentas)
+		if err != nil {
+			return nil, err
+		}
+		ventas = porCodigo[encontrado.CodigoVentas]
+	}
+
+	lotes := calcularLotes(encontrado.Ingresos, ventas, fechaReferencia(filtro.FechaFin))
+
+	return &models.ReporteLotes{
+		CodigoProducto: encontrado.CodigoProducto,
+		CodigoVentas:   encontrado.CodigoVentas,
+		Nombre:         encontrado.Nombre,
+		Lotes:          lotes,
+	}, nil
+}
+
+// hojaLotes genera la hoja con los lotes de cada producto del reporte combinado
+func (s *ReporteService) hojaLotes(filtro models.ReporteFiltro, reportes []models.ReporteCombinado) (HojaExcel, error) {
+	hoja := HojaExcel{
+		Nombre: "Lotes",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Codigo_Producto"},
+			{Titulo: "CODIGO VENTAS"},
+			{Titulo: "NOMBRE", Ancho: 40},
+			{Titulo: "ZETA"},
+			{Titulo: "AÑO PRODUCCION"},
+			{Titulo: "FECHA INGRESO"},
+			{Titulo: "DIAS EN INVENTARIO"},
+			{Titulo: "UNIDADES INGRESADAS", Decimal: true},
+			{Titulo: "UNIDADES VENDIDAS", Decimal: true},
+			{Titulo: "UNIDADES RESTANTES", Decimal: true},
+			{Titulo: "% VENDIDO", Decimal: true},
+			{Titulo: "VENTA NETA CLP"},
+			{Titulo: "ULTIMA FECHA VENTA"},
+			{Titulo: "SIN INGRESO"},
+		},
+	}
+
+	ventas, err := s.ventasPorCodigo(filtroLotes(filtro, reportes...), filtro.CodigoProducto)
+	if err != nil {
+		return hoja, err
+	}
+	referencia := fechaReferencia(filtro.FechaFin)
+
+	ordenados := append([]models.ReporteCombinado(nil), reportes...)
+	sort.SliceStable(ordenados, func(i, j int) bool {
+		return ordenados[i].CodigoProducto < ordenados[j].CodigoProducto
+	})
+
+	for _, r := range ordenados {
+		var ventasProducto []models.VentaDetallada
+		if r.CodigoVentas != "" {
+			ventasProducto = ventas[r.CodigoVentas]
+		}
+		for _, lote := range calcularLotes(r.Ingresos, ventasProducto, referencia) {
+			sinIngreso := ""
+			if lote.SinIngreso {
+				sinIngreso = "SI"
+			}
+			hoja.Filas = append(hoja.Filas, []interface{}{
+				r.CodigoProducto,
+				r.CodigoVentas,
+				r.Nombre,
+				lote.Zeta,
+				lote.AnioProduccion,
+				lote.FechaIngreso,
+				lote.DiasEnInventario,
+				lote.UnidadesIngresadas,
+				lote.UnidadesVendidas,
+				lote.UnidadesRestantes,
+				lote.PorcentajeVendido,
+				lote.VentaNetaClp,
+				lote.UltimaFechaVenta,
+				sinIngreso,
+			})
+		}
+	}
+
+	return hoja, nil
+}
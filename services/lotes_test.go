@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ventaZeta crea una línea de venta de un lote; zeta vacío es una venta sin lote informado
+func ventaZeta(fecha, codigo, zeta string, cantidad float64) datasource.LineaVentaFixture {
+	v := ventaPrueba(fecha, codigo, cantidad, 1000)
+	v.Zeta = zeta
+	return v
+}
+
+// describirLotes resume vendidas/ingresadas, restantes y venta neta de cada lote
+func describirLotes(lotes []models.LoteProducto) string {
+	var partes []string
+	for _, l := range lotes {
+		zeta := l.Zeta
+		if l.SinIngreso {
+			zeta += "(sin ingreso)"
+		}
+		partes = append(partes, fmt.Sprintf("%s %v/%v quedan %v $%d", zeta, l.UnidadesVendidas, l.UnidadesIngresadas, l.UnidadesRestantes, l.VentaNetaClp))
+	}
+	return strings.Join(partes, ", ")
+}
+
+func TestGenerarReporteLotes(t *testing.T) {
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("LOTES", "Z1", "2024-11-01", 10, 400),
+		ingresoPrueba("LOTES", "Z2", "2024-12-01", 10, 400),
+	}
+	casos := []struct {
+		nombre   string
+		ventas   []datasource.LineaVentaFixture
+		esperado string
+	}{
+		{
+			// Las 6 unidades de Z1 vendidas antes del período también descuentan del lote
+			nombre: "ventas por Zeta antes y dentro del período",
+			ventas: []datasource.LineaVentaFixture{
+				ventaZeta("2024-11-20", "LOTES", "Z1", 6),
+				ventaZeta("2025-01-05", "LOTES", "Z1", 4),
+				ventaZeta("2025-01-06", "LOTES", "Z2", 3),
+			},
+			esperado: "Z1 10/10 quedan 0 $10000, Z2 3/10 quedan 7 $3000",
+		},
+		{
+			// Sin Zeta: noviembre solo puede salir de Z1; en enero se termina Z1 y se sigue con Z2.
+			// La venta de octubre es anterior al primer ingreso y lo que excede lo ingresado
+			// queda sin lote.
+			nombre: "ventas sin Zeta en orden de ingreso",
+			ventas: []datasource.LineaVentaFixture{
+				ventaZeta("2024-10-15", "LOTES", "", 5),
+				ventaZeta("2024-11-20", "LOTES", "", 8),
+				ventaZeta("2025-01-05", "LOTES", "", 5),
+				ventaZeta("2025-01-06", "LOTES", "", 10),
+			},
+			esperado: "Z1 10/10 quedan 0 $10000, Z2 10/10 quedan 0 $10000, (sin ingreso) 3/0 quedan -3 $3000",
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			s := servicioPrueba(t, c.ventas, ingresos)
+			reporte, err := s.GenerarReporteLotes(models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10"}, "LOTES")
+			if err != nil {
+				t.Fatalf("GenerarReporteLotes: %v", err)
+			}
+			if obtenido := describirLotes(reporte.Lotes); obtenido != c.esperado {
+				t.Errorf("lotes:\n%s\nse esperaba:\n%s", obtenido, c.esperado)
+			}
+		})
+	}
+}
+
+func TestCalcularLotesVentaNetaFIFO(t *testing.T) {
+	// 3 unidades por $1.000 entre un lote con 2 unidades y el siguiente
+	ingresos := []models.MetadatoProducto{
+		{Zeta: "Z2", UnidadesIngresadas: 5, FechaIngreso: fechaPrueba(t, "2025-01-02")},
+		{Zeta: "Z1", UnidadesIngresadas: 2, FechaIngreso: fechaPrueba(t, "2025-01-01")},
+	}
+	ventas := []models.VentaDetallada{{FechaEmision: "2025-01-03", Cantidad: 3, TotalVentaCLP: 1000}}
+
+	obtenido := describirLotes(calcularLotes(ingresos, ventas, fechaPrueba(t, "2025-01-10")))
+	if esperado := "Z2 1/5 quedan 4 $333, Z1 2/2 quedan 0 $667"; obtenido != esperado {
+		t.Errorf("calcularLotes = %s, se esperaba %s", obtenido, esperado)
+	}
+}
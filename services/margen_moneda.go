@@ -0,0 +1,150 @@
+package services
+
+import (
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// tipoCambioIngresos devuelve el tipo de cambio promedio de los ingresos de un producto,
+// ponderado por unidades. Sin historial se usa la fecha del primer ingreso.
+func tipoCambioIngresos(tabla TablaTipoCambio, r models.ReporteCombinado) float64 {
+	var unidades, suma float64
+	for _, ing := range r.Ingresos {
+		if ing.FechaIngreso.IsZero() || ing.UnidadesIngresadas <= 0 {
+			continue
+		}
+		if tc, ok := tabla.Aplicar(ing.FechaIngreso); ok {
+			unidades += ing.UnidadesIngresadas
+			suma += ing.UnidadesIngresadas * tc.Valor
+		}
+	}
+	if unidades > 0 {
+		return suma / unidades
+	}
+
+	if primero, err := time.Parse("2006-01-02", r.FechaPrimerIngreso); err == nil {
+		if tc, ok := tabla.Aplicar(primero); ok {
+			return tc.Valor
+		}
+	}
+	return 0
+}
+
+// lineaCosteada es una línea de venta convertida al tipo de cambio de su fecha
+type lineaCosteada struct {
+	ventaClp   float64
+	ventaUsd   float64
+	unidades   float64
+	costoUsd   float64 // Unidades × costo unitario USD
+	costoClp   float64 // costoUsd al tipo de cambio de la fecha
+	tipoCambio models.TipoCambioAplicado
+	sinCosto   bool // La línea no trae costo de STOCKS y se usó el CIF promedio
+}
+
+// costearLinea convierte una línea de venta al tipo de cambio de su fecha de emisión. El costo es
+// el costo unitario USD de la venta (STOCKS); si no viene informado se usa el CIF promedio del
+// inventario. Las líneas de documentos anulados, sin fecha válida o sin tipo de cambio no se
+// consideran, de modo que la venta y el costo siempre cubren las mismas líneas.
+func costearLinea(tabla TablaTipoCambio, venta models.VentaDetallada, cifPromedioUsd float64) (lineaCosteada, bool) {
+	if venta.Nula {
+		return lineaCosteada{}, false
+	}
+	dia, ok := parsearFechaVenta(venta.FechaEmision)
+	if !ok {
+		return lineaCosteada{}, false
+	}
+	tc, ok := tabla.Aplicar(dia)
+	if !ok {
+		return lineaCosteada{}, false
+	}
+
+	linea := lineaCosteada{
+		ventaClp:   float64(venta.TotalVentaCLP),
+		ventaUsd:   float64(venta.TotalVentaCLP) / tc.Valor,
+		unidades:   venta.Cantidad,
+		tipoCambio: tc,
+	}
+	costoUnitario := venta.CostoUnitarioUSD
+	if costoUnitario <= 0 {
+		costoUnitario = cifPromedioUsd
+		linea.sinCosto = true
+	}
+	linea.costoUsd = venta.Cantidad * costoUnitario
+	linea.costoClp = linea.costoUsd * tc.Valor
+	return linea, true
+}
+
+// calcularMargenMoneda convierte las ventas y costos de un producto a USD y CLP con el tipo
+// de cambio de cada fecha (ver costearLinea)
+func calcularMargenMoneda(tabla TablaTipoCambio, r models.ReporteCombinado, ventas []models.VentaDetallada) *models.MargenMoneda {
+	margen := &models.MargenMoneda{TipoCambioIngresos: tipoCambioIngresos(tabla, r)}
+	margen.CifPromedioClp = r.CifPromedioUsd * margen.TipoCambioIngresos
+
+	for _, venta := range ventas {
+		linea, ok := costearLinea(tabla, venta, r.CifPromedioUsd)
+		if !ok {
+			continue
+		}
+		if linea.tipoCambio.Aproximado {
+			margen.LineasAproximadas++
+		}
+		if linea.sinCosto {
+			margen.LineasSinCosto++
+		}
+		margen.VentaNetaClp += linea.ventaClp
+		margen.VentaNetaUsd += linea.ventaUsd
+		margen.CostoUsd += linea.costoUsd
+		margen.CostoClp += linea.costoClp
+	}
+
+	margen.MargenUsd = margen.VentaNetaUsd - margen.CostoUsd
+	margen.MargenClp = margen.VentaNetaClp - margen.CostoClp
+	if margen.VentaNetaClp != 0 {
+		margen.MargenPct = margen.MargenClp / margen.VentaNetaClp * 100
+	}
+	if margen.VentaNetaUsd != 0 {
+		margen.TipoCambioVentas = margen.VentaNetaClp / margen.VentaNetaUsd
+	}
+
+	for _, valor := range []*float64{
+		&margen.VentaNetaUsd, &margen.CostoUsd, &margen.CostoClp, &margen.MargenUsd, &margen.MargenClp,
+		&margen.MargenPct, &margen.TipoCambioVentas, &margen.TipoCambioIngresos, &margen.CifPromedioClp,
+	} {
+		*valor = redondear(*valor, 2)
+	}
+	return margen
+}
+
+// aplicarMargenMoneda completa el margen en USD y CLP de las filas del reporte combinado,
+// buscando las líneas de venta por código de ventas
+func aplicarMargenMoneda(tabla TablaTipoCambio, reportes []models.ReporteCombinado, ventas map[string][]models.VentaDetallada) {
+	for i := range reportes {
+		var lineas []models.VentaDetallada
+		if reportes[i].CodigoVentas != "" {
+			lineas = ventas[reportes[i].CodigoVentas]
+		}
+		reportes[i].MargenMoneda = calcularMargenMoneda(tabla, reportes[i], lineas)
+	}
+}
+
+// columnasMargenMoneda define las columnas del margen en USD y CLP en la exportación
+var columnasMargenMoneda = []ColumnaExcel{
+	{Titulo: "VENTA NETA USD", Decimal: true},
+	{Titulo: "COSTO VENTA USD", Decimal: true},
+	{Titulo: "COSTO VENTA CLP", Decimal: true},
+	{Titulo: "MARGEN USD", Decimal: true},
+	{Titulo: "MARGEN CLP", Decimal: true},
+	{Titulo: "MARGEN %", Decimal: true},
+	{Titulo: "TC VENTAS", Decimal: true},
+	{Titulo: "TC INGRESOS", Decimal: true},
+	{Titulo: "CIF PROMEDIO CLP (TC)", Decimal: true},
+}
+
+// valoresMargenMoneda devuelve los valores de las columnas del margen en USD y CLP
+func valoresMargenMoneda(m *models.MargenMoneda) []interface{} {
+	return []interface{}{
+		m.VentaNetaUsd, m.CostoUsd, m.CostoClp, m.MargenUsd, m.MargenClp, m.MargenPct,
+		m.TipoCambioVentas, m.TipoCambioIngresos, m.CifPromedioClp,
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+func TestCalcularMargenMoneda(t *testing.T) {
+	tabla := TablaTipoCambio{valores: []models.TipoCambio{
+		{Fecha: "2025-01-02", Valor: 900},
+		{Fecha: "2025-01-10", Valor: 1000},
+	}}
+	// Tipo de cambio de los ingresos ponderado por unidades: (10 × 900 + 30 × 1.000) / 40 = 975
+	r := models.ReporteCombinado{
+		CifPromedioUsd: 10,
+		Ingresos: []models.MetadatoProducto{
+			{Zeta: "Z1", UnidadesIngresadas: 10, FechaIngreso: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+			{Zeta: "Z2", UnidadesIngresadas: 30, FechaIngreso: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+	ventas := []models.VentaDetallada{
+		// Al 900 del 2 de enero: venta USD 40, costo USD 16 y CLP 14.400
+		{FechaEmision: "2025-01-03", Cantidad: 2, TotalVentaCLP: 36000, CostoUnitarioUSD: 8},
+		// Sin costo de STOCKS: CIF promedio al 1.000 del 10 de enero
+		{FechaEmision: "2025-01-11T00:00:00Z", Cantidad: 1, TotalVentaCLP: 20000},
+		// Antes del primer tipo de cambio: usa el 900, aproximado
+		{FechaEmision: "2025-01-01", Cantidad: 1, TotalVentaCLP: 9000, CostoUnitarioUSD: 5},
+		// Los documentos anulados y las fechas no válidas no se consideran
+		{FechaEmision: "2025-01-05", Cantidad: 5, Nula: true, CostoUnitarioUSD: 8},
+		{FechaEmision: "sin fecha", Cantidad: 1, TotalVentaCLP: 1000},
+	}
+
+	margen := calcularMargenMoneda(tabla, r, ventas)
+	esperado := models.MargenMoneda{
+		VentaNetaClp:       65000,
+		VentaNetaUsd:       70,
+		CostoUsd:           31,
+		CostoClp:           28900,
+		MargenUsd:          39,
+		MargenClp:          36100,
+		MargenPct:          55.54,
+		TipoCambioVentas:   928.57,
+		TipoCambioIngresos: 975,
+		CifPromedioClp:     9750,
+		LineasAproximadas:  1,
+		LineasSinCosto:     1,
+	}
+	if *margen != esperado {
+		t.Errorf("calcularMargenMoneda =\n%+v\nse esperaba\n%+v", *margen, esperado)
+	}
+
+	// Sin ventas ni historial: el tipo de cambio de los ingresos sale de la fecha del primer ingreso
+	sinVentas := calcularMargenMoneda(tabla, models.ReporteCombinado{CifPromedioUsd: 2, FechaPrimerIngreso: "2025-01-12"}, nil)
+	if sinVentas.TipoCambioIngresos != 1000 || sinVentas.CifPromedioClp != 2000 || sinVentas.MargenPct != 0 || sinVentas.TipoCambioVentas != 0 {
+		t.Errorf("calcularMargenMoneda sin ventas = %+v", *sinVentas)
+	}
+}
@@ -0,0 +1,482 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Nombres de las estrategias de coincidencia disponibles
+const (
+	EstrategiaAlias       = "alias"
+	EstrategiaExacta      = "exacta"
+	EstrategiaNormalizada = "normalizada"
+	EstrategiaPrefijo     = "prefijo"
+	EstrategiaDistancia   = "distancia"
+)
+
+// caracteresNoAlfanumericos se elimina de los códigos al normalizarlos
+var caracteresNoAlfanumericos = regexp.MustCompile(`[^A-Z0-9]`)
+
+// normalizarCodigo normaliza un código de producto para mejorar la comparación
+func normalizarCodigo(codigo string) string {
+	// Convertir a mayúsculas y eliminar espacios en blanco
+	codigo = strings.ToUpper(strings.TrimSpace(codigo))
+
+	// Eliminar todos los caracteres especiales comunes que pueden causar problemas en comparaciones
+	return caracteresNoAlfanumericos.ReplaceAllString(codigo, "")
+}
+
+// Coincidencia describe cómo se emparejó un código de inventario con uno de ventas
+type Coincidencia struct {
+	CodigoInventario string  `json:"codigoInventario"`
+	CodigoVentas     string  `json:"codigoVentas"`
+	Metodo           string  `json:"metodo"`
+	Confianza        float64 `json:"confianza"`
+}
+
+// EstrategiaCoincidencia busca, entre los códigos de ventas aún disponibles,
+// el que corresponde a un código de inventario
+type EstrategiaCoincidencia interface {
+	// Nombre identifica la estrategia en la configuración y en el reporte
+	Nombre() string
+
+	// Buscar devuelve el código de ventas encontrado y la confianza (0 a 1)
+	Buscar(codigoInventario string, candidatos *Candidatos) (codigoVentas string, confianza float64, ok bool)
+}
+
+// TablaAlias resuelve equivalencias conocidas entre códigos de inventario y de ventas
+type TablaAlias interface {
+	BuscarAlias(codigoInventario string) (codigoVentas string, ok bool)
+}
+
+// MapaAlias es una tabla de alias fija en memoria (código inventario -> código ventas)
+type MapaAlias map[string]string
+
+// BuscarAlias implementa TablaAlias
+func (m MapaAlias) BuscarAlias(codigoInventario string) (string, bool) {
+	codigo, ok := m[codigoInventario]
+	return codigo, ok
+}
+
+// AliasEncadenados consulta varias tablas de alias en orden y usa la primera que responde
+type AliasEncadenados []TablaAlias
+
+// BuscarAlias implementa TablaAlias
+func (a AliasEncadenados) BuscarAlias(codigoInventario string) (string, bool) {
+	for _, tabla := range a {
+		if codigo, ok := tabla.BuscarAlias(codigoInventario); ok {
+			return codigo, true
+		}
+	}
+	return "", false
+}
+
+// Candidato es un código de ventas disponible con su forma normalizada
+type Candidato struct {
+	Codigo      string
+	Normalizado string
+}
+
+// Candidatos contiene los códigos de ventas y cuáles siguen sin emparejar. Los disponibles
+// se mantienen en un único índice ordenado por forma normalizada, que se actualiza en el
+// lugar al reclamar un código.
+type Candidatos struct {
+	normalizados   map[string]string
+	porNormalizado map[string][]string
+	reclamados     map[string]bool
+	indice         []Candidato // Disponibles, ordenados por (Normalizado, Codigo)
+}
+
+// nuevosCandidatos indexa los códigos de ventas para las estrategias
+func nuevosCandidatos(codigos []string) *Candidatos {
+	c := &Candidatos{
+		normalizados:   make(map[string]string, len(codigos)),
+		porNormalizado: make(map[string][]string),
+		reclamados:     make(map[string]bool),
+		indice:         make([]Candidato, 0, len(codigos)),
+	}
+
+	for _, codigo := range codigos {
+		if _, repetido := c.normalizados[codigo]; repetido {
+			continue
+		}
+		norm := normalizarCodigo(codigo)
+		c.normalizados[codigo] = norm
+		c.indice = append(c.indice, Candidato{Codigo: codigo, Normalizado: norm})
+	}
+	sort.Slice(c.indice, func(i, j int) bool { return menorCandidato(c.indice[i], c.indice[j]) })
+	for _, cand := range c.indice {
+		c.porNormalizado[cand.Normalizado] = append(c.porNormalizado[cand.Normalizado], cand.Codigo)
+	}
+	return c
+}
+
+// menorCandidato ordena los candidatos por forma normalizada y luego por código
+func menorCandidato(a, b Candidato) bool {
+	if a.Normalizado != b.Normalizado {
+		return a.Normalizado < b.Normalizado
+	}
+	return a.Codigo < b.Codigo
+}
+
+// Disponible indica si el código existe y aún no fue emparejado
+func (c *Candidatos) Disponible(codigo string) bool {
+	_, existe := c.normalizados[codigo]
+	return existe && !c.reclamados[codigo]
+}
+
+// ConPrefijo devuelve los candidatos disponibles cuya forma normalizada empieza con prefijo
+// (todos si es vacío), ordenados por forma normalizada. Es una vista del índice: no debe
+// modificarse ni conservarse después de reclamar un código.
+func (c *Candidatos) ConPrefijo(prefijo string) []Candidato {
+	desde := sort.Search(len(c.indice), func(i int) bool { return c.indice[i].Normalizado >= prefijo })
+	hasta := desde
+	for hasta < len(c.indice) && strings.HasPrefix(c.indice[hasta].Normalizado, prefijo) {
+		hasta++
+	}
+	return c.indice[desde:hasta]
+}
+
+// Normalizado devuelve la forma normalizada de un código de ventas
+func (c *Candidatos) Normalizado(codigo string) string {
+	return c.normalizados[codigo]
+}
+
+// PorNormalizado devuelve los códigos disponibles cuya forma normalizada es norm
+func (c *Candidatos) PorNormalizado(norm string) []string {
+	var disponibles []string
+	for _, codigo := range c.porNormalizado[norm] {
+		if !c.reclamados[codigo] {
+			disponibles = append(disponibles, codigo)
+		}
+	}
+	return disponibles
+}
+
+// reclamar marca un código de ventas como emparejado y lo quita del índice
+func (c *Candidatos) reclamar(codigo string) {
+	norm, existe := c.normalizados[codigo]
+	if !existe || c.reclamados[codigo] {
+		return
+	}
+	c.reclamados[codigo] = true
+
+	buscado := Candidato{Codigo: codigo, Normalizado: norm}
+	i := sort.Search(len(c.indice), func(i int) bool { return !menorCandidato(c.indice[i], buscado) })
+	if i < len(c.indice) && c.indice[i] == buscado {
+		c.indice = append(c.indice[:i], c.indice[i+1:]...)
+	}
+}
+
+// MatcherConfig define el orden de las estrategias y sus umbrales
+type MatcherConfig struct {
+	Estrategias     []string
+	PrefijoMinimo   int
+	DistanciaMaxima int
+	ConfianzaMinima float64
+}
+
+// Matcher empareja códigos de inventario (MySQL) con códigos de ventas (SQL Server)
+// aplicando estrategias en el orden configurado. Cada código de ventas se
+// empareja como máximo con un código de inventario.
+type Matcher struct {
+	config      MatcherConfig
+	alias       TablaAlias
+	estrategias []EstrategiaCoincidencia
+}
+
+// NewMatcher crea un nuevo emparejador de códigos
+func NewMatcher(config MatcherConfig, alias TablaAlias) (*Matcher, error) {
+	m := &Matcher{config: config, alias: alias}
+	estrategias, err := m.construirEstrategias(config.Estrategias)
+	if err != nil {
+		return nil, err
+	}
+	m.estrategias = estrategias
+	return m, nil
+}
+
+// ConEstrategias devuelve una copia del emparejador con otro orden de estrategias
+func (m *Matcher) ConEstrategias(nombres []string) (*Matcher, error) {
+	config := m.config
+	config.Estrategias = nombres
+	return NewMatcher(config, m.alias)
+}
+
+// Estrategias devuelve los nombres de las estrategias en el orden en que se aplican
+func (m *Matcher) Estrategias() []string {
+	nombres := make([]string, len(m.estrategias))
+	for i, e := range m.estrategias {
+		nombres[i] = e.Nombre()
+	}
+	return nombres
+}
+
+// construirEstrategias crea las estrategias a partir de sus nombres
+func (m *Matcher) construirEstrategias(nombres []string) ([]EstrategiaCoincidencia, error) {
+	var estrategias []EstrategiaCoincidencia
+	for _, nombre := range nombres {
+		switch strings.ToLower(strings.TrimSpace(nombre)) {
+		case EstrategiaAlias:
+			if m.alias != nil {
+				estrategias = append(estrategias, estrategiaAlias{alias: m.alias})
+			}
+		case EstrategiaExacta:
+			estrategias = append(estrategias, estrategiaExacta{})
+		case EstrategiaNormalizada:
+			estrategias = append(estrategias, estrategiaNormalizada{})
+		case EstrategiaPrefijo:
+			estrategias = append(estrategias, estrategiaPrefijo{minimo: m.config.PrefijoMinimo})
+		case EstrategiaDistancia:
+			estrategias = append(estrategias, estrategiaDistancia{maxima: m.config.DistanciaMaxima})
+		default:
+			return nil, fmt.Errorf("estrategia de coincidencia desconocida: %q", nombre)
+		}
+	}
+	return estrategias, nil
+}
+
+// Emparejar asocia cada código de inventario con un código de ventas.
+// Las estrategias se aplican por turnos: primero la primera estrategia sobre
+// todos los códigos, luego la siguiente sobre los que quedaron sin pareja, etc.
+// El resultado está indexado por código de inventario.
+func (m *Matcher) Emparejar(codigosInventario, codigosVentas []string) map[string]Coincidencia {
+	candidatos := nuevosCandidatos(codigosVentas)
+
+	pendientes := append([]string(nil), codigosInventario...)
+	sort.Strings(pendientes)
+
+	resultado := make(map[string]Coincidencia)
+	for _, estrategia := range m.estrategias {
+		pendientes = m.aplicarEstrategia(estrategia, pendientes, candidatos, resultado)
+	}
+
+	return resultado
+}
+
+// aplicarEstrategia empareja los códigos pendientes con una estrategia y devuelve los que
+// quedan sin pareja. Todos los códigos buscan sobre los mismos candidatos; si varios proponen
+// el mismo código de ventas gana la propuesta de mayor confianza (a igual confianza, el código
+// de inventario menor) y los demás vuelven a buscar entre los candidatos que quedan.
+func (m *Matcher) aplicarEstrategia(estrategia EstrategiaCoincidencia, pendientes []string, candidatos *Candidatos, resultado map[string]Coincidencia) []string {
+	var sinPareja []string
+	for len(pendientes) > 0 {
+		var propuestas []Coincidencia
+		for _, codigoInv := range pendientes {
+			codigoVen, confianza, ok := estrategia.Buscar(codigoInv, candidatos)
+			if !ok || confianza < m.config.ConfianzaMinima || !candidatos.Disponible(codigoVen) {
+				sinPareja = append(sinPareja, codigoInv)
+				continue
+			}
+			propuestas = append(propuestas, Coincidencia{
+				CodigoInventario: codigoInv,
+				CodigoVentas:     codigoVen,
+				Metodo:           estrategia.Nombre(),
+				Confianza:        confianza,
+			})
+		}
+
+		sort.SliceStable(propuestas, func(i, j int) bool {
+			return propuestas[i].Confianza > propuestas[j].Confianza
+		})
+		pendientes = pendientes[:0]
+		for _, p := range propuestas {
+			if !candidatos.Disponible(p.CodigoVentas) {
+				pendientes = append(pendientes, p.CodigoInventario)
+				continue
+			}
+			candidatos.reclamar(p.CodigoVentas)
+			resultado[p.CodigoInventario] = p
+		}
+		sort.Strings(pendientes)
+	}
+
+	sort.Strings(sinPareja)
+	return sinPareja
+}
+
+// estrategiaAlias usa la tabla de alias conocidos
+type estrategiaAlias struct {
+	alias TablaAlias
+}
+
+func (e estrategiaAlias) Nombre() string { return EstrategiaAlias }
+
+func (e estrategiaAlias) Buscar(codigo string, candidatos *Candidatos) (string, float64, bool) {
+	codigoVen, ok := e.alias.BuscarAlias(codigo)
+	if !ok || !candidatos.Disponible(codigoVen) {
+		return "", 0, false
+	}
+	return codigoVen, 1, true
+}
+
+// estrategiaExacta exige el mismo código en ambos sistemas
+type estrategiaExacta struct{}
+
+func (estrategiaExacta) Nombre() string { return EstrategiaExacta }
+
+func (estrategiaExacta) Buscar(codigo string, candidatos *Candidatos) (string, float64, bool) {
+	if candidatos.Disponible(codigo) {
+		return codigo, 1, true
+	}
+	return "", 0, false
+}
+
+// estrategiaNormalizada compara los códigos sin espacios, símbolos ni diferencias de mayúsculas
+type estrategiaNormalizada struct{}
+
+func (estrategiaNormalizada) Nombre() string { return EstrategiaNormalizada }
+
+func (estrategiaNormalizada) Buscar(codigo string, candidatos *Candidatos) (string, float64, bool) {
+	norm := normalizarCodigo(codigo)
+	if norm == "" {
+		return "", 0, false
+	}
+
+	codigos := candidatos.PorNormalizado(norm)
+	switch len(codigos) {
+	case 0:
+		return "", 0, false
+	case 1:
+		return codigos[0], 0.95, true
+	default:
+		// Varios códigos de ventas se normalizan igual: se toma el primero con menor confianza
+		return codigos[0], 0.8, true
+	}
+}
+
+// estrategiaPrefijo acepta códigos donde uno es prefijo del otro (normalizados),
+// con un largo mínimo común. La confianza es la proporción de caracteres compartidos.
+type estrategiaPrefijo struct {
+	minimo int
+}
+
+func (e estrategiaPrefijo) Nombre() string { return EstrategiaPrefijo }
+
+func (e estrategiaPrefijo) Buscar(codigo string, candidatos *Candidatos) (string, float64, bool) {
+	norm := normalizarCodigo(codigo)
+	if len(norm) < e.minimo {
+		return "", 0, false
+	}
+
+	var mejor string
+	var mejorConfianza float64
+	empate := false
+	considerar := func(codigoVen, normVen string) {
+		corto, largo := norm, normVen
+		if len(corto) > len(largo) {
+			corto, largo = largo, corto
+		}
+		confianza := float64(len(corto)) / float64(len(largo))
+		if confianza > mejorConfianza {
+			mejor, mejorConfianza, empate = codigoVen, confianza, false
+		} else if confianza == mejorConfianza {
+			empate = true
+		}
+	}
+
+	// Códigos de ventas que empiezan con el código: un tramo contiguo del índice
+	for _, cand := range candidatos.ConPrefijo(norm) {
+		considerar(cand.Codigo, cand.Normalizado)
+	}
+	// Códigos de ventas que son prefijo del código, con el largo mínimo
+	for largo := e.minimo; largo < len(norm); largo++ {
+		for _, codigoVen := range candidatos.PorNormalizado(norm[:largo]) {
+			considerar(codigoVen, norm[:largo])
+		}
+	}
+
+	// Si dos códigos de ventas son igual de probables no se elige ninguno
+	if mejor == "" || empate {
+		return "", 0, false
+	}
+	return mejor, mejorConfianza, true
+}
+
+// estrategiaDistancia acepta códigos con pocas diferencias de edición (Levenshtein)
+// sobre la forma normalizada. La confianza es 1 - distancia/largo.
+type estrategiaDistancia struct {
+	maxima int
+}
+
+func (e estrategiaDistancia) Nombre() string { return EstrategiaDistancia }
+
+func (e estrategiaDistancia) Buscar(codigo string, candidatos *Candidatos) (string, float64, bool) {
+	norm := normalizarCodigo(codigo)
+	if norm == "" || e.maxima <= 0 {
+		return "", 0, false
+	}
+
+	var mejor string
+	mejorDistancia := e.maxima + 1
+	empate := false
+	for _, cand := range candidatos.ConPrefijo("") {
+		if abs(len(cand.Normalizado)-len(norm)) > e.maxima {
+			continue
+		}
+
+		d := distanciaEdicion(norm, cand.Normalizado, e.maxima)
+		if d < mejorDistancia {
+			mejor, mejorDistancia, empate = cand.Codigo, d, false
+		} else if d == mejorDistancia {
+			empate = true
+		}
+	}
+
+	if mejor == "" || empate {
+		return "", 0, false
+	}
+
+	largo := len(norm)
+	if l := len(candidatos.Normalizado(mejor)); l > largo {
+		largo = l
+	}
+	return mejor, 1 - float64(mejorDistancia)/float64(largo), true
+}
+
+// distanciaEdicion calcula la distancia de Levenshtein entre a y b.
+// Si la distancia supera maxima devuelve maxima+1 sin terminar el cálculo.
+func distanciaEdicion(a, b string, maxima int) int {
+	anterior := make([]int, len(b)+1)
+	actual := make([]int, len(b)+1)
+	for j := range anterior {
+		anterior[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		actual[0] = i
+		minimoFila := actual[0]
+		for j := 1; j <= len(b); j++ {
+			costo := 1
+			if a[i-1] == b[j-1] {
+				costo = 0
+			}
+			actual[j] = minInt(minInt(anterior[j]+1, actual[j-1]+1), anterior[j-1]+costo)
+			if actual[j] < minimoFila {
+				minimoFila = actual[j]
+			}
+		}
+		if minimoFila > maxima {
+			return maxima + 1
+		}
+		anterior, actual = actual, anterior
+	}
+
+	return anterior[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
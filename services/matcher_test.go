@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// describirCoincidencias resume el resultado como "INV>VEN metodo confianza", ordenado por
+// código de inventario
+func describirCoincidencias(resultado map[string]Coincidencia) string {
+	var partes []string
+	for _, c := range resultado {
+		partes = append(partes, fmt.Sprintf("%s>%s %s %.3f", c.CodigoInventario, c.CodigoVentas, c.Metodo, c.Confianza))
+	}
+	sort.Strings(partes)
+	return strings.Join(partes, "; ")
+}
+
+func TestMatcherEmparejar(t *testing.T) {
+	config := MatcherConfig{
+		Estrategias:     []string{EstrategiaAlias, EstrategiaExacta, EstrategiaNormalizada, EstrategiaPrefijo, EstrategiaDistancia},
+		PrefijoMinimo:   4,
+		DistanciaMaxima: 1,
+		ConfianzaMinima: 0.5,
+	}
+	alias := MapaAlias{"CER-OLD": "CER 2025"}
+
+	casos := []struct {
+		nombre     string
+		inventario []string
+		ventas     []string
+		esperado   string
+	}{
+		{
+			nombre:     "exacta antes que normalizada",
+			inventario: []string{"ABC123"},
+			ventas:     []string{"ABC-123", "ABC123"},
+			esperado:   "ABC123>ABC123 exacta 1.000",
+		},
+		{
+			nombre:     "normalizada",
+			inventario: []string{"abc-123"},
+			ventas:     []string{"ABC 123"},
+			esperado:   "abc-123>ABC 123 normalizada 0.950",
+		},
+		{
+			nombre:     "normalizada con varios candidatos toma el menor con menos confianza",
+			inventario: []string{"abc123"},
+			ventas:     []string{"ABC-123", "ABC 123"},
+			esperado:   "abc123>ABC 123 normalizada 0.800",
+		},
+		{
+			nombre:     "alias",
+			inventario: []string{"CER-OLD"},
+			ventas:     []string{"CER 2025"},
+			esperado:   "CER-OLD>CER 2025 alias 1.000",
+		},
+		{
+			nombre:     "alias a un código inexistente",
+			inventario: []string{"CER-OLD"},
+			ventas:     []string{"CER 2024"},
+			esperado:   "",
+		},
+		{
+			nombre:     "prefijo del código de ventas",
+			inventario: []string{"CERCOR"},
+			ventas:     []string{"CERCORBEI"},
+			esperado:   "CERCOR>CERCORBEI prefijo 0.667",
+		},
+		{
+			nombre:     "código de ventas prefijo del de inventario",
+			inventario: []string{"ADHPEG25KG"},
+			ventas:     []string{"ADHPEG25"},
+			esperado:   "ADHPEG25KG>ADHPEG25 prefijo 0.800",
+		},
+		{
+			nombre:     "prefijo bajo el largo mínimo",
+			inventario: []string{"CER"},
+			ventas:     []string{"CERCORBEI"},
+			esperado:   "",
+		},
+		{
+			nombre:     "prefijo con empate",
+			inventario: []string{"CERCOR"},
+			ventas:     []string{"CERCORAB", "CERCORBA"},
+			esperado:   "",
+		},
+		{
+			nombre:     "distancia de edición",
+			inventario: []string{"VIN10366244"},
+			ventas:     []string{"VIN-10366245"},
+			esperado:   "VIN10366244>VIN-10366245 distancia 0.909",
+		},
+		{
+			nombre:     "distancia con empate",
+			inventario: []string{"ABCD1"},
+			ventas:     []string{"ABCD2", "ABCD3"},
+			esperado:   "",
+		},
+		{
+			nombre:     "cada código de ventas se empareja una vez",
+			inventario: []string{"ABC123", "abc-123"},
+			ventas:     []string{"ABC123"},
+			esperado:   "ABC123>ABC123 exacta 1.000",
+		},
+		{
+			nombre:     "el conflicto lo gana la mayor confianza y el otro vuelve a buscar",
+			inventario: []string{"PISO12", "PISO1234X"},
+			ventas:     []string{"PISO1234", "PISO12ZZZZZZ"},
+			esperado:   "PISO1234X>PISO1234 prefijo 0.889; PISO12>PISO12ZZZZZZ prefijo 0.500",
+		},
+		{
+			nombre:     "a igual confianza gana el código de inventario menor",
+			inventario: []string{"TILE1B", "TILE1A"},
+			ventas:     []string{"TILE1"},
+			esperado:   "TILE1A>TILE1 prefijo 0.833",
+		},
+	}
+
+	m, err := NewMatcher(config, alias)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if obtenido := describirCoincidencias(m.Emparejar(c.inventario, c.ventas)); obtenido != c.esperado {
+				t.Errorf("Emparejar = %q, se esperaba %q", obtenido, c.esperado)
+			}
+
+			// El resultado no depende del orden de los códigos
+			inventario := append([]string(nil), c.inventario...)
+			ventas := append([]string(nil), c.ventas...)
+			sort.Sort(sort.Reverse(sort.StringSlice(inventario)))
+			sort.Sort(sort.Reverse(sort.StringSlice(ventas)))
+			if obtenido := describirCoincidencias(m.Emparejar(inventario, ventas)); obtenido != c.esperado {
+				t.Errorf("Emparejar en orden inverso = %q, se esperaba %q", obtenido, c.esperado)
+			}
+		})
+	}
+}
+
+func TestMatcherConEstrategias(t *testing.T) {
+	m, err := NewMatcher(MatcherConfig{Estrategias: []string{EstrategiaExacta}, PrefijoMinimo: 4, DistanciaMaxima: 1}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+
+	soloDistancia, err := m.ConEstrategias([]string{" Distancia "})
+	if err != nil {
+		t.Fatalf("ConEstrategias: %v", err)
+	}
+	if nombres := strings.Join(soloDistancia.Estrategias(), ","); nombres != EstrategiaDistancia {
+		t.Errorf("Estrategias = %q, se esperaba %q", nombres, EstrategiaDistancia)
+	}
+	resultado := soloDistancia.Emparejar([]string{"ABC123"}, []string{"ABC123"})
+	if c := resultado["ABC123"]; c.Metodo != EstrategiaDistancia || math.Abs(c.Confianza-1) > 1e-9 {
+		t.Errorf("Emparejar = %+v, se esperaba distancia con confianza 1", c)
+	}
+
+	// Sin tabla de alias la estrategia se omite
+	sinAlias, err := m.ConEstrategias([]string{EstrategiaAlias, EstrategiaExacta})
+	if err != nil {
+		t.Fatalf("ConEstrategias: %v", err)
+	}
+	if nombres := strings.Join(sinAlias.Estrategias(), ","); nombres != EstrategiaExacta {
+		t.Errorf("Estrategias = %q, se esperaba %q", nombres, EstrategiaExacta)
+	}
+
+	if _, err := m.ConEstrategias([]string{"fonetica"}); err == nil {
+		t.Error("ConEstrategias con una estrategia desconocida no devolvió error")
+	}
+}
+
+func TestDistanciaEdicion(t *testing.T) {
+	casos := []struct {
+		a, b     string
+		maxima   int
+		esperado int
+	}{
+		{"ABC", "ABC", 2, 0},
+		{"ABC", "ABD", 2, 1},
+		{"ABC", "AC", 2, 1},
+		{"KITTEN", "SITTING", 3, 3},
+		{"KITTEN", "SITTING", 2, 3},
+		{"", "ABC", 5, 3},
+	}
+	for _, c := range casos {
+		if d := distanciaEdicion(c.a, c.b, c.maxima); d != c.esperado {
+			t.Errorf("distanciaEdicion(%q, %q, %d) = %d, se esperaba %d", c.a, c.b, c.maxima, d, c.esperado)
+		}
+	}
+}
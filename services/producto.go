@@ -0,0 +1,239 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// GenerarFichaProducto reúne todo lo que se sabe de un producto en el período: datos maestros e
+// historial de ingresos del inventario, el código emparejado en el otro sistema, el resumen y la
+// serie mensual de ventas, los indicadores de rotación y los documentos más recientes. El código
+// debe coincidir exactamente con el de inventario o el de ventas.
+func (s *ReporteService) GenerarFichaProducto(filtro models.ReporteFiltro, codigo string, params models.ParametrosFichaProducto) (*models.FichaProducto, error) {
+	// El código de ventas puede diferir del de inventario, por lo que se empareja todo el catálogo
+	filtro.CodigoProducto = ""
+	filtro.DesglosePorAnio = true
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, err
+	}
+	encontrado, err := buscarProducto(coincidentes, sinCoincidencia, codigo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los lotes cuentan las ventas desde el primer ingreso; el resto de la ficha, las del período
+	var ventas, ventasLotes []models.VentaDetallada
+	if encontrado.CodigoVentas != "" {
+		porCodigo, err := s.ventasPorCodigo(filtroLotes(filtro, *encontrado), encontrado.CodigoVentas)
+		if err != nil {
+			return nil, err
+		}
+		ventasLotes = porCodigo[encontrado.CodigoVentas]
+		for _, venta := range ventasLotes {
+			if venta.FechaEmision >= filtro.FechaInicio {
+				ventas = append(ventas, venta)
+			}
+		}
+	}
+
+	periodos, err := periodosSerie(filtro.FechaInicio, filtro.FechaFin, models.GranularidadMes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	ficha := &models.FichaProducto{
+		Codigo:       codigo,
+		FechaInicio:  filtro.FechaInicio,
+		FechaFin:     filtro.FechaFin,
+		Sucursales:   filtro.Sucursales,
+		Coincidencia: coincidenciaProducto(*encontrado, coincidentes),
+		Ingresos: models.IngresosProducto{
+			Historial:    encontrado.Ingresos,
+			PorAnio:      encontrado.IngresadaPorAnio,
+			Lotes:        calcularLotes(encontrado.Ingresos, ventasLotes, fechaReferencia(filtro.FechaFin)),
+			Advertencias: encontrado.AdvertenciasHistorial,
+		},
+		Ventas:       resumenVentasProducto(*encontrado, ventas),
+		SerieMensual: serieProducto(ventas, periodos),
+		Rotacion: models.RotacionProducto{
+			SaldoPeriodo:         encontrado.SaldoPeriodo,
+			DiasPeriodo:          encontrado.DiasPeriodo,
+			VelocidadVentaDiaria: encontrado.VelocidadVentaDiaria,
+			DiasDeStock:          encontrado.DiasDeStock,
+			RotacionInventario:   encontrado.RotacionInventario,
+			Gmroi:                encontrado.Gmroi,
+			PorcentajeVendido:    encontrado.PorcentajeVendido,
+		},
+		Documentos: documentosRecientes(ventas, params.Documentos),
+	}
+	if ficha.Coincidencia.CodigoInventario != "" {
+		ficha.Maestro = maestroProducto(*encontrado)
+	}
+	if ficha.Ingresos.Historial == nil {
+		ficha.Ingresos.Historial = []models.MetadatoProducto{}
+	}
+	if ficha.Ingresos.PorAnio == nil {
+		ficha.Ingresos.PorAnio = []models.UnidadesAnio{}
+	}
+	return ficha, nil
+}
+
+// coincidenciaProducto describe el emparejamiento del producto entre inventario y ventas. Las filas
+// sin coincidencia solo existen en ventas y llevan el código de ventas en ambos campos.
+func coincidenciaProducto(r models.ReporteCombinado, coincidentes []models.ReporteCombinado) models.CoincidenciaProducto {
+	if r.MetodoCoincidencia == "" && r.CodigoVentas != "" {
+		return models.CoincidenciaProducto{CodigoVentas: r.CodigoVentas}
+	}
+
+	coincidencia := models.CoincidenciaProducto{
+		CodigoInventario: r.CodigoProducto,
+		CodigoVentas:     r.CodigoVentas,
+		Metodo:           r.MetodoCoincidencia,
+		Confianza:        r.ConfianzaCoincidencia,
+	}
+	if r.CodigoVentas != "" {
+		for _, otro := range coincidentes {
+			if otro.CodigoVentas == r.CodigoVentas && otro.CodigoProducto != r.CodigoProducto {
+				coincidencia.OtrosCodigosInventario = append(coincidencia.OtrosCodigosInventario, otro.CodigoProducto)
+			}
+		}
+		sort.Strings(coincidencia.OtrosCodigosInventario)
+	}
+	return coincidencia
+}
+
+// maestroProducto extrae los datos de inventario de una fila del reporte combinado
+func maestroProducto(r models.ReporteCombinado) *models.MaestroProducto {
+	return &models.MaestroProducto{
+		CodigoProducto:     r.CodigoProducto,
+		Nombre:             r.Nombre,
+		Marca:              r.Marca,
+		Categoria:          r.Categoria,
+		Dimensiones:        r.Dimensiones,
+		Packing:            r.Packing,
+		CifPromedioUsd:     r.CifPromedioUsd,
+		CifPromedioClp:     r.CifPromedioClp,
+		CantidadIngresada:  r.CantidadIngresada,
+		CantidadIngresos:   r.CantidadIngresos,
+		FechaPrimerIngreso: r.FechaPrimerIngreso,
+		FechaUltimoIngreso: r.FechaUltimoIngreso,
+		DiasEnInventario:   r.DiasEnInventario,
+	}
+}
+
+// resumenVentasProducto resume las ventas del producto: los totales del reporte combinado y, de
+// las líneas, los documentos, los clientes y el desglose por sucursal
+func resumenVentasProducto(r models.ReporteCombinado, ventas []models.VentaDetallada) models.ResumenVentasProducto {
+	resumen := models.ResumenVentasProducto{
+		CantidadVendida:        r.CantidadVendida,
+		VentaNetaClp:           r.VentaNetaTotalClp,
+		UtilidadClp:            r.UtilidadClp,
+		Lineas:                 len(ventas),
+		PrecioProductoClp:      r.PrecioProductoClp,
+		PrecioOfertaClp:        r.PrecioOfertaClp,
+		PrecioVentaPromedioClp: r.PrecioVentaPromedioClp,
+		UltimaFechaVenta:       r.UltimaFechaVenta,
+		PorSucursal:            []models.VentaSucursal{},
+	}
+
+	documentos := make(map[string]bool)
+	clientes := make(map[string]bool)
+	porSucursal := make(map[int]*models.VentaSucursal)
+	for _, v := range ventas {
+		documentos[claveDocumento(v)] = true
+		if !esSinCliente(v.Cliente) {
+			clientes[v.Cliente] = true
+		}
+		fecha := ""
+		if t, ok := parsearFechaVenta(v.FechaEmision); ok {
+			fecha = formatearFecha(t)
+		}
+		if fecha != "" && (resumen.PrimeraFechaVenta == "" || fecha < resumen.PrimeraFechaVenta) {
+			resumen.PrimeraFechaVenta = fecha
+		}
+
+		suc, ok := porSucursal[v.Sucursal]
+		if !ok {
+			suc = &models.VentaSucursal{CodigoProducto: v.CodigoProducto, Sucursal: v.Sucursal}
+			porSucursal[v.Sucursal] = suc
+		}
+		suc.CantidadTotalVendida += v.Cantidad
+		suc.TotalVentasCLP += v.TotalVentaCLP
+		suc.CantidadDeVentas++
+		if fecha > suc.UltimaFechaVenta {
+			suc.UltimaFechaVenta = fecha
+		}
+	}
+	resumen.Documentos = len(documentos)
+	resumen.Clientes = len(clientes)
+	for _, suc := range porSucursal {
+		resumen.PorSucursal = append(resumen.PorSucursal, *suc)
+	}
+	sort.Slice(resumen.PorSucursal, func(i, j int) bool {
+		return resumen.PorSucursal[i].Sucursal < resumen.PorSucursal[j].Sucursal
+	})
+	return resumen
+}
+
+// serieProducto agrupa las líneas de venta del producto por período, con los períodos sin ventas en cero
+func serieProducto(ventas []models.VentaDetallada, periodos []models.PeriodoSerie) []models.PuntoSerie {
+	puntos := make([]models.PuntoSerie, len(periodos))
+	for i, p := range periodos {
+		puntos[i].Periodo = p.Periodo
+	}
+	indices := indicesPeriodos(periodos)
+	for _, v := range ventas {
+		fecha, ok := parsearFechaVenta(v.FechaEmision)
+		if !ok {
+			continue
+		}
+		i, ok := indices[fecha.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		puntos[i].Cantidad += v.Cantidad
+		puntos[i].VentaClp += v.TotalVentaCLP
+		puntos[i].Transacciones++
+	}
+	return puntos
+}
+
+// documentosRecientes agrupa las líneas por documento y devuelve los más recientes primero
+func documentosRecientes(ventas []models.VentaDetallada, limite int) []models.DocumentoProducto {
+	indice := make(map[string]int)
+	documentos := []models.DocumentoProducto{}
+	for _, v := range ventas {
+		clave := claveDocumento(v)
+		i, ok := indice[clave]
+		if !ok {
+			fecha, _ := parsearFechaVenta(v.FechaEmision)
+			i = len(documentos)
+			indice[clave] = i
+			documentos = append(documentos, models.DocumentoProducto{
+				Fecha:           formatearFecha(fecha),
+				TipoDocumento:   v.TipoDocumento,
+				CodigoDocumento: v.CodigoDocumento,
+				Sucursal:        v.Sucursal,
+				Cliente:         v.Cliente,
+				Zeta:            v.Zeta,
+				Nulo:            v.Nula,
+			})
+		}
+		documentos[i].Cantidad += v.Cantidad
+		documentos[i].VentaClp += v.TotalVentaCLP
+	}
+
+	sort.SliceStable(documentos, func(i, j int) bool {
+		if documentos[i].Fecha != documentos[j].Fecha {
+			return documentos[i].Fecha > documentos[j].Fecha
+		}
+		return documentos[i].CodigoDocumento > documentos[j].CodigoDocumento
+	})
+	if len(documentos) > limite {
+		documentos = documentos[:limite]
+	}
+	return documentos
+}
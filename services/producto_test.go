@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ventasFichaPrueba vende PISO01 (inventario PISO-01) antes y durante el período, en dos
+// sucursales, y SOLO-VENTA, que no está en inventario
+func ventasFichaPrueba() ([]datasource.LineaVentaFixture, []datasource.IngresoFixture) {
+	enero := ventaZeta("2025-01-05", "PISO01", "Z1", 4)
+	enero.Cliente = "ANA"
+	// Segunda línea de la misma boleta
+	eneroZ2 := ventaZeta("2025-01-05", "PISO01", "Z2", 1)
+	eneroZ2.Cliente = "ANA"
+	febrero := ventaZeta("2025-02-10", "PISO01", "Z2", 2)
+	febrero.Sucursal = 212
+
+	ventas := []datasource.LineaVentaFixture{
+		ventaZeta("2024-11-20", "PISO01", "Z1", 6), // Antes del período: solo cuenta para los lotes
+		enero,
+		eneroZ2,
+		febrero,
+		ventaPrueba("2025-01-07", "SOLO-VENTA", 3, 500),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("PISO-01", "Z1", "2024-11-01", 10, 400),
+		ingresoPrueba("PISO-01", "Z2", "2024-12-01", 10, 400),
+	}
+	return ventas, ingresos
+}
+
+func TestGenerarFichaProducto(t *testing.T) {
+	ventas, ingresos := ventasFichaPrueba()
+	s := servicioPrueba(t, ventas, ingresos)
+	filtro := models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-02-28"}
+
+	// El producto se encuentra por su código de inventario o de ventas
+	for _, codigo := range []string{"PISO-01", "PISO01"} {
+		t.Run(codigo, func(t *testing.T) {
+			ficha, err := s.GenerarFichaProducto(filtro, codigo, models.ParametrosFichaProducto{Documentos: 1})
+			if err != nil {
+				t.Fatalf("GenerarFichaProducto: %v", err)
+			}
+
+			c := ficha.Coincidencia
+			if c.CodigoInventario != "PISO-01" || c.CodigoVentas != "PISO01" || c.Metodo != EstrategiaNormalizada {
+				t.Errorf("coincidencia = %+v, se esperaba PISO-01 con PISO01 normalizada", c)
+			}
+			if ficha.Maestro == nil || ficha.Maestro.CantidadIngresada != 20 || ficha.Maestro.CantidadIngresos != 2 {
+				t.Errorf("maestro = %+v, se esperaban 20 unidades en 2 ingresos", ficha.Maestro)
+			}
+			if len(ficha.Ingresos.PorAnio) != 1 || ficha.Ingresos.PorAnio[0].Unidades != 20 {
+				t.Errorf("ingresos por año = %+v, se esperaba 2024 con 20 unidades", ficha.Ingresos.PorAnio)
+			}
+			// Los lotes cuentan las ventas anteriores al período
+			if obtenido, esperado := describirLotes(ficha.Ingresos.Lotes), "Z1 10/10 quedan 0 $10000, Z2 3/10 quedan 7 $3000"; obtenido != esperado {
+				t.Errorf("lotes = %s, se esperaba %s", obtenido, esperado)
+			}
+
+			v := ficha.Ventas
+			var sucursales []string
+			for _, suc := range v.PorSucursal {
+				sucursales = append(sucursales, fmt.Sprintf("%d:%v/$%d/%d", suc.Sucursal, suc.CantidadTotalVendida, suc.TotalVentasCLP, suc.CantidadDeVentas))
+			}
+			obtenido := fmt.Sprintf("%d líneas %d docs %d clientes desde %s [%s]", v.Lineas, v.Documentos, v.Clientes, v.PrimeraFechaVenta, strings.Join(sucursales, " "))
+			if esperado := "3 líneas 2 docs 1 clientes desde 2025-01-05 [211:5/$5000/2 212:2/$2000/1]"; obtenido != esperado {
+				t.Errorf("ventas = %s, se esperaba %s", obtenido, esperado)
+			}
+
+			var puntos []string
+			for _, p := range ficha.SerieMensual {
+				puntos = append(puntos, fmt.Sprintf("%s %v/$%d/%d", p.Periodo, p.Cantidad, p.VentaClp, p.Transacciones))
+			}
+			if obtenido, esperado := strings.Join(puntos, ", "), "2025-01 5/$5000/2, 2025-02 2/$2000/1"; obtenido != esperado {
+				t.Errorf("serie = %s, se esperaba %s", obtenido, esperado)
+			}
+
+			// Solo el documento más reciente
+			if len(ficha.Documentos) != 1 || ficha.Documentos[0].Fecha != "2025-02-10" || ficha.Documentos[0].Sucursal != 212 {
+				t.Errorf("documentos = %+v, se esperaba solo el del 2025-02-10 en 212", ficha.Documentos)
+			}
+		})
+	}
+}
+
+func TestGenerarFichaProductoSoloVentas(t *testing.T) {
+	ventas, ingresos := ventasFichaPrueba()
+	s := servicioPrueba(t, ventas, ingresos)
+	filtro := models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-02-28"}
+
+	ficha, err := s.GenerarFichaProducto(filtro, "SOLO-VENTA", models.ParametrosFichaProducto{Documentos: 10})
+	if err != nil {
+		t.Fatalf("GenerarFichaProducto: %v", err)
+	}
+	if ficha.Maestro != nil || ficha.Coincidencia.CodigoInventario != "" || ficha.Coincidencia.CodigoVentas != "SOLO-VENTA" {
+		t.Errorf("maestro %+v, coincidencia %+v; se esperaba solo el código de ventas", ficha.Maestro, ficha.Coincidencia)
+	}
+	if ficha.Ingresos.Historial == nil || len(ficha.Ingresos.Historial) != 0 || ficha.Ventas.CantidadVendida != 3 {
+		t.Errorf("ingresos %+v, vendidas %v; se esperaba historial vacío y 3 vendidas", ficha.Ingresos, ficha.Ventas.CantidadVendida)
+	}
+
+	if _, err := s.GenerarFichaProducto(filtro, "PISO", models.ParametrosFichaProducto{Documentos: 10}); !errors.Is(err, ErrProductoNoEncontrado) {
+		t.Errorf("GenerarFichaProducto(PISO) = %v, se esperaba ErrProductoNoEncontrado", err)
+	}
+}
+
+func TestCoincidenciaProductoOtrosCodigos(t *testing.T) {
+	coincidentes := []models.ReporteCombinado{
+		{CodigoProducto: "PISO-B", CodigoVentas: "PISO", MetodoCoincidencia: EstrategiaNormalizada},
+		{CodigoProducto: "PISO", CodigoVentas: "PISO", MetodoCoincidencia: EstrategiaExacta, ConfianzaCoincidencia: 1},
+		{CodigoProducto: "PISO-A", CodigoVentas: "PISO", MetodoCoincidencia: EstrategiaNormalizada},
+		{CodigoProducto: "MURO", CodigoVentas: "MURO", MetodoCoincidencia: EstrategiaExacta},
+	}
+	c := coincidenciaProducto(coincidentes[1], coincidentes)
+	if c.CodigoInventario != "PISO" || c.Confianza != 1 || fmt.Sprint(c.OtrosCodigosInventario) != "[PISO-A PISO-B]" {
+		t.Errorf("coincidencia = %+v, se esperaba PISO con otros [PISO-A PISO-B]", c)
+	}
+}
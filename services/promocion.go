@@ -0,0 +1,473 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// diasReferenciaPrecio son los días antes y después de cada línea en que se busca el precio de
+// lista observado del producto
+const diasReferenciaPrecio = 30
+
+// limitacionesPromociones describe en la respuesta los supuestos del análisis
+var limitacionesPromociones = []string{
+	"No hay calendario de promociones: los períodos de oferta se deducen de las ventas, por lo que los días de oferta sin ventas al inicio o al final de un período no se cuentan y la velocidad en oferta de los períodos cortos tiende a sobrestimarse.",
+	"El maestro de productos solo tiene los precios vigentes hoy: el precio de lista de cada línea es el mayor precio vendido del producto en los 30 días anteriores y posteriores, y una línea es de oferta si está bajo ese precio con el precio de oferta actual o con un precio repetido en al menos dos días.",
+	"No hay stock diario: los tramos de quiebreDias días seguidos o más sin ventas se consideran posibles quiebres de stock y se excluyen de la velocidad regular.",
+	"La velocidad regular se calcula solo con los días sin oferta a hasta ventanaBaseDias días de cada período de oferta.",
+}
+
+// lineaPromocion es una línea de venta con su fecha, su precio de lista observado y su tipo de precio
+type lineaPromocion struct {
+	venta      models.VentaDetallada
+	dia        time.Time
+	referencia int
+	tipo       string
+}
+
+// profundidad devuelve el descuento % del precio de la línea sobre su precio de lista observado
+func (l lineaPromocion) profundidad() float64 {
+	if l.referencia <= 0 || l.venta.PrecioUnitarioCLP >= l.referencia {
+		return 0
+	}
+	return float64(l.referencia-l.venta.PrecioUnitarioCLP) / float64(l.referencia) * 100
+}
+
+// preciosIguales indica si precio está a toleranciaPct % o menos del precio de referencia
+func preciosIguales(precio, referencia int, toleranciaPct float64) bool {
+	return math.Abs(float64(precio-referencia)) <= float64(referencia)*toleranciaPct/100
+}
+
+// clasificarLineas asigna a cada línea de un producto (ordenadas por fecha) su precio de lista
+// observado y su tipo de precio: lista (igual o superior al de referencia), oferta (bajo el de
+// referencia, con el precio de oferta actual o con un precio que se repite bajo la referencia en al
+// menos dos días) o descuento puntual (los demás precios bajo la referencia)
+func clasificarLineas(ventas []models.VentaDetallada, toleranciaPct float64) []lineaPromocion {
+	lineas := make([]lineaPromocion, 0, len(ventas))
+	for _, v := range ventas {
+		if dia, ok := parsearFechaVenta(v.FechaEmision); ok {
+			lineas = append(lineas, lineaPromocion{venta: v, dia: dia})
+		}
+	}
+
+	ventana := time.Duration(diasReferenciaPrecio) * 24 * time.Hour
+	desde := 0
+	for i := range lineas {
+		for lineas[i].dia.Sub(lineas[desde].dia) > ventana {
+			desde++
+		}
+		referencia := 0
+		for j := desde; j < len(lineas) && lineas[j].dia.Sub(lineas[i].dia) <= ventana; j++ {
+			if lineas[j].venta.PrecioUnitarioCLP > referencia {
+				referencia = lineas[j].venta.PrecioUnitarioCLP
+			}
+		}
+		lineas[i].referencia = referencia
+	}
+
+	// Días en que cada precio se vendió bajo la referencia
+	diasPorPrecio := make(map[int]map[time.Time]bool)
+	for _, l := range lineas {
+		if bajoReferencia(l, toleranciaPct) {
+			if diasPorPrecio[l.venta.PrecioUnitarioCLP] == nil {
+				diasPorPrecio[l.venta.PrecioUnitarioCLP] = make(map[time.Time]bool)
+			}
+			diasPorPrecio[l.venta.PrecioUnitarioCLP][l.dia] = true
+		}
+	}
+
+	for i, l := range lineas {
+		oferta := l.venta.PrecioOfertaCLP
+		switch {
+		case !bajoReferencia(l, toleranciaPct):
+			lineas[i].tipo = models.TipoPrecioLista
+		case oferta > 0 && preciosIguales(l.venta.PrecioUnitarioCLP, oferta, toleranciaPct),
+			len(diasPorPrecio[l.venta.PrecioUnitarioCLP]) >= 2:
+			lineas[i].tipo = models.TipoPrecioOferta
+		default:
+			lineas[i].tipo = models.TipoPrecioDescuento
+		}
+	}
+	return lineas
+}
+
+// bajoReferencia indica si la línea se vendió bajo su precio de lista observado, fuera de la tolerancia
+func bajoReferencia(l lineaPromocion, toleranciaPct float64) bool {
+	precio := l.venta.PrecioUnitarioCLP
+	return precio < l.referencia && !preciosIguales(precio, l.referencia, toleranciaPct)
+}
+
+// acumuladoPrecio suma las líneas de un tipo de precio para calcular sus promedios ponderados
+type acumuladoPrecio struct {
+	resumen         models.ResumenTipoPrecio
+	sumaPrecio      float64
+	sumaProfundidad float64
+}
+
+// agregar suma una línea con su profundidad de descuento
+func (a *acumuladoPrecio) agregar(l lineaPromocion) {
+	a.resumen.Lineas++
+	a.resumen.Unidades += l.venta.Cantidad
+	a.resumen.VentaClp += l.venta.TotalVentaCLP
+	a.sumaPrecio += float64(l.venta.PrecioUnitarioCLP) * l.venta.Cantidad
+	a.sumaProfundidad += l.profundidad() * l.venta.Cantidad
+}
+
+// cerrar devuelve el resumen con los promedios ponderados por unidades
+func (a *acumuladoPrecio) cerrar() models.ResumenTipoPrecio {
+	r := a.resumen
+	if r.Unidades > 0 {
+		r.PrecioPromedioClp = redondear(a.sumaPrecio/r.Unidades, 2)
+		r.ProfundidadDescuentoPct = redondear(a.sumaProfundidad/r.Unidades, 2)
+	}
+	return r
+}
+
+// acumuladoTipos suma las líneas por tipo de precio
+type acumuladoTipos struct {
+	lista, oferta, descuento acumuladoPrecio
+}
+
+// agregar suma una línea ya clasificada en su tipo de precio
+func (a *acumuladoTipos) agregar(l lineaPromocion) {
+	switch l.tipo {
+	case models.TipoPrecioOferta:
+		a.oferta.agregar(l)
+	case models.TipoPrecioDescuento:
+		a.descuento.agregar(l)
+	default:
+		a.lista.agregar(l)
+	}
+}
+
+// diaVentas resume las ventas de un producto en un día
+type diaVentas struct {
+	dia      time.Time
+	unidades float64
+	oferta   bool // Hubo ventas a precio de oferta
+	lista    bool // Hubo ventas a precio de lista
+}
+
+// ventasPorDia agrupa las líneas clasificadas por día, en orden
+func ventasPorDia(lineas []lineaPromocion) []diaVentas {
+	var dias []diaVentas
+	for _, l := range lineas {
+		if len(dias) == 0 || !dias[len(dias)-1].dia.Equal(l.dia) {
+			dias = append(dias, diaVentas{dia: l.dia})
+		}
+		d := &dias[len(dias)-1]
+		d.unidades += l.venta.Cantidad
+		d.oferta = d.oferta || l.tipo == models.TipoPrecioOferta
+		d.lista = d.lista || l.tipo == models.TipoPrecioLista
+	}
+	return dias
+}
+
+// periodosOferta forma los períodos de oferta a partir de los días con ventas (ordenados): un
+// período une los días con ventas en oferta separados por hasta brecha días, incluidos los días
+// intermedios sin ventas, y termina antes de un día con ventas solo a precio de lista. Las
+// unidades son todas las vendidas en el período, a cualquier precio.
+func periodosOferta(dias []diaVentas, brecha int) []models.PeriodoOferta {
+	var periodos []models.PeriodoOferta
+	var actual *models.PeriodoOferta
+	var hasta time.Time
+	var pendientes float64 // Unidades de descuentos puntuales después del último día en oferta
+	cerrar := func() {
+		if actual != nil {
+			actual.Hasta = hasta.Format("2006-01-02")
+			periodos = append(periodos, *actual)
+			actual = nil
+		}
+	}
+	for _, d := range dias {
+		separacion := int(d.dia.Sub(hasta).Hours() / 24)
+		switch {
+		case d.oferta && actual != nil && separacion <= brecha:
+			actual.Dias += separacion
+			actual.Unidades += pendientes + d.unidades
+			pendientes = 0
+			hasta = d.dia
+		case d.oferta:
+			cerrar()
+			actual = &models.PeriodoOferta{Desde: d.dia.Format("2006-01-02"), Dias: 1, Unidades: d.unidades}
+			pendientes = 0
+			hasta = d.dia
+		case d.lista:
+			cerrar()
+		default:
+			pendientes += d.unidades
+		}
+	}
+	cerrar()
+	return periodos
+}
+
+// diasComparables marca los días del rango [inicio, inicio+dias) que sirven de base regular: fuera
+// de los períodos de oferta, a hasta ventana días de alguno (o todos si no hay períodos) y fuera de
+// los tramos de al menos quiebre días sin ventas. Devuelve los días base y la cantidad de días
+// excluidos por posible quiebre.
+func diasComparables(inicio time.Time, dias int, porDia map[time.Time]float64, periodos []models.PeriodoOferta, ventana, quiebre int) (map[time.Time]bool, int) {
+	enOferta := make([]bool, dias)
+	cercano := make([]bool, dias)
+	indice := func(fecha string) int {
+		t, _ := time.Parse("2006-01-02", fecha)
+		return int(t.Sub(inicio).Hours() / 24)
+	}
+	for _, p := range periodos {
+		desde, hasta := indice(p.Desde), indice(p.Hasta)
+		for i := desde - ventana; i <= hasta+ventana; i++ {
+			if i < 0 || i >= dias {
+				continue
+			}
+			if i >= desde && i <= hasta {
+				enOferta[i] = true
+			}
+			cercano[i] = true
+		}
+	}
+
+	// Tramos sin ventas de al menos quiebre días: posible quiebre de stock
+	sinStock := make([]bool, dias)
+	for i := 0; i < dias; {
+		j := i
+		for j < dias && porDia[inicio.AddDate(0, 0, j)] == 0 {
+			j++
+		}
+		if j-i >= quiebre {
+			for k := i; k < j; k++ {
+				sinStock[k] = true
+			}
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+
+	base := make(map[time.Time]bool)
+	excluidos := 0
+	for i := 0; i < dias; i++ {
+		switch {
+		case enOferta[i]:
+		case sinStock[i]:
+			excluidos++
+		case len(periodos) == 0 || cercano[i]:
+			base[inicio.AddDate(0, 0, i)] = true
+		}
+	}
+	return base, excluidos
+}
+
+// analizarPromocionProducto calcula la efectividad de precios y ofertas de un producto a partir
+// de sus líneas clasificadas (sin documentos anulados), en los dias días desde inicio
+func analizarPromocionProducto(lineas []lineaPromocion, inicio time.Time, dias int, params models.ParametrosPromocion) models.ProductoPromocion {
+	ultima := lineas[len(lineas)-1].venta
+	p := models.ProductoPromocion{
+		CodigoProducto:  ultima.CodigoProducto,
+		NombreProducto:  ultima.NombreProducto,
+		PrecioBaseClp:   ultima.PrecioBaseCLP,
+		PrecioOfertaClp: ultima.PrecioOfertaCLP,
+		PeriodosOferta:  []models.PeriodoOferta{},
+	}
+
+	var tipos acumuladoTipos
+	var unidades, venta, sumaProfundidad float64
+	porDia := make(map[time.Time]float64)
+	for i, l := range lineas {
+		v := l.venta
+		if i == 0 || v.PrecioUnitarioCLP < p.PrecioMinimoClp {
+			p.PrecioMinimoClp = v.PrecioUnitarioCLP
+		}
+		if v.PrecioUnitarioCLP > p.PrecioMaximoClp {
+			p.PrecioMaximoClp = v.PrecioUnitarioCLP
+		}
+
+		unidades += v.Cantidad
+		venta += float64(v.TotalVentaCLP)
+		sumaProfundidad += l.profundidad() * v.Cantidad
+		porDia[l.dia] += v.Cantidad
+		tipos.agregar(l)
+	}
+	p.Lista, p.Oferta, p.Descuento = tipos.lista.cerrar(), tipos.oferta.cerrar(), tipos.descuento.cerrar()
+	if unidades > 0 {
+		p.ProfundidadDescuentoPct = redondear(sumaProfundidad/unidades, 2)
+		p.UnidadesOfertaPct = redondear(p.Oferta.Unidades/unidades*100, 2)
+	}
+	if venta > 0 {
+		p.VentaOfertaPct = redondear(float64(p.Oferta.VentaClp)/venta*100, 2)
+	}
+
+	// Períodos de oferta y velocidad dentro de ellos y en los días regulares comparables
+	var unidadesOferta float64
+	for _, periodo := range periodosOferta(ventasPorDia(lineas), params.BrechaDias) {
+		unidadesOferta += periodo.Unidades
+		p.DiasOferta += periodo.Dias
+		p.PeriodosOferta = append(p.PeriodosOferta, periodo)
+	}
+	p.DiasRegulares = dias - p.DiasOferta
+	if p.DiasRegulares < 0 {
+		p.DiasRegulares = 0
+	}
+
+	base, quiebre := diasComparables(inicio, dias, porDia, p.PeriodosOferta, params.VentanaBaseDias, params.QuiebreDias)
+	var unidadesBase float64
+	for dia := range base {
+		unidadesBase += porDia[dia]
+	}
+	p.DiasBase, p.DiasQuiebre = len(base), quiebre
+
+	if p.DiasOferta > 0 {
+		p.VelocidadOferta = redondear(unidadesOferta/float64(p.DiasOferta), 4)
+	}
+	if p.DiasBase > 0 {
+		p.VelocidadRegular = redondear(unidadesBase/float64(p.DiasBase), 4)
+	}
+	if p.DiasOferta > 0 && p.VelocidadRegular > 0 {
+		lift := redondear((p.VelocidadOferta/p.VelocidadRegular-1)*100, 2)
+		p.LiftPct = &lift
+	}
+	return p
+}
+
+// GenerarAnalisisPromociones clasifica cada línea de venta del período como precio de lista,
+// de oferta o descuento puntual frente al precio de lista observado en sus ventas, y calcula por
+// producto la profundidad de descuento, las unidades y la venta en oferta y la diferencia de
+// velocidad diaria entre los períodos de oferta y los días regulares comparables. Los supuestos
+// del análisis se informan en Limitaciones.
+func (s *VentasService) GenerarAnalisisPromociones(filtro models.VentasFiltro, params models.ParametrosPromocion) (*models.ReportePromociones, error) {
+	ventas, err := s.GetVentasDetalladas(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	porCodigo := make(map[string][]models.VentaDetallada)
+	for _, v := range ventas {
+		if v.Nula || v.Cantidad <= 0 {
+			continue
+		}
+		if _, ok := parsearFechaVenta(v.FechaEmision); !ok {
+			continue
+		}
+		porCodigo[v.CodigoProducto] = append(porCodigo[v.CodigoProducto], v)
+	}
+
+	reporte := &models.ReportePromociones{
+		FechaInicio:  filtro.FechaInicio,
+		FechaFin:     filtro.FechaFin,
+		Sucursales:   filtro.Sucursales,
+		DiasPeriodo:  diasPeriodo(filtro.FechaInicio, filtro.FechaFin, time.Now()),
+		Parametros:   params,
+		Limitaciones: limitacionesPromociones,
+		Productos:    make([]models.ProductoPromocion, 0, len(porCodigo)),
+	}
+	inicio, _ := time.Parse("2006-01-02", filtro.FechaInicio)
+
+	var total acumuladoTipos
+	for _, ventasProducto := range porCodigo {
+		sort.SliceStable(ventasProducto, func(i, j int) bool {
+			return ventasProducto[i].FechaEmision < ventasProducto[j].FechaEmision
+		})
+		lineas := clasificarLineas(ventasProducto, params.ToleranciaPct)
+		reporte.Productos = append(reporte.Productos, analizarPromocionProducto(lineas, inicio, reporte.DiasPeriodo, params))
+		for _, l := range lineas {
+			total.agregar(l)
+		}
+	}
+	reporte.Lista, reporte.Oferta, reporte.Descuento = total.lista.cerrar(), total.oferta.cerrar(), total.descuento.cerrar()
+
+	sort.Slice(reporte.Productos, func(i, j int) bool {
+		return reporte.Productos[i].CodigoProducto < reporte.Productos[j].CodigoProducto
+	})
+	return reporte, nil
+}
+
+// ExportarAnalisisPromociones exporta la efectividad de precios y ofertas a Excel, con una hoja
+// por producto, otra con los períodos de oferta y otra con las limitaciones del análisis
+func (s *VentasService) ExportarAnalisisPromociones(filtro models.VentasFiltro, params models.ParametrosPromocion) ([]byte, string, error) {
+	reporte, err := s.GenerarAnalisisPromociones(filtro, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	productos := HojaExcel{
+		Nombre: "Efectividad Ofertas",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Código de Producto"},
+			{Titulo: "Nombre del Producto", Ancho: 40},
+			{Titulo: "PRECIO BASE CLP"},
+			{Titulo: "PRECIO OFERTA CLP"},
+			{Titulo: "PRECIO MINIMO CLP"},
+			{Titulo: "PRECIO MAXIMO CLP"},
+			{Titulo: "UNIDADES LISTA", Decimal: true},
+			{Titulo: "VENTA LISTA CLP"},
+			{Titulo: "UNIDADES OFERTA", Decimal: true},
+			{Titulo: "VENTA OFERTA CLP"},
+			{Titulo: "UNIDADES DESCUENTO", Decimal: true},
+			{Titulo: "VENTA DESCUENTO CLP"},
+			{Titulo: "DESCUENTO PROMEDIO OFERTA %", Decimal: true},
+			{Titulo: "DESCUENTO PROMEDIO PUNTUAL %", Decimal: true},
+			{Titulo: "DESCUENTO PROMEDIO TOTAL %", Decimal: true},
+			{Titulo: "% UNIDADES EN OFERTA", Decimal: true},
+			{Titulo: "% VENTA EN OFERTA", Decimal: true},
+			{Titulo: "DIAS OFERTA"},
+			{Titulo: "DIAS BASE"},
+			{Titulo: "DIAS POSIBLE QUIEBRE"},
+			{Titulo: "VELOCIDAD OFERTA", Decimal: true},
+			{Titulo: "VELOCIDAD REGULAR", Decimal: true},
+			{Titulo: "LIFT %", Decimal: true},
+		},
+	}
+	periodos := HojaExcel{
+		Nombre: "Períodos de Oferta",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Código de Producto"},
+			{Titulo: "Nombre del Producto", Ancho: 40},
+			{Titulo: "DESDE"},
+			{Titulo: "HASTA"},
+			{Titulo: "DIAS"},
+			{Titulo: "UNIDADES", Decimal: true},
+		},
+	}
+	for _, p := range reporte.Productos {
+		var lift interface{}
+		if p.LiftPct != nil {
+			lift = *p.LiftPct
+		}
+		productos.Filas = append(productos.Filas, []interface{}{
+			p.CodigoProducto, p.NombreProducto,
+			p.PrecioBaseClp, p.PrecioOfertaClp, p.PrecioMinimoClp, p.PrecioMaximoClp,
+			p.Lista.Unidades, p.Lista.VentaClp,
+			p.Oferta.Unidades, p.Oferta.VentaClp,
+			p.Descuento.Unidades, p.Descuento.VentaClp,
+			p.Oferta.ProfundidadDescuentoPct, p.Descuento.ProfundidadDescuentoPct, p.ProfundidadDescuentoPct,
+			p.UnidadesOfertaPct, p.VentaOfertaPct,
+			p.DiasOferta, p.DiasBase, p.DiasQuiebre, p.VelocidadOferta, p.VelocidadRegular, lift,
+		})
+		for _, periodo := range p.PeriodosOferta {
+			periodos.Filas = append(periodos.Filas, []interface{}{
+				p.CodigoProducto, p.NombreProducto, periodo.Desde, periodo.Hasta, periodo.Dias, periodo.Unidades,
+			})
+		}
+	}
+
+	notas := HojaExcel{
+		Nombre:   "Limitaciones",
+		Columnas: []ColumnaExcel{{Titulo: "LIMITACION", Ancho: 120}},
+	}
+	for _, limitacion := range reporte.Limitaciones {
+		notas.Filas = append(notas.Filas, []interface{}{limitacion})
+	}
+
+	excelBytes, err := s.excelService.GenerarLibro(productos, periodos, notas)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("Efectividad_Ofertas_%s_%s_al_%s.xlsx",
+		nombreSucursales(filtro), filtro.FechaInicio, filtro.FechaFin)
+	return excelBytes, filename, nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ventasMarzo crea una línea de PROMO por cada día de marzo de 2025 indicado, con cantidad
+// unidades a precio. El maestro tiene hoy precio base 1.200 y el precio de oferta indicado.
+func ventasMarzo(cantidad float64, precio, ofertaMaestro int, dias ...int) []datasource.LineaVentaFixture {
+	var ventas []datasource.LineaVentaFixture
+	for _, dia := range dias {
+		v := ventaPrueba(fmt.Sprintf("2025-03-%02d", dia), "PROMO", cantidad, precio)
+		v.PrecioBaseCLP, v.PrecioOfertaCLP = 1200, ofertaMaestro
+		ventas = append(ventas, v)
+	}
+	return ventas
+}
+
+// describirPromocion resume los tipos de precio, los períodos y las velocidades de un producto
+func describirPromocion(p models.ProductoPromocion) string {
+	var periodos []string
+	for _, periodo := range p.PeriodosOferta {
+		periodos = append(periodos, fmt.Sprintf("%s..%s %dd %vu", periodo.Desde, periodo.Hasta, periodo.Dias, periodo.Unidades))
+	}
+	lift := "nil"
+	if p.LiftPct != nil {
+		lift = fmt.Sprint(*p.LiftPct)
+	}
+	return fmt.Sprintf("lista %d/%v oferta %d/%v descuento %d/%v | %s | dias %d/%d base %d quiebre %d | %v/%v lift %s",
+		p.Lista.Lineas, p.Lista.Unidades, p.Oferta.Lineas, p.Oferta.Unidades, p.Descuento.Lineas, p.Descuento.Unidades,
+		strings.Join(periodos, ", "), p.DiasOferta, p.DiasRegulares, p.DiasBase, p.DiasQuiebre,
+		p.VelocidadOferta, p.VelocidadRegular, lift)
+}
+
+func TestGenerarAnalisisPromociones(t *testing.T) {
+	casos := []struct {
+		nombre      string
+		ventas      [][]datasource.LineaVentaFixture
+		ventanaBase int
+		esperado    string
+		profundidad float64
+	}{
+		{
+			// Sin precio de oferta en el maestro: 900 se repite bajo la lista de 1.000 en tres días.
+			// Los días sin ventas dentro del período cuentan como días de oferta.
+			nombre: "oferta por precio repetido",
+			ventas: [][]datasource.LineaVentaFixture{
+				ventasMarzo(1, 1000, 0, 2, 5, 8, 18, 22, 26, 30),
+				ventasMarzo(3, 900, 0, 11, 13, 15),
+			},
+			esperado:    "lista 7/7 oferta 3/9 descuento 0/0 | 2025-03-11..2025-03-15 5d 9u | dias 5/26 base 26 quiebre 0 | 1.8/0.2692 lift 568.65",
+			profundidad: 10,
+		},
+		{
+			// Sin ventas desde el 16: posible quiebre de stock, fuera de la base regular
+			nombre: "quiebre de stock después de la oferta",
+			ventas: [][]datasource.LineaVentaFixture{
+				ventasMarzo(1, 1000, 0, 2, 5, 8),
+				ventasMarzo(3, 900, 0, 11, 13, 15),
+			},
+			esperado:    "lista 3/3 oferta 3/9 descuento 0/0 | 2025-03-11..2025-03-15 5d 9u | dias 5/26 base 10 quiebre 16 | 1.8/0.3 lift 500",
+			profundidad: 10,
+		},
+		{
+			// Un precio bajo la lista que no es el de oferta ni se repite no forma un período
+			nombre: "descuento puntual",
+			ventas: [][]datasource.LineaVentaFixture{
+				ventasMarzo(1, 1000, 0, 2, 5, 8, 18, 22),
+				ventasMarzo(1, 700, 0, 20),
+			},
+			esperado:    "lista 5/5 oferta 0/0 descuento 1/1 |  | dias 0/31 base 31 quiebre 0 | 0/0.1935 lift nil",
+			profundidad: 0,
+		},
+		{
+			// Una sola venta al precio de oferta del maestro; la base son los días del 9 al 23
+			nombre: "oferta del maestro con ventana base corta",
+			ventas: [][]datasource.LineaVentaFixture{
+				ventasMarzo(1, 1000, 850, 2, 5, 8, 18, 22, 26, 30),
+				ventasMarzo(1, 850, 850, 16),
+			},
+			ventanaBase: 7,
+			esperado:    "lista 7/7 oferta 1/1 descuento 0/0 | 2025-03-16..2025-03-16 1d 1u | dias 1/30 base 14 quiebre 0 | 1/0.1429 lift 599.79",
+			profundidad: 15,
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			var ventas []datasource.LineaVentaFixture
+			for _, grupo := range c.ventas {
+				ventas = append(ventas, grupo...)
+			}
+			s := servicioPrueba(t, ventas, nil)
+			params := models.ParametrosPromocion{VentanaBaseDias: c.ventanaBase}
+			if err := params.Validar(); err != nil {
+				t.Fatalf("Validar: %v", err)
+			}
+
+			reporte, err := s.ventasService.GenerarAnalisisPromociones(models.VentasFiltro{FechaInicio: "2025-03-01", FechaFin: "2025-03-31"}, params)
+			if err != nil {
+				t.Fatalf("GenerarAnalisisPromociones: %v", err)
+			}
+			if len(reporte.Productos) != 1 {
+				t.Fatalf("productos = %d, se esperaba 1", len(reporte.Productos))
+			}
+			if len(reporte.Limitaciones) == 0 {
+				t.Error("el reporte no informa sus limitaciones")
+			}
+
+			p := reporte.Productos[0]
+			if obtenido := describirPromocion(p); obtenido != c.esperado {
+				t.Errorf("producto:\n%s\nse esperaba:\n%s", obtenido, c.esperado)
+			}
+			if !aproximado(p.Oferta.ProfundidadDescuentoPct, c.profundidad) {
+				t.Errorf("profundidad en oferta = %v, se esperaba %v", p.Oferta.ProfundidadDescuentoPct, c.profundidad)
+			}
+			if reporte.Oferta != p.Oferta {
+				t.Errorf("total en oferta = %+v, se esperaba %+v", reporte.Oferta, p.Oferta)
+			}
+		})
+	}
+}
+
+func TestPeriodosOferta(t *testing.T) {
+	dia := func(d int, oferta, lista bool) diaVentas {
+		return diaVentas{dia: fechaPrueba(t, fmt.Sprintf("2025-03-%02d", d)), unidades: 1, oferta: oferta, lista: lista}
+	}
+	casos := []struct {
+		nombre   string
+		dias     []diaVentas
+		brecha   int
+		esperado string
+	}{
+		{
+			nombre:   "brecha mayor separa los períodos",
+			dias:     []diaVentas{dia(1, true, false), dia(3, true, false), dia(12, true, false)},
+			brecha:   7,
+			esperado: "2025-03-01..2025-03-03 3d 2u, 2025-03-12..2025-03-12 1d 1u",
+		},
+		{
+			nombre:   "una venta a precio de lista cierra el período",
+			dias:     []diaVentas{dia(1, true, false), dia(2, false, true), dia(3, true, false)},
+			brecha:   7,
+			esperado: "2025-03-01..2025-03-01 1d 1u, 2025-03-03..2025-03-03 1d 1u",
+		},
+		{
+			nombre:   "un día con oferta y lista sigue en oferta",
+			dias:     []diaVentas{dia(1, true, false), dia(2, true, true), dia(3, true, false)},
+			brecha:   7,
+			esperado: "2025-03-01..2025-03-03 3d 3u",
+		},
+		{
+			nombre:   "los descuentos puntuales entre ofertas quedan en el período",
+			dias:     []diaVentas{dia(1, true, false), dia(2, false, false), dia(4, true, false), dia(5, false, false)},
+			brecha:   7,
+			esperado: "2025-03-01..2025-03-04 4d 3u",
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			var periodos []string
+			for _, p := range periodosOferta(c.dias, c.brecha) {
+				periodos = append(periodos, fmt.Sprintf("%s..%s %dd %vu", p.Desde, p.Hasta, p.Dias, p.Unidades))
+			}
+			if obtenido := strings.Join(periodos, ", "); obtenido != c.esperado {
+				t.Errorf("periodosOferta = %q, se esperaba %q", obtenido, c.esperado)
+			}
+		})
+	}
+}
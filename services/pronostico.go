@@ -0,0 +1,342 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ventanaPromedioMovil es la cantidad de meses del promedio móvil
+const ventanaPromedioMovil = 3
+
+// temporada es el largo del ciclo estacional (meses) de Holt-Winters
+const temporada = 12
+
+// alfaCroston es la constante de suavizado del método de Croston
+const alfaCroston = 0.1
+
+// metodoPronostico ajusta un método a una serie mensual y pronostica los próximos h meses.
+// Devuelve nil si la serie no alcanza para el método.
+type metodoPronostico func(serie []float64, h int) []float64
+
+// metodosPronostico asocia cada método con su implementación
+var metodosPronostico = map[string]metodoPronostico{
+	models.MetodoPromedioMovil: pronosticoPromedioMovil,
+	models.MetodoSuavizado:     pronosticoSuavizado,
+	models.MetodoHoltWinters:   pronosticoHoltWinters,
+	models.MetodoCroston:       pronosticoCroston,
+}
+
+// pronosticoPromedioMovil repite el promedio de los últimos meses
+func pronosticoPromedioMovil(serie []float64, h int) []float64 {
+	if len(serie) == 0 {
+		return nil
+	}
+	ventana := serie[max(0, len(serie)-ventanaPromedioMovil):]
+	promedio, _ := promedioYDesviacion(ventana)
+	return repetir(promedio, h)
+}
+
+// pronosticoSuavizado aplica suavizado exponencial doble (Holt) con nivel y tendencia,
+// eligiendo las constantes que minimizan el error de un paso dentro de la serie
+func pronosticoSuavizado(serie []float64, h int) []float64 {
+	if len(serie) < 3 {
+		return nil
+	}
+
+	mejorError := math.Inf(1)
+	var nivel, tendencia float64
+	for _, alfa := range []float64{0.2, 0.4, 0.6, 0.8} {
+		for _, beta := range []float64{0.05, 0.1, 0.2} {
+			l, t := serie[0], serie[1]-serie[0]
+			sse := 0.0
+			for _, y := range serie[1:] {
+				e := y - (l + t)
+				sse += e * e
+				anterior := l
+				l = alfa*y + (1-alfa)*(l+t)
+				t = beta*(l-anterior) + (1-beta)*t
+			}
+			if sse < mejorError {
+				mejorError, nivel, tendencia = sse, l, t
+			}
+		}
+	}
+
+	resultado := make([]float64, h)
+	for i := range resultado {
+		resultado[i] = math.Max(nivel+float64(i+1)*tendencia, 0)
+	}
+	return resultado
+}
+
+// pronosticoHoltWinters aplica Holt-Winters aditivo con estacionalidad anual; requiere
+// al menos dos temporadas completas
+func pronosticoHoltWinters(serie []float64, h int) []float64 {
+	n := len(serie)
+	if n < 2*temporada {
+		return nil
+	}
+
+	primera, _ := promedioYDesviacion(serie[:temporada])
+	segunda, _ := promedioYDesviacion(serie[temporada : 2*temporada])
+
+	mejorError := math.Inf(1)
+	var nivel, tendencia float64
+	var estacion []float64
+	for _, alfa := range []float64{0.2, 0.4, 0.6} {
+		for _, beta := range []float64{0.05, 0.1, 0.2} {
+			for _, gamma := range []float64{0.1, 0.3, 0.5} {
+				l, t := primera, (segunda-primera)/temporada
+				s := make([]float64, temporada)
+				for i := range s {
+					s[i] = serie[i] - primera
+				}
+
+				sse := 0.0
+				for i := temporada; i < n; i++ {
+					e := serie[i] - (l + t + s[i%temporada])
+					sse += e * e
+					anterior := l
+					l = alfa*(serie[i]-s[i%temporada]) + (1-alfa)*(l+t)
+					t = beta*(l-anterior) + (1-beta)*t
+					s[i%temporada] = gamma*(serie[i]-l) + (1-gamma)*s[i%temporada]
+				}
+				if sse < mejorError {
+					mejorError, nivel, tendencia, estacion = sse, l, t, s
+				}
+			}
+		}
+	}
+
+	resultado := make([]float64, h)
+	for i := range resultado {
+		resultado[i] = math.Max(nivel+float64(i+1)*tendencia+estacion[(n+i)%temporada], 0)
+	}
+	return resultado
+}
+
+// pronosticoCroston estima por separado el tamaño de la demanda y el intervalo entre meses
+// con venta; el pronóstico mensual es tamaño / intervalo
+func pronosticoCroston(serie []float64, h int) []float64 {
+	var tamano, intervalo float64
+	meses := 1.0
+	iniciado := false
+	for _, y := range serie {
+		if y <= 0 {
+			meses++
+			continue
+		}
+		if !iniciado {
+			tamano, intervalo, iniciado = y, meses, true
+		} else {
+			tamano = alfaCroston*y + (1-alfaCroston)*tamano
+			intervalo = alfaCroston*meses + (1-alfaCroston)*intervalo
+		}
+		meses = 1
+	}
+	if !iniciado {
+		return nil
+	}
+	return repetir(tamano/intervalo, h)
+}
+
+// repetir devuelve h copias del valor
+func repetir(valor float64, h int) []float64 {
+	resultado := make([]float64, h)
+	for i := range resultado {
+		resultado[i] = valor
+	}
+	return resultado
+}
+
+// mesesBacktest devuelve cuántos meses finales se reservan para evaluar los métodos
+func mesesBacktest(n, horizonte int) int {
+	return min(horizonte, n/4)
+}
+
+// pronosticarSerie elige el método con menor error en el backtest (o el indicado), lo ajusta a
+// toda la serie y calcula los intervalos de predicción con el error cuadrático del backtest
+func pronosticarSerie(serie []float64, params models.ParametrosPronostico) models.PronosticoProducto {
+	resultado := models.PronosticoProducto{Historia: serie}
+	resultado.DemandaPromedioHistoria, _ = promedioYDesviacion(serie)
+	for _, y := range serie {
+		if y <= 0 {
+			resultado.MesesSinVenta++
+		}
+	}
+	if resultado.MesesSinVenta == len(serie) {
+		resultado.Metodo = models.MetodoSinDatos
+		resultado.Pronostico = make([]models.PuntoPronostico, params.Horizonte)
+		return resultado
+	}
+
+	metodos := models.MetodosPronostico
+	if params.Metodo != "" {
+		metodos = []string{params.Metodo}
+	}
+
+	// Backtest: ajustar sin los últimos meses y comparar con lo vendido
+	var sigma float64
+	if k := mesesBacktest(len(serie), params.Horizonte); k > 0 {
+		entrenamiento, prueba := serie[:len(serie)-k], serie[len(serie)-k:]
+		errores := make(map[string][2]float64) // MAE y RMSE
+		for _, metodo := range metodos {
+			estimado := metodosPronostico[metodo](entrenamiento, k)
+			if estimado == nil {
+				continue
+			}
+			var absoluto, cuadrado float64
+			for i, y := range prueba {
+				e := y - estimado[i]
+				absoluto += math.Abs(e)
+				cuadrado += e * e
+			}
+			mae := absoluto / float64(k)
+			errores[metodo] = [2]float64{mae, math.Sqrt(cuadrado / float64(k))}
+			resultado.Backtest = append(resultado.Backtest, models.ErrorBacktest{Metodo: metodo, Mae: redondear(mae, 2)})
+		}
+
+		for _, metodo := range metodos {
+			e, ok := errores[metodo]
+			if ok && (resultado.MaeBacktest == nil || e[0] < *resultado.MaeBacktest) {
+				mae := redondear(e[0], 2)
+				resultado.Metodo, resultado.MaeBacktest, sigma = metodo, &mae, e[1]
+			}
+		}
+	}
+
+	// Sin backtest posible: método indicado o, si no alcanza la historia, promedio móvil,
+	// con la desviación de la historia para los intervalos
+	if resultado.MaeBacktest == nil {
+		resultado.Metodo = params.Metodo
+		_, sigma = promedioYDesviacion(serie)
+	}
+	var estimado []float64
+	if resultado.Metodo != "" {
+		estimado = metodosPronostico[resultado.Metodo](serie, params.Horizonte)
+	}
+	if estimado == nil {
+		resultado.Metodo = models.MetodoPromedioMovil
+		estimado = pronosticoPromedioMovil(serie, params.Horizonte)
+	}
+
+	z := params.ValorZ()
+	resultado.Pronostico = make([]models.PuntoPronostico, params.Horizonte)
+	for i, cantidad := range estimado {
+		margen := z * sigma * math.Sqrt(float64(i+1))
+		resultado.Pronostico[i] = models.PuntoPronostico{
+			Cantidad: redondear(cantidad, 2),
+			Inferior: redondear(math.Max(cantidad-margen, 0), 2),
+			Superior: redondear(cantidad+margen, 2),
+		}
+		resultado.TotalPronosticado += cantidad
+	}
+	resultado.TotalPronosticado = redondear(resultado.TotalPronosticado, 2)
+	resultado.DemandaPromedioHistoria = redondear(resultado.DemandaPromedioHistoria, 2)
+	return resultado
+}
+
+// redondear redondea un valor a la cantidad de decimales indicada
+func redondear(valor float64, decimales int) float64 {
+	factor := math.Pow(10, float64(decimales))
+	return math.Round(valor*factor) / factor
+}
+
+// ventanaHistoria devuelve el rango de meses completos usado como historia: termina en el último
+// mes completo hasta la fecha de fin (o hoy, si es anterior)
+func ventanaHistoria(fechaFin string, meses int, hoy time.Time) (time.Time, time.Time) {
+	fin := time.Date(hoy.Year(), hoy.Month(), hoy.Day(), 0, 0, 0, 0, time.UTC)
+	if f, err := time.Parse("2006-01-02", fechaFin); err == nil && f.Before(fin) {
+		fin = f
+	}
+	// Si el mes de fin no está completo, la historia termina el mes anterior
+	if fin.AddDate(0, 0, 1).Day() != 1 {
+		fin = time.Date(fin.Year(), fin.Month(), 0, 0, 0, 0, 0, time.UTC)
+	}
+	inicio := time.Date(fin.Year(), fin.Month()-time.Month(meses)+1, 1, 0, 0, 0, 0, time.UTC)
+	return inicio, fin
+}
+
+// GenerarPronostico pronostica la demanda mensual de cada producto vendido en la historia.
+// La historia son los últimos meses completos hasta la fecha de fin del filtro; se usan las
+// sucursales y el código del filtro.
+func (s *VentasService) GenerarPronostico(filtro models.VentasFiltro, params models.ParametrosPronostico) (*models.ReportePronostico, error) {
+	inicio, fin := ventanaHistoria(filtro.FechaFin, params.Historia, time.Now())
+	filtro.FechaInicio = inicio.Format("2006-01-02")
+	filtro.FechaFin = fin.Format("2006-01-02")
+
+	serie, err := s.GetSerieVentas(filtro, models.GranularidadMes)
+	if err != nil {
+		return nil, err
+	}
+
+	reporte := &models.ReportePronostico{
+		Parametros:     params,
+		Sucursales:     filtro.Sucursales,
+		InicioHistoria: filtro.FechaInicio,
+		FinHistoria:    filtro.FechaFin,
+		Productos:      make([]models.PronosticoProducto, 0, len(serie.Productos)),
+	}
+	for i := 1; i <= params.Horizonte; i++ {
+		mes := time.Date(fin.Year(), fin.Month()+time.Month(i), 1, 0, 0, 0, 0, time.UTC)
+		reporte.Periodos = append(reporte.Periodos, mes.Format("2006-01"))
+	}
+
+	for _, producto := range serie.Productos {
+		historia := make([]float64, len(producto.Puntos))
+		for i, punto := range producto.Puntos {
+			historia[i] = punto.Cantidad
+		}
+
+		pronostico := pronosticarSerie(historia, params)
+		pronostico.CodigoProducto = producto.CodigoProducto
+		pronostico.NombreProducto = producto.NombreProducto
+		for i := range pronostico.Pronostico {
+			pronostico.Pronostico[i].Periodo = reporte.Periodos[i]
+		}
+		reporte.Productos = append(reporte.Productos, pronostico)
+	}
+
+	sort.SliceStable(reporte.Productos, func(i, j int) bool {
+		return reporte.Productos[i].TotalPronosticado > reporte.Productos[j].TotalPronosticado
+	})
+	return reporte, nil
+}
+
+// aplicarPronosticos incorpora el pronóstico de cada producto al reporte combinado y lo
+// compara con el saldo del período (ingresado - vendido)
+func aplicarPronosticos(reportes []models.ReporteCombinado, pronostico *models.ReportePronostico) {
+	porCodigo := make(map[string]models.PronosticoProducto, len(pronostico.Productos))
+	for _, p := range pronostico.Productos {
+		porCodigo[p.CodigoProducto] = p
+	}
+
+	for i := range reportes {
+		r := &reportes[i]
+		resumen := &models.ResumenPronostico{
+			Metodo:    models.MetodoSinDatos,
+			Horizonte: pronostico.Parametros.Horizonte,
+		}
+		if p, ok := porCodigo[r.CodigoVentas]; ok && r.CodigoVentas != "" {
+			resumen.Metodo = p.Metodo
+			for _, punto := range p.Pronostico {
+				resumen.DemandaPronosticada += punto.Cantidad
+				resumen.Inferior += punto.Inferior
+				resumen.Superior += punto.Superior
+			}
+		}
+
+		resumen.DemandaPronosticada = redondear(resumen.DemandaPronosticada, 2)
+		resumen.Inferior = redondear(resumen.Inferior, 2)
+		resumen.Superior = redondear(resumen.Superior, 2)
+		if mensual := resumen.DemandaPronosticada / float64(resumen.Horizonte); mensual > 0 {
+			cobertura := redondear(r.SaldoPeriodo/mensual, 2)
+			resumen.CoberturaMeses = &cobertura
+		}
+		resumen.StockFaltante = redondear(math.Max(resumen.DemandaPronosticada-r.SaldoPeriodo, 0), 2)
+		r.Pronostico = resumen
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ventasMensuales crea una venta el día 15 de cada mes desde enero de 2025 con las cantidades indicadas
+func ventasMensuales(codigo string, cantidades ...float64) []datasource.LineaVentaFixture {
+	var ventas []datasource.LineaVentaFixture
+	for i, cantidad := range cantidades {
+		if cantidad > 0 {
+			ventas = append(ventas, ventaPrueba(fmt.Sprintf("2025-%02d-15", i+1), codigo, cantidad, 1000))
+		}
+	}
+	return ventas
+}
+
+func TestGenerarPronostico(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		ventas    []datasource.LineaVentaFixture
+		metodo    string // Método forzado; vacío elige por backtest
+		esperado  string
+		cantidad  float64
+		intervalo bool // El pronóstico tiene un intervalo de predicción no nulo
+		sinVentas int
+	}{
+		{
+			// Todos los métodos aciertan el backtest; gana el primero en el orden de preferencia
+			nombre:   "demanda constante",
+			ventas:   ventasMensuales("CONSTANTE", 10, 10, 10, 10, 10, 10),
+			esperado: models.MetodoPromedioMovil,
+			cantidad: 10,
+		},
+		{
+			// Croston: tamaño 12 cada 2 meses
+			nombre:    "demanda intermitente con método forzado",
+			ventas:    ventasMensuales("INTERMITENTE", 0, 12, 0, 12, 0, 12),
+			metodo:    models.MetodoCroston,
+			esperado:  models.MetodoCroston,
+			cantidad:  6,
+			intervalo: true,
+			sinVentas: 3,
+		},
+		{
+			// El suavizado de Holt sigue la tendencia sin error en el backtest, por lo que el
+			// intervalo no se abre
+			nombre:   "demanda con tendencia",
+			ventas:   ventasMensuales("TENDENCIA", 10, 20, 30, 40, 50, 60),
+			esperado: models.MetodoSuavizado,
+			cantidad: 70,
+		},
+		{
+			// La venta de julio queda fuera de la historia (enero a junio)
+			nombre:    "sin ventas en la historia",
+			ventas:    ventasMensuales("FUERA", 0, 0, 0, 0, 0, 0, 25),
+			esperado:  models.MetodoSinDatos,
+			sinVentas: 6,
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			s := servicioPrueba(t, c.ventas, nil)
+			params := models.ParametrosPronostico{Horizonte: 3, Historia: 6, Metodo: c.metodo}
+			if err := params.Validar(); err != nil {
+				t.Fatalf("Validar: %v", err)
+			}
+
+			reporte, err := s.ventasService.GenerarPronostico(models.VentasFiltro{FechaFin: "2025-06-30"}, params)
+			if err != nil {
+				t.Fatalf("GenerarPronostico: %v", err)
+			}
+			if reporte.InicioHistoria != "2025-01-01" || reporte.FinHistoria != "2025-06-30" {
+				t.Errorf("historia = %s a %s, se esperaba 2025-01-01 a 2025-06-30", reporte.InicioHistoria, reporte.FinHistoria)
+			}
+			if periodos := fmt.Sprint(reporte.Periodos); periodos != "[2025-07 2025-08 2025-09]" {
+				t.Errorf("Periodos = %s, se esperaba [2025-07 2025-08 2025-09]", periodos)
+			}
+
+			if c.esperado == models.MetodoSinDatos {
+				if len(reporte.Productos) != 0 {
+					t.Errorf("productos = %d, se esperaba ninguno", len(reporte.Productos))
+				}
+				return
+			}
+			if len(reporte.Productos) != 1 {
+				t.Fatalf("productos = %d, se esperaba 1", len(reporte.Productos))
+			}
+			p := reporte.Productos[0]
+			if p.Metodo != c.esperado {
+				t.Errorf("Metodo = %q, se esperaba %q", p.Metodo, c.esperado)
+			}
+			if p.MesesSinVenta != c.sinVentas {
+				t.Errorf("MesesSinVenta = %d, se esperaba %d", p.MesesSinVenta, c.sinVentas)
+			}
+			primero := p.Pronostico[0]
+			if primero.Periodo != "2025-07" || !aproximado(primero.Cantidad, c.cantidad) {
+				t.Errorf("primer mes = %+v, se esperaba 2025-07 con %v", primero, c.cantidad)
+			}
+			if conIntervalo := primero.Superior > primero.Inferior; conIntervalo != c.intervalo {
+				t.Errorf("intervalo = [%v, %v], se esperaba intervalo %v", primero.Inferior, primero.Superior, c.intervalo)
+			}
+			if primero.Inferior > primero.Cantidad || primero.Superior < primero.Cantidad {
+				t.Errorf("el intervalo [%v, %v] no contiene el pronóstico %v", primero.Inferior, primero.Superior, primero.Cantidad)
+			}
+		})
+	}
+}
+
+func TestPronosticarSerieSinVentas(t *testing.T) {
+	params := models.ParametrosPronostico{Horizonte: 2, Nivel: 95}
+	p := pronosticarSerie([]float64{0, 0, 0, 0}, params)
+	if p.Metodo != models.MetodoSinDatos || p.MesesSinVenta != 4 || len(p.Pronostico) != 2 || p.TotalPronosticado != 0 {
+		t.Errorf("pronosticarSerie = %+v, se esperaba sin_datos con 2 meses en cero", p)
+	}
+}
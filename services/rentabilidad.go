@@ -0,0 +1,397 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// indicadoresMargen calcula el margen, el margen %, el markup y el margen unitario de una venta
+// con el costo indicado. La contribución se completa al conocer el margen total.
+func indicadoresMargen(venta, unidades, costo float64) models.IndicadoresMargen {
+	ind := models.IndicadoresMargen{
+		Venta:    venta,
+		Unidades: unidades,
+		Costo:    costo,
+		Margen:   venta - costo,
+	}
+	if venta != 0 {
+		ind.MargenPct = ind.Margen / venta * 100
+	}
+	if costo != 0 {
+		ind.Markup = ind.Margen / costo * 100
+	}
+	if unidades != 0 {
+		ind.MargenUnitario = ind.Margen / unidades
+	}
+	ind.BajoCosto = ind.Margen < 0
+	return ind
+}
+
+// costeoStocks resume las líneas de venta de un producto costeadas con costearLinea
+type costeoStocks struct {
+	ventaClp  float64
+	unidades  float64
+	costoUsd  float64
+	costoClp  float64
+	bajoCosto int // Líneas vendidas bajo el costo unitario en CLP
+	sinCosto  int // Líneas sin costo de STOCKS, costeadas al CIF promedio
+}
+
+// costoStocks costea las líneas de venta de un producto con las mismas reglas que el margen en
+// USD y CLP (ver costearLinea), de modo que la venta, las unidades y el costo cubren las mismas
+// líneas. costoCifClp es el costo unitario CIF del producto con el que se detectan las líneas
+// vendidas bajo costo; si es 0 se compara con el costo de la línea.
+func costoStocks(tabla TablaTipoCambio, ventas []models.VentaDetallada, cifPromedioUsd, costoCifClp float64) costeoStocks {
+	var c costeoStocks
+	for _, venta := range ventas {
+		linea, ok := costearLinea(tabla, venta, cifPromedioUsd)
+		if !ok {
+			continue
+		}
+		c.ventaClp += linea.ventaClp
+		c.unidades += linea.unidades
+		c.costoUsd += linea.costoUsd
+		c.costoClp += linea.costoClp
+		if linea.sinCosto {
+			c.sinCosto++
+		}
+
+		if linea.unidades <= 0 {
+			continue
+		}
+		costoUnitario := costoCifClp
+		if costoUnitario == 0 {
+			costoUnitario = linea.costoClp / linea.unidades
+		}
+		if costoUnitario > 0 && linea.ventaClp/linea.unidades < costoUnitario {
+			c.bajoCosto++
+		}
+	}
+	return c
+}
+
+// productoRentabilidad calcula la rentabilidad de una fila del reporte combinado
+func productoRentabilidad(tabla TablaTipoCambio, r models.ReporteCombinado, ventas []models.VentaDetallada) models.ProductoRentabilidad {
+	p := models.ProductoRentabilidad{
+		CodigoProducto: r.CodigoProducto,
+		CodigoVentas:   r.CodigoVentas,
+		Nombre:         r.Nombre,
+		Marca:          r.Marca,
+		Categoria:      r.Categoria,
+		Dimensiones:    r.Dimensiones,
+		Unidades:       r.CantidadVendida,
+		VentaNetaClp:   float64(r.VentaNetaTotalClp),
+		CostoCifUsd:    r.CifPromedioUsd,
+		SinInventario:  r.CantidadIngresada == 0 && r.CifPromedioClp == 0,
+	}
+	if p.Unidades != 0 {
+		p.PrecioPromedioClp = p.VentaNetaClp / p.Unidades
+	}
+
+	// Costo CIF: el mismo de UTILIDAD_CLP
+	p.Cif = indicadoresMargen(p.VentaNetaClp, p.Unidades, p.Unidades*r.CifPromedioClp)
+
+	costeo := costoStocks(tabla, ventas, r.CifPromedioUsd, r.CifPromedioClp)
+	p.LineasBajoCosto = costeo.bajoCosto
+	p.LineasSinCosto = costeo.sinCosto
+	if costeo.unidades > 0 {
+		p.CostoStocksUsd = costeo.costoUsd / costeo.unidades
+	}
+	if !tabla.Vacia() {
+		stocks := indicadoresMargen(costeo.ventaClp, costeo.unidades, costeo.costoClp)
+		p.Stocks = &stocks
+	}
+
+	p.BajoCosto = p.Cif.BajoCosto || (p.Stocks != nil && p.Stocks.BajoCosto)
+	return p
+}
+
+// grupoVacio devuelve el nombre de un grupo, o el indicado si el producto no lo tiene informado
+func grupoVacio(valor, vacio string) string {
+	if valor = strings.ToUpper(strings.TrimSpace(valor)); valor != "" {
+		return valor
+	}
+	return vacio
+}
+
+// grupoMarca, grupoCategoria y grupoDimensiones devuelven el grupo de un producto en cada agrupación
+func grupoMarca(p models.ProductoRentabilidad) string {
+	return grupoVacio(p.Marca, "SIN MARCA")
+}
+
+func grupoCategoria(p models.ProductoRentabilidad) string {
+	return grupoVacio(p.Categoria, "SIN CATEGORIA")
+}
+
+func grupoDimensiones(p models.ProductoRentabilidad) string {
+	return grupoVacio(p.Dimensiones, "SIN DIMENSIONES")
+}
+
+// sumarRentabilidad acumula un producto en un grupo
+func sumarRentabilidad(g *models.GrupoRentabilidad, p models.ProductoRentabilidad) {
+	g.Productos++
+	if p.BajoCosto {
+		g.ProductosBajoCosto++
+	}
+	g.Unidades += p.Unidades
+	g.VentaNetaClp += p.VentaNetaClp
+	g.Cif.Costo += p.Cif.Costo
+	if p.Stocks != nil {
+		if g.Stocks == nil {
+			g.Stocks = &models.IndicadoresMargen{}
+		}
+		g.Stocks.Venta += p.Stocks.Venta
+		g.Stocks.Unidades += p.Stocks.Unidades
+		g.Stocks.Costo += p.Stocks.Costo
+	}
+}
+
+// cerrarGrupo calcula los indicadores de un grupo a partir de sus sumas y del margen total
+func cerrarGrupo(g *models.GrupoRentabilidad, total models.GrupoRentabilidad) {
+	g.Cif = indicadoresMargen(g.VentaNetaClp, g.Unidades, g.Cif.Costo)
+	g.Cif.Contribucion = contribucion(g.Cif.Margen, total.Cif.Margen)
+	if g.Stocks != nil {
+		stocks := indicadoresMargen(g.Stocks.Venta, g.Stocks.Unidades, g.Stocks.Costo)
+		if total.Stocks != nil {
+			stocks.Contribucion = contribucion(stocks.Margen, total.Stocks.Margen)
+		}
+		g.Stocks = &stocks
+	}
+}
+
+// redondearIndicadores redondea los indicadores a 2 decimales para la respuesta
+func redondearIndicadores(ind *models.IndicadoresMargen) {
+	if ind == nil {
+		return
+	}
+	for _, valor := range []*float64{&ind.Venta, &ind.Unidades, &ind.Costo, &ind.Margen, &ind.MargenPct, &ind.Markup, &ind.MargenUnitario, &ind.Contribucion} {
+		*valor = redondear(*valor, 2)
+	}
+}
+
+// contribucion devuelve el porcentaje del margen total que aporta un margen
+func contribucion(margen, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return margen / total * 100
+}
+
+// agruparRentabilidad suma los productos por grupo; los grupos quedan ordenados por margen CIF
+// de mayor a menor
+func agruparRentabilidad(productos []models.ProductoRentabilidad, grupo func(models.ProductoRentabilidad) string, total models.GrupoRentabilidad) []models.GrupoRentabilidad {
+	indice := make(map[string]int)
+	grupos := []models.GrupoRentabilidad{}
+	for _, p := range productos {
+		nombre := grupo(p)
+		i, ok := indice[nombre]
+		if !ok {
+			i = len(grupos)
+			indice[nombre] = i
+			grupos = append(grupos, models.GrupoRentabilidad{Grupo: nombre})
+		}
+		sumarRentabilidad(&grupos[i], p)
+	}
+	for i := range grupos {
+		cerrarGrupo(&grupos[i], total)
+	}
+	sort.SliceStable(grupos, func(i, j int) bool {
+		return grupos[i].Cif.Margen > grupos[j].Cif.Margen
+	})
+	return grupos
+}
+
+// GenerarReporteRentabilidad calcula margen bruto %, markup, margen unitario y contribución al
+// margen total de los productos vendidos en el período, con el costo CIF del inventario y con el
+// costo unitario de STOCKS de las ventas, y los totaliza por marca, categoría y dimensiones.
+// La base STOCKS requiere tipos de cambio registrados para convertir el costo a CLP.
+func (s *ReporteService) GenerarReporteRentabilidad(filtro models.ReporteFiltro) (*models.ReporteRentabilidad, error) {
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, err
+	}
+	ventas, err := s.ventasPorCodigo(filtro, filtro.CodigoProducto)
+	if err != nil {
+		return nil, err
+	}
+	tabla := s.tipoCambio.Tabla()
+
+	reporte := &models.ReporteRentabilidad{
+		ConCostoStocks: !tabla.Vacia(),
+		Productos:      []models.ProductoRentabilidad{},
+		Total:          models.GrupoRentabilidad{Grupo: "TOTAL"},
+	}
+	for _, lista := range [][]models.ReporteCombinado{coincidentes, sinCoincidencia} {
+		for _, r := range lista {
+			if r.CantidadVendida == 0 && r.VentaNetaTotalClp == 0 {
+				continue
+			}
+			var lineas []models.VentaDetallada
+			if r.CodigoVentas != "" {
+				lineas = ventas[r.CodigoVentas]
+			}
+			p := productoRentabilidad(tabla, r, lineas)
+			sumarRentabilidad(&reporte.Total, p)
+			reporte.Productos = append(reporte.Productos, p)
+		}
+	}
+	cerrarGrupo(&reporte.Total, reporte.Total)
+	reporte.Total.Cif.Contribucion = 100
+	if reporte.Total.Stocks != nil {
+		reporte.Total.Stocks.Contribucion = 100
+	}
+
+	for i := range reporte.Productos {
+		p := &reporte.Productos[i]
+		p.Cif.Contribucion = contribucion(p.Cif.Margen, reporte.Total.Cif.Margen)
+		if p.Stocks != nil && reporte.Total.Stocks != nil {
+			p.Stocks.Contribucion = contribucion(p.Stocks.Margen, reporte.Total.Stocks.Margen)
+		}
+	}
+	sort.SliceStable(reporte.Productos, func(i, j int) bool {
+		return reporte.Productos[i].Cif.Margen > reporte.Productos[j].Cif.Margen
+	})
+
+	reporte.PorMarca = agruparRentabilidad(reporte.Productos, grupoMarca, reporte.Total)
+	reporte.PorCategoria = agruparRentabilidad(reporte.Productos, grupoCategoria, reporte.Total)
+	reporte.PorDimensiones = agruparRentabilidad(reporte.Productos, grupoDimensiones, reporte.Total)
+
+	for i := range reporte.Productos {
+		p := &reporte.Productos[i]
+		p.PrecioPromedioClp = redondear(p.PrecioPromedioClp, 2)
+		p.CostoStocksUsd = redondear(p.CostoStocksUsd, 2)
+		redondearIndicadores(&p.Cif)
+		redondearIndicadores(p.Stocks)
+	}
+	for _, grupos := range [][]models.GrupoRentabilidad{reporte.PorMarca, reporte.PorCategoria, reporte.PorDimensiones} {
+		for i := range grupos {
+			redondearIndicadores(&grupos[i].Cif)
+			redondearIndicadores(grupos[i].Stocks)
+		}
+	}
+	redondearIndicadores(&reporte.Total.Cif)
+	redondearIndicadores(reporte.Total.Stocks)
+
+	return reporte, nil
+}
+
+// columnasRentabilidad devuelve las columnas de las hojas de rentabilidad; las de la base
+// STOCKS solo se incluyen si hay tipos de cambio
+func columnasRentabilidad(titulo string, conStocks bool) []ColumnaExcel {
+	columnas := []ColumnaExcel{
+		{Titulo: titulo, Ancho: 20},
+		{Titulo: "Codigo_Producto"},
+		{Titulo: "NOMBRE", Ancho: 40},
+		{Titulo: "UNIDADES", Decimal: true},
+		{Titulo: "VENTA NETA CLP", Ancho: 18, Decimal: true},
+		{Titulo: "PRECIO PROMEDIO CLP", Decimal: true},
+		{Titulo: "COSTO CIF CLP", Ancho: 18, Decimal: true},
+		{Titulo: "MARGEN CIF CLP", Ancho: 18, Decimal: true},
+		{Titulo: "MARGEN CIF %", Decimal: true},
+		{Titulo: "MARKUP CIF %", Decimal: true},
+		{Titulo: "MARGEN UNITARIO CIF", Decimal: true},
+		{Titulo: "CONTRIBUCION CIF %", Decimal: true},
+	}
+	if conStocks {
+		columnas = append(columnas,
+			ColumnaExcel{Titulo: "COSTO STOCKS CLP", Ancho: 18, Decimal: true},
+			ColumnaExcel{Titulo: "MARGEN STOCKS CLP", Ancho: 18, Decimal: true},
+			ColumnaExcel{Titulo: "MARGEN STOCKS %", Decimal: true},
+			ColumnaExcel{Titulo: "MARKUP STOCKS %", Decimal: true},
+			ColumnaExcel{Titulo: "MARGEN UNITARIO STOCKS", Decimal: true},
+			ColumnaExcel{Titulo: "CONTRIBUCION STOCKS %", Decimal: true},
+		)
+	}
+	return append(columnas,
+		ColumnaExcel{Titulo: "LINEAS BAJO COSTO"},
+		ColumnaExcel{Titulo: "LINEAS SIN COSTO STOCKS"},
+		ColumnaExcel{Titulo: "BAJO COSTO"},
+	)
+}
+
+// valoresIndicadores devuelve los valores de las columnas de una base de costo
+func valoresIndicadores(ind models.IndicadoresMargen) []interface{} {
+	return []interface{}{ind.Costo, ind.Margen, ind.MargenPct, ind.Markup, ind.MargenUnitario, ind.Contribucion}
+}
+
+// filaRentabilidad arma la fila de un producto
+func filaRentabilidad(grupo string, p models.ProductoRentabilidad, conStocks bool) []interface{} {
+	fila := []interface{}{grupo, p.CodigoProducto, p.Nombre, p.Unidades, p.VentaNetaClp, p.PrecioPromedioClp}
+	fila = append(fila, valoresIndicadores(p.Cif)...)
+	if conStocks {
+		fila = append(fila, valoresIndicadores(*p.Stocks)...)
+	}
+	bajoCosto := "NO"
+	if p.BajoCosto {
+		bajoCosto = "SI"
+	}
+	return append(fila, p.LineasBajoCosto, p.LineasSinCosto, bajoCosto)
+}
+
+// filaGrupoRentabilidad arma la fila de subtotal de un grupo
+func filaGrupoRentabilidad(titulo string, g models.GrupoRentabilidad, conStocks bool) []interface{} {
+	var precio interface{}
+	if g.Unidades != 0 {
+		precio = g.VentaNetaClp / g.Unidades
+	}
+	fila := []interface{}{titulo, nil, fmt.Sprintf("%d productos", g.Productos), g.Unidades, g.VentaNetaClp, precio}
+	fila = append(fila, valoresIndicadores(g.Cif)...)
+	if conStocks && g.Stocks != nil {
+		fila = append(fila, valoresIndicadores(*g.Stocks)...)
+	}
+	return append(fila, nil, nil, fmt.Sprintf("%d productos", g.ProductosBajoCosto))
+}
+
+// hojaRentabilidad genera una hoja con los productos agrupados, un subtotal por grupo y el total
+// general. Los productos vendidos bajo costo se marcan en rojo.
+func hojaRentabilidad(nombre, titulo string, reporte *models.ReporteRentabilidad, grupos []models.GrupoRentabilidad, grupo func(models.ProductoRentabilidad) string) HojaExcel {
+	hoja := HojaExcel{Nombre: nombre, Columnas: columnasRentabilidad(titulo, reporte.ConCostoStocks)}
+	for _, g := range grupos {
+		for _, p := range reporte.Productos {
+			if grupo(p) != g.Grupo {
+				continue
+			}
+			if p.BajoCosto {
+				hoja.FilasAlerta = append(hoja.FilasAlerta, len(hoja.Filas))
+			}
+			hoja.Filas = append(hoja.Filas, filaRentabilidad(g.Grupo, p, reporte.ConCostoStocks))
+		}
+		hoja.FilasResaltadas = append(hoja.FilasResaltadas, len(hoja.Filas))
+		hoja.Filas = append(hoja.Filas, filaGrupoRentabilidad("TOTAL "+g.Grupo, g, reporte.ConCostoStocks))
+	}
+	hoja.FilasResaltadas = append(hoja.FilasResaltadas, len(hoja.Filas))
+	hoja.Filas = append(hoja.Filas, filaGrupoRentabilidad("TOTAL GENERAL", reporte.Total, reporte.ConCostoStocks))
+	return hoja
+}
+
+// ExportarReporteRentabilidad exporta la rentabilidad agrupada por marca, categoría y dimensiones,
+// con subtotales por grupo, y una hoja con los productos vendidos bajo costo
+func (s *ReporteService) ExportarReporteRentabilidad(filtro models.ReporteFiltro) ([]byte, string, error) {
+	reporte, err := s.GenerarReporteRentabilidad(filtro)
+	if err != nil {
+		return nil, "", err
+	}
+
+	bajoCosto := HojaExcel{Nombre: "Bajo Costo", Columnas: columnasRentabilidad("MARCA", reporte.ConCostoStocks)}
+	for _, p := range reporte.Productos {
+		if p.BajoCosto || p.LineasBajoCosto > 0 {
+			bajoCosto.Filas = append(bajoCosto.Filas, filaRentabilidad(grupoMarca(p), p, reporte.ConCostoStocks))
+		}
+	}
+
+	excelBytes, err := s.excelService.GenerarLibro(
+		hojaRentabilidad("Por Marca", "MARCA", reporte, reporte.PorMarca, grupoMarca),
+		hojaRentabilidad("Por Categoría", "CATEGORIA", reporte, reporte.PorCategoria, grupoCategoria),
+		hojaRentabilidad("Por Dimensiones", "DIMENSIONES", reporte, reporte.PorDimensiones, grupoDimensiones),
+		bajoCosto,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("Rentabilidad_%s_al_%s.xlsx", filtro.FechaInicio, filtro.FechaFin)
+	return excelBytes, filename, nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+func TestCostoStocks(t *testing.T) {
+	tabla := TablaTipoCambio{valores: []models.TipoCambio{{Fecha: "2025-01-01", Valor: 1000}}}
+	ventas := []models.VentaDetallada{
+		// $4.000 por unidad: bajo el CIF de $5.000, pero sobre el costo de la línea de $3.000
+		{FechaEmision: "2025-01-03", Cantidad: 2, TotalVentaCLP: 8000, CostoUnitarioUSD: 3},
+		// Sin costo de STOCKS: se costea al CIF promedio de USD 5
+		{FechaEmision: "2025-01-04", Cantidad: 1, TotalVentaCLP: 6000},
+		// $2.000 por unidad: bajo ambos costos
+		{FechaEmision: "2025-01-05", Cantidad: 1, TotalVentaCLP: 2000, CostoUnitarioUSD: 3},
+		// Los documentos anulados no se consideran
+		{FechaEmision: "2025-01-05", Cantidad: 4, TotalVentaCLP: 100, CostoUnitarioUSD: 3, Nula: true},
+	}
+
+	casos := []struct {
+		nombre      string
+		costoCifClp float64
+		bajoCosto   int
+	}{
+		{nombre: "compara con el CIF", costoCifClp: 5000, bajoCosto: 2},
+		{nombre: "sin CIF compara con el costo de la línea", costoCifClp: 0, bajoCosto: 1},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			costeo := costoStocks(tabla, ventas, 5, c.costoCifClp)
+			esperado := costeoStocks{ventaClp: 16000, unidades: 4, costoUsd: 14, costoClp: 14000, bajoCosto: c.bajoCosto, sinCosto: 1}
+			if costeo != esperado {
+				t.Errorf("costoStocks = %+v, se esperaba %+v", costeo, esperado)
+			}
+		})
+	}
+}
+
+func TestProductoRentabilidad(t *testing.T) {
+	r := models.ReporteCombinado{
+		CodigoProducto:    "PISO",
+		CodigoVentas:      "PISO",
+		CantidadVendida:   4,
+		VentaNetaTotalClp: 16000,
+		CantidadIngresada: 10,
+		CifPromedioUsd:    5,
+		CifPromedioClp:    4500,
+	}
+	ventas := []models.VentaDetallada{
+		{FechaEmision: "2025-01-03", Cantidad: 2, TotalVentaCLP: 8000, CostoUnitarioUSD: 3},
+		{FechaEmision: "2025-01-04", Cantidad: 1, TotalVentaCLP: 6000},
+		{FechaEmision: "2025-01-05", Cantidad: 1, TotalVentaCLP: 2000, CostoUnitarioUSD: 3},
+	}
+
+	// Sin tipos de cambio solo se calcula la base CIF: 16.000 - 4 × 4.500
+	sinTabla := productoRentabilidad(TablaTipoCambio{}, r, ventas)
+	if sinTabla.Stocks != nil || sinTabla.Cif.Margen != -2000 || !sinTabla.Cif.BajoCosto || !sinTabla.BajoCosto {
+		t.Errorf("sin tipos de cambio: Stocks = %v, margen CIF = %v, bajo costo %v/%v; se esperaba nil, -2000, true/true",
+			sinTabla.Stocks, sinTabla.Cif.Margen, sinTabla.Cif.BajoCosto, sinTabla.BajoCosto)
+	}
+	// Las líneas solo se revisan una vez costeadas, lo que requiere el tipo de cambio de su fecha
+	if sinTabla.LineasBajoCosto != 0 {
+		t.Errorf("sin tipos de cambio: LineasBajoCosto = %d, se esperaba 0", sinTabla.LineasBajoCosto)
+	}
+
+	tabla := TablaTipoCambio{valores: []models.TipoCambio{{Fecha: "2025-01-01", Valor: 1000}}}
+	p := productoRentabilidad(tabla, r, ventas)
+	if p.Stocks == nil {
+		t.Fatal("Stocks = nil con tipos de cambio registrados")
+	}
+	// STOCKS: 16.000 - 14.000; dos líneas bajo el CIF de $4.500 por unidad
+	redondearIndicadores(p.Stocks)
+	esperado := models.IndicadoresMargen{Venta: 16000, Unidades: 4, Costo: 14000, Margen: 2000, MargenPct: 12.5, Markup: 14.29, MargenUnitario: 500}
+	if *p.Stocks != esperado {
+		t.Errorf("Stocks = %+v, se esperaba %+v", *p.Stocks, esperado)
+	}
+	if p.LineasBajoCosto != 2 || p.LineasSinCosto != 1 || p.CostoStocksUsd != 3.5 || p.PrecioPromedioClp != 4000 {
+		t.Errorf("líneas bajo costo %d, sin costo %d, costo STOCKS USD %v, precio %v; se esperaba 2, 1, 3.5 y 4000",
+			p.LineasBajoCosto, p.LineasSinCosto, p.CostoStocksUsd, p.PrecioPromedioClp)
+	}
+	// El margen STOCKS es positivo, pero el CIF no
+	if !p.BajoCosto {
+		t.Error("BajoCosto = false con margen CIF negativo")
+	}
+}
+
+// ingresoMarca crea un ingreso de prueba con marca y categoría
+func ingresoMarca(codigo, marca, categoria string, costoClp float64) datasource.IngresoFixture {
+	ingreso := ingresoPrueba(codigo, "Z1", "2024-12-01", 100, costoClp)
+	ingreso.Marca = marca
+	ingreso.Categoria = categoria
+	return ingreso
+}
+
+// describirGrupos resume grupo, productos, productos bajo costo y margen y contribución CIF y STOCKS
+func describirGrupos(grupos []models.GrupoRentabilidad) string {
+	var partes []string
+	for _, g := range grupos {
+		partes = append(partes, fmt.Sprintf("%s %d/%d %v %v%% STOCKS %v %v%%", g.Grupo, g.Productos, g.ProductosBajoCosto,
+			g.Cif.Margen, g.Cif.Contribucion, g.Stocks.Margen, g.Stocks.Contribucion))
+	}
+	return strings.Join(partes, ", ")
+}
+
+func TestGenerarReporteRentabilidad(t *testing.T) {
+	// Costo STOCKS de USD 0,5, 0,2 y 0,5 por unidad al tipo de cambio de $1.000
+	ventas := []datasource.LineaVentaFixture{
+		ventaPrueba("2025-01-05", "PISO", 10, 1000),
+		ventaPrueba("2025-01-06", "MURO", 5, 300), // Bajo el CIF de $400, sobre el costo STOCKS de $200
+		ventaPrueba("2025-01-07", "PEGAMENTO", 4, 1000),
+	}
+	for i, costoUsd := range []float64{0.5, 0.2, 0.5} {
+		ventas[i].CostoUnitarioUSD = costoUsd
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoMarca("PISO", "ALFA", "PISOS", 400),
+		ingresoMarca("MURO", "Alfa", "MUROS", 400),
+		ingresoMarca("PEGAMENTO", "BETA", "PISOS", 500),
+		ingresoMarca("SIN-VENTA", "BETA", "PISOS", 500),
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+	if _, err := s.tipoCambio.Importar(strings.NewReader("FECHA;VALOR\n2025-01-01;1000\n"), "dolar.csv", "prueba"); err != nil {
+		t.Fatalf("Importar: %v", err)
+	}
+
+	reporte, err := s.GenerarReporteRentabilidad(models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10"})
+	if err != nil {
+		t.Fatalf("GenerarReporteRentabilidad: %v", err)
+	}
+	// STOCKS: 5.000 + 500 + 2.000
+	if !reporte.ConCostoStocks || reporte.Total.Stocks == nil || reporte.Total.Stocks.Margen != 7500 || reporte.Total.Stocks.Contribucion != 100 {
+		t.Errorf("total STOCKS = %+v, se esperaba margen 7500 y contribución 100", reporte.Total.Stocks)
+	}
+
+	// Margen total 6.000 - 500 + 2.000 = 7.500; los productos sin ventas no se incluyen
+	var productos []string
+	for _, p := range reporte.Productos {
+		productos = append(productos, fmt.Sprintf("%s %v %v%% %d %v", p.CodigoProducto, p.Cif.Margen, p.Cif.Contribucion, p.LineasBajoCosto, p.BajoCosto))
+	}
+	if obtenido, esperado := strings.Join(productos, ", "), "PISO 6000 80% 0 false, PEGAMENTO 2000 26.67% 0 false, MURO -500 -6.67% 1 true"; obtenido != esperado {
+		t.Errorf("productos:\n%s\nse esperaba:\n%s", obtenido, esperado)
+	}
+
+	grupos := []struct {
+		nombre   string
+		grupos   []models.GrupoRentabilidad
+		esperado string
+	}{
+		// Las marcas se agrupan sin distinguir mayúsculas
+		{"por marca", reporte.PorMarca, "ALFA 2/1 5500 73.33% STOCKS 5500 73.33%, BETA 1/0 2000 26.67% STOCKS 2000 26.67%"},
+		{"por categoría", reporte.PorCategoria, "PISOS 2/0 8000 106.67% STOCKS 7000 93.33%, MUROS 1/1 -500 -6.67% STOCKS 500 6.67%"},
+		{"total", []models.GrupoRentabilidad{reporte.Total}, "TOTAL 3/1 7500 100% STOCKS 7500 100%"},
+	}
+	for _, g := range grupos {
+		if obtenido := describirGrupos(g.grupos); obtenido != g.esperado {
+			t.Errorf("%s = %s, se esperaba %s", g.nombre, obtenido, g.esperado)
+		}
+	}
+
+	// La hoja por marca intercala los productos de cada grupo con su subtotal y marca en rojo los
+	// vendidos bajo costo
+	hoja := hojaRentabilidad("Por Marca", "MARCA", reporte, reporte.PorMarca, grupoMarca)
+	var filas []string
+	for _, fila := range hoja.Filas {
+		filas = append(filas, fmt.Sprint(fila[0], " ", fila[1]))
+	}
+	esperadas := "ALFA PISO, ALFA MURO, TOTAL ALFA <nil>, BETA PEGAMENTO, TOTAL BETA <nil>, TOTAL GENERAL <nil>"
+	if obtenido := strings.Join(filas, ", "); obtenido != esperadas {
+		t.Errorf("filas = %s, se esperaba %s", obtenido, esperadas)
+	}
+	if fmt.Sprint(hoja.FilasAlerta) != "[1]" || fmt.Sprint(hoja.FilasResaltadas) != "[2 4 5]" {
+		t.Errorf("filas en rojo %v y resaltadas %v, se esperaba [1] y [2 4 5]", hoja.FilasAlerta, hoja.FilasResaltadas)
+	}
+}
@@ -1,17 +1,12 @@
 package services
 
 import (
-	"bytes"
 	"fmt"
 	"log"
-	"regexp"
 	"sort"
-	"strconv"
-	"strings"
+	"time"
 
-	"github.com/pablojnd/rotacion/db"
 	"github.com/pablojnd/rotacion/models"
-	"github.com/xuri/excelize/v2"
 )
 
 // ReporteService proporciona métodos para generar reportes combinados
@@ -19,392 +14,92 @@ type ReporteService struct {
 	ventasService     *VentasService
 	inventarioService *InventarioService
 	excelService      *ExcelService
-	sqlServer         *db.SQLServerDB
-	mysql             *db.MySQLDB
+	matcher           *Matcher
+	parametrosABC     models.ParametrosABC
+	reposicion        models.ConfigReposicion
+	tipoCambio        *TipoCambioService
 }
 
 // NewReporteService crea un nuevo servicio de reportes combinados
 func NewReporteService(
-	sqlServer *db.SQLServerDB,
-	mysql *db.MySQLDB,
 	ventasService *VentasService,
 	inventarioService *InventarioService,
 	excelService *ExcelService,
+	matcher *Matcher,
+	parametrosABC models.ParametrosABC,
+	reposicion models.ConfigReposicion,
+	tipoCambio *TipoCambioService,
 ) *ReporteService {
 	return &ReporteService{
 		ventasService:     ventasService,
 		inventarioService: inventarioService,
 		excelService:      excelService,
-		sqlServer:         sqlServer,
-		mysql:             mysql,
+		matcher:           matcher,
+		parametrosABC:     parametrosABC,
+		reposicion:        reposicion,
+		tipoCambio:        tipoCambio,
 	}
 }
 
-// normalizarCodigo normaliza un código de producto para mejorar la comparación
-func normalizarCodigo(codigo string) string {
-	// Convertir a mayúsculas
-	codigo = strings.ToUpper(codigo)
-
-	// Eliminar espacios en blanco
-	codigo = strings.TrimSpace(codigo)
-	codigo = strings.ReplaceAll(codigo, " ", "")
-
-	// Eliminar todos los caracteres especiales comunes que pueden causar problemas en comparaciones
-	codigo = regexp.MustCompile(`[^A-Z0-9]`).ReplaceAllString(codigo, "")
-
-	return codigo
-}
-
 // GenerarReporteCombinado genera un reporte combinado de inventario y ventas
 func (s *ReporteService) GenerarReporteCombinado(filtro models.ReporteFiltro) ([]models.ReporteCombinado, []models.ReporteCombinado, error) {
-	// 1. Primero obtener datos de ventas (SQL Server)
-	ventasFiltro := models.VentasFiltro{
-		FechaInicio:    filtro.FechaInicio,
-		FechaFin:       filtro.FechaFin,
-		Sucursal:       filtro.Sucursal,
-		CodigoProducto: filtro.CodigoProducto,
+	// Usar el orden de estrategias solicitado, si lo hay
+	matcher := s.matcher
+	if len(filtro.Estrategias) > 0 {
+		var err error
+		matcher, err = s.matcher.ConEstrategias(filtro.Estrategias)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	datosVentas, err := s.ventasService.GetVentasAgrupadas(ventasFiltro)
+
+	// 1. Primero obtener datos de ventas (SQL Server)
+	datosVentas, err := s.ventasService.GetVentasAgrupadas(filtro.FiltroVentas())
 	if err != nil {
 		return nil, nil, fmt.Errorf("error al obtener datos de ventas: %v", err)
 	}
 
 	// 2. Obtener datos de inventario (MySQL)
-	inventarioFiltro := models.InventarioFiltro{
-		Anio:           filtro.Anio,
-		CodigoProducto: filtro.CodigoProducto,
-	}
-	datosInventario, err := s.inventarioService.GetInventario(inventarioFiltro)
+	datosInventario, err := s.inventarioService.GetInventario(filtro.FiltroInventario())
 	if err != nil {
 		return nil, nil, fmt.Errorf("error al obtener datos de inventario: %v", err)
 	}
 
 	log.Printf("Total registros obtenidos - Ventas: %d, Inventario: %d", len(datosVentas), len(datosInventario))
 
-	// 3. Crear mappings para facilitar la búsqueda
-	// Crear mapa de inventario con claves estándar y normalizadas
-	inventarioMap := make(map[string]map[string]interface{}) // clave original -> datos
-	inventarioMapNormalizado := make(map[string]string)      // clave normalizada -> clave original
-
-	// Extraer nombres de columnas para diagnóstico
-	var columnasInventario []string
-	if len(datosInventario) > 0 {
-		for k := range datosInventario[0] {
-			columnasInventario = append(columnasInventario, k)
-		}
-		log.Printf("Columnas en inventario: %v", columnasInventario)
+	// 3. Emparejar códigos de inventario con códigos de ventas
+	ventasMap := make(map[string]models.VentaAgrupada, len(datosVentas))
+	codigosVentas := make([]string, 0, len(datosVentas))
+	for _, item := range datosVentas {
+		ventasMap[item.CodigoProducto] = item
+		codigosVentas = append(codigosVentas, item.CodigoProducto)
 	}
 
+	codigosInventario := make([]string, 0, len(datosInventario))
 	for _, item := range datosInventario {
-		if codigo, ok := item["Código de Producto"].(string); ok {
-			inventarioMap[codigo] = item
-
-			// Guardar también clave normalizada
-			codigoNorm := normalizarCodigo(codigo)
-			inventarioMapNormalizado[codigoNorm] = codigo
-			log.Printf("Inventario: Original='%s', Normalizado='%s'", codigo, codigoNorm)
-		}
-	}
-
-	// Crear mapa de ventas con claves estándar y normalizadas
-	ventasMap := make(map[string]map[string]interface{}) // clave original -> datos
-	ventasMapNormalizado := make(map[string]string)      // clave normalizada -> clave original
-
-	// Extraer nombres de columnas para diagnóstico
-	var columnasVentas []string
-	if len(datosVentas) > 0 {
-		for k := range datosVentas[0] {
-			columnasVentas = append(columnasVentas, k)
-		}
-		log.Printf("Columnas en ventas: %v", columnasVentas)
-	}
-
-	for _, item := range datosVentas {
-		if codigo, ok := item["Código de Producto"].(string); ok {
-			ventasMap[codigo] = item
-
-			// Guardar también clave normalizada
-			codigoNorm := normalizarCodigo(codigo)
-			ventasMapNormalizado[codigoNorm] = codigo
-			log.Printf("Ventas: Original='%s', Normalizado='%s'", codigo, codigoNorm)
-		}
+		codigosInventario = append(codigosInventario, item.CodigoProducto)
 	}
 
-	// Algunos diagnósticos para mejorar la depuración
-	{
-		// Imprimir algunos ejemplos de códigos para verificación
-		count := 0
-		for codInv, _ := range inventarioMap {
-			if count < 5 {
-				log.Printf("Muestra inventario #%d: Código='%s', Normalizado='%s'", count+1, codInv, normalizarCodigo(codInv))
-				count++
-			} else {
-				break
-			}
-		}
-
-		count = 0
-		for codVen, _ := range ventasMap {
-			if count < 5 {
-				log.Printf("Muestra ventas #%d: Código='%s', Normalizado='%s'", count+1, codVen, normalizarCodigo(codVen))
-				count++
-			} else {
-				break
-			}
-		}
-	}
+	coincidencias := matcher.Emparejar(codigosInventario, codigosVentas)
 
-	// 4. Generar reportes
+	// 4. Procesar productos de inventario
 	var reportesCoincidentes []models.ReporteCombinado
 	var reportesSinCoincidencia []models.ReporteCombinado
+	ventasEmparejadas := make(map[string]bool, len(coincidencias))
+	metodos := make(map[string]int)
+	dias := diasPeriodo(filtro.FechaInicio, filtro.FechaFin, time.Now())
 
-	// Imprimimos un conjunto de coincidencias esperadas para verificación manual
-	log.Printf("VERIFICANDO COINCIDENCIAS DE EJEMPLO:")
-	for codInv, _ := range inventarioMap {
-		codInvNorm := normalizarCodigo(codInv)
-		for codVen, _ := range ventasMap {
-			codVenNorm := normalizarCodigo(codVen)
-			if codInvNorm == codVenNorm {
-				log.Printf("Debería coincidir: Inv='%s', Ven='%s', normalizados='%s'", codInv, codVen, codInvNorm)
-				break
-			}
-		}
-	}
-
-	// Diagnóstico: probar con algunos códigos específicos mencionados
-	for _, codInv := range []string{"CERCORBEI", "CERMADBRI", "GREACRGRI", "IL2508TIT", "PASZQ1802", "VIINA10366244"} {
-		codInvNorm := normalizarCodigo(codInv)
-		log.Printf("Buscando coincidencias para '%s' (norm: '%s'):", codInv, codInvNorm)
-
-		// Buscar exacto en ventas
-		if venData, existe := ventasMap[codInv]; existe {
-			log.Printf("  ✓ Coincidencia exacta en ventasMap! Datos: nombre=%v, cantidad=%v",
-				venData["Nombre del Producto"], venData["Cantidad Total Vendida"])
-		} else {
-			log.Printf("  ✗ No hay coincidencia exacta en ventasMap")
-		}
-
-		// Buscar normalizado
-		if codVen, existe := ventasMapNormalizado[codInvNorm]; existe {
-			venData := ventasMap[codVen]
-			log.Printf("  ✓ Coincidencia normalizada! codVen='%s', nombre=%v, cantidad=%v",
-				codVen, venData["Nombre del Producto"], venData["Cantidad Total Vendida"])
-		} else {
-			log.Printf("  ✗ No hay coincidencia normalizada")
-		}
-
-		// Buscar por substring como último recurso
-		encontrado := false
-		for codVen, datos := range ventasMap {
-			if strings.Contains(codVen, codInv) || strings.Contains(codInv, codVen) {
-				log.Printf("  ✓ Coincidencia substring! '%s' contiene/está contenido en '%s', nombre=%v, cantidad=%v",
-					codInv, codVen, datos["Nombre del Producto"], datos["Cantidad Total Vendida"])
-				encontrado = true
-				break
-			}
-		}
-		if !encontrado {
-			log.Printf("  ✗ No hay coincidencia substring")
-		}
-	}
-
-	// 5. Procesar productos de inventario primero
-	coincidenciasEncontradas := 0
-
-	for codigoProductoInv, invData := range inventarioMap {
-		reporte := models.ReporteCombinado{
-			CodigoProducto: codigoProductoInv,
-		}
-
-		// Llenar datos básicos de inventario
-		if marca, ok := invData["Marca del Producto"].(string); ok {
-			reporte.Marca = marca
-		}
-		if cat, ok := invData["Categoría Principal"].(string); ok {
-			reporte.Categoria = cat
-		}
-		if dim, ok := invData["Subcategoría/Dimensiones"].(string); ok {
-			reporte.Dimensiones = dim
-		}
-		if nombre, ok := invData["Nombre Aduanero"].(string); ok {
-			reporte.Nombre = nombre
-		}
-		if pack, ok := invData["Unidades por Caja"].(float64); ok {
-			reporte.Packing = pack
-		}
-		if cif, ok := invData["Costo Promedio CIF (USD)"].(float64); ok {
-			reporte.CifPromedioUsd = cif
-		}
-		if cifClp, ok := invData["Costo Promedio Unitario (CLP)"].(float64); ok {
-			reporte.CifPromedioClp = cifClp
-		}
-		if cant, ok := invData["Total Unidades Ingresadas"].(float64); ok {
-			reporte.CantidadIngresada = cant
-		}
-		if dias, ok := invData["Días Desde Primer Ingreso"].(int); ok {
-			reporte.DiasEnInventario = dias
-		}
-		if fechaInicio, ok := invData["Fecha Primer Ingreso"].(string); ok {
-			reporte.FechaPrimerIngreso = fechaInicio
-		}
-		if fechaFin, ok := invData["Fecha Último Ingreso"].(string); ok {
-			reporte.FechaUltimoIngreso = fechaFin
-		}
-		if ingresos, ok := invData["Cantidad de Ingresos"].(int); ok {
-			reporte.CantidadIngresos = ingresos
-		}
-		if historial, ok := invData["Historial de Ingresos (JSON)"].(string); ok {
-			reporte.HistorialIngresos = historial
-		}
-
-		// Buscar ventas para este producto con múltiples estrategias mejoradas
-		var venData map[string]interface{}
-		var encontrado bool
-		var metodoCoincidencia string
-
-		// PASO 1: Buscar coincidencia exacta
-		if datos, ok := ventasMap[codigoProductoInv]; ok {
-			venData = datos
-			encontrado = true
-			metodoCoincidencia = "exacta"
-		}
-
-		// PASO 2: Buscar con normalización
-		if !encontrado {
-			codigoNorm := normalizarCodigo(codigoProductoInv)
-			if codigoVentas, ok := ventasMapNormalizado[codigoNorm]; ok {
-				venData = ventasMap[codigoVentas]
-				encontrado = true
-				metodoCoincidencia = "normalizada"
-			}
-		}
-
-		// PASO 3: Buscar coincidencia parcial en cualquier dirección
-		if !encontrado {
-			for codigoVentas, datos := range ventasMap {
-				// Comparar en ambas direcciones
-				if strings.Contains(strings.ToUpper(codigoProductoInv), strings.ToUpper(codigoVentas)) ||
-					strings.Contains(strings.ToUpper(codigoVentas), strings.ToUpper(codigoProductoInv)) {
-					venData = datos
-					encontrado = true
-					metodoCoincidencia = "substring"
-					break
-				}
-			}
-		}
-
-		// PASO 4: Verificar manualmente algunos de los códigos que sabemos deberían coincidir
-		if !encontrado && (codigoProductoInv == "CERCORBEI" || codigoProductoInv == "CERMADBRI" ||
-			codigoProductoInv == "GREACRGRI" || codigoProductoInv == "IL2508TIT" ||
-			codigoProductoInv == "PASZQ1802" || codigoProductoInv == "VIINA10366244") {
-			// Buscar manualmente recorriendo todas las ventas
-			for codVenta, venta := range ventasMap {
-				if strings.Contains(codigoProductoInv, strings.TrimSpace(codVenta)) ||
-					strings.Contains(strings.TrimSpace(codVenta), codigoProductoInv) ||
-					(len(codigoProductoInv) >= 5 && len(codVenta) >= 5 &&
-						strings.Contains(codigoProductoInv[:5], codVenta[:5])) {
-					venData = venta
-					encontrado = true
-					metodoCoincidencia = "manual-especial"
-					break
-				}
-			}
-		}
-
-		if encontrado {
-			coincidenciasEncontradas++
-			if coincidenciasEncontradas <= 10 {
-				log.Printf("COINCIDENCIA #%d: Inv='%s', Método=%s, Campos ventas: precio=%v, cantVend=%v",
-					coincidenciasEncontradas, codigoProductoInv, metodoCoincidencia,
-					venData["Precio Base (CLP)"], venData["Cantidad Total Vendida"])
-			}
-
-			// Extraer datos de ventas con diagnóstico mejorado
-			if precio, ok := venData["Precio Base (CLP)"].(int); ok {
-				reporte.PrecioProductoClp = precio
-			} else if precio, ok := venData["Precio Base (CLP)"].(int64); ok {
-				reporte.PrecioProductoClp = int(precio)
-			} else if precio, ok := venData["Precio Base (CLP)"].(string); ok {
-				if p, err := strconv.Atoi(precio); err == nil {
-					reporte.PrecioProductoClp = p
-				}
-			} else {
-				log.Printf("No se pudo extraer Precio Base para %s, tipo: %T", codigoProductoInv, venData["Precio Base (CLP)"])
-			}
+	for _, invData := range datosInventario {
+		reporte := reporteDesdeInventario(invData)
 
-			if precioOf, ok := venData["Precio de Oferta (CLP)"].(int); ok {
-				reporte.PrecioOfertaClp = precioOf
-			} else if precioOf, ok := venData["Precio de Oferta (CLP)"].(string); ok {
-				if p, err := strconv.Atoi(precioOf); err == nil {
-					reporte.PrecioOfertaClp = p
-				}
-			}
+		if c, ok := coincidencias[invData.CodigoProducto]; ok {
+			ventasEmparejadas[c.CodigoVentas] = true
+			metodos[c.Metodo]++
 
-			if precioProm, ok := venData["Precio Promedio Ponderado (CLP)"].(int); ok {
-				reporte.PrecioVentaPromedioClp = precioProm
-			} else if precioProm, ok := venData["Precio Promedio Ponderado (CLP)"].(int64); ok {
-				reporte.PrecioVentaPromedioClp = int(precioProm)
-			} else if precioProm, ok := venData["Precio Promedio Ponderado (CLP)"].(float64); ok {
-				reporte.PrecioVentaPromedioClp = int(precioProm)
-			} else if precioProm, ok := venData["Precio Promedio Ponderado (CLP)"].(string); ok {
-				if p, err := strconv.ParseFloat(precioProm, 64); err == nil {
-					reporte.PrecioVentaPromedioClp = int(p)
-				}
-			}
-
-			// Para Cantidad Total Vendida - manejo más robusto de tipos
-			if cantVend, ok := venData["Cantidad Total Vendida"].(float64); ok {
-				reporte.CantidadVendida = cantVend
-			} else if cantVend, ok := venData["Cantidad Total Vendida"].(int); ok {
-				reporte.CantidadVendida = float64(cantVend)
-			} else if cantVend, ok := venData["Cantidad Total Vendida"].(int64); ok {
-				reporte.CantidadVendida = float64(cantVend)
-			} else if cantVend, ok := venData["Cantidad Total Vendida"].(string); ok {
-				if f, err := strconv.ParseFloat(cantVend, 64); err == nil {
-					reporte.CantidadVendida = f
-				}
-			} else if cantVend, ok := venData["Cantidad Vendida"].(string); ok {
-				if f, err := strconv.ParseFloat(cantVend, 64); err == nil {
-					reporte.CantidadVendida = f
-				}
-			} else {
-				log.Printf("No se pudo extraer Cantidad Vendida para %s, tipo: %T",
-					codigoProductoInv, venData["Cantidad Total Vendida"])
-				reporte.CantidadVendida = 0
-			}
-
-			// Para Venta Total - manejo más robusto de tipos
-			if ventaTotal, ok := venData["Total Ventas (CLP)"].(int); ok {
-				reporte.VentaNetaTotalClp = ventaTotal
-			} else if ventaTotal, ok := venData["Total Ventas (CLP)"].(int64); ok {
-				reporte.VentaNetaTotalClp = int(ventaTotal)
-			} else if ventaTotal, ok := venData["Total Ventas (CLP)"].(float64); ok {
-				reporte.VentaNetaTotalClp = int(ventaTotal)
-			} else if ventaTotal, ok := venData["Total Ventas (CLP)"].(string); ok {
-				if vt, err := strconv.ParseFloat(ventaTotal, 64); err == nil {
-					reporte.VentaNetaTotalClp = int(vt)
-				}
-			} else {
-				log.Printf("No se pudo extraer Venta Total para %s, tipo: %T",
-					codigoProductoInv, venData["Total Ventas (CLP)"])
-				reporte.VentaNetaTotalClp = 0
-			}
-
-			if fecha, ok := venData["Última Fecha de Venta"].(string); ok {
-				reporte.UltimaFechaVenta = fecha
-			}
-
-			if trans, ok := venData["Cantidad de Ventas Registradas"].(int); ok {
-				reporte.CantidadTransacciones = trans
-			} else if trans, ok := venData["Cantidad de Ventas Registradas"].(int64); ok {
-				reporte.CantidadTransacciones = int(trans)
-			} else if trans, ok := venData["Cantidad de Ventas Registradas"].(float64); ok {
-				reporte.CantidadTransacciones = int(trans)
-			} else if trans, ok := venData["Cantidad de Ventas Registradas"].(string); ok {
-				if t, err := strconv.Atoi(trans); err == nil {
-					reporte.CantidadTransacciones = t
-				}
-			}
+			reporte.CodigoVentas = c.CodigoVentas
+			reporte.MetodoCoincidencia = c.Metodo
+			reporte.ConfianzaCoincidencia = c.Confianza
+			aplicarVentas(&reporte, ventasMap[c.CodigoVentas])
 
 			// Calcular campos derivados
 			if reporte.CantidadIngresada > 0 && reporte.CantidadVendida > 0 {
@@ -413,85 +108,45 @@ func (s *ReporteService) GenerarReporteCombinado(filtro models.ReporteFiltro) ([
 
 			// Cálculo de utilidad
 			reporte.UtilidadClp = float64(reporte.VentaNetaTotalClp) - (reporte.CantidadVendida * reporte.CifPromedioClp)
-		} else {
-			if codigoProductoInv == "CERCORBEI" || codigoProductoInv == "CERMADBRI" ||
-				codigoProductoInv == "GREACRGRI" || codigoProductoInv == "IL2508TIT" ||
-				codigoProductoInv == "PASZQ1802" || codigoProductoInv == "VIINA10366244" {
-				log.Printf("NO SE ENCONTRÓ COINCIDENCIA para código especial: %s", codigoProductoInv)
-			}
-			reporte.CantidadVendida = 0
-			reporte.PorcentajeVendido = 0
-			reporte.UtilidadClp = 0
 		}
 
+		calcularRotacion(&reporte, dias)
+
 		reportesCoincidentes = append(reportesCoincidentes, reporte)
 	}
 
-	// 6. Procesar productos de ventas que no tienen correspondencia en inventario
-	for codigoProductoVentas, venData := range ventasMap {
-		// Verificar si este producto de ventas ya fue procesado con inventario
-		codigoNorm := normalizarCodigo(codigoProductoVentas)
-		encontradoEnInventario := false
-
-		// Buscar por código exacto
-		if _, existe := inventarioMap[codigoProductoVentas]; existe {
-			encontradoEnInventario = true
+	// 5. Procesar productos de ventas que no se emparejaron con inventario
+	for _, venData := range datosVentas {
+		if ventasEmparejadas[venData.CodigoProducto] {
+			continue
 		}
 
-		// Buscar por código normalizado
-		if !encontradoEnInventario {
-			for _, codigoInv := range inventarioMapNormalizado {
-				if normalizarCodigo(codigoInv) == codigoNorm {
-					encontradoEnInventario = true
-					break
-				}
-			}
+		reporte := models.ReporteCombinado{
+			CodigoProducto: venData.CodigoProducto,
+			Nombre:         venData.NombreProducto,
+			CodigoVentas:   venData.CodigoProducto,
 		}
+		aplicarVentas(&reporte, venData)
 
-		// Si no está en inventario, agregarlo a "sin coincidencia"
-		if !encontradoEnInventario {
-			reporte := models.ReporteCombinado{
-				CodigoProducto: codigoProductoVentas,
-			}
-
-			// Extraer datos de ventas
-			if nombre, ok := venData["Nombre del Producto"].(string); ok {
-				reporte.Nombre = nombre
-			}
-			if precio, ok := venData["Precio Base (CLP)"].(int); ok {
-				reporte.PrecioProductoClp = precio
-			}
-			if precioOf, ok := venData["Precio de Oferta (CLP)"].(int); ok {
-				reporte.PrecioOfertaClp = precioOf
-			}
-			if precioProm, ok := venData["Precio Promedio Ponderado (CLP)"].(int); ok {
-				reporte.PrecioVentaPromedioClp = precioProm
-			}
-			if cantVend, ok := venData["Cantidad Total Vendida"].(float64); ok {
-				reporte.CantidadVendida = cantVend
-			}
-			if ventaTotal, ok := venData["Total Ventas (CLP)"].(int); ok {
-				reporte.VentaNetaTotalClp = ventaTotal
-			}
-			if fecha, ok := venData["Última Fecha de Venta"].(string); ok {
-				reporte.UltimaFechaVenta = fecha
-			}
-			if trans, ok := venData["Cantidad de Ventas Registradas"].(int); ok {
-				reporte.CantidadTransacciones = trans
-			}
+		// Producto vendido pero no en inventario
+		reporte.CantidadIngresada = 0
+		reporte.PorcentajeVendido = 100
+		reporte.UtilidadClp = float64(reporte.VentaNetaTotalClp)
+		calcularRotacion(&reporte, dias)
 
-			// Producto vendido pero no en inventario
-			reporte.CantidadIngresada = 0
-			reporte.PorcentajeVendido = 100
-			reporte.UtilidadClp = float64(reporte.VentaNetaTotalClp)
+		reportesSinCoincidencia = append(reportesSinCoincidencia, reporte)
+	}
 
-			reportesSinCoincidencia = append(reportesSinCoincidencia, reporte)
-		}
+	// Los códigos de la lista pueden ser de inventario o de ventas, por lo que se filtra después
+	// de emparejar; los rankings y la clasificación ABC se calculan solo sobre la lista
+	if len(filtro.Codigos) > 0 {
+		reportesCoincidentes = filtrarPorCodigos(filtro, reportesCoincidentes)
+		reportesSinCoincidencia = filtrarPorCodigos(filtro, reportesSinCoincidencia)
 	}
 
-	// 7. Aplicar rankings como antes
+	// 6. Aplicar rankings
 	// Ordenar por cantidad vendida para el ranking de cantidad
-	sort.Slice(reportesCoincidentes, func(i, j int) bool {
+	sort.SliceStable(reportesCoincidentes, func(i, j int) bool {
 		return reportesCoincidentes[i].CantidadVendida > reportesCoincidentes[j].CantidadVendida
 	})
 	for i := range reportesCoincidentes {
@@ -499,168 +154,343 @@ func (s *ReporteService) GenerarReporteCombinado(filtro models.ReporteFiltro) ([
 	}
 
 	// Ordenar por venta total para el ranking de venta
-	sort.Slice(reportesCoincidentes, func(i, j int) bool {
+	sort.SliceStable(reportesCoincidentes, func(i, j int) bool {
 		return reportesCoincidentes[i].VentaNetaTotalClp > reportesCoincidentes[j].VentaNetaTotalClp
 	})
 	for i := range reportesCoincidentes {
 		reportesCoincidentes[i].RankingVenta = i + 1
 	}
 
-	log.Printf("Resultados finales - Total inventario: %d, Total ventas: %d, Coincidencias encontradas: %d, Reportes generados: %d",
-		len(inventarioMap), len(ventasMap), coincidenciasEncontradas, len(reportesCoincidentes))
+	// 7. Clasificar productos en clases ABC
+	s.asignarClasesABC(reportesCoincidentes, reportesSinCoincidencia)
+
+	// 8. Incorporar el pronóstico de demanda, si se solicitó
+	if filtro.Pronostico != nil {
+		pronostico, err := s.ventasService.GenerarPronostico(filtro.FiltroVentas(), *filtro.Pronostico)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error al generar pronóstico: %v", err)
+		}
+		aplicarPronosticos(reportesCoincidentes, pronostico)
+		aplicarPronosticos(reportesSinCoincidencia, pronostico)
+	}
+
+	// 9. Incorporar el stock disponible a la fecha de corte, si se solicitó
+	if filtro.StockAFecha != "" {
+		stockFiltro := models.StockFiltro{Fecha: filtro.StockAFecha, CodigoProducto: filtro.CodigoProducto}
+		if err := stockFiltro.Validar(); err != nil {
+			return nil, nil, err
+		}
+		datos, err := s.cargarDatosStock(stockFiltro)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error al calcular stock a la fecha: %v", err)
+		}
+		aplicarStock(reportesCoincidentes, reportesSinCoincidencia, datos, matcher)
+	}
+
+	// 10. Calcular el margen en USD y CLP al tipo de cambio de cada fecha, si hay tipos de cambio
+	if tabla := s.tipoCambio.Tabla(); !tabla.Vacia() {
+		ventas, err := s.ventasPorCodigo(filtro, filtro.CodigoProducto)
+		if err != nil {
+			return nil, nil, err
+		}
+		aplicarMargenMoneda(tabla, reportesCoincidentes, ventas)
+		aplicarMargenMoneda(tabla, reportesSinCoincidencia, ventas)
+	}
+
+	log.Printf("Resultados finales - Total inventario: %d, Total ventas: %d, Coincidencias: %d %v, Sin coincidencia: %d",
+		len(datosInventario), len(datosVentas), len(coincidencias), metodos, len(reportesSinCoincidencia))
 	return reportesCoincidentes, reportesSinCoincidencia, nil
 }
 
-// ExportarReporteCombinado exporta el reporte combinado a Excel
-func (s *ReporteService) ExportarReporteCombinado(filtro models.ReporteFiltro) ([]byte, string, error) {
-	// 1. Generar el reporte combinado
-	reportesCoincidentes, reportesSinCoincidencia, err := s.GenerarReporteCombinado(filtro)
-	if err != nil {
-		return nil, "", err
+// GenerarReporteCombinadoFiltrado genera el reporte combinado y conserva las filas que cumplen el
+// filtro de productos. Los rankings y las clases ABC se calculan antes de filtrar, por lo que son
+// los mismos que en el reporte completo.
+func (s *ReporteService) GenerarReporteCombinadoFiltrado(filtro models.ReporteFiltro, productos models.FiltroProductos) ([]models.ReporteCombinado, []models.ReporteCombinado, error) {
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil || !productos.Activo() {
+		return coincidentes, sinCoincidencia, err
 	}
+	return filtrarReportes(coincidentes, productos.Incluye), filtrarReportes(sinCoincidencia, productos.Incluye), nil
+}
 
-	// 2. Crear un nuevo archivo Excel
-	f := excelize.NewFile()
-	defer f.Close()
+// filtrarPorCodigos conserva las filas del reporte que están en la lista de códigos del filtro
+func filtrarPorCodigos(filtro models.ReporteFiltro, reportes []models.ReporteCombinado) []models.ReporteCombinado {
+	return filtrarReportes(reportes, filtro.IncluyeProducto)
+}
 
-	// 3. Configurar la primera hoja para reportes coincidentes
-	sheetNameCoincidentes := "Productos Coincidentes"
-	indexCoincidentes, err := f.NewSheet(sheetNameCoincidentes)
-	if err != nil {
-		return nil, "", err
+// filtrarReportes conserva las filas del reporte para las que incluye devuelve true
+func filtrarReportes(reportes []models.ReporteCombinado, incluye func(models.ReporteCombinado) bool) []models.ReporteCombinado {
+	var filtrados []models.ReporteCombinado
+	for _, r := range reportes {
+		if incluye(r) {
+			filtrados = append(filtrados, r)
+		}
 	}
-	f.SetActiveSheet(indexCoincidentes)
+	return filtrados
+}
 
-	// 4. Formatos de celda
-	styleHeader, err := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{
-			Bold: true,
-			Size: 11,
-		},
-		Fill: excelize.Fill{
-			Type:    "pattern",
-			Color:   []string{"#DCE6F1"},
-			Pattern: 1,
-		},
-		Border: []excelize.Border{
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
-		Alignment: &excelize.Alignment{
-			Horizontal: "center",
-			Vertical:   "center",
-			WrapText:   true,
-		},
-	})
-	if err != nil {
-		return nil, "", err
+// reporteDesdeInventario crea una fila del reporte con los datos de inventario (MySQL)
+func reporteDesdeInventario(inv models.Inventario) models.ReporteCombinado {
+	return models.ReporteCombinado{
+		CodigoProducto:     inv.CodigoProducto,
+		Marca:              inv.MarcaProducto,
+		Categoria:          inv.CategoriaPrincipal,
+		Dimensiones:        inv.SubcategoriaDimensiones,
+		Nombre:             inv.NombreAduanero,
+		Packing:            inv.UnidadesPorCaja,
+		CifPromedioUsd:     inv.CostoPromedioCIF,
+		CifPromedioClp:     inv.CostoPromedioUnitario,
+		CantidadIngresada:  inv.TotalUnidadesIngresadas,
+		DiasEnInventario:   inv.DiasDesdePrimerIngreso,
+		FechaPrimerIngreso: formatearFecha(inv.FechaPrimerIngreso),
+		FechaUltimoIngreso: formatearFecha(inv.FechaUltimoIngreso),
+		CantidadIngresos:   inv.CantidadIngresos,
+		HistorialIngresos:  inv.HistorialIngresos,
+
+		Ingresos:              inv.Ingresos,
+		AdvertenciasHistorial: inv.AdvertenciasHistorial,
+		IngresadaPorAnio:      inv.UnidadesPorAnio,
 	}
+}
 
-	// Estilo para números
-	styleNumber, _ := f.NewStyle(&excelize.Style{
-		NumFmt: 2, // Formato numérico con 2 decimales
-	})
+// aplicarVentas completa una fila del reporte con los datos de ventas (SQL Server)
+func aplicarVentas(reporte *models.ReporteCombinado, venta models.VentaAgrupada) {
+	reporte.PrecioProductoClp = venta.PrecioBaseCLP
+	reporte.PrecioOfertaClp = venta.PrecioOfertaCLP
+	reporte.PrecioVentaPromedioClp = venta.PrecioPromedioPonderadoCLP
+	reporte.CantidadVendida = venta.CantidadTotalVendida
+	reporte.VentaNetaTotalClp = venta.TotalVentasCLP
+	reporte.UltimaFechaVenta = venta.UltimaFechaVenta
+	reporte.CantidadTransacciones = venta.CantidadDeVentas
+	reporte.VentasPorSucursal = venta.VentasPorSucursal
+}
 
-	// Definir el orden de las columnas según lo solicitado
-	columnas := []string{
-		"Codigo_Producto", "NOMBRE", "MARCA", "CATEGORIA", "DIMENSIONES", "PACKING",
-		"CIF PROMEDIO USD", "CANTIDAD VENDIDA", "CANTIDAD TRANSACCIONES", "% VENDIDO",
-		"PRECIO PRODUCTO CLP", "PRECIO OFERTA CLP", "PROMEDIO DEL PRECIO VENTA CLP",
-		"FECHA ULTIMO INGRESO", "ULTIMA FECHA VENTA", "CANTIDAD INGRESADA",
-		"FECHA PRIMER INGRESO", "CANTIDAD DE DIAS EN INVENTARIO", "VENTA NETA TOTAL CLP",
-		"UTILIDAD CLP", "RANKING POR CANTIDAD VENDIDA", "RANKING VENTA",
-	}
-
-	// Función para escribir una hoja de Excel con reportes
-	escribirHojaExcel := func(sheetName string, reportes []models.ReporteCombinado) {
-		// Escribir encabezados
-		for i, col := range columnas {
-			cell := fmt.Sprintf("%c1", 'A'+i)
-			f.SetCellValue(sheetName, cell, col)
-			f.SetCellStyle(sheetName, cell, cell, styleHeader)
-		}
+// formatearFecha formatea una fecha como YYYY-MM-DD, o vacío si no está definida
+func formatearFecha(fecha time.Time) string {
+	if fecha.IsZero() {
+		return ""
+	}
+	return fecha.Format("2006-01-02")
+}
 
-		// Escribir datos
-		for i, reporte := range reportes {
-			row := i + 2 // La fila 1 es para encabezados
-
-			// Mapa para evitar repetición
-			data := map[string]interface{}{
-				"A": reporte.CodigoProducto,
-				"B": reporte.Nombre,
-				"C": reporte.Marca,
-				"D": reporte.Categoria,
-				"E": reporte.Dimensiones,
-				"F": reporte.Packing,
-				"G": reporte.CifPromedioUsd,
-				"H": reporte.CantidadVendida,
-				"I": reporte.CantidadTransacciones,
-				"J": reporte.PorcentajeVendido,
-				"K": reporte.PrecioProductoClp,
-				"L": reporte.PrecioOfertaClp,
-				"M": reporte.PrecioVentaPromedioClp,
-				"N": reporte.FechaUltimoIngreso,
-				"O": reporte.UltimaFechaVenta,
-				"P": reporte.CantidadIngresada,
-				"Q": reporte.FechaPrimerIngreso,
-				"R": reporte.DiasEnInventario,
-				"S": reporte.VentaNetaTotalClp,
-				"T": reporte.UtilidadClp,
-				"U": reporte.RankingCantidad,
-				"V": reporte.RankingVenta,
-			}
+// columnaReporte define una columna de la exportación del reporte combinado
+type columnaReporte struct {
+	ColumnaExcel
+	valor func(r models.ReporteCombinado) interface{}
+}
+
+// columnasReporteCombinado define el orden de las columnas según lo solicitado
+var columnasReporteCombinado = []columnaReporte{
+	{ColumnaExcel{Titulo: "Codigo_Producto"}, func(r models.ReporteCombinado) interface{} { return r.CodigoProducto }},
+	{ColumnaExcel{Titulo: "NOMBRE", Ancho: 40}, func(r models.ReporteCombinado) interface{} { return r.Nombre }},
+	{ColumnaExcel{Titulo: "MARCA", Ancho: 20}, func(r models.ReporteCombinado) interface{} { return r.Marca }},
+	{ColumnaExcel{Titulo: "CATEGORIA", Ancho: 20}, func(r models.ReporteCombinado) interface{} { return r.Categoria }},
+	{ColumnaExcel{Titulo: "DIMENSIONES"}, func(r models.ReporteCombinado) interface{} { return r.Dimensiones }},
+	{ColumnaExcel{Titulo: "PACKING", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.Packing }},
+	{ColumnaExcel{Titulo: "CIF PROMEDIO USD", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.CifPromedioUsd }},
+	{ColumnaExcel{Titulo: "CANTIDAD VENDIDA", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.CantidadVendida }},
+	{ColumnaExcel{Titulo: "CANTIDAD TRANSACCIONES"}, func(r models.ReporteCombinado) interface{} { return r.CantidadTransacciones }},
+	{ColumnaExcel{Titulo: "% VENDIDO", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.PorcentajeVendido }},
+	{ColumnaExcel{Titulo: "PRECIO PRODUCTO CLP"}, func(r models.ReporteCombinado) interface{} { return r.PrecioProductoClp }},
+	{ColumnaExcel{Titulo: "PRECIO OFERTA CLP"}, func(r models.ReporteCombinado) interface{} { return r.PrecioOfertaClp }},
+	{ColumnaExcel{Titulo: "PROMEDIO DEL PRECIO VENTA CLP"}, func(r models.ReporteCombinado) interface{} { return r.PrecioVentaPromedioClp }},
+	{ColumnaExcel{Titulo: "FECHA ULTIMO INGRESO"}, func(r models.ReporteCombinado) interface{} { return r.FechaUltimoIngreso }},
+	{ColumnaExcel{Titulo: "ULTIMA FECHA VENTA"}, func(r models.ReporteCombinado) interface{} { return r.UltimaFechaVenta }},
+	{ColumnaExcel{Titulo: "CANTIDAD INGRESADA", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.CantidadIngresada }},
+	{ColumnaExcel{Titulo: "FECHA PRIMER INGRESO"}, func(r models.ReporteCombinado) interface{} { return r.FechaPrimerIngreso }},
+	{ColumnaExcel{Titulo: "CANTIDAD DE DIAS EN INVENTARIO"}, func(r models.ReporteCombinado) interface{} { return r.DiasEnInventario }},
+	{ColumnaExcel{Titulo: "VENTA NETA TOTAL CLP"}, func(r models.ReporteCombinado) interface{} { return r.VentaNetaTotalClp }},
+	{ColumnaExcel{Titulo: "UTILIDAD CLP", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.UtilidadClp }},
+	{ColumnaExcel{Titulo: "RANKING POR CANTIDAD VENDIDA"}, func(r models.ReporteCombinado) interface{} { return r.RankingCantidad }},
+	{ColumnaExcel{Titulo: "RANKING VENTA"}, func(r models.ReporteCombinado) interface{} { return r.RankingVenta }},
+	{ColumnaExcel{Titulo: "CODIGO VENTAS"}, func(r models.ReporteCombinado) interface{} { return r.CodigoVentas }},
+	{ColumnaExcel{Titulo: "METODO COINCIDENCIA"}, func(r models.ReporteCombinado) interface{} { return r.MetodoCoincidencia }},
+	{ColumnaExcel{Titulo: "CONFIANZA COINCIDENCIA", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.ConfianzaCoincidencia }},
+	{ColumnaExcel{Titulo: "SALDO PERIODO", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.SaldoPeriodo }},
+	{ColumnaExcel{Titulo: "DIAS PERIODO"}, func(r models.ReporteCombinado) interface{} { return r.DiasPeriodo }},
+	{ColumnaExcel{Titulo: "VELOCIDAD VENTA DIARIA", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.VelocidadVentaDiaria }},
+	{ColumnaExcel{Titulo: "DIAS DE STOCK", Decimal: true}, func(r models.ReporteCombinado) interface{} {
+		if r.DiasDeStock == nil {
+			return nil
+		}
+		return *r.DiasDeStock
+	}},
+	{ColumnaExcel{Titulo: "ROTACION INVENTARIO", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.RotacionInventario }},
+	{ColumnaExcel{Titulo: "GMROI", Decimal: true}, func(r models.ReporteCombinado) interface{} { return r.Gmroi }},
+	{ColumnaExcel{Titulo: "CLASE ABC"}, func(r models.ReporteCombinado) interface{} { return r.ClaseABC }},
+}
 
-			// Establecer valores y aplicar estilos
-			for col, val := range data {
-				cell := fmt.Sprintf("%s%d", col, row)
-				f.SetCellValue(sheetName, cell, val)
+// columnasPronostico son las columnas del pronóstico de demanda en el reporte combinado
+var columnasPronostico = []ColumnaExcel{
+	{Titulo: "METODO PRONOSTICO"},
+	{Titulo: "MESES PRONOSTICO"},
+	{Titulo: "DEMANDA PRONOSTICADA", Decimal: true},
+	{Titulo: "DEMANDA INFERIOR", Decimal: true},
+	{Titulo: "DEMANDA SUPERIOR", Decimal: true},
+	{Titulo: "COBERTURA MESES", Decimal: true},
+	{Titulo: "STOCK FALTANTE", Decimal: true},
+}
 
-				// Aplicar estilo numérico a columnas apropiadas
-				if col == "F" || col == "G" || col == "H" || col == "J" || col == "P" || col == "T" {
-					f.SetCellStyle(sheetName, cell, cell, styleNumber)
-				}
-			}
+// valoresPronostico devuelve los valores de las columnas del pronóstico
+func valoresPronostico(p *models.ResumenPronostico) []interface{} {
+	var cobertura interface{}
+	if p.CoberturaMeses != nil {
+		cobertura = *p.CoberturaMeses
+	}
+	return []interface{}{p.Metodo, p.Horizonte, p.DemandaPronosticada, p.Inferior, p.Superior, cobertura, p.StockFaltante}
+}
+
+// hojaSellThroughLotes genera una hoja con el sell-through de cada lote de ingreso
+func hojaSellThroughLotes(reportes []models.ReporteCombinado) HojaExcel {
+	hoja := HojaExcel{
+		Nombre: "Sell-Through por Lote",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Codigo_Producto"},
+			{Titulo: "NOMBRE", Ancho: 40},
+			{Titulo: "ZETA"},
+			{Titulo: "FECHA INGRESO"},
+			{Titulo: "UNIDADES INGRESADAS", Decimal: true},
+			{Titulo: "UNIDADES VENDIDAS", Decimal: true},
+			{Titulo: "% VENDIDO", Decimal: true},
+		},
+	}
+	for _, r := range reportes {
+		for _, lote := range r.SellThroughLotes {
+			hoja.Filas = append(hoja.Filas, []interface{}{
+				r.CodigoProducto,
+				r.Nombre,
+				lote.Zeta,
+				lote.FechaIngreso,
+				lote.UnidadesIngresadas,
+				lote.UnidadesVendidas,
+				lote.PorcentajeVendido,
+			})
 		}
+	}
+	return hoja
+}
 
-		// Auto-ajustar columnas para mejor legibilidad
-		for i := range columnas {
-			// Columnas que necesitan más espacio
-			width := 15.0 // Ancho base
-			colName := string(rune('A' + i))
-			if colName == "B" { // Nombre del producto suele ser largo
-				width = 40.0
-			} else if colName == "C" || colName == "D" { // Marca, Categoría
-				width = 20.0
+// hojaReporteCombinado convierte filas del reporte combinado en una hoja de Excel.
+// Si las filas traen desglose por sucursal se agregan columnas de cantidad y venta por sucursal,
+// y si traen desglose por año de producción, columnas de cantidad ingresada por año.
+func hojaReporteCombinado(nombre string, reportes []models.ReporteCombinado) HojaExcel {
+	desgloses := make([][]models.VentaSucursal, len(reportes))
+	for i, r := range reportes {
+		desgloses[i] = r.VentasPorSucursal
+	}
+	sucursales := SucursalesConVentas(desgloses...)
+	ingresosPorAnio := make([][]models.UnidadesAnio, len(reportes))
+	for i, r := range reportes {
+		ingresosPorAnio[i] = r.IngresadaPorAnio
+	}
+	anios := AniosDesglose(ingresosPorAnio...)
+
+	hoja := HojaExcel{Nombre: nombre}
+	for _, col := range columnasReporteCombinado {
+		hoja.Columnas = append(hoja.Columnas, col.ColumnaExcel)
+	}
+	for _, anio := range anios {
+		hoja.Columnas = append(hoja.Columnas, ColumnaExcel{Titulo: "CANTIDAD INGRESADA " + anio, Decimal: true})
+	}
+	for _, suc := range sucursales {
+		hoja.Columnas = append(hoja.Columnas,
+			ColumnaExcel{Titulo: fmt.Sprintf("CANTIDAD VENDIDA SUC %d", suc), Decimal: true},
+			ColumnaExcel{Titulo: fmt.Sprintf("VENTA NETA CLP SUC %d", suc)},
+		)
+	}
+	conPronostico := len(reportes) > 0 && reportes[0].Pronostico != nil
+	if conPronostico {
+		hoja.Columnas = append(hoja.Columnas, columnasPronostico...)
+	}
+	conStock := len(reportes) > 0 && reportes[0].StockAFecha != nil
+	if conStock {
+		hoja.Columnas = append(hoja.Columnas,
+			ColumnaExcel{Titulo: "STOCK A FECHA", Decimal: true},
+			ColumnaExcel{Titulo: "STOCK NEGATIVO"},
+		)
+	}
+	conMargen := len(reportes) > 0 && reportes[0].MargenMoneda != nil
+	if conMargen {
+		hoja.Columnas = append(hoja.Columnas, columnasMargenMoneda...)
+	}
+
+	for _, reporte := range reportes {
+		fila := make([]interface{}, len(columnasReporteCombinado), len(hoja.Columnas))
+		for i, col := range columnasReporteCombinado {
+			fila[i] = col.valor(reporte)
+		}
+		for _, anio := range anios {
+			fila = append(fila, unidadesEnAnio(reporte.IngresadaPorAnio, anio))
+		}
+		for _, suc := range sucursales {
+			venta, _ := ventaEnSucursal(reporte.VentasPorSucursal, suc)
+			fila = append(fila, venta.CantidadTotalVendida, venta.TotalVentasCLP)
+		}
+		if conPronostico {
+			fila = append(fila, valoresPronostico(reporte.Pronostico)...)
+		}
+		if conStock {
+			negativo := "NO"
+			if reporte.StockNegativo {
+				negativo = "SI"
 			}
-			f.SetColWidth(sheetName, colName, colName, width)
+			fila = append(fila, *reporte.StockAFecha, negativo)
+		}
+		if conMargen {
+			fila = append(fila, valoresMargenMoneda(reporte.MargenMoneda)...)
 		}
+		hoja.Filas = append(hoja.Filas, fila)
 	}
+	return hoja
+}
 
-	// Escribir datos en la primera hoja
-	escribirHojaExcel(sheetNameCoincidentes, reportesCoincidentes)
+// ExportarReporteCombinado exporta a Excel el reporte combinado, con las filas que cumplen el
+// filtro de productos. El resumen ABC se calcula con el reporte completo, igual que CLASE_ABC,
+// para que sus porcentajes correspondan a las clases mostradas.
+func (s *ReporteService) ExportarReporteCombinado(filtro models.ReporteFiltro, productos models.FiltroProductos) ([]byte, string, error) {
+	// 1. Generar el reporte combinado y resumir las clases ABC antes de filtrar
+	reportesCoincidentes, reportesSinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, "", err
+	}
+	resumenABC := s.resumenABC(reportesCoincidentes, reportesSinCoincidencia)
+	if productos.Activo() {
+		reportesCoincidentes = filtrarReportes(reportesCoincidentes, productos.Incluye)
+		reportesSinCoincidencia = filtrarReportes(reportesSinCoincidencia, productos.Incluye)
+	}
 
-	// 5. Crear segunda hoja para productos sin coincidencia
+	// 2. Primera hoja para reportes coincidentes y segunda para productos sin coincidencia
+	hojas := []HojaExcel{hojaReporteCombinado("Productos Coincidentes", reportesCoincidentes)}
 	if len(reportesSinCoincidencia) > 0 {
-		sheetNameSinCoincidencia := "Productos Sin Coincidencia"
-		_, err := f.NewSheet(sheetNameSinCoincidencia)
-		if err != nil {
-			return nil, "", err
-		}
-		escribirHojaExcel(sheetNameSinCoincidencia, reportesSinCoincidencia)
+		hojas = append(hojas, hojaReporteCombinado("Productos Sin Coincidencia", reportesSinCoincidencia))
 	}
+	hojas = append(hojas, hojaSellThroughLotes(reportesCoincidentes))
 
-	// 6. Eliminar la hoja predeterminada "Sheet1"
-	f.DeleteSheet("Sheet1")
+	hojaLotes, err := s.hojaLotes(filtro, reportesCoincidentes)
+	if err != nil {
+		return nil, "", err
+	}
+	hojas = append(hojas, hojaLotes)
+	hojas = append(hojas, hojaResumenABC(resumenABC, s.parametrosABC))
 
-	// 7. Guardar el archivo Excel en un buffer
-	var buffer bytes.Buffer
-	if err := f.Write(&buffer); err != nil {
+	// 3. Generar el archivo Excel
+	excelBytes, err := s.excelService.GenerarLibro(hojas...)
+	if err != nil {
 		return nil, "", err
 	}
 
-	// 8. Crear nombre de archivo descriptivo
-	filename := fmt.Sprintf("Reporte_Combinado_%s_%s.xlsx",
+	// 4. Crear nombre de archivo descriptivo
+	sufijo := ""
+	if len(filtro.Codigos) > 0 {
+		sufijo += fmt.Sprintf("_Lista_%d_Codigos", len(filtro.Codigos))
+	}
+	if productos.Activo() {
+		sufijo += "_Filtrado"
+	}
+	filename := fmt.Sprintf("Reporte_Combinado_%s_%s%s.xlsx",
 		filtro.FechaInicio,
-		filtro.FechaFin)
+		filtro.FechaFin,
+		sufijo)
 
-	return buffer.Bytes(), filename, nil
+	return excelBytes, filename, nil
 }
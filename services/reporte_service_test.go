@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// describirFilas resume código, ranking de venta y clase ABC de cada fila
+func describirFilas(reportes []models.ReporteCombinado) string {
+	var partes []string
+	for _, r := range reportes {
+		partes = append(partes, fmt.Sprintf("%s #%d %s", r.CodigoVentas, r.RankingVenta, r.ClaseABC))
+	}
+	return strings.Join(partes, ", ")
+}
+
+func TestGenerarReporteCombinadoFiltrado(t *testing.T) {
+	muro := ingresoPrueba("MURO", "Z1", "2024-12-01", 50, 200)
+	muro.Marca = "OTRA"
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("PISO", "Z1", "2024-12-01", 100, 400),
+		muro,
+	}
+	ventas := []datasource.LineaVentaFixture{
+		ventaPrueba("2025-01-05", "PISO", 10, 1000),
+		ventaPrueba("2025-01-06", "MURO", 90, 1000),
+		ventaPrueba("2025-01-07", "SOLO-VENTA", 2, 1000),
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+	filtro := models.ReporteFiltro{Anio: 2025, FechaInicio: "2025-01-01", FechaFin: "2025-01-31"}
+	ventaMinima := 20000
+
+	casos := []struct {
+		nombre                        string
+		productos                     models.FiltroProductos
+		coincidentes, sinCoincidencia string
+	}{
+		// El ranking de venta solo ordena las filas coincidentes
+		{"sin filtro", models.FiltroProductos{}, "MURO #1 A, PISO #2 B", "SOLO-VENTA #0 C"},
+		// El ranking y la clase ABC son los del reporte completo
+		{"marca", models.FiltroProductos{Marcas: []string{"marca"}}, "PISO #2 B", ""},
+		{"venta mínima", models.FiltroProductos{VentaMinimaClp: &ventaMinima}, "MURO #1 A", ""},
+		{"solo sin coincidencia", models.FiltroProductos{SoloSinCoincidencia: true}, "", "SOLO-VENTA #0 C"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			coincidentes, sinCoincidencia, err := s.GenerarReporteCombinadoFiltrado(filtro, c.productos)
+			if err != nil {
+				t.Fatalf("GenerarReporteCombinadoFiltrado: %v", err)
+			}
+			if obtenido := describirFilas(coincidentes); obtenido != c.coincidentes {
+				t.Errorf("coincidentes = %q, se esperaba %q", obtenido, c.coincidentes)
+			}
+			if obtenido := describirFilas(sinCoincidencia); obtenido != c.sinCoincidencia {
+				t.Errorf("sin coincidencia = %q, se esperaba %q", obtenido, c.sinCoincidencia)
+			}
+		})
+	}
+}
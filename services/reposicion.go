@@ -0,0 +1,343 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// sinMarca agrupa los productos sin marca en la sugerencia de compra
+const sinMarca = "SIN MARCA"
+
+// cuantilNormal devuelve el cuantil de la normal estándar para la probabilidad p (0 < p < 1),
+// con la aproximación racional de Acklam (error relativo menor a 1.2e-9)
+func cuantilNormal(p float64) float64 {
+	a := []float64{-3.969683028665376e+01, 2.209460984245205e+02, -2.759285104469687e+02,
+		1.383577518672690e+02, -3.066479806614716e+01, 2.506628277459239e+00}
+	b := []float64{-5.447609879822406e+01, 1.615858368580409e+02, -1.556989798598866e+02,
+		6.680131188771972e+01, -1.328068155288572e+01}
+	c := []float64{-7.784894002430293e-03, -3.223964580411365e-01, -2.400758277161838e+00,
+		-2.549732539343734e+00, 4.374664141464968e+00, 2.938163982698783e+00}
+	d := []float64{7.784695709041462e-03, 3.224671290700398e-01, 2.445134137142996e+00,
+		3.754408661907416e+00}
+
+	const bajo = 0.02425
+	switch {
+	case p < bajo:
+		q := math.Sqrt(-2 * math.Log(p))
+		return (((((c[0]*q+c[1])*q+c[2])*q+c[3])*q+c[4])*q + c[5]) /
+			((((d[0]*q+d[1])*q+d[2])*q+d[3])*q + 1)
+	case p > 1-bajo:
+		q := math.Sqrt(-2 * math.Log(1-p))
+		return -(((((c[0]*q+c[1])*q+c[2])*q+c[3])*q+c[4])*q + c[5]) /
+			((((d[0]*q+d[1])*q+d[2])*q+d[3])*q + 1)
+	default:
+		q := p - 0.5
+		r := q * q
+		return (((((a[0]*r+a[1])*r+a[2])*r+a[3])*r+a[4])*r + a[5]) * q /
+			(((((b[0]*r+b[1])*r+b[2])*r+b[3])*r+b[4])*r + 1)
+	}
+}
+
+// desviacionesDiarias estima, por código de ventas, la desviación estándar de la demanda diaria
+// a partir de la demanda mensual: σ diaria = σ mensual / √(días promedio por mes)
+func desviacionesDiarias(serie *models.SerieVentas, dias int) map[string]float64 {
+	desviaciones := make(map[string]float64, len(serie.Productos))
+	if len(serie.Periodos) == 0 {
+		return desviaciones
+	}
+	diasPorMes := float64(dias) / float64(len(serie.Periodos))
+
+	for _, producto := range serie.Productos {
+		demanda := make([]float64, len(producto.Puntos))
+		for i, punto := range producto.Puntos {
+			demanda[i] = punto.Cantidad
+		}
+		_, desviacion := promedioYDesviacion(demanda)
+		desviaciones[producto.CodigoProducto] = desviacion / math.Sqrt(diasPorMes)
+	}
+	return desviaciones
+}
+
+// redondearPacking redondea hacia arriba la cantidad a cajas completas; sin packing, a unidades
+func redondearPacking(cantidad, packing float64) (float64, float64) {
+	if cantidad <= 0 {
+		return 0, 0
+	}
+	if packing <= 0 {
+		return math.Ceil(cantidad), 0
+	}
+	cajas := math.Ceil(cantidad / packing)
+	return cajas * packing, cajas
+}
+
+// calcularReposicion calcula stock de seguridad, punto de reorden y pedido sugerido:
+//
+//	stock de seguridad = z(nivel de servicio) × σ diaria × √lead time
+//	punto de reorden   = demanda diaria × lead time + stock de seguridad
+//	pedido sugerido    = punto de reorden + demanda diaria × días de cobertura - stock actual
+//
+// El stock actual es el stock a la fecha de corte (StockAFecha), no el saldo del período.
+// El pedido solo se sugiere si el stock actual está en o bajo el punto de reorden.
+func calcularReposicion(r models.ReporteCombinado, politica models.PoliticaReposicion, desviacion float64, diasCobertura int) models.ProductoReposicion {
+	demanda := r.VelocidadVentaDiaria
+	stockActual := 0.0
+	if r.StockAFecha != nil {
+		stockActual = *r.StockAFecha
+	}
+	seguridad := cuantilNormal(politica.NivelServicio/100) * desviacion * math.Sqrt(float64(politica.LeadTimeDias))
+	reorden := demanda*float64(politica.LeadTimeDias) + seguridad
+
+	producto := models.ProductoReposicion{
+		CodigoProducto:   r.CodigoProducto,
+		CodigoVentas:     r.CodigoVentas,
+		Nombre:           r.Nombre,
+		Marca:            r.Marca,
+		Categoria:        r.Categoria,
+		Dimensiones:      r.Dimensiones,
+		Packing:          r.Packing,
+		StockActual:      stockActual,
+		DemandaDiaria:    redondear(demanda, 4),
+		DesviacionDiaria: redondear(desviacion, 4),
+		NivelServicio:    politica.NivelServicio,
+		LeadTimeDias:     politica.LeadTimeDias,
+		StockSeguridad:   redondear(seguridad, 2),
+		PuntoReorden:     redondear(reorden, 2),
+		CostoUnitarioUsd: r.CifPromedioUsd,
+	}
+
+	stock := math.Max(stockActual, 0)
+	if demanda > 0 {
+		diasDeStock := redondear(stock/demanda, 2)
+		producto.DiasDeStock = &diasDeStock
+	}
+	if stock <= reorden {
+		producto.RequiereReposicion = true
+		faltante := reorden + demanda*float64(diasCobertura) - stock
+		producto.CantidadSugerida, producto.Cajas = redondearPacking(faltante, r.Packing)
+		producto.CostoPedidoUsd = producto.CantidadSugerida * r.CifPromedioUsd
+		producto.CostoPedidoClp = producto.CantidadSugerida * r.CifPromedioClp
+	}
+	return producto
+}
+
+// marcaReposicion devuelve la marca con que se agrupa un producto
+func marcaReposicion(marca string) string {
+	if marca = strings.TrimSpace(marca); marca == "" {
+		return sinMarca
+	}
+	return marca
+}
+
+// GenerarReporteReposicion calcula, para cada producto con inventario y ventas en el período,
+// el stock de seguridad, el punto de reorden y la cantidad a pedir redondeada al packing.
+// La demanda diaria es la velocidad de venta del período y su variabilidad se estima con la
+// demanda mensual. El nivel de servicio y el lead time se resuelven por marca, categoría o
+// valor por defecto, salvo que la consulta los indique. El stock actual es el stock disponible
+// a la fecha de fin (o hoy, si es anterior), igual que /api/stock.
+func (s *ReporteService) GenerarReporteReposicion(filtro models.ReporteFiltro, params models.ParametrosReposicion) (*models.ReporteReposicion, error) {
+	if err := params.Validar(); err != nil {
+		return nil, err
+	}
+	if params.DiasCobertura == 0 {
+		params.DiasCobertura = s.reposicion.DiasCobertura
+	}
+
+	if filtro.StockAFecha == "" {
+		filtro.StockAFecha = fechaReferencia(filtro.FechaFin).Format("2006-01-02")
+	}
+	coincidentes, _, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	serie, err := s.ventasService.GetSerieVentas(filtro.FiltroVentas(), models.GranularidadMes)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la demanda mensual: %v", err)
+	}
+
+	reporte := &models.ReporteReposicion{
+		Parametros: params,
+		Productos:  []models.ProductoReposicion{},
+	}
+	if len(coincidentes) > 0 {
+		reporte.DiasPeriodo = coincidentes[0].DiasPeriodo
+	}
+	desviaciones := desviacionesDiarias(serie, reporte.DiasPeriodo)
+
+	for _, r := range coincidentes {
+		if r.VelocidadVentaDiaria <= 0 {
+			continue
+		}
+		if params.Marca != "" && !strings.EqualFold(strings.TrimSpace(r.Marca), strings.TrimSpace(params.Marca)) {
+			continue
+		}
+
+		politica := s.reposicion.Politica(r.Marca, r.Categoria)
+		if params.NivelServicio != 0 {
+			politica.NivelServicio = params.NivelServicio
+		}
+		if params.LeadTimeDias != 0 {
+			politica.LeadTimeDias = params.LeadTimeDias
+		}
+
+		reporte.Productos = append(reporte.Productos, calcularReposicion(r, politica, desviaciones[r.CodigoVentas], params.DiasCobertura))
+	}
+
+	// Agrupar por marca; dentro de cada marca, mayor costo de pedido primero
+	sort.SliceStable(reporte.Productos, func(i, j int) bool {
+		mi, mj := marcaReposicion(reporte.Productos[i].Marca), marcaReposicion(reporte.Productos[j].Marca)
+		if mi != mj {
+			return mi < mj
+		}
+		return reporte.Productos[i].CostoPedidoUsd > reporte.Productos[j].CostoPedidoUsd
+	})
+
+	reporte.ResumenMarca, reporte.Total = resumirReposicion(reporte.Productos)
+	return reporte, nil
+}
+
+// resumirReposicion suma el pedido sugerido de cada marca (productos ordenados por marca)
+func resumirReposicion(productos []models.ProductoReposicion) ([]models.ResumenReposicionMarca, models.ResumenReposicionMarca) {
+	resumen := []models.ResumenReposicionMarca{}
+	total := models.ResumenReposicionMarca{Marca: "Total"}
+	for _, p := range productos {
+		if p.CantidadSugerida <= 0 {
+			continue
+		}
+		marca := marcaReposicion(p.Marca)
+		if len(resumen) == 0 || resumen[len(resumen)-1].Marca != marca {
+			resumen = append(resumen, models.ResumenReposicionMarca{Marca: marca})
+		}
+		for _, r := range []*models.ResumenReposicionMarca{&resumen[len(resumen)-1], &total} {
+			r.Productos++
+			r.Unidades += p.CantidadSugerida
+			r.Cajas += p.Cajas
+			r.CostoPedidoUsd += p.CostoPedidoUsd
+			r.CostoPedidoClp += p.CostoPedidoClp
+		}
+	}
+	return resumen, total
+}
+
+// ExportarReporteReposicion exporta la sugerencia de compra agrupada por marca, con un
+// subtotal por marca, y el detalle del cálculo de reposición
+func (s *ReporteService) ExportarReporteReposicion(filtro models.ReporteFiltro, params models.ParametrosReposicion) ([]byte, string, error) {
+	reporte, err := s.GenerarReporteReposicion(filtro, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Hoja para proveedores: solo los productos a pedir, agrupados por marca
+	sugerencia := HojaExcel{
+		Nombre: "Sugerencia de Compra",
+		Columnas: []ColumnaExcel{
+			{Titulo: "MARCA", Ancho: 20},
+			{Titulo: "Codigo_Producto"},
+			{Titulo: "NOMBRE", Ancho: 40},
+			{Titulo: "DIMENSIONES", Ancho: 20},
+			{Titulo: "PACKING", Decimal: true},
+			{Titulo: "CAJAS", Decimal: true},
+			{Titulo: "UNIDADES", Decimal: true},
+			{Titulo: "COSTO UNITARIO USD", Decimal: true},
+			{Titulo: "COSTO TOTAL USD", Ancho: 18, Decimal: true},
+			{Titulo: "COSTO TOTAL CLP", Ancho: 18, Decimal: true},
+		},
+	}
+	subtotal := func(r models.ResumenReposicionMarca, titulo string) {
+		sugerencia.FilasResaltadas = append(sugerencia.FilasResaltadas, len(sugerencia.Filas))
+		sugerencia.Filas = append(sugerencia.Filas, []interface{}{
+			titulo, nil, fmt.Sprintf("%d productos", r.Productos), nil, nil,
+			r.Cajas, r.Unidades, nil, r.CostoPedidoUsd, r.CostoPedidoClp,
+		})
+	}
+	for _, marca := range reporte.ResumenMarca {
+		for _, p := range reporte.Productos {
+			if p.CantidadSugerida <= 0 || marcaReposicion(p.Marca) != marca.Marca {
+				continue
+			}
+			sugerencia.Filas = append(sugerencia.Filas, []interface{}{
+				marca.Marca,
+				p.CodigoProducto,
+				p.Nombre,
+				p.Dimensiones,
+				p.Packing,
+				p.Cajas,
+				p.CantidadSugerida,
+				p.CostoUnitarioUsd,
+				p.CostoPedidoUsd,
+				p.CostoPedidoClp,
+			})
+		}
+		subtotal(marca, "TOTAL "+marca.Marca)
+	}
+	subtotal(reporte.Total, "TOTAL GENERAL")
+
+	// Hoja de cálculo: todos los productos con demanda en el período
+	calculo := HojaExcel{
+		Nombre: "Cálculo Reposición",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Codigo_Producto"},
+			{Titulo: "CODIGO VENTAS"},
+			{Titulo: "NOMBRE", Ancho: 40},
+			{Titulo: "MARCA", Ancho: 20},
+			{Titulo: "CATEGORIA", Ancho: 20},
+			{Titulo: "PACKING", Decimal: true},
+			{Titulo: "STOCK ACTUAL", Decimal: true},
+			{Titulo: "DEMANDA DIARIA", Decimal: true},
+			{Titulo: "DESVIACION DIARIA", Decimal: true},
+			{Titulo: "NIVEL SERVICIO %", Decimal: true},
+			{Titulo: "LEAD TIME DIAS"},
+			{Titulo: "STOCK SEGURIDAD", Decimal: true},
+			{Titulo: "PUNTO REORDEN", Decimal: true},
+			{Titulo: "DIAS DE STOCK", Decimal: true},
+			{Titulo: "REQUIERE REPOSICION"},
+			{Titulo: "CANTIDAD SUGERIDA", Decimal: true},
+			{Titulo: "CAJAS", Decimal: true},
+		},
+	}
+	for _, p := range reporte.Productos {
+		var diasDeStock interface{}
+		if p.DiasDeStock != nil {
+			diasDeStock = *p.DiasDeStock
+		}
+		requiere := "NO"
+		if p.RequiereReposicion {
+			requiere = "SI"
+		}
+		calculo.Filas = append(calculo.Filas, []interface{}{
+			p.CodigoProducto,
+			p.CodigoVentas,
+			p.Nombre,
+			p.Marca,
+			p.Categoria,
+			p.Packing,
+			p.StockActual,
+			p.DemandaDiaria,
+			p.DesviacionDiaria,
+			p.NivelServicio,
+			p.LeadTimeDias,
+			p.StockSeguridad,
+			p.PuntoReorden,
+			diasDeStock,
+			requiere,
+			p.CantidadSugerida,
+			p.Cajas,
+		})
+	}
+
+	excelBytes, err := s.excelService.GenerarLibro(sugerencia, calculo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nombre := "Sugerencia_Compra"
+	if params.Marca != "" {
+		nombre += "_" + strings.ReplaceAll(strings.TrimSpace(params.Marca), " ", "_")
+	}
+	filename := fmt.Sprintf("%s_%s_al_%s.xlsx", nombre, filtro.FechaInicio, filtro.FechaFin)
+	return excelBytes, filename, nil
+}
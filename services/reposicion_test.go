@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+func TestGenerarReporteReposicionStockAFecha(t *testing.T) {
+	// VIEJO ingresó en junio y vendió 85 unidades antes del período: su saldo del período es 90,
+	// pero a la fecha de fin solo quedan 5
+	ventas := []datasource.LineaVentaFixture{
+		ventaPrueba("2024-07-15", "VIEJO", 40, 2000),
+		ventaPrueba("2024-10-15", "VIEJO", 45, 2000),
+		ventaPrueba("2025-01-03", "VIEJO", 5, 2000),
+		ventaPrueba("2025-01-08", "VIEJO", 5, 2000),
+		ventaPrueba("2025-01-04", "NUEVO", 10, 2000),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("VIEJO", "Z1", "2024-06-01", 100, 1000),
+		ingresoPrueba("NUEVO", "Z1", "2025-01-02", 100, 1000),
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+
+	params := models.ParametrosReposicion{NivelServicio: 95, LeadTimeDias: 10, DiasCobertura: 20}
+	reporte, err := s.GenerarReporteReposicion(models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10"}, params)
+	if err != nil {
+		t.Fatalf("GenerarReporteReposicion: %v", err)
+	}
+	productos := make(map[string]models.ProductoReposicion)
+	for _, p := range reporte.Productos {
+		productos[p.CodigoProducto] = p
+	}
+
+	// Demanda de 1 unidad diaria y sin variación mensual: punto de reorden 10
+	casos := []struct {
+		codigo    string
+		stock     float64
+		diasStock float64
+		reponer   bool
+		sugerida  float64
+	}{
+		// 10 + 1 × 20 - 5
+		{codigo: "VIEJO", stock: 5, diasStock: 5, reponer: true, sugerida: 25},
+		{codigo: "NUEVO", stock: 90, diasStock: 90, reponer: false, sugerida: 0},
+	}
+	for _, c := range casos {
+		t.Run(c.codigo, func(t *testing.T) {
+			p, ok := productos[c.codigo]
+			if !ok {
+				t.Fatalf("el producto no está en el reporte")
+			}
+			if p.PuntoReorden != 10 {
+				t.Errorf("PuntoReorden = %v, se esperaba 10", p.PuntoReorden)
+			}
+			if p.StockActual != c.stock || p.DiasDeStock == nil || *p.DiasDeStock != c.diasStock {
+				t.Errorf("stock = %v y días de stock = %v, se esperaba %v y %v", p.StockActual, p.DiasDeStock, c.stock, c.diasStock)
+			}
+			if p.RequiereReposicion != c.reponer || p.CantidadSugerida != c.sugerida {
+				t.Errorf("reposición = %v con %v unidades, se esperaba %v con %v", p.RequiereReposicion, p.CantidadSugerida, c.reponer, c.sugerida)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// diasPeriodo devuelve la cantidad de días del período de ventas, incluyendo ambos
+// extremos y sin contar días posteriores a hoy (aún no pueden tener ventas)
+func diasPeriodo(fechaInicio, fechaFin string, hoy time.Time) int {
+	inicio, err := time.Parse("2006-01-02", fechaInicio)
+	if err != nil {
+		return 1
+	}
+	fin, err := time.Parse("2006-01-02", fechaFin)
+	if err != nil {
+		return 1
+	}
+
+	hoy = time.Date(hoy.Year(), hoy.Month(), hoy.Day(), 0, 0, 0, 0, time.UTC)
+	if fin.After(hoy) {
+		fin = hoy
+	}
+
+	dias := int(fin.Sub(inicio).Hours()/24) + 1
+	if dias < 1 {
+		return 1
+	}
+	return dias
+}
+
+// calcularRotacion completa los indicadores de rotación de una fila del reporte:
+//
+//   - Saldo del período = unidades ingresadas - unidades vendidas en el período (mínimo 0)
+//   - Velocidad de venta diaria = unidades vendidas / días del período
+//   - Días de stock = saldo del período / velocidad diaria (sin valor si no hubo ventas)
+//   - Inventario promedio = (unidades ingresadas + saldo del período) / 2
+//   - Rotación de inventario = unidades vendidas / inventario promedio
+//   - GMROI = utilidad / (inventario promedio × costo promedio unitario CLP)
+//   - Sell-through por lote = ventas asignadas al lote en orden de ingreso / unidades del lote
+//
+// Las unidades ingresadas son todas las de los años de producción considerados, pero las
+// vendidas son solo las del período, así que el saldo no es el stock físico (ver /api/stock)
+// y los días de stock, la rotación y el GMROI cambian con el largo del período.
+func calcularRotacion(reporte *models.ReporteCombinado, dias int) {
+	reporte.DiasPeriodo = dias
+	reporte.SaldoPeriodo = math.Max(reporte.CantidadIngresada-reporte.CantidadVendida, 0)
+	reporte.VelocidadVentaDiaria = reporte.CantidadVendida / float64(dias)
+
+	if reporte.VelocidadVentaDiaria > 0 {
+		diasDeStock := reporte.SaldoPeriodo / reporte.VelocidadVentaDiaria
+		reporte.DiasDeStock = &diasDeStock
+	}
+
+	inventarioPromedio := (reporte.CantidadIngresada + reporte.SaldoPeriodo) / 2
+	if inventarioPromedio > 0 {
+		reporte.RotacionInventario = reporte.CantidadVendida / inventarioPromedio
+
+		if costoPromedio := inventarioPromedio * reporte.CifPromedioClp; costoPromedio > 0 {
+			reporte.Gmroi = reporte.UtilidadClp / costoPromedio
+		}
+	}
+
+	reporte.SellThroughLotes = sellThroughPorLote(reporte.Ingresos, reporte.CantidadVendida)
+}
+
+// sellThroughPorLote reparte las unidades vendidas entre los lotes, del más antiguo al más reciente
+func sellThroughPorLote(lotes []models.MetadatoProducto, vendidas float64) []models.SellThroughLote {
+	if len(lotes) == 0 {
+		return nil
+	}
+
+	resultado := make([]models.SellThroughLote, 0, len(lotes))
+	pendientes := vendidas
+	for _, lote := range lotes {
+		asignadas := math.Min(pendientes, lote.UnidadesIngresadas)
+		if asignadas < 0 {
+			asignadas = 0
+		}
+		pendientes -= asignadas
+
+		item := models.SellThroughLote{
+			Zeta:               lote.Zeta,
+			FechaIngreso:       formatearFecha(lote.FechaIngreso),
+			UnidadesIngresadas: lote.UnidadesIngresadas,
+			UnidadesVendidas:   asignadas,
+		}
+		if lote.UnidadesIngresadas > 0 {
+			item.PorcentajeVendido = asignadas / lote.UnidadesIngresadas * 100
+		}
+		resultado = append(resultado, item)
+	}
+	return resultado
+}
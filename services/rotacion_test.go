@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+func TestDiasPeriodo(t *testing.T) {
+	hoy := time.Date(2025, 6, 15, 10, 30, 0, 0, time.UTC)
+	casos := []struct {
+		inicio, fin string
+		esperado    int
+	}{
+		{"2025-01-01", "2025-01-31", 31},
+		{"2025-06-01", "2025-06-30", 15}, // Sin días futuros
+		{"2025-01-01", "2025-01-01", 1},
+		{"2025-02-01", "2025-01-01", 1},
+		{"2025-01-01", "fin", 1},
+	}
+	for _, c := range casos {
+		if dias := diasPeriodo(c.inicio, c.fin, hoy); dias != c.esperado {
+			t.Errorf("diasPeriodo(%s, %s) = %d, se esperaba %d", c.inicio, c.fin, dias, c.esperado)
+		}
+	}
+}
+
+func TestReporteCombinadoRotacion(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{
+		ventaPrueba("2025-01-03", "ROTA", 12, 2000),
+		ventaPrueba("2025-01-08", "ROTA", 8, 2000),
+		ventaPrueba("2025-01-05", "LOTES", 15, 1000),
+		ventaPrueba("2025-01-06", "SOLO-VENTA", 5, 500),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("ROTA", "Z1", "2024-12-01", 100, 1000),
+		ingresoPrueba("LOTES", "Z2", "2024-12-01", 10, 400),
+		ingresoPrueba("LOTES", "Z1", "2024-11-01", 10, 400),
+		ingresoPrueba("SIN-VENTA", "Z1", "2024-12-01", 10, 700),
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10"})
+	if err != nil {
+		t.Fatalf("GenerarReporteCombinado: %v", err)
+	}
+	filas := make(map[string]models.ReporteCombinado)
+	for _, r := range append(coincidentes, sinCoincidencia...) {
+		filas[r.CodigoProducto] = r
+	}
+
+	casos := []struct {
+		codigo      string
+		saldo       float64
+		velocidad   float64
+		diasDeStock float64 // -1 sin valor
+		rotacion    float64
+		gmroi       float64
+		lotes       string
+		abc         string
+	}{
+		// Saldo 80, inventario promedio 90, utilidad 40.000 - 20 × 1.000
+		{codigo: "ROTA", saldo: 80, velocidad: 2, diasDeStock: 40, rotacion: 20.0 / 90, gmroi: 20000.0 / 90000, lotes: "Z1 20/100", abc: "A"},
+		// Las ventas se asignan primero al lote más antiguo
+		{codigo: "LOTES", saldo: 5, velocidad: 1.5, diasDeStock: 5 / 1.5, rotacion: 15 / 12.5, gmroi: 9000.0 / 5000, lotes: "Z1 10/10, Z2 5/10", abc: "A"},
+		{codigo: "SIN-VENTA", saldo: 10, velocidad: 0, diasDeStock: -1, rotacion: 0, gmroi: 0, lotes: "Z1 0/10", abc: "C"},
+		// Sin inventario; los productos anteriores ya acumulan el 95,7 % de la venta
+		{codigo: "SOLO-VENTA", saldo: 0, velocidad: 0.5, diasDeStock: 0, rotacion: 0, gmroi: 0, abc: "C"},
+	}
+	for _, c := range casos {
+		t.Run(c.codigo, func(t *testing.T) {
+			r, ok := filas[c.codigo]
+			if !ok {
+				t.Fatalf("el producto no está en el reporte")
+			}
+			if r.DiasPeriodo != 10 {
+				t.Errorf("DiasPeriodo = %d, se esperaba 10", r.DiasPeriodo)
+			}
+			if !aproximado(r.SaldoPeriodo, c.saldo) || !aproximado(r.VelocidadVentaDiaria, c.velocidad) {
+				t.Errorf("saldo = %v y velocidad = %v, se esperaba %v y %v", r.SaldoPeriodo, r.VelocidadVentaDiaria, c.saldo, c.velocidad)
+			}
+			switch {
+			case c.diasDeStock < 0 && r.DiasDeStock != nil:
+				t.Errorf("DiasDeStock = %v, se esperaba sin valor", *r.DiasDeStock)
+			case c.diasDeStock >= 0 && (r.DiasDeStock == nil || !aproximado(*r.DiasDeStock, c.diasDeStock)):
+				t.Errorf("DiasDeStock = %v, se esperaba %v", r.DiasDeStock, c.diasDeStock)
+			}
+			if !aproximado(r.RotacionInventario, c.rotacion) || !aproximado(r.Gmroi, c.gmroi) {
+				t.Errorf("rotación = %v y GMROI = %v, se esperaba %v y %v", r.RotacionInventario, r.Gmroi, c.rotacion, c.gmroi)
+			}
+
+			var lotes []string
+			for _, l := range r.SellThroughLotes {
+				lotes = append(lotes, fmt.Sprintf("%s %v/%v", l.Zeta, l.UnidadesVendidas, l.UnidadesIngresadas))
+			}
+			if strings.Join(lotes, ", ") != c.lotes {
+				t.Errorf("sell-through = %q, se esperaba %q", strings.Join(lotes, ", "), c.lotes)
+			}
+			if r.ClaseABC != c.abc {
+				t.Errorf("ClaseABC = %q, se esperaba %q", r.ClaseABC, c.abc)
+			}
+		})
+	}
+}
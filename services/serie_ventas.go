@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ErrSerieNoValida indica que los parámetros de la serie de ventas no son válidos
+var ErrSerieNoValida = errors.New("serie de ventas no válida")
+
+// maxPeriodosSerie limita los períodos de una serie (las columnas del Excel)
+const maxPeriodosSerie = 1000
+
+// periodosSerie divide el rango en períodos consecutivos según la granularidad. El rango
+// termina hoy si la fecha de fin es posterior (los días futuros aún no tienen ventas).
+func periodosSerie(fechaInicio, fechaFin, granularidad string, hoy time.Time) ([]models.PeriodoSerie, error) {
+	inicio, err := time.Parse("2006-01-02", fechaInicio)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fecha de inicio no válida", ErrSerieNoValida)
+	}
+	fin, err := time.Parse("2006-01-02", fechaFin)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fecha de fin no válida", ErrSerieNoValida)
+	}
+	hoy = time.Date(hoy.Year(), hoy.Month(), hoy.Day(), 0, 0, 0, 0, time.UTC)
+	if fin.After(hoy) {
+		fin = hoy
+	}
+
+	var periodos []models.PeriodoSerie
+	for desde := inicio; !desde.After(fin); {
+		var hasta time.Time
+		var etiqueta string
+		switch granularidad {
+		case models.GranularidadDia:
+			hasta = desde
+			etiqueta = desde.Format("2006-01-02")
+		case models.GranularidadSemana:
+			// Semanas ISO, de lunes a domingo
+			lunes := desde.AddDate(0, 0, -((int(desde.Weekday()) + 6) % 7))
+			hasta = lunes.AddDate(0, 0, 6)
+			anio, semana := lunes.ISOWeek()
+			etiqueta = fmt.Sprintf("%d-W%02d", anio, semana)
+		default:
+			hasta = time.Date(desde.Year(), desde.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+			etiqueta = desde.Format("2006-01")
+		}
+		if hasta.After(fin) {
+			hasta = fin
+		}
+
+		periodos = append(periodos, models.PeriodoSerie{
+			Periodo:     etiqueta,
+			FechaInicio: desde.Format("2006-01-02"),
+			FechaFin:    hasta.Format("2006-01-02"),
+		})
+		if len(periodos) > maxPeriodosSerie {
+			return nil, fmt.Errorf("%w: el rango supera los %d períodos, use una granularidad mayor", ErrSerieNoValida, maxPeriodosSerie)
+		}
+		desde = hasta.AddDate(0, 0, 1)
+	}
+	return periodos, nil
+}
+
+// indicesPeriodos devuelve el índice del período de cada día, para ubicar cada venta
+func indicesPeriodos(periodos []models.PeriodoSerie) map[string]int {
+	indices := make(map[string]int)
+	for i, p := range periodos {
+		desde, _ := time.Parse("2006-01-02", p.FechaInicio)
+		hasta, _ := time.Parse("2006-01-02", p.FechaFin)
+		for d := desde; !d.After(hasta); d = d.AddDate(0, 0, 1) {
+			indices[d.Format("2006-01-02")] = i
+		}
+	}
+	return indices
+}
+
+// GetSerieVentas obtiene las unidades, la venta y las transacciones de cada producto por
+// período (día, semana o mes), con los períodos sin ventas en cero. La venta corresponde al
+// total de cada línea (las líneas de documentos nulos suman 0).
+func (s *VentasService) GetSerieVentas(filtro models.VentasFiltro, granularidad string) (*models.SerieVentas, error) {
+	periodos, err := periodosSerie(filtro.FechaInicio, filtro.FechaFin, granularidad, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	ventas, err := s.GetVentasDetalladas(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := indicesPeriodos(periodos)
+	series := make(map[string]*models.SerieProducto)
+	for _, venta := range ventas {
+		fecha, ok := parsearFechaVenta(venta.FechaEmision)
+		if !ok {
+			continue
+		}
+		i, ok := indices[fecha.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+
+		serie, ok := series[venta.CodigoProducto]
+		if !ok {
+			serie = &models.SerieProducto{
+				CodigoProducto: venta.CodigoProducto,
+				NombreProducto: venta.NombreProducto,
+				Puntos:         make([]models.PuntoSerie, len(periodos)),
+			}
+			for j, p := range periodos {
+				serie.Puntos[j].Periodo = p.Periodo
+			}
+			series[venta.CodigoProducto] = serie
+		}
+
+		serie.Puntos[i].Cantidad += venta.Cantidad
+		serie.Puntos[i].VentaClp += venta.TotalVentaCLP
+		serie.Puntos[i].Transacciones++
+		serie.Cantidad += venta.Cantidad
+		serie.VentaClp += venta.TotalVentaCLP
+		serie.Transacciones++
+	}
+
+	resultado := &models.SerieVentas{
+		FechaInicio:  filtro.FechaInicio,
+		FechaFin:     filtro.FechaFin,
+		Granularidad: granularidad,
+		Sucursales:   filtro.Sucursales,
+		Periodos:     periodos,
+		Productos:    make([]models.SerieProducto, 0, len(series)),
+	}
+	for _, serie := range series {
+		resultado.Productos = append(resultado.Productos, *serie)
+	}
+	sort.Slice(resultado.Productos, func(i, j int) bool {
+		return resultado.Productos[i].CodigoProducto < resultado.Productos[j].CodigoProducto
+	})
+
+	return resultado, nil
+}
+
+// ExportarSerieVentas exporta la serie de ventas a Excel, con los períodos como columnas
+// y una hoja por medida (unidades, venta y transacciones)
+func (s *VentasService) ExportarSerieVentas(filtro models.VentasFiltro, granularidad string) ([]byte, string, error) {
+	serie, err := s.GetSerieVentas(filtro, granularidad)
+	if err != nil {
+		return nil, "", err
+	}
+
+	medidas := []struct {
+		hoja    string
+		decimal bool
+		total   func(p models.SerieProducto) interface{}
+		valor   func(p models.PuntoSerie) interface{}
+	}{
+		{"Unidades", true,
+			func(p models.SerieProducto) interface{} { return p.Cantidad },
+			func(p models.PuntoSerie) interface{} { return p.Cantidad }},
+		{"Venta CLP", false,
+			func(p models.SerieProducto) interface{} { return p.VentaClp },
+			func(p models.PuntoSerie) interface{} { return p.VentaClp }},
+		{"Transacciones", false,
+			func(p models.SerieProducto) interface{} { return p.Transacciones },
+			func(p models.PuntoSerie) interface{} { return p.Transacciones }},
+	}
+
+	hojas := make([]HojaExcel, 0, len(medidas))
+	for _, medida := range medidas {
+		hoja := HojaExcel{
+			Nombre: medida.hoja,
+			Columnas: []ColumnaExcel{
+				{Titulo: "Código de Producto"},
+				{Titulo: "Nombre del Producto", Ancho: 40},
+			},
+		}
+		for _, p := range serie.Periodos {
+			hoja.Columnas = append(hoja.Columnas, ColumnaExcel{Titulo: p.Periodo, Ancho: 12, Decimal: medida.decimal})
+		}
+		hoja.Columnas = append(hoja.Columnas, ColumnaExcel{Titulo: "Total", Decimal: medida.decimal})
+
+		for _, producto := range serie.Productos {
+			fila := make([]interface{}, 0, len(hoja.Columnas))
+			fila = append(fila, producto.CodigoProducto, producto.NombreProducto)
+			for _, punto := range producto.Puntos {
+				fila = append(fila, medida.valor(punto))
+			}
+			fila = append(fila, medida.total(producto))
+			hoja.Filas = append(hoja.Filas, fila)
+		}
+		hojas = append(hojas, hoja)
+	}
+
+	excelBytes, err := s.excelService.GenerarLibro(hojas...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := fmt.Sprintf("Serie_Ventas_%s_%s_%s_al_%s.xlsx",
+		granularidad, nombreSucursales(filtro), filtro.FechaInicio, filtro.FechaFin)
+	return excelBytes, filename, nil
+}
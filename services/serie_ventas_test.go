@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// describirPeriodos resume cada período como "etiqueta desde..hasta"
+func describirPeriodos(periodos []models.PeriodoSerie) string {
+	var partes []string
+	for _, p := range periodos {
+		partes = append(partes, fmt.Sprintf("%s %s..%s", p.Periodo, p.FechaInicio, p.FechaFin))
+	}
+	return strings.Join(partes, ", ")
+}
+
+func TestPeriodosSerie(t *testing.T) {
+	casos := []struct {
+		nombre       string
+		inicio, fin  string
+		granularidad string
+		esperado     string
+	}{
+		{
+			nombre: "meses incompletos en los extremos", inicio: "2024-01-15", fin: "2024-03-10", granularidad: models.GranularidadMes,
+			esperado: "2024-01 2024-01-15..2024-01-31, 2024-02 2024-02-01..2024-02-29, 2024-03 2024-03-01..2024-03-10",
+		},
+		{
+			// El 1 de enero de 2025 es miércoles y pertenece a la semana ISO 1 de 2025
+			nombre: "semanas ISO", inicio: "2024-12-25", fin: "2025-01-06", granularidad: models.GranularidadSemana,
+			esperado: "2024-W52 2024-12-25..2024-12-29, 2025-W01 2024-12-30..2025-01-05, 2025-W02 2025-01-06..2025-01-06",
+		},
+		{
+			nombre: "días", inicio: "2025-02-27", fin: "2025-03-01", granularidad: models.GranularidadDia,
+			esperado: "2025-02-27 2025-02-27..2025-02-27, 2025-02-28 2025-02-28..2025-02-28, 2025-03-01 2025-03-01..2025-03-01",
+		},
+		{
+			// Hoy es el 20 de marzo: el rango termina hoy
+			nombre: "fecha de fin futura", inicio: "2025-02-01", fin: "2025-12-31", granularidad: models.GranularidadMes,
+			esperado: "2025-02 2025-02-01..2025-02-28, 2025-03 2025-03-01..2025-03-20",
+		},
+		{
+			nombre: "inicio posterior a hoy", inicio: "2025-04-01", fin: "2025-04-30", granularidad: models.GranularidadMes,
+			esperado: "",
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			periodos, err := periodosSerie(c.inicio, c.fin, c.granularidad, fechaPrueba(t, "2025-03-20"))
+			if err != nil {
+				t.Fatalf("periodosSerie: %v", err)
+			}
+			if obtenido := describirPeriodos(periodos); obtenido != c.esperado {
+				t.Errorf("periodos:\n%s\nse esperaba:\n%s", obtenido, c.esperado)
+			}
+		})
+	}
+
+	errores := []struct{ inicio, fin, granularidad string }{
+		{"2025-13-01", "2025-03-01", models.GranularidadMes},
+		{"2025-01-01", "marzo", models.GranularidadMes},
+		// Más de 1000 días
+		{"2020-01-01", "2025-01-01", models.GranularidadDia},
+	}
+	for _, e := range errores {
+		if _, err := periodosSerie(e.inicio, e.fin, e.granularidad, fechaPrueba(t, "2025-03-20")); !errors.Is(err, ErrSerieNoValida) {
+			t.Errorf("periodosSerie(%s, %s, %s) = %v, se esperaba ErrSerieNoValida", e.inicio, e.fin, e.granularidad, err)
+		}
+	}
+}
+
+func TestGetSerieVentas(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{
+		ventaPrueba("2025-01-10", "PISO", 2, 1000),
+		ventaPrueba("2025-01-20", "PISO", 3, 1000),
+		ventaPrueba("2025-03-05", "PISO", 1, 1000),
+		ventaPrueba("2025-03-06", "MURO", 4, 500),
+		ventaPrueba("2025-04-01", "PISO", 9, 1000), // Fuera del rango
+	}
+	s := NewVentasService(datasource.NewFixturesFromData(ventas, nil), NewExcelService())
+
+	serie, err := s.GetSerieVentas(models.VentasFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-03-31"}, models.GranularidadMes)
+	if err != nil {
+		t.Fatalf("GetSerieVentas: %v", err)
+	}
+
+	var partes []string
+	for _, p := range serie.Productos {
+		var puntos []string
+		for _, punto := range p.Puntos {
+			puntos = append(puntos, fmt.Sprintf("%s %v/$%d/%d", punto.Periodo, punto.Cantidad, punto.VentaClp, punto.Transacciones))
+		}
+		partes = append(partes, fmt.Sprintf("%s %v/$%d/%d: %s", p.CodigoProducto, p.Cantidad, p.VentaClp, p.Transacciones, strings.Join(puntos, " ")))
+	}
+	// Febrero no tiene ventas y se informa en cero para ambos productos
+	esperado := "MURO 4/$2000/1: 2025-01 0/$0/0 2025-02 0/$0/0 2025-03 4/$2000/1 | " +
+		"PISO 6/$6000/3: 2025-01 5/$5000/2 2025-02 0/$0/0 2025-03 1/$1000/1"
+	if obtenido := strings.Join(partes, " | "); obtenido != esperado {
+		t.Errorf("serie:\n%s\nse esperaba:\n%s", obtenido, esperado)
+	}
+}
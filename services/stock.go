@@ -0,0 +1,292 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// claveZeta normaliza un Zeta para cruzar ingresos con ventas
+func claveZeta(zeta string) string {
+	return strings.ToUpper(strings.TrimSpace(zeta))
+}
+
+// calcularStockProducto acumula ingresos y ventas de un producto, en total y por lote (Zeta).
+// Las líneas de documentos anulados no descuentan stock y se informan aparte.
+func calcularStockProducto(producto *models.StockProducto, ingresos []models.IngresoStock, ventas []models.VentaDetallada) {
+	producto.Lotes = make([]models.StockLote, 0, len(ingresos))
+	indice := make(map[string]int, len(ingresos))
+	for _, ing := range ingresos {
+		producto.UnidadesIngresadas += ing.UnidadesIngresadas
+		fecha := formatearFecha(ing.FechaIngreso)
+		if producto.PrimerIngreso == "" || fecha < producto.PrimerIngreso {
+			producto.PrimerIngreso = fecha
+		}
+		if fecha > producto.UltimoIngreso {
+			producto.UltimoIngreso = fecha
+		}
+
+		// El mismo Zeta ingresado más de una vez se acumula en un solo lote
+		zeta := claveZeta(ing.Zeta)
+		if i, ok := indice[zeta]; ok {
+			producto.Lotes[i].UnidadesIngresadas += ing.UnidadesIngresadas
+			continue
+		}
+		indice[zeta] = len(producto.Lotes)
+		producto.Lotes = append(producto.Lotes, models.StockLote{
+			Zeta:               ing.Zeta,
+			AnioProduccion:     ing.AnioProduccion,
+			FechaIngreso:       fecha,
+			UnidadesIngresadas: ing.UnidadesIngresadas,
+		})
+	}
+
+	for _, venta := range ventas {
+		zeta := claveZeta(venta.Zeta)
+		i, ok := indice[zeta]
+		if !ok {
+			indice[zeta] = len(producto.Lotes)
+			i = len(producto.Lotes)
+			producto.Lotes = append(producto.Lotes, models.StockLote{Zeta: venta.Zeta, SinIngreso: true})
+		}
+
+		lote := &producto.Lotes[i]
+		if venta.Nula {
+			lote.UnidadesAnuladas += venta.Cantidad
+			producto.UnidadesAnuladas += venta.Cantidad
+			continue
+		}
+		lote.UnidadesVendidas += venta.Cantidad
+		producto.UnidadesVendidas += venta.Cantidad
+		if venta.FechaEmision > producto.UltimaVenta {
+			producto.UltimaVenta = venta.FechaEmision
+		}
+	}
+
+	for i := range producto.Lotes {
+		lote := &producto.Lotes[i]
+		lote.Stock = lote.UnidadesIngresadas - lote.UnidadesVendidas
+		lote.StockNegativo = lote.Stock < 0
+	}
+	producto.Stock = producto.UnidadesIngresadas - producto.UnidadesVendidas
+	producto.StockNegativo = producto.Stock < 0
+}
+
+// datosStock contiene los ingresos y las ventas acumulados hasta una fecha de corte,
+// agrupados por código de inventario y de ventas
+type datosStock struct {
+	inicio            time.Time
+	ingresos          map[string][]models.IngresoStock
+	codigosInventario []string
+	ventas            map[string][]models.VentaDetallada
+	codigosVentas     []string
+}
+
+// cargarDatosStock obtiene los ingresos de todos los años hasta la fecha de corte y las ventas
+// de todas las sucursales desde el primer ingreso (o desde el inicio del año de corte)
+func (s *ReporteService) cargarDatosStock(filtro models.StockFiltro) (*datosStock, error) {
+	ingresos, err := s.inventarioService.GetIngresosHastaFecha(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	corte, _ := time.Parse("2006-01-02", filtro.Fecha)
+	datos := &datosStock{
+		inicio:   time.Date(corte.Year(), 1, 1, 0, 0, 0, 0, time.UTC),
+		ingresos: make(map[string][]models.IngresoStock),
+		ventas:   make(map[string][]models.VentaDetallada),
+	}
+	for _, ing := range ingresos {
+		if _, ok := datos.ingresos[ing.CodigoProducto]; !ok {
+			datos.codigosInventario = append(datos.codigosInventario, ing.CodigoProducto)
+		}
+		datos.ingresos[ing.CodigoProducto] = append(datos.ingresos[ing.CodigoProducto], ing)
+		if !ing.FechaIngreso.IsZero() && ing.FechaIngreso.Before(datos.inicio) {
+			datos.inicio = ing.FechaIngreso
+		}
+	}
+
+	// El código de ventas puede diferir del de inventario, por lo que se consultan todas las ventas
+	ventas, err := s.ventasService.GetVentasDetalladas(models.VentasFiltro{
+		FechaInicio: datos.inicio.Format("2006-01-02"),
+		FechaFin:    filtro.Fecha,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener ventas detalladas: %v", err)
+	}
+	for _, venta := range ventas {
+		if _, ok := datos.ventas[venta.CodigoProducto]; !ok {
+			datos.codigosVentas = append(datos.codigosVentas, venta.CodigoProducto)
+		}
+		datos.ventas[venta.CodigoProducto] = append(datos.ventas[venta.CodigoProducto], venta)
+	}
+	return datos, nil
+}
+
+// producto calcula el stock de un código de inventario con las ventas de la coincidencia
+// indicada (sin coincidencia, solo ingresos)
+func (d *datosStock) producto(codigo string, coincidencia *Coincidencia) models.StockProducto {
+	producto := models.StockProducto{CodigoProducto: codigo}
+	if ingresos := d.ingresos[codigo]; len(ingresos) > 0 {
+		producto.Nombre = ingresos[0].NombreAduanero
+		producto.Marca = ingresos[0].Marca
+		producto.Categoria = ingresos[0].Categoria
+	}
+	var ventas []models.VentaDetallada
+	if coincidencia != nil {
+		producto.CodigoVentas = coincidencia.CodigoVentas
+		producto.MetodoCoincidencia = coincidencia.Metodo
+		ventas = d.ventas[coincidencia.CodigoVentas]
+	}
+	calcularStockProducto(&producto, d.ingresos[codigo], ventas)
+	return producto
+}
+
+// productoSinIngresos calcula el stock de un código de ventas sin ingresos emparejados:
+// todo lo vendido es stock negativo
+func (d *datosStock) productoSinIngresos(codigoVentas string) models.StockProducto {
+	producto := models.StockProducto{
+		CodigoProducto: codigoVentas,
+		CodigoVentas:   codigoVentas,
+		SinIngresos:    true,
+	}
+	if ventas := d.ventas[codigoVentas]; len(ventas) > 0 {
+		producto.Nombre = ventas[0].NombreProducto
+	}
+	calcularStockProducto(&producto, nil, d.ventas[codigoVentas])
+	return producto
+}
+
+// GenerarReporteStock calcula el stock disponible de cada producto a la fecha de corte:
+// ingresos de todos los años hasta esa fecha menos las ventas de todas las sucursales desde
+// el primer ingreso, sin contar las líneas de documentos anulados. Los códigos de inventario
+// y de ventas se emparejan igual que en el reporte combinado; los productos vendidos sin
+// ingresos emparejados se informan con stock negativo.
+func (s *ReporteService) GenerarReporteStock(filtro models.StockFiltro) (*models.ReporteStock, error) {
+	if err := filtro.Validar(); err != nil {
+		return nil, err
+	}
+
+	matcher := s.matcher
+	if len(filtro.Estrategias) > 0 {
+		var err error
+		matcher, err = s.matcher.ConEstrategias(filtro.Estrategias)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	datos, err := s.cargarDatosStock(filtro)
+	if err != nil {
+		return nil, err
+	}
+	coincidencias := matcher.Emparejar(datos.codigosInventario, datos.codigosVentas)
+
+	reporte := &models.ReporteStock{
+		Fecha:        filtro.Fecha,
+		InicioVentas: datos.inicio.Format("2006-01-02"),
+		Productos:    make([]models.StockProducto, 0, len(datos.codigosInventario)),
+	}
+
+	emparejadas := make(map[string]bool, len(coincidencias))
+	for _, codigo := range datos.codigosInventario {
+		var coincidencia *Coincidencia
+		if c, ok := coincidencias[codigo]; ok {
+			emparejadas[c.CodigoVentas] = true
+			coincidencia = &c
+		}
+		reporte.Productos = append(reporte.Productos, datos.producto(codigo, coincidencia))
+	}
+
+	// Productos vendidos sin ingresos emparejados: todo lo vendido es stock negativo
+	for _, codigo := range datos.codigosVentas {
+		if emparejadas[codigo] || !coincideCodigoFiltro(codigo, filtro.CodigoProducto) {
+			continue
+		}
+		producto := datos.productoSinIngresos(codigo)
+		if producto.UnidadesVendidas == 0 {
+			// Solo ventas anuladas: no afectan el stock
+			continue
+		}
+		reporte.Productos = append(reporte.Productos, producto)
+	}
+
+	sort.SliceStable(reporte.Productos, func(i, j int) bool {
+		return reporte.Productos[i].CodigoProducto < reporte.Productos[j].CodigoProducto
+	})
+
+	for _, p := range reporte.Productos {
+		if p.Stock > 0 {
+			reporte.UnidadesEnStock += p.Stock
+		}
+		if p.StockNegativo {
+			reporte.ProductosNegativos++
+		}
+		if p.SinIngresos {
+			reporte.ProductosSinIngresos++
+		}
+		for _, lote := range p.Lotes {
+			if lote.StockNegativo {
+				reporte.LotesNegativos++
+			}
+		}
+	}
+
+	return reporte, nil
+}
+
+// coincideCodigoFiltro replica el filtro por código de las consultas (contiene, vacío = todos)
+func coincideCodigoFiltro(codigo, filtro string) bool {
+	return filtro == "" || strings.Contains(codigo, filtro)
+}
+
+// aplicarStock completa el stock a la fecha de corte en las filas del reporte combinado con el
+// mismo emparejamiento que muestra cada fila: las filas con inventario usan las ventas de su
+// CodigoVentas y las filas solo de ventas no tienen ingresos. Las filas de inventario sin ventas
+// en el período se emparejan con las ventas acumuladas cuyo código no aparece en el reporte, de
+// modo que se descuenten las ventas anteriores al período.
+func aplicarStock(coincidentes, sinCoincidencia []models.ReporteCombinado, datos *datosStock, matcher *Matcher) {
+	enReporte := make(map[string]bool, len(coincidentes)+len(sinCoincidencia))
+	var pendientes []string
+	for _, lista := range [][]models.ReporteCombinado{coincidentes, sinCoincidencia} {
+		for _, r := range lista {
+			if r.CodigoVentas != "" {
+				enReporte[r.CodigoVentas] = true
+			}
+		}
+	}
+	for _, r := range coincidentes {
+		if r.CodigoVentas == "" {
+			pendientes = append(pendientes, r.CodigoProducto)
+		}
+	}
+	var libres []string
+	for _, codigo := range datos.codigosVentas {
+		if !enReporte[codigo] {
+			libres = append(libres, codigo)
+		}
+	}
+	anteriores := matcher.Emparejar(pendientes, libres)
+
+	asignar := func(r *models.ReporteCombinado, p models.StockProducto) {
+		stock := p.Stock
+		r.StockAFecha = &stock
+		r.StockNegativo = stock < 0
+	}
+	for i := range coincidentes {
+		r := &coincidentes[i]
+		var coincidencia *Coincidencia
+		if r.CodigoVentas != "" {
+			coincidencia = &Coincidencia{CodigoVentas: r.CodigoVentas, Metodo: r.MetodoCoincidencia}
+		} else if c, ok := anteriores[r.CodigoProducto]; ok {
+			coincidencia = &c
+		}
+		asignar(r, datos.producto(r.CodigoProducto, coincidencia))
+	}
+	for i := range sinCoincidencia {
+		asignar(&sinCoincidencia[i], datos.productoSinIngresos(sinCoincidencia[i].CodigoVentas))
+	}
+}
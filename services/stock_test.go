@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// describirStock resume ingresadas, vendidas, anuladas y stock de un producto y de sus lotes;
+// "!" marca el stock negativo
+func describirStock(p models.StockProducto) string {
+	negativo := func(n bool) string {
+		if n {
+			return "!"
+		}
+		return ""
+	}
+	partes := []string{fmt.Sprintf("%v-%v(%v nulas)=%v%s", p.UnidadesIngresadas, p.UnidadesVendidas, p.UnidadesAnuladas, p.Stock, negativo(p.StockNegativo))}
+	for _, l := range p.Lotes {
+		zeta := l.Zeta
+		if l.SinIngreso {
+			zeta += "?"
+		}
+		partes = append(partes, fmt.Sprintf("%s %v-%v=%v%s", zeta, l.UnidadesIngresadas, l.UnidadesVendidas, l.Stock, negativo(l.StockNegativo)))
+	}
+	return strings.Join(partes, " | ")
+}
+
+func TestGenerarReporteStock(t *testing.T) {
+	nula := ventaZeta("2025-01-12", "PISO", "Z2", 3)
+	nula.Nula = true
+	ventas := []datasource.LineaVentaFixture{
+		ventaZeta("2024-11-15", "PISO", "Z1", 7),
+		ventaZeta("2025-01-05", "PISO", "Z1", 5), // Z1 vende más de lo ingresado
+		ventaZeta("2025-01-06", "PISO", "Z2", 2),
+		ventaZeta("2025-01-07", "PISO", "Z9", 1), // Zeta sin ingreso
+		nula,
+		ventaZeta("2025-02-10", "PISO", "Z2", 3), // Posterior a la fecha de corte
+		ventaZeta("2025-01-08", "SOLO-VENTA", "", 4),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("PISO", "Z1", "2024-11-01", 10, 400),
+		ingresoPrueba("PISO", "Z2", "2024-12-01", 5, 400),
+		ingresoPrueba("PISO", "Z3", "2025-02-01", 8, 400), // Posterior a la fecha de corte
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+
+	reporte, err := s.GenerarReporteStock(models.StockFiltro{Fecha: "2025-01-31"})
+	if err != nil {
+		t.Fatalf("GenerarReporteStock: %v", err)
+	}
+	if reporte.InicioVentas != "2024-11-01" {
+		t.Errorf("InicioVentas = %s, se esperaba 2024-11-01", reporte.InicioVentas)
+	}
+
+	esperados := map[string]string{
+		// El total del producto cuadra aunque dos lotes queden negativos
+		"PISO":       "15-15(3 nulas)=0 | Z1 10-12=-2! | Z2 5-2=3 | Z9? 0-1=-1!",
+		"SOLO-VENTA": "0-4(0 nulas)=-4! | ? 0-4=-4!",
+	}
+	if len(reporte.Productos) != len(esperados) {
+		t.Errorf("productos = %d, se esperaban %d", len(reporte.Productos), len(esperados))
+	}
+	for _, p := range reporte.Productos {
+		if obtenido := describirStock(p); obtenido != esperados[p.CodigoProducto] {
+			t.Errorf("%s:\n%s\nse esperaba:\n%s", p.CodigoProducto, obtenido, esperados[p.CodigoProducto])
+		}
+	}
+
+	if reporte.UnidadesEnStock != 0 || reporte.ProductosNegativos != 1 || reporte.LotesNegativos != 3 || reporte.ProductosSinIngresos != 1 {
+		t.Errorf("totales = %v en stock, %d productos y %d lotes negativos, %d sin ingresos; se esperaba 0, 1, 3 y 1",
+			reporte.UnidadesEnStock, reporte.ProductosNegativos, reporte.LotesNegativos, reporte.ProductosSinIngresos)
+	}
+}
+
+func TestReporteCombinadoStockAFecha(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{
+		// Antes del período se vendió con el código exacto; en el período, con otro código que se
+		// empareja por normalización
+		ventaPrueba("2024-12-15", "AB-1", 30, 1000),
+		ventaPrueba("2025-01-05", "AB1", 10, 1000),
+		// Sin ventas en el período
+		ventaPrueba("2024-08-01", "VIEJO", 20, 1000),
+		ventaPrueba("2025-01-06", "SOLO-VENTA", 4, 1000),
+	}
+	ingresos := []datasource.IngresoFixture{
+		ingresoPrueba("AB-1", "Z1", "2024-12-01", 100, 400),
+		ingresoPrueba("VIEJO", "Z1", "2024-06-01", 50, 400),
+	}
+	s := servicioPrueba(t, ventas, ingresos)
+
+	filtro := models.ReporteFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10", StockAFecha: "2025-01-10"}
+	coincidentes, sinCoincidencia, err := s.GenerarReporteCombinado(filtro)
+	if err != nil {
+		t.Fatalf("GenerarReporteCombinado: %v", err)
+	}
+
+	obtenidos := make(map[string]string)
+	for _, r := range append(coincidentes, sinCoincidencia...) {
+		if r.StockAFecha == nil {
+			t.Fatalf("%s sin STOCK_A_FECHA", r.CodigoProducto)
+		}
+		obtenidos[r.CodigoProducto] = fmt.Sprintf("%s %v %v", r.CodigoVentas, *r.StockAFecha, r.StockNegativo)
+	}
+	esperados := map[string]string{
+		// El stock descuenta las ventas del código que muestra la fila (AB1), no las de AB-1
+		"AB-1": "AB1 90 false",
+		// Las ventas anteriores al período se emparejan con los códigos que no están en el reporte
+		"VIEJO":      " 30 false",
+		"SOLO-VENTA": "SOLO-VENTA -4 true",
+	}
+	for codigo, esperado := range esperados {
+		if obtenidos[codigo] != esperado {
+			t.Errorf("%s = %q, se esperaba %q", codigo, obtenidos[codigo], esperado)
+		}
+	}
+}
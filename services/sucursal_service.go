@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/db"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// ErrSucursalNoValida indica que una sucursal solicitada no es válida o no existe en el ERP
+var ErrSucursalNoValida = errors.New("sucursal no válida")
+
+// vigenciaCatalogoSucursales es el tiempo durante el que se reutiliza el catálogo para validar
+const vigenciaCatalogoSucursales = 10 * time.Minute
+
+// SucursalService entrega el catálogo de sucursales y valida las sucursales solicitadas
+type SucursalService struct {
+	ventasSource datasource.VentasSource
+	defecto      int
+
+	mu          sync.Mutex
+	conocidas   map[int]bool
+	consultadas time.Time
+}
+
+// NewSucursalService crea un nuevo servicio de sucursales con la sucursal por defecto indicada
+func NewSucursalService(ventasSource datasource.VentasSource, defecto int) *SucursalService {
+	return &SucursalService{
+		ventasSource: ventasSource,
+		defecto:      defecto,
+	}
+}
+
+// consultarSucursales obtiene las sucursales con sus documentos de venta en el período
+func (s *SucursalService) consultarSucursales(fechaInicio, fechaFin string) ([]models.Sucursal, error) {
+	rows, err := s.ventasSource.Sucursales(models.VentasFiltro{FechaInicio: fechaInicio, FechaFin: fechaFin})
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar sucursales: %v", err)
+	}
+	defer rows.Close()
+
+	sucursales := make([]models.Sucursal, 0)
+	if err := db.ScanAll(rows, &sucursales); err != nil {
+		return nil, fmt.Errorf("error al decodificar sucursales: %v", err)
+	}
+
+	for i := range sucursales {
+		sucursales[i].TieneVentas = sucursales[i].DocumentosPeriodo > 0
+		sucursales[i].SucursalPorDefecto = sucursales[i].ID == s.defecto
+	}
+	return sucursales, nil
+}
+
+// ListarSucursales devuelve el catálogo de sucursales indicando cuáles tienen ventas en el período
+func (s *SucursalService) ListarSucursales(fechaInicio, fechaFin string) (*models.CatalogoSucursales, error) {
+	sucursales, err := s.consultarSucursales(fechaInicio, fechaFin)
+	if err != nil {
+		return nil, err
+	}
+	s.recordar(sucursales)
+
+	return &models.CatalogoSucursales{
+		FechaInicio:        fechaInicio,
+		FechaFin:           fechaFin,
+		SucursalPorDefecto: s.defecto,
+		Sucursales:         sucursales,
+	}, nil
+}
+
+// recordar guarda los IDs del catálogo para validar las siguientes solicitudes
+func (s *SucursalService) recordar(sucursales []models.Sucursal) {
+	conocidas := make(map[int]bool, len(sucursales))
+	for _, suc := range sucursales {
+		conocidas[suc.ID] = true
+	}
+
+	s.mu.Lock()
+	s.conocidas = conocidas
+	s.consultadas = time.Now()
+	s.mu.Unlock()
+}
+
+// sucursalesConocidas devuelve los IDs del catálogo, consultándolo si no está vigente
+func (s *SucursalService) sucursalesConocidas() (map[int]bool, error) {
+	s.mu.Lock()
+	if s.conocidas != nil && time.Since(s.consultadas) < vigenciaCatalogoSucursales {
+		conocidas := s.conocidas
+		s.mu.Unlock()
+		return conocidas, nil
+	}
+	s.mu.Unlock()
+
+	hoy := time.Now().Format("2006-01-02")
+	sucursales, err := s.consultarSucursales(hoy, hoy)
+	if err != nil {
+		return nil, err
+	}
+	s.recordar(sucursales)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conocidas, nil
+}
+
+// Validar comprueba que todas las sucursales existan en el catálogo del ERP.
+// Si el catálogo está vacío no se valida, para no bloquear las consultas.
+func (s *SucursalService) Validar(sucursales []int) error {
+	if len(sucursales) == 0 {
+		return nil
+	}
+
+	conocidas, err := s.sucursalesConocidas()
+	if err != nil {
+		return err
+	}
+	if len(conocidas) == 0 {
+		log.Printf("Catálogo de sucursales vacío; se omite la validación de sucursales")
+		return nil
+	}
+
+	for _, suc := range sucursales {
+		if !conocidas[suc] {
+			return fmt.Errorf("%w: %d no existe (consulte /api/sucursales)", ErrSucursalNoValida, suc)
+		}
+	}
+	return nil
+}
+
+// ParseSucursales interpreta el parámetro sucursal (vacío = sucursal por defecto, "todas"
+// o lista separada por comas) y valida que las sucursales existan
+func (s *SucursalService) ParseSucursales(valor string) ([]int, error) {
+	sucursales, err := models.ParseSucursales(valor, s.defecto)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSucursalNoValida, err)
+	}
+	if err := s.Validar(sucursales); err != nil {
+		return nil, err
+	}
+	return sucursales, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+)
+
+func TestSucursalServiceListarSucursales(t *testing.T) {
+	febrero := ventaSucursal(212, "PISO", 1, 1000)
+	febrero.Fecha = "2025-02-03"
+	ventas := []datasource.LineaVentaFixture{
+		ventaSucursal(211, "PISO", 1, 1000),
+		ventaSucursal(211, "MURO", 1, 1000),
+		febrero,
+	}
+	s := NewSucursalService(datasource.NewFixturesFromData(ventas, nil), 212)
+
+	catalogo, err := s.ListarSucursales("2025-01-01", "2025-01-31")
+	if err != nil {
+		t.Fatalf("ListarSucursales: %v", err)
+	}
+	var partes []string
+	for _, suc := range catalogo.Sucursales {
+		partes = append(partes, fmt.Sprintf("%d %d %v %v", suc.ID, suc.DocumentosPeriodo, suc.TieneVentas, suc.SucursalPorDefecto))
+	}
+	// La sucursal 212 está en el catálogo aunque no venda en enero
+	if obtenido, esperado := strings.Join(partes, ", "), "211 2 true false, 212 0 false true"; obtenido != esperado {
+		t.Errorf("sucursales = %s, se esperaba %s", obtenido, esperado)
+	}
+	if catalogo.SucursalPorDefecto != 212 {
+		t.Errorf("SucursalPorDefecto = %d, se esperaba 212", catalogo.SucursalPorDefecto)
+	}
+}
+
+func TestSucursalServiceParseSucursales(t *testing.T) {
+	ventas := []datasource.LineaVentaFixture{
+		ventaSucursal(211, "PISO", 1, 1000),
+		ventaSucursal(212, "PISO", 1, 1000),
+	}
+	s := NewSucursalService(datasource.NewFixturesFromData(ventas, nil), 211)
+
+	casos := []struct {
+		valor    string
+		esperado string
+		error    bool
+	}{
+		{valor: "", esperado: "[211]"},
+		{valor: "212,211", esperado: "[212 211]"},
+		{valor: "todas", esperado: "[]"},
+		{valor: "211,999", error: true},
+		{valor: "centro", error: true},
+	}
+	for _, c := range casos {
+		sucursales, err := s.ParseSucursales(c.valor)
+		switch {
+		case c.error && !errors.Is(err, ErrSucursalNoValida):
+			t.Errorf("ParseSucursales(%q) = %v, %v; se esperaba ErrSucursalNoValida", c.valor, sucursales, err)
+		case !c.error && (err != nil || fmt.Sprint(sucursales) != c.esperado):
+			t.Errorf("ParseSucursales(%q) = %v, %v; se esperaba %s", c.valor, sucursales, err, c.esperado)
+		}
+	}
+
+	if _, err := s.ParseSucursales("999"); err == nil || !strings.Contains(err.Error(), "999 no existe") {
+		t.Errorf("ParseSucursales(999) = %v, se esperaba un error que nombre la sucursal", err)
+	}
+
+	// Sin catálogo no se puede validar y no se bloquean las consultas
+	sinCatalogo := NewSucursalService(datasource.NewFixturesFromData(nil, nil), 211)
+	if err := sinCatalogo.Validar([]int{999}); err != nil {
+		t.Errorf("Validar con el catálogo vacío = %v, se esperaba nil", err)
+	}
+}
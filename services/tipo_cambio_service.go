@@ -0,0 +1,333 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+	"github.com/pablojnd/rotacion/utils"
+)
+
+// Errores devueltos por TipoCambioService
+var (
+	ErrTipoCambioNoEncontrado = errors.New("tipo de cambio no encontrado")
+	ErrTipoCambioInvalido     = errors.New("tipo de cambio no válido")
+)
+
+// formatosFechaTipoCambio son los formatos de fecha aceptados al importar. "01-02-06" y
+// "1/2/06 15:04" son los formatos con que se leen las celdas de fecha de Excel sin formato
+// personalizado (mm-dd-yy y m/d/yy h:mm).
+var formatosFechaTipoCambio = []string{"2006-01-02", "02-01-2006", "02/01/2006", "2006/01/02", "01-02-06", "1/2/06 15:04"}
+
+// TipoCambioService administra la tabla persistente de tipos de cambio USD→CLP por fecha.
+// Los valores se guardan en un archivo JSON local, igual que los alias de códigos.
+type TipoCambioService struct {
+	ruta    string
+	mu      sync.RWMutex
+	valores map[string]models.TipoCambio // fecha YYYY-MM-DD -> tipo de cambio
+}
+
+// NewTipoCambioService crea el servicio y carga los tipos de cambio existentes desde ruta
+func NewTipoCambioService(ruta string) (*TipoCambioService, error) {
+	s := &TipoCambioService{
+		ruta:    ruta,
+		valores: make(map[string]models.TipoCambio),
+	}
+
+	data, err := os.ReadFile(ruta)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer tipos de cambio: %v", err)
+	}
+
+	var lista []models.TipoCambio
+	if err := json.Unmarshal(data, &lista); err != nil {
+		return nil, fmt.Errorf("error al leer tipos de cambio de %s: %v", ruta, err)
+	}
+	for _, tc := range lista {
+		s.valores[tc.Fecha] = tc
+	}
+
+	return s, nil
+}
+
+// Listar devuelve los tipos de cambio ordenados por fecha, opcionalmente entre desde y hasta
+// (YYYY-MM-DD, incluidas; vacío = sin límite)
+func (s *TipoCambioService) Listar(desde, hasta string) []models.TipoCambio {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lista := s.listar()
+	filtrados := lista[:0]
+	for _, tc := range lista {
+		if (desde != "" && tc.Fecha < desde) || (hasta != "" && tc.Fecha > hasta) {
+			continue
+		}
+		filtrados = append(filtrados, tc)
+	}
+	return filtrados
+}
+
+// Consultar devuelve el tipo de cambio aplicable a una fecha (YYYY-MM-DD)
+func (s *TipoCambioService) Consultar(fecha string) (models.TipoCambioAplicado, error) {
+	dia, err := time.Parse("2006-01-02", strings.TrimSpace(fecha))
+	if err != nil {
+		return models.TipoCambioAplicado{}, fmt.Errorf("%w: fecha %q, debe estar en formato YYYY-MM-DD", ErrTipoCambioInvalido, fecha)
+	}
+
+	aplicado, ok := s.Tabla().Aplicar(dia)
+	if !ok {
+		return models.TipoCambioAplicado{}, fmt.Errorf("%w: no hay tipos de cambio registrados", ErrTipoCambioNoEncontrado)
+	}
+	return aplicado, nil
+}
+
+// Guardar crea o actualiza el tipo de cambio de una fecha.
+// Devuelve el valor guardado e indica si fue creado.
+func (s *TipoCambioService) Guardar(tc models.TipoCambio, usuario string) (models.TipoCambio, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	anterior, existia := s.valores[strings.TrimSpace(tc.Fecha)]
+	guardado, creado, err := s.guardar(tc, usuario, time.Now())
+	if err != nil {
+		return models.TipoCambio{}, false, err
+	}
+	if err := s.persistir(); err != nil {
+		// Revertir el cambio en memoria para mantenerla igual al archivo
+		if existia {
+			s.valores[guardado.Fecha] = anterior
+		} else {
+			delete(s.valores, guardado.Fecha)
+		}
+		return models.TipoCambio{}, false, err
+	}
+	return guardado, creado, nil
+}
+
+// Eliminar borra el tipo de cambio de una fecha
+func (s *TipoCambioService) Eliminar(fecha string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fecha = strings.TrimSpace(fecha)
+	anterior, ok := s.valores[fecha]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTipoCambioNoEncontrado, fecha)
+	}
+	delete(s.valores, fecha)
+
+	if err := s.persistir(); err != nil {
+		s.valores[fecha] = anterior
+		return err
+	}
+	return nil
+}
+
+// Importar carga tipos de cambio de forma masiva desde un archivo XLSX o CSV.
+// El archivo debe tener encabezados con la fecha (FECHA o DIA) y el valor (VALOR, DOLAR,
+// TIPO_CAMBIO o DOLAR_OBSERVADO). Se aceptan decimales con coma o punto.
+// Las filas con errores se informan y no detienen la carga.
+func (s *TipoCambioService) Importar(r io.Reader, nombreArchivo string, usuario string) (models.ResultadoImportacionTipoCambio, error) {
+	var resultado models.ResultadoImportacionTipoCambio
+
+	filas, err := utils.LeerTablaArchivo(r, nombreArchivo)
+	if err != nil {
+		return resultado, err
+	}
+	if len(filas) == 0 {
+		return resultado, fmt.Errorf("%w: el archivo está vacío", ErrTipoCambioInvalido)
+	}
+
+	encabezados := filas[0]
+	colFecha := utils.BuscarColumna(encabezados, "fecha", "dia", "día", "date")
+	colValor := utils.BuscarColumna(encabezados, "valor", "dolar", "dólar", "tipoCambio", "dolarObservado", "dólarObservado", "usd", "clp")
+	if colFecha < 0 || colValor < 0 {
+		return resultado, fmt.Errorf("%w: el archivo debe tener las columnas FECHA y VALOR", ErrTipoCambioInvalido)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ahora := time.Now()
+	fuente := "archivo " + filepath.Base(nombreArchivo)
+	for i, fila := range filas[1:] {
+		textoFecha := utils.ValorCelda(fila, colFecha)
+		textoValor := utils.ValorCelda(fila, colValor)
+		if textoFecha == "" && textoValor == "" {
+			continue // Fila en blanco
+		}
+
+		// i+2: la fila 1 es el encabezado
+		fecha, err := parsearFechaTipoCambio(textoFecha)
+		if err != nil {
+			resultado.Errores = append(resultado.Errores, fmt.Sprintf("fila %d: %v", i+2, err))
+			continue
+		}
+		valor, err := parsearValorTipoCambio(textoValor)
+		if err != nil {
+			resultado.Errores = append(resultado.Errores, fmt.Sprintf("fila %d: %v", i+2, err))
+			continue
+		}
+
+		_, creado, err := s.guardar(models.TipoCambio{Fecha: fecha, Valor: valor, Fuente: fuente}, usuario, ahora)
+		if err != nil {
+			resultado.Errores = append(resultado.Errores, fmt.Sprintf("fila %d: %v", i+2, err))
+			continue
+		}
+		if creado {
+			resultado.Creados++
+		} else {
+			resultado.Actualizados++
+		}
+	}
+
+	if resultado.Creados+resultado.Actualizados > 0 {
+		if err := s.persistir(); err != nil {
+			return resultado, err
+		}
+	}
+
+	return resultado, nil
+}
+
+// Tabla devuelve una copia ordenada de los tipos de cambio para convertir muchas fechas
+// sin mantener el bloqueo
+func (s *TipoCambioService) Tabla() TablaTipoCambio {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return TablaTipoCambio{valores: s.listar()}
+}
+
+// guardar valida y registra un tipo de cambio en memoria; requiere tener el bloqueo de escritura
+func (s *TipoCambioService) guardar(tc models.TipoCambio, usuario string, ahora time.Time) (models.TipoCambio, bool, error) {
+	tc.Fecha = strings.TrimSpace(tc.Fecha)
+	tc.Fuente = strings.TrimSpace(tc.Fuente)
+
+	if _, err := time.Parse("2006-01-02", tc.Fecha); err != nil {
+		return models.TipoCambio{}, false, fmt.Errorf("%w: fecha %q, debe estar en formato YYYY-MM-DD", ErrTipoCambioInvalido, tc.Fecha)
+	}
+	if tc.Valor <= 0 {
+		return models.TipoCambio{}, false, fmt.Errorf("%w: el valor debe ser mayor que 0", ErrTipoCambioInvalido)
+	}
+
+	_, existe := s.valores[tc.Fecha]
+	tc.ActualizadoPor = usuario
+	tc.ActualizadoEn = ahora
+
+	s.valores[tc.Fecha] = tc
+	return tc, !existe, nil
+}
+
+// listar devuelve los tipos de cambio ordenados por fecha; requiere tener el bloqueo
+func (s *TipoCambioService) listar() []models.TipoCambio {
+	lista := make([]models.TipoCambio, 0, len(s.valores))
+	for _, tc := range s.valores {
+		lista = append(lista, tc)
+	}
+	sort.Slice(lista, func(i, j int) bool {
+		return lista[i].Fecha < lista[j].Fecha
+	})
+	return lista
+}
+
+// persistir escribe los tipos de cambio en disco de forma atómica; requiere tener el bloqueo
+func (s *TipoCambioService) persistir() error {
+	data, err := json.MarshalIndent(s.listar(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error al serializar tipos de cambio: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.ruta), 0o755); err != nil {
+		return fmt.Errorf("error al crear directorio de tipos de cambio: %v", err)
+	}
+
+	tmp := s.ruta + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error al guardar tipos de cambio: %v", err)
+	}
+	if err := os.Rename(tmp, s.ruta); err != nil {
+		return fmt.Errorf("error al guardar tipos de cambio: %v", err)
+	}
+	return nil
+}
+
+// parsearFechaTipoCambio interpreta la fecha de una fila importada
+func parsearFechaTipoCambio(texto string) (string, error) {
+	for _, formato := range formatosFechaTipoCambio {
+		if fecha, err := time.Parse(formato, texto); err == nil {
+			return fecha.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("%w: fecha %q no reconocida", ErrTipoCambioInvalido, texto)
+}
+
+// parsearValorTipoCambio interpreta el valor de una fila importada, aceptando
+// "943.12", "943,12", "1.234,56", "1,234.56" o "$ 943". El último separador es el decimal.
+func parsearValorTipoCambio(texto string) (float64, error) {
+	limpio := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(texto), "$"))
+	if strings.LastIndex(limpio, ",") > strings.LastIndex(limpio, ".") {
+		// Coma decimal: los puntos son separadores de miles
+		limpio = strings.ReplaceAll(limpio, ".", "")
+		limpio = strings.ReplaceAll(limpio, ",", ".")
+	} else {
+		limpio = strings.ReplaceAll(limpio, ",", "")
+	}
+	valor, err := strconv.ParseFloat(limpio, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: valor %q no numérico", ErrTipoCambioInvalido, texto)
+	}
+	return valor, nil
+}
+
+// TablaTipoCambio es una copia ordenada por fecha de los tipos de cambio registrados
+type TablaTipoCambio struct {
+	valores []models.TipoCambio
+}
+
+// Vacia indica si no hay tipos de cambio registrados
+func (t TablaTipoCambio) Vacia() bool {
+	return len(t.valores) == 0
+}
+
+// Aplicar devuelve el tipo de cambio de la fecha o el último anterior. Las fechas anteriores
+// al primer valor registrado usan ese valor y se marcan como aproximadas.
+// Devuelve false si la tabla está vacía.
+func (t TablaTipoCambio) Aplicar(fecha time.Time) (models.TipoCambioAplicado, bool) {
+	if len(t.valores) == 0 {
+		return models.TipoCambioAplicado{}, false
+	}
+
+	dia := fecha.Format("2006-01-02")
+	// Primer valor con fecha posterior al día; el anterior a ese es el vigente
+	i := sort.Search(len(t.valores), func(i int) bool { return t.valores[i].Fecha > dia })
+	aplicado := models.TipoCambioAplicado{Fecha: dia}
+	tc := t.valores[0]
+	if i > 0 {
+		tc = t.valores[i-1]
+	} else {
+		aplicado.Aproximado = true
+	}
+	aplicado.FechaValor = tc.Fecha
+	aplicado.Valor = tc.Valor
+	consultado, _ := time.Parse("2006-01-02", dia)
+	if desde, err := time.Parse("2006-01-02", tc.Fecha); err == nil {
+		aplicado.DiasDesfase = int(consultado.Sub(desde).Hours() / 24)
+		if aplicado.DiasDesfase < 0 {
+			aplicado.DiasDesfase = -aplicado.DiasDesfase
+		}
+	}
+	return aplicado, true
+}
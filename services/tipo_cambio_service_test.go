@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+func TestTablaTipoCambioAplicar(t *testing.T) {
+	// Jueves 2, viernes 3 y lunes 6 de enero
+	tabla := TablaTipoCambio{valores: []models.TipoCambio{
+		{Fecha: "2025-01-02", Valor: 900},
+		{Fecha: "2025-01-03", Valor: 910},
+		{Fecha: "2025-01-06", Valor: 920},
+	}}
+	casos := []struct {
+		nombre   string
+		fecha    string
+		esperado models.TipoCambioAplicado
+	}{
+		{"mismo día", "2025-01-03", models.TipoCambioAplicado{Fecha: "2025-01-03", FechaValor: "2025-01-03", Valor: 910}},
+		{"fin de semana usa el viernes", "2025-01-05", models.TipoCambioAplicado{Fecha: "2025-01-05", FechaValor: "2025-01-03", Valor: 910, DiasDesfase: 2}},
+		{"después del último valor", "2025-02-01", models.TipoCambioAplicado{Fecha: "2025-02-01", FechaValor: "2025-01-06", Valor: 920, DiasDesfase: 26}},
+		{"antes del primer valor", "2024-12-31", models.TipoCambioAplicado{Fecha: "2024-12-31", FechaValor: "2025-01-02", Valor: 900, Aproximado: true, DiasDesfase: 2}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			aplicado, ok := tabla.Aplicar(fechaPrueba(t, c.fecha))
+			if !ok || aplicado != c.esperado {
+				t.Errorf("Aplicar(%s) = %+v, %v; se esperaba %+v", c.fecha, aplicado, ok, c.esperado)
+			}
+		})
+	}
+
+	if _, ok := (TablaTipoCambio{}).Aplicar(fechaPrueba(t, "2025-01-03")); ok {
+		t.Error("Aplicar con la tabla vacía devolvió un tipo de cambio")
+	}
+}
+
+func TestParsearValorTipoCambio(t *testing.T) {
+	casos := []struct {
+		texto    string
+		esperado float64
+		error    bool
+	}{
+		{texto: "943.12", esperado: 943.12},
+		{texto: "943,12", esperado: 943.12},
+		{texto: "1.234,56", esperado: 1234.56},
+		{texto: "1,234.56", esperado: 1234.56},
+		{texto: "$ 943", esperado: 943},
+		{texto: " $943,5 ", esperado: 943.5},
+		{texto: "", error: true},
+		{texto: "USD", error: true},
+	}
+	for _, c := range casos {
+		valor, err := parsearValorTipoCambio(c.texto)
+		switch {
+		case c.error && !errors.Is(err, ErrTipoCambioInvalido):
+			t.Errorf("parsearValorTipoCambio(%q) = %v, %v; se esperaba ErrTipoCambioInvalido", c.texto, valor, err)
+		case !c.error && (err != nil || valor != c.esperado):
+			t.Errorf("parsearValorTipoCambio(%q) = %v, %v; se esperaba %v", c.texto, valor, err, c.esperado)
+		}
+	}
+}
+
+func TestTipoCambioServiceListarEImportar(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "tipo_cambio.json")
+	s, err := NewTipoCambioService(ruta)
+	if err != nil {
+		t.Fatalf("NewTipoCambioService: %v", err)
+	}
+
+	csv := "FECHA;VALOR\n2025-01-06;920\n03/01/2025;\"910,5\"\n2025-01-02;$ 900\nmañana;950\n"
+	resultado, err := s.Importar(strings.NewReader(csv), "dolar.csv", "prueba")
+	if err != nil {
+		t.Fatalf("Importar: %v", err)
+	}
+	if resultado.Creados != 3 || len(resultado.Errores) != 1 || !strings.HasPrefix(resultado.Errores[0], "fila 5:") {
+		t.Errorf("Importar = %+v, se esperaban 3 creados y un error en la fila 5", resultado)
+	}
+
+	// Se relee del archivo para verificar que se persistió
+	s, err = NewTipoCambioService(ruta)
+	if err != nil {
+		t.Fatalf("NewTipoCambioService: %v", err)
+	}
+	casos := []struct {
+		desde, hasta string
+		esperado     string
+	}{
+		{"", "", "2025-01-02=900 2025-01-03=910.5 2025-01-06=920"},
+		{"2025-01-03", "", "2025-01-03=910.5 2025-01-06=920"},
+		{"", "2025-01-05", "2025-01-02=900 2025-01-03=910.5"},
+		{"2025-01-04", "2025-01-05", ""},
+	}
+	for _, c := range casos {
+		var valores []string
+		for _, tc := range s.Listar(c.desde, c.hasta) {
+			valores = append(valores, fmt.Sprintf("%s=%v", tc.Fecha, tc.Valor))
+		}
+		if obtenido := strings.Join(valores, " "); obtenido != c.esperado {
+			t.Errorf("Listar(%q, %q) = %q, se esperaba %q", c.desde, c.hasta, obtenido, c.esperado)
+		}
+	}
+}
@@ -1,72 +1,143 @@
 package services
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pablojnd/rotacion/datasource"
 	"github.com/pablojnd/rotacion/db"
 	"github.com/pablojnd/rotacion/models"
-	"github.com/pablojnd/rotacion/queries/sqlserver"
-	"github.com/pablojnd/rotacion/utils"
 )
 
 // VentasService proporciona métodos para trabajar con datos de ventas
 type VentasService struct {
-	sqlServer    *db.SQLServerDB
+	ventasSource datasource.VentasSource
 	excelService *ExcelService
 }
 
 // NewVentasService crea un nuevo servicio de ventas
-func NewVentasService(sqlServer *db.SQLServerDB, excelService *ExcelService) *VentasService {
+func NewVentasService(ventasSource datasource.VentasSource, excelService *ExcelService) *VentasService {
 	return &VentasService{
-		sqlServer:    sqlServer,
+		ventasSource: ventasSource,
 		excelService: excelService,
 	}
 }
 
-// GetVentas obtiene las ventas según los filtros proporcionados
-func (s *VentasService) GetVentas(filtro models.VentasFiltro, tipo models.TipoConsultaVentas) ([]map[string]interface{}, error) {
+// consultarVentas obtiene las filas de ventas del origen de datos según el tipo de consulta
+func (s *VentasService) consultarVentas(filtro models.VentasFiltro, tipo models.TipoConsultaVentas) (db.Rows, error) {
+	if tipo == models.ConsultaVentasAgrupada {
+		return s.ventasSource.VentasAgrupadas(filtro)
+	}
+	return s.ventasSource.VentasDetalladas(filtro)
+}
+
+// GetVentasDetalladas obtiene cada línea de venta según los filtros proporcionados
+func (s *VentasService) GetVentasDetalladas(filtro models.VentasFiltro) ([]models.VentaDetallada, error) {
 	// Validar filtros
 	if err := filtro.Validar(); err != nil {
 		return nil, err
 	}
 
-	var query string
-	var args []interface{}
+	// Ejecutar la consulta con los parámetros
+	rows, err := s.ventasSource.VentasDetalladas(filtro)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	// Seleccionar la consulta según el tipo
-	if tipo == models.ConsultaVentasAgrupada {
-		query = sqlserver.GetVentasAgrupadasQuery()
-		args = []interface{}{
-			filtro.FechaInicio, filtro.FechaFin, filtro.Sucursal, // Para Boletas
-			filtro.FechaInicio, filtro.FechaFin, filtro.Sucursal, // Para Facturas
-			filtro.CodigoProducto, filtro.CodigoProducto, // Para filtrado por código
-		}
-	} else {
-		query = sqlserver.GetVentasQuery()
-		args = []interface{}{
-			filtro.FechaInicio, filtro.FechaFin, filtro.Sucursal, // Para Boletas
-			filtro.FechaInicio, filtro.FechaFin, filtro.Sucursal, // Para Facturas
-			filtro.CodigoProducto, filtro.CodigoProducto, // Para filtrado por código
-		}
+	// Decodificar resultados
+	ventas := make([]models.VentaDetallada, 0)
+	if err := db.ScanAll(rows, &ventas); err != nil {
+		return nil, fmt.Errorf("error al decodificar ventas: %v", err)
+	}
+
+	return ventas, nil
+}
+
+// GetVentasAgrupadas obtiene las ventas agrupadas por producto según los filtros proporcionados
+func (s *VentasService) GetVentasAgrupadas(filtro models.VentasFiltro) ([]models.VentaAgrupada, error) {
+	// Validar filtros
+	if err := filtro.Validar(); err != nil {
+		return nil, err
 	}
 
 	// Ejecutar la consulta con los parámetros
-	rows, err := s.sqlServer.ExecuteQuery(query, args...)
+	rows, err := s.ventasSource.VentasAgrupadas(filtro)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	// Convertir resultados a JSON
-	result, err := utils.RowsToJSON(rows)
+	// Decodificar resultados
+	ventas := make([]models.VentaAgrupada, 0)
+	if err := db.ScanAll(rows, &ventas); err != nil {
+		return nil, fmt.Errorf("error al decodificar ventas agrupadas: %v", err)
+	}
+
+	// Con varias sucursales, agregar el desglose de cada producto por sucursal
+	if filtro.VariasSucursales() {
+		porSucursal, err := s.GetVentasPorSucursal(filtro)
+		if err != nil {
+			return nil, err
+		}
+		porCodigo := make(map[string][]models.VentaSucursal)
+		for _, v := range porSucursal {
+			porCodigo[v.CodigoProducto] = append(porCodigo[v.CodigoProducto], v)
+		}
+		for i := range ventas {
+			ventas[i].VentasPorSucursal = porCodigo[ventas[i].CodigoProducto]
+		}
+	}
+
+	return ventas, nil
+}
+
+// GetVentasPorSucursal obtiene las ventas de cada producto desglosadas por sucursal
+func (s *VentasService) GetVentasPorSucursal(filtro models.VentasFiltro) ([]models.VentaSucursal, error) {
+	if err := filtro.Validar(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.ventasSource.VentasPorSucursal(filtro)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	ventas := make([]models.VentaSucursal, 0)
+	if err := db.ScanAll(rows, &ventas); err != nil {
+		return nil, fmt.Errorf("error al decodificar ventas por sucursal: %v", err)
+	}
 
-	return result, nil
+	return ventas, nil
 }
 
-// GetVentasAgrupadas obtiene las ventas agrupadas por producto según los filtros proporcionados
-func (s *VentasService) GetVentasAgrupadas(filtro models.VentasFiltro) ([]map[string]interface{}, error) {
-	return s.GetVentas(filtro, models.ConsultaVentasAgrupada)
+// SucursalesConVentas devuelve las sucursales presentes en el desglose, ordenadas
+func SucursalesConVentas(desgloses ...[]models.VentaSucursal) []int {
+	vistas := make(map[int]bool)
+	var sucursales []int
+	for _, desglose := range desgloses {
+		for _, v := range desglose {
+			if !vistas[v.Sucursal] {
+				vistas[v.Sucursal] = true
+				sucursales = append(sucursales, v.Sucursal)
+			}
+		}
+	}
+	sort.Ints(sucursales)
+	return sucursales
+}
+
+// ventaEnSucursal busca las ventas de una sucursal dentro del desglose
+func ventaEnSucursal(desglose []models.VentaSucursal, sucursal int) (models.VentaSucursal, bool) {
+	for _, v := range desglose {
+		if v.Sucursal == sucursal {
+			return v, true
+		}
+	}
+	return models.VentaSucursal{Sucursal: sucursal}, false
 }
 
 // ExportVentasToExcel exporta ventas a un archivo Excel
@@ -79,28 +150,21 @@ func (s *VentasService) ExportVentasToExcel(filtro models.VentasFiltro, tipo mod
 	// Nombre del archivo
 	filename := generateVentasFilename(filtro)
 
-	var query string
-	var args []interface{}
-
-	// Seleccionar la consulta según el tipo
-	if tipo == models.ConsultaVentasAgrupada {
-		query = sqlserver.GetVentasAgrupadasQuery()
-		args = []interface{}{
-			filtro.FechaInicio, filtro.FechaFin, filtro.Sucursal, // Para Boletas
-			filtro.FechaInicio, filtro.FechaFin, filtro.Sucursal, // Para Facturas
-			filtro.CodigoProducto, filtro.CodigoProducto, // Para filtrado por código
+	// Las ventas agrupadas de varias sucursales llevan columnas de desglose por sucursal
+	if tipo == models.ConsultaVentasAgrupada && filtro.VariasSucursales() {
+		ventas, err := s.GetVentasAgrupadas(filtro)
+		if err != nil {
+			return nil, "", err
 		}
-	} else {
-		query = sqlserver.GetVentasQuery()
-		args = []interface{}{
-			filtro.FechaInicio, filtro.FechaFin, filtro.Sucursal, // Para Boletas
-			filtro.FechaInicio, filtro.FechaFin, filtro.Sucursal, // Para Facturas
-			filtro.CodigoProducto, filtro.CodigoProducto, // Para filtrado por código
+		excelBytes, err := s.excelService.GenerarLibro(hojaVentasAgrupadas(ventas))
+		if err != nil {
+			return nil, "", err
 		}
+		return excelBytes, filename, nil
 	}
 
 	// Obtener y ejecutar la consulta
-	rows, err := s.sqlServer.ExecuteQuery(query, args...)
+	rows, err := s.consultarVentas(filtro, tipo)
 	if err != nil {
 		return nil, "", err
 	}
@@ -120,11 +184,98 @@ func (s *VentasService) ExportVentasAgrupadasToExcel(filtro models.VentasFiltro)
 	return s.ExportVentasToExcel(filtro, models.ConsultaVentasAgrupada)
 }
 
+// hojaVentasAgrupadas genera la hoja de ventas agrupadas con una pareja de columnas
+// (cantidad y venta) por cada sucursal
+func hojaVentasAgrupadas(ventas []models.VentaAgrupada) HojaExcel {
+	hoja := HojaExcel{
+		Nombre: "Datos",
+		Columnas: []ColumnaExcel{
+			{Titulo: "Código de Producto"},
+			{Titulo: "Nombre del Producto", Ancho: 40},
+			{Titulo: "Costo Unitario (USD)", Decimal: true},
+			{Titulo: "Precio Base (CLP)"},
+			{Titulo: "Precio de Oferta (CLP)"},
+			{Titulo: "Cantidad Total Vendida", Decimal: true},
+			{Titulo: "Total Ventas (CLP)"},
+			{Titulo: "Última Fecha de Venta"},
+			{Titulo: "Precio Promedio Ponderado (CLP)"},
+			{Titulo: "Precio Mínimo (CLP)"},
+			{Titulo: "Precio Máximo (CLP)"},
+			{Titulo: "Cantidad de Ventas Registradas"},
+			{Titulo: "Unidades Boleta", Decimal: true},
+			{Titulo: "Unidades Factura", Decimal: true},
+			{Titulo: "Ventas Boleta (CLP)"},
+			{Titulo: "Ventas Factura (CLP)"},
+			{Titulo: "Documentos Boleta"},
+			{Titulo: "Documentos Factura"},
+			{Titulo: "Documentos Nulos"},
+			{Titulo: "Unidades Nulas", Decimal: true},
+		},
+	}
+
+	desgloses := make([][]models.VentaSucursal, len(ventas))
+	for i, v := range ventas {
+		desgloses[i] = v.VentasPorSucursal
+	}
+	sucursales := SucursalesConVentas(desgloses...)
+	for _, suc := range sucursales {
+		hoja.Columnas = append(hoja.Columnas,
+			ColumnaExcel{Titulo: fmt.Sprintf("Cantidad Sucursal %d", suc), Decimal: true},
+			ColumnaExcel{Titulo: fmt.Sprintf("Ventas (CLP) Sucursal %d", suc)},
+		)
+	}
+
+	for _, v := range ventas {
+		fila := []interface{}{
+			v.CodigoProducto,
+			v.NombreProducto,
+			v.CostoUnitarioUSD,
+			v.PrecioBaseCLP,
+			v.PrecioOfertaCLP,
+			v.CantidadTotalVendida,
+			v.TotalVentasCLP,
+			v.UltimaFechaVenta,
+			v.PrecioPromedioPonderadoCLP,
+			v.PrecioMinimoCLP,
+			v.PrecioMaximoCLP,
+			v.CantidadDeVentas,
+			v.UnidadesBoleta,
+			v.UnidadesFactura,
+			v.VentasBoletaCLP,
+			v.VentasFacturaCLP,
+			v.DocumentosBoleta,
+			v.DocumentosFactura,
+			v.DocumentosNulos,
+			v.UnidadesNulas,
+		}
+		for _, suc := range sucursales {
+			venta, _ := ventaEnSucursal(v.VentasPorSucursal, suc)
+			fila = append(fila, venta.CantidadTotalVendida, venta.TotalVentasCLP)
+		}
+		hoja.Filas = append(hoja.Filas, fila)
+	}
+	return hoja
+}
+
 // generateVentasFilename genera un nombre de archivo para el reporte de ventas
 func generateVentasFilename(filtro models.VentasFiltro) string {
-	base := "Ventas_Sucursal_" + string(filtro.Sucursal) + "_" + filtro.FechaInicio + "_al_" + filtro.FechaFin
+	base := "Ventas_" + nombreSucursales(filtro) + "_" + filtro.FechaInicio + "_al_" + filtro.FechaFin
 	if filtro.CodigoProducto != "" {
 		base += "_Producto_" + filtro.CodigoProducto
 	}
+	if len(filtro.Codigos) > 0 {
+		base += fmt.Sprintf("_Lista_%d_Codigos", len(filtro.Codigos))
+	}
 	return base + ".xlsx"
 }
+
+// nombreSucursales describe las sucursales del filtro para usarlas en nombres de archivo
+func nombreSucursales(filtro models.VentasFiltro) string {
+	if filtro.TodasLasSucursales() {
+		return "Todas_las_Sucursales"
+	}
+	if len(filtro.Sucursales) == 1 {
+		return "Sucursal_" + strconv.Itoa(filtro.Sucursales[0])
+	}
+	return "Sucursales_" + strings.ReplaceAll(filtro.SucursalesTexto(), ",", "-")
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pablojnd/rotacion/datasource"
+	"github.com/pablojnd/rotacion/models"
+)
+
+// lineaDocumento crea una línea de un documento de la sucursal 211 cuyo total es totalDocumento
+func lineaDocumento(tipo, documento, codigo string, cantidad float64, total, totalDocumento int) datasource.LineaVentaFixture {
+	return datasource.LineaVentaFixture{
+		Fecha:             "2025-01-05",
+		Sucursal:          211,
+		TipoDocumento:     tipo,
+		CodigoDocumento:   documento,
+		CodigoProducto:    codigo,
+		PrecioVentaCLP:    total / int(cantidad),
+		Cantidad:          cantidad,
+		TotalProductoCLP:  total,
+		TotalDocumentoCLP: totalDocumento,
+	}
+}
+
+func TestGetVentasAgrupadasTotales(t *testing.T) {
+	nula := lineaDocumento(models.TipoDocumentoBoleta, "B3", "PISO", 1, 500, 500)
+	nula.Nula = true
+	ventas := []datasource.LineaVentaFixture{
+		// Boleta de $3.000 con dos productos
+		lineaDocumento(models.TipoDocumentoBoleta, "B1", "PISO", 2, 2000, 3000),
+		lineaDocumento(models.TipoDocumentoBoleta, "B1", "MURO", 1, 1000, 3000),
+		lineaDocumento(models.TipoDocumentoFactura, "F1", "PISO", 4, 4000, 4000),
+		nula,
+	}
+	s := NewVentasService(datasource.NewFixturesFromData(ventas, nil), NewExcelService())
+
+	agrupadas, err := s.GetVentasAgrupadas(models.VentasFiltro{FechaInicio: "2025-01-01", FechaFin: "2025-01-10", Sucursales: []int{211}})
+	if err != nil {
+		t.Fatalf("GetVentasAgrupadas: %v", err)
+	}
+
+	obtenidos := make(map[string]string)
+	for _, v := range agrupadas {
+		obtenidos[v.CodigoProducto] = fmt.Sprintf("total %d líneas %d boleta %d factura %d",
+			v.TotalVentasCLP, v.TotalLineasCLP, v.VentasBoletaCLP, v.VentasFacturaCLP)
+	}
+	esperados := map[string]string{
+		// TOTAL_VENTAS conserva la suma del total del documento de cada línea: la boleta B1 se
+		// acredita completa a PISO y a MURO. El desglose por tipo suma el total de las líneas.
+		"PISO": "total 7000 líneas 6000 boleta 2000 factura 4000",
+		"MURO": "total 3000 líneas 1000 boleta 1000 factura 0",
+	}
+	if len(obtenidos) != len(esperados) {
+		t.Errorf("productos = %v, se esperaba %v", obtenidos, esperados)
+	}
+	for codigo, esperado := range esperados {
+		if obtenidos[codigo] != esperado {
+			t.Errorf("%s = %q, se esperaba %q", codigo, obtenidos[codigo], esperado)
+		}
+	}
+}
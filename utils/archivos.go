@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/pablojnd/rotacion/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// LeerTablaArchivo lee un archivo CSV o XLSX subido por el usuario y devuelve sus filas.
+// El formato se determina por la extensión del nombre; en XLSX se usa la primera hoja.
+// En CSV se acepta coma o punto y coma como separador.
+func LeerTablaArchivo(r io.Reader, nombre string) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(nombre)) {
+	case ".xlsx", ".xlsm":
+		return leerTablaXLSX(r)
+	case ".csv", ".txt":
+		return leerTablaCSV(r)
+	default:
+		return nil, fmt.Errorf("formato de archivo no soportado: %q (use .xlsx o .csv)", nombre)
+	}
+}
+
+// leerTablaXLSX lee las filas de la primera hoja de un libro Excel
+func leerTablaXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir archivo Excel: %v", err)
+	}
+	defer f.Close()
+
+	hojas := f.GetSheetList()
+	if len(hojas) == 0 {
+		return nil, fmt.Errorf("el archivo Excel no contiene hojas")
+	}
+
+	filas, err := f.GetRows(hojas[0])
+	if err != nil {
+		return nil, fmt.Errorf("error al leer hoja %q: %v", hojas[0], err)
+	}
+	return filas, nil
+}
+
+// leerTablaCSV lee un CSV detectando el separador en la primera línea
+func leerTablaCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer archivo CSV: %v", err)
+	}
+	// Quitar BOM de UTF-8 que agrega Excel al guardar como CSV
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	primeraLinea := string(data)
+	if i := strings.IndexByte(primeraLinea, '\n'); i >= 0 {
+		primeraLinea = primeraLinea[:i]
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	if strings.Count(primeraLinea, ";") > strings.Count(primeraLinea, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	filas, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error al leer archivo CSV: %v", err)
+	}
+	return filas, nil
+}
+
+// BuscarColumna devuelve el índice del primer encabezado que coincide con alguno
+// de los nombres indicados (sin distinguir mayúsculas, acentos, espacios ni guiones bajos), o -1
+func BuscarColumna(encabezados []string, nombres ...string) int {
+	for _, nombre := range nombres {
+		buscado := normalizarEncabezado(nombre)
+		for i, encabezado := range encabezados {
+			if normalizarEncabezado(encabezado) == buscado {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// normalizarEncabezado simplifica un encabezado para compararlo
+func normalizarEncabezado(encabezado string) string {
+	encabezado = QuitarAcentos(strings.ToLower(strings.TrimSpace(encabezado)))
+	return strings.NewReplacer(" ", "", "_", "", "-", "", ".", "").Replace(encabezado)
+}
+
+// reemplazoAcentos reemplaza las vocales acentuadas y la eñe por su letra base
+var reemplazoAcentos = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"Á", "A", "É", "E", "Í", "I", "Ó", "O", "Ú", "U", "Ü", "U", "Ñ", "N",
+)
+
+// QuitarAcentos reemplaza las vocales acentuadas y la eñe de un texto por su letra base
+func QuitarAcentos(texto string) string {
+	return reemplazoAcentos.Replace(texto)
+}
+
+// ValorCelda devuelve el valor de la columna indicada o vacío si la fila es más corta
+func ValorCelda(fila []string, indice int) string {
+	if indice < 0 || indice >= len(fila) {
+		return ""
+	}
+	return strings.TrimSpace(fila[indice])
+}
+
+// EncabezadoFilaOmitida es el encabezado HTTP de la respuesta que informa la primera fila de un
+// archivo de códigos de una columna que se descartó por parecer un encabezado
+const EncabezadoFilaOmitida = "X-Fila-Omitida"
+
+// LeerCodigosSolicitud obtiene la lista de códigos de una consulta POST: un JSON
+// {"codigos": [...]} o un archivo XLSX/CSV enviado como multipart/form-data en el campo
+// "archivo". La lista se normaliza con models.NormalizarCodigos. Si se descarta la primera
+// fila de un archivo por parecer un encabezado, se informa en EncabezadoFilaOmitida.
+func LeerCodigosSolicitud(w http.ResponseWriter, r *http.Request, tamanoMaximo int64) ([]string, error) {
+	var codigos []string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(tamanoMaximo); err != nil {
+			return nil, fmt.Errorf("error al leer el formulario: %v", err)
+		}
+		archivo, header, err := r.FormFile("archivo")
+		if err != nil {
+			return nil, errors.New("debe enviar el archivo en el campo 'archivo'")
+		}
+		defer archivo.Close()
+
+		var omitida string
+		if codigos, omitida, err = LeerCodigosArchivo(archivo, header.Filename); err != nil {
+			return nil, err
+		}
+		if omitida != "" {
+			w.Header().Set(EncabezadoFilaOmitida, omitida)
+		}
+	} else {
+		var cuerpo struct {
+			Codigos []string `json:"codigos"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, tamanoMaximo)).Decode(&cuerpo); err != nil {
+			return nil, fmt.Errorf("error al leer la lista de códigos: %v", err)
+		}
+		codigos = cuerpo.Codigos
+	}
+	return models.NormalizarCodigos(codigos)
+}
+
+// encabezadosCodigo son los encabezados reconocidos de la columna de códigos
+var encabezadosCodigo = []string{"codigo", "codigoProducto", "codigo de producto", "COD_ART",
+	"CODIGO_INTERNO", "codigoInventario", "codigoVentas", "sku"}
+
+// LeerCodigosArchivo lee los códigos de producto de un archivo XLSX o CSV: la columna con
+// encabezado CODIGO (o Código de Producto, COD_ART, CODIGO_INTERNO, SKU), sin la fila de
+// encabezados. Sin encabezado reconocido solo se acepta un archivo de una columna, que se lee
+// desde la primera fila salvo que esta sea un encabezado (ver pareceEncabezadoCodigo); en ese
+// caso también devuelve el valor descartado.
+func LeerCodigosArchivo(r io.Reader, nombre string) ([]string, string, error) {
+	filas, err := LeerTablaArchivo(r, nombre)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(filas) == 0 {
+		return nil, "", errors.New("el archivo está vacío")
+	}
+
+	columna := BuscarColumna(filas[0], encabezadosCodigo...)
+	desde := 1
+	omitida := ""
+	if columna < 0 {
+		if columnasConValor(filas[0]) > 1 {
+			return nil, "", errors.New("no se encontró la columna de códigos: use el encabezado CODIGO, COD_ART, CODIGO_INTERNO o SKU")
+		}
+		columna = 0
+		if primera := ValorCelda(filas[0], 0); pareceEncabezadoCodigo(primera) {
+			omitida = primera
+		} else {
+			desde = 0
+		}
+	}
+
+	codigos := make([]string, 0, len(filas)-desde)
+	for _, fila := range filas[desde:] {
+		codigos = append(codigos, ValorCelda(fila, columna))
+	}
+	return codigos, omitida, nil
+}
+
+// columnasConValor cuenta las celdas no vacías de una fila
+func columnasConValor(fila []string) int {
+	n := 0
+	for i := range fila {
+		if ValorCelda(fila, i) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// palabrasEncabezadoCodigo son otros encabezados de una columna de códigos, normalizados
+var palabrasEncabezadoCodigo = []string{"codigos", "producto", "productos", "articulo", "articulos", "skus"}
+
+// pareceEncabezadoCodigo indica si la primera celda de un archivo de una columna es un
+// encabezado en vez de un código: un encabezado conocido o un texto con espacios o acentos
+// (p. ej. "Códigos a consultar"), que no aparecen en los códigos. "SKU-1001" es un código.
+func pareceEncabezadoCodigo(valor string) bool {
+	if strings.ContainsAny(valor, " \t") || QuitarAcentos(valor) != valor {
+		return true
+	}
+	normalizado := normalizarEncabezado(valor)
+	for _, palabra := range append(encabezadosCodigo, palabrasEncabezadoCodigo...) {
+		if normalizado == normalizarEncabezado(palabra) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLeerCodigosArchivo(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		csv      string
+		codigos  string
+		omitida  string
+		conError bool
+	}{
+		{nombre: "encabezado reconocido", csv: "Código de Producto\nA-1\nB-2\n", codigos: "A-1,B-2"},
+		{nombre: "columna de códigos entre varias", csv: "NOMBRE;SKU\nPiso;A-1\nMuro;B-2\n", codigos: "A-1,B-2"},
+		{nombre: "una columna sin encabezado", csv: "A-1\nB-2\n", codigos: "A-1,B-2"},
+		// Antes se descartaba por contener "sku"
+		{nombre: "código que contiene una palabra de encabezado", csv: "SKU-1001\nSKU-1002\n", codigos: "SKU-1001,SKU-1002"},
+		{nombre: "código PRODUCTO1", csv: "PRODUCTO1\nB-2\n", codigos: "PRODUCTO1,B-2"},
+		{nombre: "encabezado con espacios y acentos", csv: "Códigos a consultar\nA-1\n", codigos: "A-1", omitida: "Códigos a consultar"},
+		{nombre: "encabezado conocido en plural", csv: "Productos\nA-1\n", codigos: "A-1", omitida: "Productos"},
+		{nombre: "encabezado con acento", csv: "Artículo\nA-1\n", codigos: "A-1", omitida: "Artículo"},
+		{nombre: "varias columnas sin encabezado reconocido", csv: "A-1;Piso\nB-2;Muro\n", conError: true},
+		{nombre: "vacío", csv: "", conError: true},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			codigos, omitida, err := LeerCodigosArchivo(strings.NewReader(c.csv), "codigos.csv")
+			if c.conError {
+				if err == nil {
+					t.Errorf("LeerCodigosArchivo = %v, se esperaba error", codigos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LeerCodigosArchivo: %v", err)
+			}
+			if strings.Join(codigos, ",") != c.codigos || omitida != c.omitida {
+				t.Errorf("LeerCodigosArchivo = %v omitiendo %q, se esperaba %s omitiendo %q", codigos, omitida, c.codigos, c.omitida)
+			}
+		})
+	}
+}
+
+func TestLeerCodigosSolicitudInformaFilaOmitida(t *testing.T) {
+	var cuerpo bytes.Buffer
+	formulario := multipart.NewWriter(&cuerpo)
+	parte, err := formulario.CreateFormFile("archivo", "codigos.csv")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	parte.Write([]byte("Lista de productos\nA-1\nA-1\nB-2\n"))
+	formulario.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/reporte/combinado", &cuerpo)
+	r.Header.Set("Content-Type", formulario.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	codigos, err := LeerCodigosSolicitud(w, r, 1<<20)
+	if err != nil {
+		t.Fatalf("LeerCodigosSolicitud: %v", err)
+	}
+	if strings.Join(codigos, ",") != "A-1,B-2" {
+		t.Errorf("códigos = %v, se esperaba [A-1 B-2]", codigos)
+	}
+	if omitida := w.Header().Get(EncabezadoFilaOmitida); omitida != "Lista de productos" {
+		t.Errorf("%s = %q, se esperaba %q", EncabezadoFilaOmitida, omitida, "Lista de productos")
+	}
+}
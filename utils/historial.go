@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pablojnd/rotacion/models"
+)
+
+// HistorialIngresos es el resultado de interpretar la columna "Historial de Ingresos (JSON)"
+type HistorialIngresos struct {
+	Ingresos     []models.MetadatoProducto
+	Truncado     bool     // El texto terminó antes de cerrar el último ingreso (GROUP_CONCAT truncado)
+	Advertencias []string // Problemas encontrados; los ingresos inválidos se omiten
+}
+
+// ParseHistorialIngresos interpreta el historial de ingresos que MySQL arma con
+// GROUP_CONCAT en un formato parecido a JSON con comillas simples y sin escapar:
+//
+//	[{'Zeta':'Z1001','Año Producción':'2025','Unidades Ingresadas':240,'Fecha Ingreso':'2025-01-08'}]
+//
+// Acepta comillas simples o dobles, secuencias de escape con barra invertida y
+// apóstrofes dentro de los valores (ver cierraTexto). Si el texto se corta antes de
+// cerrar un ingreso, como ocurre al superar group_concat_max_len, marca el historial
+// como truncado y conserva los ingresos completos. esperados es la cantidad de ingresos
+// distintos del grupo (COUNT(DISTINCT ...) sobre la misma expresión que el historial); si
+// se interpretan menos, el corte cayó justo entre dos ingresos y el historial también se
+// marca como truncado (0 no compara). Los ingresos se devuelven ordenados por fecha de
+// ingreso.
+func ParseHistorialIngresos(texto string, esperados int) HistorialIngresos {
+	var resultado HistorialIngresos
+
+	texto = strings.TrimSpace(texto)
+	if texto == "" || texto == "null" {
+		return resultado
+	}
+
+	p := &parserHistorial{texto: []rune(texto)}
+	objetos, err := p.lista()
+	if err == errHistorialTruncado {
+		resultado.Truncado = true
+		resultado.Advertencias = append(resultado.Advertencias,
+			fmt.Sprintf("historial truncado: solo se interpretaron %d ingresos completos", len(objetos)))
+	} else if err != nil {
+		resultado.Advertencias = append(resultado.Advertencias, err.Error())
+	} else if esperados > 0 && len(objetos) < esperados {
+		resultado.Truncado = true
+		resultado.Advertencias = append(resultado.Advertencias,
+			fmt.Sprintf("historial truncado: se interpretaron %d de %d ingresos", len(objetos), esperados))
+	}
+
+	for i, objeto := range objetos {
+		ingreso, err := ingresoDesdeObjeto(objeto)
+		if err != nil {
+			resultado.Advertencias = append(resultado.Advertencias, fmt.Sprintf("ingreso %d omitido: %v", i+1, err))
+			continue
+		}
+		resultado.Ingresos = append(resultado.Ingresos, ingreso)
+	}
+
+	OrdenarIngresos(resultado.Ingresos)
+	return resultado
+}
+
+// OrdenarIngresos ordena los ingresos del más antiguo al más reciente
+func OrdenarIngresos(ingresos []models.MetadatoProducto) {
+	sort.SliceStable(ingresos, func(i, j int) bool {
+		return ingresos[i].FechaIngreso.Before(ingresos[j].FechaIngreso)
+	})
+}
+
+// ingresoDesdeObjeto convierte los campos de un ingreso a MetadatoProducto
+func ingresoDesdeObjeto(objeto map[string]string) (models.MetadatoProducto, error) {
+	ingreso := models.MetadatoProducto{
+		Zeta:           objeto["Zeta"],
+		AnioProduccion: objeto["Año Producción"],
+	}
+
+	unidades, ok := objeto["Unidades Ingresadas"]
+	if !ok {
+		return ingreso, fmt.Errorf("falta 'Unidades Ingresadas'")
+	}
+	valor, err := strconv.ParseFloat(strings.TrimSpace(unidades), 64)
+	if err != nil {
+		return ingreso, fmt.Errorf("unidades no numéricas %q", unidades)
+	}
+	ingreso.UnidadesIngresadas = valor
+
+	if fecha := strings.TrimSpace(objeto["Fecha Ingreso"]); fecha != "" {
+		t, err := time.Parse("2006-01-02", fecha)
+		if err != nil {
+			return ingreso, fmt.Errorf("fecha de ingreso no válida %q", fecha)
+		}
+		ingreso.FechaIngreso = t
+	}
+
+	return ingreso, nil
+}
+
+// errHistorialTruncado indica que el texto terminó en medio de un ingreso
+var errHistorialTruncado = errors.New("historial truncado")
+
+// parserHistorial recorre el texto del historial carácter a carácter
+type parserHistorial struct {
+	texto []rune
+	pos   int
+}
+
+// fin indica si se llegó al final del texto
+func (p *parserHistorial) fin() bool {
+	return p.pos >= len(p.texto)
+}
+
+// saltarEspacios avanza sobre espacios en blanco
+func (p *parserHistorial) saltarEspacios() {
+	for !p.fin() && strings.ContainsRune(" \t\r\n", p.texto[p.pos]) {
+		p.pos++
+	}
+}
+
+// siguiente devuelve la posición y el próximo carácter no blanco desde la posición indicada,
+// o 0 al final
+func (p *parserHistorial) siguiente(desde int) (int, rune) {
+	for i := desde; i < len(p.texto); i++ {
+		if !strings.ContainsRune(" \t\r\n", p.texto[i]) {
+			return i, p.texto[i]
+		}
+	}
+	return len(p.texto), 0
+}
+
+// lista interpreta [ objeto, objeto, ... ]
+func (p *parserHistorial) lista() ([]map[string]string, error) {
+	var objetos []map[string]string
+
+	p.saltarEspacios()
+	if p.fin() || p.texto[p.pos] != '[' {
+		return nil, fmt.Errorf("historial no válido: se esperaba '[' al inicio")
+	}
+	p.pos++
+
+	for {
+		p.saltarEspacios()
+		if p.fin() {
+			return objetos, errHistorialTruncado
+		}
+
+		switch p.texto[p.pos] {
+		case ']':
+			p.pos++
+			if p.saltarEspacios(); !p.fin() {
+				return objetos, fmt.Errorf("historial con texto adicional en la posición %d", p.pos)
+			}
+			return objetos, nil
+		case ',':
+			// Un separador seguido del cierre es un GROUP_CONCAT cortado justo después de un ingreso
+			if _, c := p.siguiente(p.pos + 1); c == ']' {
+				return objetos, errHistorialTruncado
+			}
+			p.pos++
+			continue
+		case '{':
+			objeto, err := p.objeto()
+			if err != nil {
+				return objetos, err
+			}
+			objetos = append(objetos, objeto)
+		default:
+			return objetos, fmt.Errorf("historial no válido: carácter inesperado %q en la posición %d", p.texto[p.pos], p.pos)
+		}
+	}
+}
+
+// objeto interpreta { 'clave': valor, ... }
+func (p *parserHistorial) objeto() (map[string]string, error) {
+	objeto := make(map[string]string)
+	p.pos++ // '{'
+
+	for {
+		p.saltarEspacios()
+		if p.fin() {
+			return nil, errHistorialTruncado
+		}
+
+		switch p.texto[p.pos] {
+		case '}':
+			p.pos++
+			return objeto, nil
+		case ',':
+			p.pos++
+			continue
+		case ']':
+			// CONCAT agrega ']' después de un GROUP_CONCAT cortado a mitad de un ingreso
+			return nil, errHistorialTruncado
+		}
+
+		clave, err := p.valor(true)
+		if err != nil {
+			return nil, err
+		}
+
+		p.saltarEspacios()
+		if p.fin() {
+			return nil, errHistorialTruncado
+		}
+		if p.texto[p.pos] != ':' {
+			return nil, fmt.Errorf("historial no válido: se esperaba ':' después de %q", clave)
+		}
+		p.pos++
+
+		p.saltarEspacios()
+		valor, err := p.valor(false)
+		if err != nil {
+			return nil, err
+		}
+		objeto[clave] = valor
+	}
+}
+
+// valor interpreta un texto entre comillas o un valor sin comillas (número, null); esClave
+// indica si es la clave de un campo, que solo puede terminar antes de ':'
+func (p *parserHistorial) valor(esClave bool) (string, error) {
+	if p.fin() {
+		return "", errHistorialTruncado
+	}
+
+	comilla := p.texto[p.pos]
+	if comilla != '\'' && comilla != '"' {
+		inicio := p.pos
+		for !p.fin() && !strings.ContainsRune(",:}] \t\r\n", p.texto[p.pos]) {
+			p.pos++
+		}
+		if p.fin() {
+			return "", errHistorialTruncado
+		}
+		return string(p.texto[inicio:p.pos]), nil
+	}
+	p.pos++
+
+	var sb strings.Builder
+	for !p.fin() {
+		c := p.texto[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.texto):
+			p.pos++
+			switch p.texto[p.pos] {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			default:
+				sb.WriteRune(p.texto[p.pos])
+			}
+		case c == comilla:
+			if _, d := p.siguiente(p.pos + 1); d == 0 {
+				p.pos++
+				return sb.String(), errHistorialTruncado
+			}
+			if p.cierraTexto(p.pos, comilla, esClave) {
+				p.pos++
+				return sb.String(), nil
+			}
+			sb.WriteRune(c)
+		default:
+			sb.WriteRune(c)
+		}
+		p.pos++
+	}
+
+	return "", errHistorialTruncado
+}
+
+// cierraTexto indica si la comilla en la posición i cierra el texto según lo que le sigue:
+// una clave termina antes de ':' y un valor antes de '}' (seguida de ',', ']' o el final), de
+// ',' seguida de la comilla de la próxima clave, o de ']' al final del texto (GROUP_CONCAT
+// cortado). Cualquier otra comilla es parte del valor, como en 'D'Angelo, S.A.'.
+func (p *parserHistorial) cierraTexto(i int, comilla rune, esClave bool) bool {
+	j, c := p.siguiente(i + 1)
+	if esClave {
+		return c == ':'
+	}
+
+	_, d := p.siguiente(j + 1)
+	switch c {
+	case '}':
+		return d == ',' || d == ']' || d == 0
+	case ',':
+		return d == comilla || d == 0
+	case ']':
+		return d == 0
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHistorialIngresos(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		texto     string
+		esperados int
+		zetas     []string
+		truncado  bool
+		avisos    int
+	}{
+		{
+			nombre: "vacio",
+			texto:  "",
+		},
+		{
+			nombre:    "dos ingresos ordenados por fecha",
+			texto:     "[{'Zeta':'Z2','Año Producción':'2025','Unidades Ingresadas':120,'Fecha Ingreso':'2025-04-20'},{'Zeta':'Z1','Año Producción':'2025','Unidades Ingresadas':240,'Fecha Ingreso':'2025-01-08'}]",
+			esperados: 2,
+			zetas:     []string{"Z1", "Z2"},
+		},
+		{
+			nombre: "comillas dobles y espacios",
+			texto:  `[ { "Zeta" : "Z1" , "Año Producción" : "2025" , "Unidades Ingresadas" : 10 , "Fecha Ingreso" : "2025-01-08" } ]`,
+			zetas:  []string{"Z1"},
+		},
+		{
+			nombre: "apostrofe dentro del valor",
+			texto:  "[{'Zeta':'D'Angelo','Año Producción':'2025','Unidades Ingresadas':5,'Fecha Ingreso':'2025-01-08'}]",
+			zetas:  []string{"D'Angelo"},
+		},
+		{
+			nombre: "comilla seguida de separador dentro del valor",
+			texto:  "[{'Zeta':'Lote 'A', norte','Año Producción':'2025','Unidades Ingresadas':5,'Fecha Ingreso':'2025-01-08'}]",
+			zetas:  []string{"Lote 'A', norte"},
+		},
+		{
+			nombre: "comilla seguida de dos puntos dentro del valor",
+			texto:  "[{'Zeta':'Lote 'B': sur','Año Producción':'2025','Unidades Ingresadas':5,'Fecha Ingreso':'2025-01-08'}]",
+			zetas:  []string{"Lote 'B': sur"},
+		},
+		{
+			nombre: "comilla escapada",
+			texto:  `[{'Zeta':'O\'Neil','Año Producción':'2025','Unidades Ingresadas':5,'Fecha Ingreso':'2025-01-08'}]`,
+			zetas:  []string{"O'Neil"},
+		},
+		{
+			nombre:    "truncado a mitad de un ingreso",
+			texto:     "[{'Zeta':'Z1','Año Producción':'2025','Unidades Ingresadas':240,'Fecha Ingreso':'2025-01-08'},{'Zeta':'Z2','Año Prod]",
+			esperados: 2,
+			zetas:     []string{"Z1"},
+			truncado:  true,
+			avisos:    1,
+		},
+		{
+			nombre:   "truncado sin cierre",
+			texto:    "[{'Zeta':'Z1','Año Producción':'2025','Unidades Ingresadas':240,'Fecha Ingreso':'2025-01-08'},{'Zeta':'Z2'",
+			zetas:    []string{"Z1"},
+			truncado: true,
+			avisos:   1,
+		},
+		{
+			nombre:    "truncado justo después del separador",
+			texto:     "[{'Zeta':'Z1','Año Producción':'2025','Unidades Ingresadas':240,'Fecha Ingreso':'2025-01-08'},]",
+			esperados: 2,
+			zetas:     []string{"Z1"},
+			truncado:  true,
+			avisos:    1,
+		},
+		{
+			nombre:    "truncado justo después de un ingreso",
+			texto:     "[{'Zeta':'Z1','Año Producción':'2025','Unidades Ingresadas':240,'Fecha Ingreso':'2025-01-08'}]",
+			esperados: 3,
+			zetas:     []string{"Z1"},
+			truncado:  true,
+			avisos:    1,
+		},
+		{
+			nombre: "ingreso invalido omitido",
+			texto:  "[{'Zeta':'Z1','Año Producción':'2025','Unidades Ingresadas':'abc','Fecha Ingreso':'2025-01-08'},{'Zeta':'Z2','Año Producción':'2025','Unidades Ingresadas':3,'Fecha Ingreso':'2025-02-01'}]",
+			zetas:  []string{"Z2"},
+			avisos: 1,
+		},
+		{
+			nombre: "texto adicional",
+			texto:  "[{'Zeta':'Z1','Año Producción':'2025','Unidades Ingresadas':3,'Fecha Ingreso':'2025-02-01'}] x",
+			zetas:  []string{"Z1"},
+			avisos: 1,
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			h := ParseHistorialIngresos(c.texto, c.esperados)
+			var zetas []string
+			for _, ing := range h.Ingresos {
+				zetas = append(zetas, ing.Zeta)
+			}
+			if strings.Join(zetas, "|") != strings.Join(c.zetas, "|") {
+				t.Errorf("zetas = %q, se esperaba %q", zetas, c.zetas)
+			}
+			if h.Truncado != c.truncado {
+				t.Errorf("truncado = %v, se esperaba %v", h.Truncado, c.truncado)
+			}
+			if len(h.Advertencias) != c.avisos {
+				t.Errorf("advertencias = %q, se esperaban %d", h.Advertencias, c.avisos)
+			}
+		})
+	}
+}
@@ -8,21 +8,17 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/pablojnd/rotacion/db"
 	"github.com/xuri/excelize/v2"
 )
 
 // RowsToJSON convierte filas SQL a un slice de mapas
-func RowsToJSON(rows *sql.Rows) ([]map[string]interface{}, error) {
+func RowsToJSON(rows db.Rows) ([]map[string]interface{}, error) {
 	columns, err := rows.Columns()
 	if err != nil {
 		return nil, err
 	}
 
-	columnTypes, err := rows.ColumnTypes()
-	if err != nil {
-		return nil, err
-	}
-
 	count := len(columns)
 	result := make([]map[string]interface{}, 0)
 
@@ -42,31 +38,18 @@ func RowsToJSON(rows *sql.Rows) ([]map[string]interface{}, error) {
 		for i, column := range columns {
 			value := values[i]
 
-			// Convertir tipos especiales de SQL al formato adecuado
-			if value != nil {
-				switch columnTypes[i].DatabaseTypeName() {
-				case "DECIMAL", "NUMERIC", "MONEY", "SMALLMONEY":
-					if v, ok := value.([]byte); ok {
-						row[column] = string(v)
-					} else {
-						row[column] = value
-					}
-				default:
-					if b, ok := value.([]byte); ok {
-						row[column] = string(b)
-					} else {
-						row[column] = value
-					}
-				}
+			// Convertir tipos especiales de SQL (DECIMAL, NUMERIC, MONEY, texto) al formato adecuado
+			if b, ok := value.([]byte); ok {
+				row[column] = string(b)
 			} else {
-				row[column] = nil
+				row[column] = value
 			}
 		}
 
 		result = append(result, row)
 	}
 
-	return result, nil
+	return result, rows.Err()
 }
 
 // GenerateExcel genera un archivo Excel a partir de filas SQL